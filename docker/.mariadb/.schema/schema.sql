@@ -1,3 +1,28 @@
+CREATE TABLE IF NOT EXISTS users (
+    id              VARCHAR(36) NOT NULL,
+    username        VARCHAR(64) NOT NULL,
+    password_hash   CHAR(60) NOT NULL,
+    created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    CONSTRAINT pk PRIMARY KEY (id),
+    CONSTRAINT unique_username UNIQUE (username)
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id              VARCHAR(36) NOT NULL,
+    name            VARCHAR(64) NOT NULL,
+    prefix          VARCHAR(16) NOT NULL,
+    key_hash        CHAR(64) NOT NULL,
+    scopes          VARCHAR(255) NOT NULL,
+    created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at    DATETIME DEFAULT NULL,
+    revoked_at      DATETIME DEFAULT NULL,
+
+    CONSTRAINT pk PRIMARY KEY (id),
+    CONSTRAINT unique_key_hash UNIQUE (key_hash)
+);
+
 CREATE TABLE IF NOT EXISTS videos (
     id              VARCHAR(36) NOT NULL,
     title           VARCHAR(64) NOT NULL,
@@ -7,9 +32,113 @@ CREATE TABLE IF NOT EXISTS videos (
     updated_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     source_path     VARCHAR(64) NOT NULL,
     cover_path      VARCHAR(64),
+    source_hash     CHAR(64),
+    description     VARCHAR(2048) NOT NULL DEFAULT '',
+    owner_id        VARCHAR(36),
+    archived_at     DATETIME,
 
     CONSTRAINT pk PRIMARY KEY (id),
-    CONSTRAINT unique_title UNIQUE (title)
+    CONSTRAINT unique_title UNIQUE (title),
+    CONSTRAINT unique_source_hash UNIQUE (source_hash),
+    CONSTRAINT fk_v_owner_id FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE SET NULL,
+    FULLTEXT INDEX ft_title_description (title, description)
+);
+
+CREATE TABLE IF NOT EXISTS video_tags (
+    video_id        VARCHAR(36) NOT NULL,
+    tag             VARCHAR(32) NOT NULL,
+
+    CONSTRAINT pk PRIMARY KEY (video_id, tag),
+    CONSTRAINT fk_t_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE,
+    FULLTEXT INDEX ft_tag (tag)
+);
+
+CREATE TABLE IF NOT EXISTS playlists (
+    id              VARCHAR(36) NOT NULL,
+    title           VARCHAR(64) NOT NULL,
+    description     VARCHAR(2048) NOT NULL DEFAULT '',
+    owner_id        VARCHAR(36),
+    created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    CONSTRAINT pk PRIMARY KEY (id),
+    CONSTRAINT fk_p_owner_id FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS playlist_videos (
+    playlist_id     VARCHAR(36) NOT NULL,
+    video_id        VARCHAR(36) NOT NULL,
+    position        INT NOT NULL,
+    added_at        DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT pk PRIMARY KEY (playlist_id, video_id),
+    CONSTRAINT fk_pv_p_id FOREIGN KEY (playlist_id) REFERENCES playlists (id) ON DELETE CASCADE,
+    CONSTRAINT fk_pv_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE,
+    INDEX idx_playlist_position (playlist_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS playback_sessions (
+    id                  VARCHAR(36) NOT NULL,
+    video_id            VARCHAR(36) NOT NULL,
+    viewer              VARCHAR(128) NOT NULL,
+    segments_total      INT NOT NULL DEFAULT 0,
+    duration            DOUBLE NOT NULL DEFAULT 0,
+    segments_watched    INT NOT NULL DEFAULT 0,
+    started_at          DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at          DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    CONSTRAINT pk PRIMARY KEY (id),
+    CONSTRAINT fk_ps_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE,
+    INDEX idx_video_watched (video_id, segments_watched)
+);
+
+CREATE TABLE IF NOT EXISTS playback_segments (
+    session_id      VARCHAR(36) NOT NULL,
+    segment         INT NOT NULL,
+    watched_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT pk PRIMARY KEY (session_id, segment),
+    CONSTRAINT fk_psg_ps_id FOREIGN KEY (session_id) REFERENCES playback_sessions (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS watch_history (
+    user_id         VARCHAR(36) NOT NULL,
+    video_id        VARCHAR(36) NOT NULL,
+    position        DOUBLE NOT NULL DEFAULT 0,
+    watched_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT pk PRIMARY KEY (user_id, video_id),
+    CONSTRAINT fk_wh_u_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_wh_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE,
+    INDEX idx_user_watched_at (user_id, watched_at)
+);
+
+CREATE TABLE IF NOT EXISTS subtitles (
+    video_id        VARCHAR(36) NOT NULL,
+    language        VARCHAR(35) NOT NULL,
+    label           VARCHAR(64) NOT NULL,
+    created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT pk PRIMARY KEY (video_id, language),
+    CONSTRAINT fk_st_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS video_audio_languages (
+    video_id        VARCHAR(36) NOT NULL,
+    language        VARCHAR(8) NOT NULL,
+    position        INT NOT NULL,
+
+    CONSTRAINT pk PRIMARY KEY (video_id, language),
+    CONSTRAINT fk_al_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS chapters (
+    video_id        VARCHAR(36) NOT NULL,
+    start_time      DOUBLE NOT NULL,
+    title           VARCHAR(128) NOT NULL,
+
+    CONSTRAINT pk PRIMARY KEY (video_id, start_time),
+    CONSTRAINT fk_c_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE
 );
 
 CREATE TABLE IF NOT EXISTS uploads (
@@ -19,6 +148,11 @@ CREATE TABLE IF NOT EXISTS uploads (
     uploaded_at     DATETIME,
     created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    upload_length   BIGINT NOT NULL DEFAULT 0,
+    upload_offset   BIGINT NOT NULL DEFAULT 0,
+    upload_parts    INT NOT NULL DEFAULT 0,
+    s3_upload_id    VARCHAR(1024) NOT NULL DEFAULT '',
+    upload_progress INT NOT NULL DEFAULT 0,
 
     CONSTRAINT pk PRIMARY KEY (id),
     CONSTRAINT fk_v_id FOREIGN KEY (video_id) REFERENCES videos (id)
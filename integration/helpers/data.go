@@ -7,13 +7,20 @@ type VideoListResponse struct {
 	LastPage int         `json:"_lastpage"`
 }
 
+type VideoPageResponse struct {
+	Videos []VideoInfo `json:"videos"`
+	Total  int         `json:"_total"`
+}
+
 type VideoJson struct {
-	ID         string     `json:"id" example:"aaaa-b56b-..."`
-	Title      string     `json:"title" example:"A Title"`
-	Status     string     `json:"status" example:"VIDEO_STATUS_ENCODING"`
-	UploadedAt *time.Time `json:"uploadedAt" example:"2022-04-15T12:59:52Z"`
-	CreatedAt  *time.Time `json:"createdAt" example:"2022-04-15T12:59:52Z"`
-	UpdatedAt  *time.Time `json:"updatedAt" example:"2022-04-15T12:59:52Z"`
+	ID          string     `json:"id" example:"aaaa-b56b-..."`
+	Title       string     `json:"title" example:"A Title"`
+	Status      string     `json:"status" example:"VIDEO_STATUS_ENCODING"`
+	UploadedAt  *time.Time `json:"uploadedAt" example:"2022-04-15T12:59:52Z"`
+	CreatedAt   *time.Time `json:"createdAt" example:"2022-04-15T12:59:52Z"`
+	UpdatedAt   *time.Time `json:"updatedAt" example:"2022-04-15T12:59:52Z"`
+	Description string     `json:"description" example:"A description"`
+	Tags        []string   `json:"tags" example:"nature,mountain"`
 }
 
 type Link struct {
@@ -28,13 +35,16 @@ type Response struct {
 }
 
 type VideoStatus struct {
-	Title  string `json:"title" example:"AmazingTitle"`
-	Status string `json:"status" example:"UPLOADED"`
+	Title          string `json:"title" example:"AmazingTitle"`
+	Status         string `json:"status" example:"UPLOADED"`
+	UploadProgress int    `json:"uploadProgress" example:"42"`
 }
 
 type VideoInfo struct {
-	Title          string `json:"title" example:"amazingtitle"`
-	UploadDateUnix int64  `json:"uploadDateUnix" example:"1652173257"`
+	Title          string   `json:"title" example:"amazingtitle"`
+	UploadDateUnix int64    `json:"uploadDateUnix" example:"1652173257"`
+	Description    string   `json:"description" example:"A description"`
+	Tags           []string `json:"tags" example:"nature,mountain"`
 }
 
 type TransformerServiceListResponse struct {
@@ -100,6 +100,33 @@ func (s *Session) Post(path string, payload interface{}) (int, io.Reader, error)
 	return resp.StatusCode, resp.Body, nil
 }
 
+func (s *Session) Patch(path string, payload interface{}) (int, io.Reader, error) {
+	parsedURL, err := url.Parse(s.host + path)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	_ = json.NewEncoder(buf).Encode(payload)
+	bufNC := io.NopCloser(buf)
+
+	var c http.Client
+	s.headers.Set(http.CanonicalHeaderKey("Content-Type"), "application/json")
+	defer s.headers.Del(http.CanonicalHeaderKey("Content-Type"))
+
+	resp, err := c.Do(&http.Request{
+		Method: http.MethodPatch,
+		URL:    parsedURL,
+		Body:   bufNC,
+		Header: s.headers,
+	})
+
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, resp.Body, nil
+}
+
 func (s *Session) PostMultipart(path, title, filename string, video io.Reader, cover io.Reader) (int, io.Reader, error) {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
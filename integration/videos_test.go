@@ -178,6 +178,37 @@ func Test_Videos(t *testing.T) {
 
 			})
 
+			g.Describe("With video source already uploaded >", func() {
+				g.Before(func() {
+					uploadVideoWaitForEncode(&videoLocation, &pathUpload, &videoTitle, &videoID, session)
+				})
+
+				g.It("Returns an error with the existing video", func() {
+					t.Log("PATH - POST - " + pathUpload)
+
+					g.Timeout(GOBLIN_TEST_TIMEOUT)
+
+					// Open video file
+					f, err := os.Open(videoLocation)
+					require.NoError(t, err)
+					defer f.Close()
+
+					// Post same video upload with another title
+					code, body, err := session.PostMultipart(pathUpload, videoTitle+"-duplicate", "video.avi", f, nil)
+					require.NoError(t, err)
+					require.Equal(t, 409, code)
+
+					// Reading the body
+					rawBody, err := ioutil.ReadAll(body)
+					require.NoError(t, err)
+
+					var uploadResponse helpers.Response
+					err = json.Unmarshal(rawBody, &uploadResponse)
+					require.NoError(t, err)
+					require.Equal(t, videoID, uploadResponse.Video.ID)
+				})
+			})
+
 			g.Describe("With image as video file >", func() {
 				g.It("Returns an error unsported media format", func() {
 					t.Log("PATH - POST - " + pathUpload)
@@ -266,6 +297,33 @@ func Test_Videos(t *testing.T) {
 						require.Equal(t, videoTitle, videoData.Videos[0].Title)
 					})
 				})
+
+				g.Describe("With one video and query parameters >", func() {
+					g.Before(func() {
+						uploadVideoWaitForEncode(&videoLocation, &pathUpload, &videoTitle, &videoID, session)
+					})
+
+					g.It("Returns a page of videos with One element", func() {
+
+						g.Timeout(GOBLIN_TEST_TIMEOUT)
+
+						code, body, err := session.Get("/api/v1/videos?status=Complete,Archive&sort=title&order=asc&limit=1")
+						require.NoError(t, err)
+						require.Equal(t, 200, code)
+
+						// Reading the body
+						rawBody, err := ioutil.ReadAll(body)
+						require.NoError(t, err)
+
+						var videoData helpers.VideoPageResponse
+						err = json.Unmarshal(rawBody, &videoData)
+						require.NoError(t, err)
+
+						require.Equal(t, 1, videoData.Total)
+						require.Equal(t, 1, len(videoData.Videos))
+						require.Equal(t, videoTitle, videoData.Videos[0].Title)
+					})
+				})
 			})
 		})
 
@@ -326,6 +384,71 @@ func Test_Videos(t *testing.T) {
 			})
 		})
 
+		//////////////////
+		// UPDATE VIDEO //
+		//////////////////
+		g.Describe("Update >", func() {
+			g.Before(func() {
+				uploadVideoWaitForEncode(&videoLocation, &pathUpload, &videoTitle, &videoID, session)
+			})
+
+			g.It("Update video description and tags", func() {
+
+				g.Timeout(GOBLIN_TEST_TIMEOUT)
+
+				update := map[string]interface{}{
+					"description": "A description",
+					"tags":        []string{"Nature", "mountain"},
+				}
+				code, _, err := session.Patch("/api/v1/videos/"+videoID, update)
+				require.NoError(t, err)
+				require.Equal(t, 200, code)
+
+				code, body, err := session.Get("/api/v1/videos/" + videoID + "/info")
+				require.NoError(t, err)
+				require.Equal(t, 200, code)
+
+				// Reading the body
+				rawBody, err := ioutil.ReadAll(body)
+				require.NoError(t, err)
+
+				// Retrieve video informations
+				var infoResponse helpers.VideoInfo
+				err = json.Unmarshal(rawBody, &infoResponse)
+				require.NoError(t, err)
+				require.Equal(t, videoTitle, infoResponse.Title)
+				require.Equal(t, "A description", infoResponse.Description)
+				require.Equal(t, []string{"mountain", "nature"}, infoResponse.Tags)
+			})
+		})
+
+		g.Describe("Search >", func() {
+			g.Before(func() {
+				uploadVideoWaitForEncode(&videoLocation, &pathUpload, &videoTitle, &videoID, session)
+				_, _, _ = session.Patch("/api/v1/videos/"+videoID, map[string]interface{}{"tags": []string{"mountain"}})
+			})
+
+			g.It("Search videos by title and tags", func() {
+
+				g.Timeout(GOBLIN_TEST_TIMEOUT)
+
+				for query, expected := range map[string]int{"test": 1, "mount": 1, "unknown": 0} {
+					code, body, err := session.Get("/api/v1/videos/search?q=" + query)
+					require.NoError(t, err)
+					require.Equal(t, 200, code)
+
+					// Reading the body
+					rawBody, err := ioutil.ReadAll(body)
+					require.NoError(t, err)
+
+					var videoData helpers.VideoListResponse
+					err = json.Unmarshal(rawBody, &videoData)
+					require.NoError(t, err)
+					require.Equal(t, expected, len(videoData.Videos), query)
+				}
+			})
+		})
+
 		//////////////////
 		// STREAM VIDEO //
 		//////////////////
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+// ApiKeyHeader is the header holding the API key of the machine clients
+const ApiKeyHeader = "X-API-Key"
+
+// Keys are "vgl_" followed by 32 random bytes. The prefix shown in the lists is long enough to tell keys apart.
+const (
+	apiKeyTag          = "vgl_"
+	apiKeyPrefixLength = len(apiKeyTag) + 8
+)
+
+// LastUsedInterval is the precision of the last usage of the keys : it is not written on every request
+const LastUsedInterval = time.Minute
+
+var ErrRevokedApiKey = errors.New("revoked api key")
+
+// GenerateApiKey returns a new random key and its prefix
+func GenerateApiKey() (string, string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", err
+	}
+	key := apiKeyTag + base64.RawURLEncoding.EncodeToString(random)
+	return key, key[:apiKeyPrefixLength], nil
+}
+
+// HashApiKey returns the hash stored in database. Keys are random enough for a fast hash.
+func HashApiKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// AuthenticateApiKey returns the context holding the API key, and updates its last usage
+func (a Authenticator) AuthenticateApiKey(ctx context.Context, key string) (context.Context, error) {
+	apiKey, err := a.ApiKeysDAO.GetApiKeyFromHash(ctx, HashApiKey(key))
+	if err != nil {
+		log.Debug("Cannot authenticate api key : ", err)
+		return nil, err
+	}
+	if apiKey.RevokedAt != nil {
+		log.Debug("Api key " + apiKey.ID + " is revoked")
+		return nil, ErrRevokedApiKey
+	}
+
+	// The request goes on even if its usage cannot be recorded
+	if apiKey.LastUsedAt == nil || time.Since(*apiKey.LastUsedAt) >= LastUsedInterval {
+		if err := a.ApiKeysDAO.UpdateApiKeyLastUsed(ctx, apiKey.ID); err != nil {
+			log.Error("Cannot update last usage of api key "+apiKey.ID+" : ", err)
+		}
+	}
+
+	return ContextWithApiKey(ctx, apiKey), nil
+}
+
+// ContextWithApiKey returns a copy of the context holding the authenticated API key
+func ContextWithApiKey(ctx context.Context, apiKey *models.ApiKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey, apiKey)
+}
+
+// ApiKeyFromContext returns the authenticated API key, or nil for the users and the shared account
+func ApiKeyFromContext(ctx context.Context) *models.ApiKey {
+	apiKey, _ := ctx.Value(apiKeyContextKey).(*models.ApiKey)
+	return apiKey
+}
+
+// HasScope returns true if the request is allowed by this scope : users and the shared account have every scope
+func HasScope(ctx context.Context, scope string) bool {
+	apiKey := ApiKeyFromContext(ctx)
+	return apiKey == nil || apiKey.HasScope(scope)
+}
+
+// IsAdmin returns true for the shared account, the only one allowed to manage the API keys
+func IsAdmin(ctx context.Context) bool {
+	return UserFromContext(ctx) == nil && ApiKeyFromContext(ctx) == nil
+}
+
+// RequireScope rejects the requests of the API keys without this scope
+func RequireScope(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !HasScope(r.Context(), scope) {
+			log.Error("Api key without scope ", scope)
+			http.Error(w, "Missing scope "+scope, http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAdmin rejects the requests of the users and of the API keys
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAdmin(r.Context()) {
+			log.Error("Admin route requested without the shared account")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
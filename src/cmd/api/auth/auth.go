@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type contextKey int
+
+const (
+	userContextKey contextKey = iota
+	apiKeyContextKey
+	streamTokenContextKey
+	apiKeyCheckContextKey
+	clientIDContextKey
+)
+
+var ErrNoCredentials = errors.New("no credentials")
+
+// Authenticator checks the API key, the bearer token or the basic auth credentials of the requests.
+// They can be the ones of the shared account (USER_AUTH / PWD_AUTH), used by the webapp and allowed to manage
+// every video, the ones of a user account, only allowed to manage its own videos, or an API key of a machine
+// client, allowed to manage every video within its scopes.
+type Authenticator struct {
+	UserAuth   string
+	PwdAuth    string
+	UsersDAO   *dao.UsersDAO
+	ApiKeysDAO *dao.ApiKeysDAO
+	Tokens     TokenManager
+	Streams    StreamSigner
+}
+
+// Middleware rejects the requests without valid credentials, and adds the authenticated user
+// to the context of the others.
+func (a Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, err := a.AuthenticateRequest(r)
+		if err != nil {
+			unauthorized(w)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// AuthenticateRequest returns the context of the request holding its authenticated user or API key,
+// from the API key, the bearer token or the basic auth credentials
+func (a Authenticator) AuthenticateRequest(r *http.Request) (context.Context, error) {
+	if key := r.Header.Get(ApiKeyHeader); key != "" {
+		// The key is already validated when the client is identified
+		if check, ok := r.Context().Value(apiKeyCheckContextKey).(apiKeyCheck); ok {
+			if check.err != nil {
+				return nil, check.err
+			}
+			return r.Context(), nil
+		}
+		return a.AuthenticateApiKey(r.Context(), key)
+	}
+
+	if token, ok := BearerToken(r); ok {
+		return a.AuthenticateToken(r.Context(), token)
+	}
+
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	return a.AuthenticateCredentials(r.Context(), username, password)
+}
+
+// AuthenticateToken returns the context holding the user of the access token. The token is enough :
+// the user is not read from the database.
+func (a Authenticator) AuthenticateToken(ctx context.Context, token string) (context.Context, error) {
+	claims, err := a.Tokens.Verify(token, AccessToken)
+	if err != nil {
+		log.Debug("Invalid access token : ", err)
+		return nil, err
+	}
+
+	if claims.Subject == "" {
+		return ctx, nil
+	}
+	return ContextWithUser(ctx, &models.User{ID: claims.Subject, Username: claims.Username}), nil
+}
+
+// AuthenticateCredentials returns the context holding the user with these credentials
+func (a Authenticator) AuthenticateCredentials(ctx context.Context, username, password string) (context.Context, error) {
+	user, err := a.Login(ctx, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return ctx, nil
+	}
+	return ContextWithUser(ctx, user), nil
+}
+
+// Login returns the user with these credentials, or nil for the shared account
+func (a Authenticator) Login(ctx context.Context, username, password string) (*models.User, error) {
+	if a.isSharedAccount(username, password) {
+		return nil, nil
+	}
+	return a.Authenticate(ctx, username, password)
+}
+
+// Authenticate returns the user with these credentials
+func (a Authenticator) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	user, err := a.UsersDAO.GetUserFromUsername(ctx, username)
+	if err != nil {
+		log.Debug("Cannot authenticate user ", username, " : ", err)
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		log.Debug("Wrong password for user ", username)
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (a Authenticator) isSharedAccount(username, password string) bool {
+	// Compare in constant time, both of them to not tell which one is wrong
+	validUser := subtle.ConstantTimeCompare([]byte(username), []byte(a.UserAuth)) == 1
+	validPwd := subtle.ConstantTimeCompare([]byte(password), []byte(a.PwdAuth)) == 1
+	return validUser && validPwd
+}
+
+// BearerToken returns the token of the Authorization header, if it is a bearer one
+func BearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if len(header) < len("Bearer ") || !strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+		return "", false
+	}
+	return strings.TrimSpace(header[len("Bearer "):]), true
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Add("WWW-Authenticate", `Bearer realm="Restricted"`)
+	w.Header().Add("WWW-Authenticate", `Basic realm="Restricted"`)
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+// ContextWithUser returns a copy of the context holding the authenticated user
+func ContextWithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+// UserFromContext returns the authenticated user, or nil for the shared account
+func UserFromContext(ctx context.Context) *models.User {
+	user, _ := ctx.Value(userContextKey).(*models.User)
+	return user
+}
+
+// OwnerID returns the ID of the authenticated user, or nil for the shared account
+func OwnerID(ctx context.Context) *string {
+	if user := UserFromContext(ctx); user != nil {
+		return &user.ID
+	}
+	return nil
+}
+
+// CanManageVideo returns true if the authenticated user can update, archive, delete or upload the video :
+// the shared account and the API keys can manage every video, a user only the videos it uploaded.
+func CanManageVideo(ctx context.Context, video *models.Video) bool {
+	user := UserFromContext(ctx)
+	return user == nil || (video.OwnerID != nil && *video.OwnerID == user.ID)
+}
+
+// CanManagePlaylist returns true if the authenticated user can update or delete the playlist, like CanManageVideo
+func CanManagePlaylist(ctx context.Context, playlist *models.Playlist) bool {
+	user := UserFromContext(ctx)
+	return user == nil || (playlist.OwnerID != nil && *playlist.OwnerID == user.ID)
+}
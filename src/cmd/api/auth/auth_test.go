@@ -0,0 +1,325 @@
+package auth_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+func TestTokens(t *testing.T) { //nolint:cyclop
+	user := &models.User{ID: "6f0c54b1-2b9d-4c8a-a0f4-3d2e7b0f1c11", Username: "alice"}
+	oldKeys := auth.TokenManager{
+		Keys:              map[string][]byte{"key-1": []byte("secret-1")},
+		SigningKeyID:      "key-1",
+		AccessExpiration:  time.Minute,
+		RefreshExpiration: time.Hour,
+	}
+	rotatedKeys := auth.TokenManager{
+		Keys:             map[string][]byte{"key-1": []byte("secret-1"), "key-2": []byte("secret-2")},
+		SigningKeyID:     "key-2",
+		AccessExpiration: time.Minute,
+	}
+	newKeys := auth.TokenManager{
+		Keys:             map[string][]byte{"key-2": []byte("secret-2")},
+		SigningKeyID:     "key-2",
+		AccessExpiration: time.Minute,
+	}
+
+	oldTokens, err := oldKeys.NewTokens(user, "dev")
+	require.NoError(t, err)
+	rotatedTokens, err := rotatedKeys.NewTokens(user, "dev")
+	require.NoError(t, err)
+	expiredToken, err := oldKeys.Sign(auth.Claims{Subject: user.ID, Type: auth.AccessToken, ExpiresAt: time.Now().Add(-time.Second).Unix()})
+	require.NoError(t, err)
+
+	// Same claims, signed by nobody
+	parts := strings.Split(oldTokens.AccessToken, ".")
+	unsignedToken := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT","kid":"key-1"}`)) + "." + parts[1] + "."
+	tamperedToken := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"another","typ":"access","exp":9999999999}`)) + "." + parts[2]
+
+	cases := []struct {
+		name          string
+		giveManager   auth.TokenManager
+		giveToken     string
+		giveType      string
+		expectedError error
+	}{
+		{
+			name:        "Verify access token",
+			giveManager: oldKeys,
+			giveToken:   oldTokens.AccessToken,
+			giveType:    auth.AccessToken,
+		},
+		{
+			name:        "Verify refresh token",
+			giveManager: oldKeys,
+			giveToken:   oldTokens.RefreshToken,
+			giveType:    auth.RefreshToken,
+		},
+		{
+			name:        "Verify token of the previous key while rotating",
+			giveManager: rotatedKeys,
+			giveToken:   oldTokens.AccessToken,
+			giveType:    auth.AccessToken,
+		},
+		{
+			name:        "Verify token of the new key while rotating",
+			giveManager: rotatedKeys,
+			giveToken:   rotatedTokens.AccessToken,
+			giveType:    auth.AccessToken,
+		},
+		{
+			name:          "Verify fails with removed key",
+			giveManager:   newKeys,
+			giveToken:     oldTokens.AccessToken,
+			giveType:      auth.AccessToken,
+			expectedError: auth.ErrInvalidToken,
+		},
+		{
+			name:          "Verify fails with refresh token used as access token",
+			giveManager:   oldKeys,
+			giveToken:     oldTokens.RefreshToken,
+			giveType:      auth.AccessToken,
+			expectedError: auth.ErrInvalidToken,
+		},
+		{
+			name:          "Verify fails with expired token",
+			giveManager:   oldKeys,
+			giveToken:     expiredToken,
+			giveType:      auth.AccessToken,
+			expectedError: auth.ErrExpiredToken,
+		},
+		{
+			name:          "Verify fails with unsigned token",
+			giveManager:   oldKeys,
+			giveToken:     unsignedToken,
+			giveType:      auth.AccessToken,
+			expectedError: auth.ErrInvalidToken,
+		},
+		{
+			name:          "Verify fails with tampered token",
+			giveManager:   oldKeys,
+			giveToken:     tamperedToken,
+			giveType:      auth.AccessToken,
+			expectedError: auth.ErrInvalidToken,
+		},
+		{
+			name:          "Verify fails without keys",
+			giveToken:     oldTokens.AccessToken,
+			giveType:      auth.AccessToken,
+			expectedError: auth.ErrTokensDisabled,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tt.giveManager.Verify(tt.giveToken, tt.giveType)
+			if tt.expectedError != nil {
+				require.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, user.ID, claims.Subject)
+			require.Equal(t, user.Username, claims.Username)
+		})
+	}
+}
+
+func TestParseSigningKeys(t *testing.T) {
+	keys, err := auth.ParseSigningKeys([]string{"key-1:secret:with:colons", "key-2:secret"})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]byte{"key-1": []byte("secret:with:colons"), "key-2": []byte("secret")}, keys)
+
+	keys, err = auth.ParseSigningKeys([]string{""})
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	_, err = auth.ParseSigningKeys([]string{"secret"})
+	require.Error(t, err)
+}
+
+func TestMiddlewareWithBearerToken(t *testing.T) {
+	authenticator := auth.Authenticator{
+		UserAuth: "dev",
+		PwdAuth:  "test",
+		Tokens: auth.TokenManager{
+			Keys:             map[string][]byte{"key-1": []byte("secret")},
+			SigningKeyID:     "key-1",
+			AccessExpiration: time.Minute,
+		},
+	}
+	user := &models.User{ID: "6f0c54b1-2b9d-4c8a-a0f4-3d2e7b0f1c11", Username: "alice"}
+	userTokens, err := authenticator.Tokens.NewTokens(user, "dev")
+	require.NoError(t, err)
+	sharedTokens, err := authenticator.Tokens.NewTokens(nil, "dev")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name             string
+		giveToken        string
+		expectedHTTPCode int
+		expectedOwnerID  *string
+	}{
+		{
+			name:             "Authenticate user token",
+			giveToken:        userTokens.AccessToken,
+			expectedHTTPCode: 200,
+			expectedOwnerID:  &user.ID,
+		},
+		{
+			name:             "Authenticate shared account token",
+			giveToken:        sharedTokens.AccessToken,
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "Authenticate fails with refresh token",
+			giveToken:        userTokens.RefreshToken,
+			expectedHTTPCode: 401,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var ownerID *string
+			handler := authenticator.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ownerID = auth.OwnerID(r.Context())
+			}))
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/api/v1/videos", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.giveToken)
+
+			handler.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			require.Equal(t, tt.expectedOwnerID, ownerID)
+		})
+	}
+}
+
+func TestClientID(t *testing.T) { //nolint:cyclop
+	apiKey := "vgl_4Zq8n1Xc7Vb2Mk9Lp3Rt6Wy0Ah5Sd8Fg2Jk4Lm7Nq1"
+	apiKeyID := "0d4c7a2e-8f1b-4b6a-9c3d-5e2f1a7b9c40"
+	tokens := auth.TokenManager{
+		Keys:             map[string][]byte{"key-1": []byte("secret")},
+		SigningKeyID:     "key-1",
+		AccessExpiration: time.Minute,
+	}
+	user := &models.User{ID: "6f0c54b1-2b9d-4c8a-a0f4-3d2e7b0f1c11", Username: "alice"}
+	userTokens, err := tokens.NewTokens(user, "dev")
+	require.NoError(t, err)
+	sharedTokens, err := tokens.NewTokens(nil, "dev")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name             string
+		giveHeaders      map[string]string
+		giveProxy        bool
+		giveKeyLookup    bool
+		giveKeyFound     bool
+		expectedID       string
+		expectedHTTPCode int
+	}{
+		{
+			name:             "User of the access token",
+			giveHeaders:      map[string]string{"Authorization": "Bearer " + userTokens.AccessToken},
+			expectedID:       "user:" + user.ID,
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "IP for the shared account",
+			giveHeaders:      map[string]string{"Authorization": "Bearer " + sharedTokens.AccessToken},
+			expectedID:       "ip:192.0.2.1",
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "IP for an invalid token",
+			giveHeaders:      map[string]string{"Authorization": "Bearer invalid"},
+			expectedID:       "ip:192.0.2.1",
+			expectedHTTPCode: 401,
+		},
+		{
+			name:             "Valid API key, looked up once",
+			giveHeaders:      map[string]string{auth.ApiKeyHeader: apiKey},
+			giveKeyLookup:    true,
+			giveKeyFound:     true,
+			expectedID:       "apikey:" + apiKeyID,
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "IP for a wrong API key",
+			giveHeaders:      map[string]string{auth.ApiKeyHeader: apiKey},
+			giveKeyLookup:    true,
+			expectedID:       "ip:192.0.2.1",
+			expectedHTTPCode: 401,
+		},
+		{
+			name:             "IP of the trusted proxy header",
+			giveHeaders:      map[string]string{"X-Forwarded-For": "203.0.113.7, 10.0.0.1"},
+			giveProxy:        true,
+			expectedID:       "ip:203.0.113.7",
+			expectedHTTPCode: 401,
+		},
+		{
+			name:             "IP of the connection without trusted proxy",
+			giveHeaders:      map[string]string{"X-Forwarded-For": "203.0.113.7"},
+			expectedID:       "ip:192.0.2.1",
+			expectedHTTPCode: 401,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectApiKeysDAOCreation(mock)
+			if tt.giveKeyLookup {
+				lookup := mock.ExpectQuery(regexp.QuoteMeta(dao.ApiKeysRequests[dao.GetApiKeyFromHash])).WithArgs(auth.HashApiKey(apiKey))
+				if tt.giveKeyFound {
+					columns := []string{"id", "name", "prefix", "key_hash", "scopes", "created_at", "last_used_at", "revoked_at"}
+					lookup.WillReturnRows(sqlmock.NewRows(columns).AddRow(apiKeyID, "ci", apiKey[:12], auth.HashApiKey(apiKey), "videos:read", time.Now(), nil, nil))
+					mock.ExpectExec(regexp.QuoteMeta(dao.ApiKeysRequests[dao.UpdateApiKeyLastUsed])).WithArgs(apiKeyID).WillReturnResult(sqlmock.NewResult(0, 1))
+				} else {
+					lookup.WillReturnError(sql.ErrNoRows)
+				}
+			}
+
+			apiKeysDAO, err := dao.CreateApiKeysDAO(context.Background(), db)
+			require.NoError(t, err)
+			authenticator := auth.Authenticator{ApiKeysDAO: apiKeysDAO, Tokens: tokens}
+
+			var clientID string
+			handler := authenticator.IdentifyMiddleware(tt.giveProxy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				clientID = auth.ClientID(r)
+				authenticator.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(w, r)
+			}))
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/api/v1/videos", nil)
+			for header, value := range tt.giveHeaders {
+				req.Header.Set(header, value)
+			}
+
+			handler.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedID, clientID)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
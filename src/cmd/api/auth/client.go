@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// apiKeyCheck is the result of the validation of the API key of a request, done once by IdentifyMiddleware
+type apiKeyCheck struct {
+	err error
+}
+
+// IdentifyMiddleware adds the client of the request to its context, for the rate limits and the playback
+// analytics. It runs before them, and before the authentication which reuses the validated API key.
+func (a Authenticator) IdentifyMiddleware(trustProxy bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(a.Identify(r, trustProxy)))
+		})
+	}
+}
+
+// Identify returns the context of the request holding its client : a valid API key, the user of a valid access
+// token, else the IP. A wrong API key is identified by the IP, so that guessing keys is limited like any other
+// request of this IP. The shared account is used by every webapp visitor : they are told apart by their IP.
+func (a Authenticator) Identify(r *http.Request, trustProxy bool) context.Context {
+	ctx := r.Context()
+	clientID := "ip:" + ClientIP(r, trustProxy)
+
+	if key := r.Header.Get(ApiKeyHeader); key != "" {
+		keyCtx, err := a.AuthenticateApiKey(ctx, key)
+		if err == nil {
+			ctx = keyCtx
+			clientID = "apikey:" + ApiKeyFromContext(ctx).ID
+		}
+		ctx = context.WithValue(ctx, apiKeyCheckContextKey, apiKeyCheck{err: err})
+	} else if token, ok := BearerToken(r); ok {
+		if claims, err := a.Tokens.Verify(token, AccessToken); err == nil && claims.Subject != "" {
+			clientID = "user:" + claims.Subject
+		}
+	}
+
+	return context.WithValue(ctx, clientIDContextKey, clientID)
+}
+
+// ClientID returns the client of the request added by IdentifyMiddleware, else its IP
+func ClientID(r *http.Request) string {
+	if clientID, ok := r.Context().Value(clientIDContextKey).(string); ok {
+		return clientID
+	}
+	return "ip:" + ClientIP(r, false)
+}
+
+// ClientIP returns the IP of the client, the first one of X-Forwarded-For if the API is behind a trusted proxy
+func ClientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			ip, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(ip)
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+// StreamTokenParam is the query parameter holding the stream token
+const StreamTokenParam = "token"
+
+// StreamSigner signs the tokens of the streams of a video, or of a playlist. A token is its expiration and the
+// HMAC-SHA256 of the ID and of this expiration : it only gives access to the streams of one video, or of one
+// playlist, until it expires.
+type StreamSigner struct {
+	Secret     []byte
+	Expiration time.Duration
+}
+
+// Enabled returns false when no secret is configured : the streams then require credentials
+func (s StreamSigner) Enabled() bool {
+	return len(s.Secret) > 0
+}
+
+// Sign returns a new token for the streams of the video
+func (s StreamSigner) Sign(videoID string) (string, error) {
+	if !s.Enabled() {
+		return "", ErrTokensDisabled
+	}
+
+	expiresAt := strconv.FormatInt(time.Now().Add(s.Expiration).Unix(), 10)
+	return expiresAt + "." + base64.RawURLEncoding.EncodeToString(sign(s.Secret, videoID+"."+expiresAt)), nil
+}
+
+// Verify checks that the token has been signed for the streams of the video and has not expired
+func (s StreamSigner) Verify(token, videoID string) error {
+	if !s.Enabled() {
+		return ErrTokensDisabled
+	}
+
+	expiresAt, signature, found := strings.Cut(token, ".")
+	if !found {
+		return ErrInvalidToken
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(decoded, sign(s.Secret, videoID+"."+expiresAt)) {
+		return ErrInvalidToken
+	}
+
+	expiration, err := strconv.ParseInt(expiresAt, 10, 64)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	if time.Now().Unix() >= expiration {
+		return ErrExpiredToken
+	}
+
+	return nil
+}
+
+// StreamMiddleware authorizes the requests of the streams with the stream token of the video, or, without token,
+// with the credentials of the requests, like Middleware.
+func (a Authenticator) StreamMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.URL.Query().Get(StreamTokenParam); token != "" {
+			if err := a.Streams.Verify(token, mux.Vars(r)["id"]); err != nil {
+				log.Debug("Invalid stream token : ", err)
+				http.Error(w, "Invalid stream token", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(ContextWithStreamToken(r.Context(), token)))
+			return
+		}
+
+		ctx, err := a.AuthenticateRequest(r)
+		if err != nil {
+			unauthorized(w)
+			return
+		}
+
+		RequireScope(models.ScopeVideosRead, next).ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ContextWithStreamToken returns a copy of the context holding the stream token authorizing the request
+func ContextWithStreamToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, streamTokenContextKey, token)
+}
+
+// StreamTokenFromContext returns the stream token authorizing the request, or "" if it has credentials
+func StreamTokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(streamTokenContextKey).(string)
+	return token
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+// Types of the tokens : access tokens authenticate the requests, refresh tokens get new tokens
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
+)
+
+var (
+	ErrTokensDisabled = errors.New("no signing key configured")
+	ErrInvalidToken   = errors.New("invalid token")
+	ErrExpiredToken   = errors.New("expired token")
+)
+
+// TokenManager signs and verifies JWTs with HMAC-SHA256. Keys are identified by their ID (kid header),
+// new tokens are signed with the signing key, and tokens signed with the other keys remain valid :
+// to rotate keys, add the new key, make it the signing key, then remove the old one once its tokens expired.
+type TokenManager struct {
+	Keys              map[string][]byte
+	SigningKeyID      string
+	AccessExpiration  time.Duration
+	RefreshExpiration time.Duration
+}
+
+// Claims of the tokens. An empty subject is the shared account.
+type Claims struct {
+	Subject   string `json:"sub"`
+	Username  string `json:"name"`
+	Type      string `json:"typ"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// TokenPair is issued on login and on refresh
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+type tokenHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// ParseSigningKeys parses keys given as "kid:secret"
+func ParseSigningKeys(values []string) (map[string][]byte, error) {
+	keys := map[string][]byte{}
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		kid, secret, found := strings.Cut(value, ":")
+		if !found || kid == "" || secret == "" {
+			return nil, fmt.Errorf("signing key must be kid:secret")
+		}
+		keys[kid] = []byte(secret)
+	}
+	return keys, nil
+}
+
+// Enabled returns false when no key is configured : only basic auth can be used
+func (m TokenManager) Enabled() bool {
+	return len(m.Keys) > 0
+}
+
+// NewTokens issues the access and refresh tokens of the user, nil for the shared account
+func (m TokenManager) NewTokens(user *models.User, sharedUsername string) (*TokenPair, error) {
+	claims := Claims{Username: sharedUsername}
+	if user != nil {
+		claims.Subject = user.ID
+		claims.Username = user.Username
+	}
+
+	now := time.Now()
+	claims.IssuedAt = now.Unix()
+
+	claims.Type = AccessToken
+	claims.ExpiresAt = now.Add(m.AccessExpiration).Unix()
+	accessToken, err := m.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	claims.Type = RefreshToken
+	claims.ExpiresAt = now.Add(m.RefreshExpiration).Unix()
+	refreshToken, err := m.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{AccessToken: accessToken, RefreshToken: refreshToken, ExpiresIn: m.AccessExpiration}, nil
+}
+
+// Sign returns the token of the claims, signed with the signing key
+func (m TokenManager) Sign(claims Claims) (string, error) {
+	key, ok := m.Keys[m.SigningKeyID]
+	if !ok {
+		return "", ErrTokensDisabled
+	}
+
+	header, err := json.Marshal(tokenHeader{Algorithm: "HS256", Type: "JWT", KeyID: m.SigningKeyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sign(key, unsigned)), nil
+}
+
+// Verify returns the claims of a valid token of this type
+func (m TokenManager) Verify(token, tokenType string) (*Claims, error) {
+	if !m.Enabled() {
+		return nil, ErrTokensDisabled
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+	// The algorithm is fixed, never the one chosen by the token
+	if header.Algorithm != "HS256" {
+		return nil, ErrInvalidToken
+	}
+	key, ok := m.Keys[header.KeyID]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, sign(key, parts[0]+"."+parts[1])) {
+		return nil, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if claims.Type != tokenType {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+
+	return &claims, nil
+}
+
+func sign(key []byte, unsigned string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(unsigned))
+	return mac.Sum(nil)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
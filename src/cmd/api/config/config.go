@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/caarlos0/env/v6"
 )
 
@@ -12,12 +14,37 @@ type Config struct {
 	UserAuth string `env:"USER_AUTH,required"`
 	PwdAuth  string `env:"PWD_AUTH,required"`
 
+	// Token signing keys as kid:secret, new tokens are signed with the JWT_KEY_ID one
+	JWTKeys              []string      `env:"JWT_KEYS" envDefault:""`
+	JWTKeyID             string        `env:"JWT_KEY_ID" envDefault:""`
+	JWTAccessExpiration  time.Duration `env:"JWT_ACCESS_EXPIRATION" envDefault:"15m"`
+	JWTRefreshExpiration time.Duration `env:"JWT_REFRESH_EXPIRATION" envDefault:"168h"`
+
+	// Secret signing the stream tokens of the HLS playlists, streams require credentials without it
+	StreamTokenSecret     string        `env:"STREAM_TOKEN_SECRET" envDefault:""`
+	StreamTokenExpiration time.Duration `env:"STREAM_TOKEN_EXPIRATION" envDefault:"6h"`
+
 	S3Host    string `env:"S3_HOST" envDefault:""`
 	S3AuthKey string `env:"S3_AUTH_KEY,required"`
 	S3AuthPwd string `env:"S3_AUTH_PWD,required"`
 	S3Bucket  string `env:"S3_BUCKET" envDefault:"voogle-video"`
 	S3Region  string `env:"S3_REGION" envDefault:"eu-west-3"`
 
+	S3PresignExpiration time.Duration `env:"S3_PRESIGN_EXPIRATION" envDefault:"1h"`
+
+	RetentionPeriod   time.Duration `env:"RETENTION_PERIOD" envDefault:"0"`
+	RetentionInterval time.Duration `env:"RETENTION_INTERVAL" envDefault:"1h"`
+
+	// Token buckets per client (user, API key or IP) : requests per second and burst, a zero rate disables the limit
+	RateLimitReadRate          float64 `env:"RATE_LIMIT_READ_RATE" envDefault:"20"`
+	RateLimitReadBurst         int     `env:"RATE_LIMIT_READ_BURST" envDefault:"100"`
+	RateLimitUploadRate        float64 `env:"RATE_LIMIT_UPLOAD_RATE" envDefault:"5"`
+	RateLimitUploadBurst       int     `env:"RATE_LIMIT_UPLOAD_BURST" envDefault:"20"`
+	RateLimitTransformRate     float64 `env:"RATE_LIMIT_TRANSFORM_RATE" envDefault:"2"`
+	RateLimitTransformBurst    int     `env:"RATE_LIMIT_TRANSFORM_BURST" envDefault:"10"`
+	RateLimitTransformInFlight int     `env:"RATE_LIMIT_TRANSFORM_IN_FLIGHT" envDefault:"4"`
+	RateLimitTrustProxy        bool    `env:"RATE_LIMIT_TRUST_PROXY" envDefault:"false"`
+
 	RabbitmqAddr string `env:"RABBITMQ_ADDR,required"`
 	RabbitmqUser string `env:"RABBITMQ_USER,required"`
 	RabbitmqPwd  string `env:"RABBITMQ_PWD,required"`
@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+// MaxApiKeyNameLength is the size of the name column
+const MaxApiKeyNameLength = 64
+
+// ApiKeyCreateRequest describes the new key
+type ApiKeyCreateRequest struct {
+	Name   string   `json:"name" example:"ci-pipeline"`
+	Scopes []string `json:"scopes" example:"videos:read,videos:write"`
+}
+
+// ApiKeyCreateResponse holds the key itself : it is only returned once
+type ApiKeyCreateResponse struct {
+	ApiKey jsonDTO.ApiKeyJson `json:"apiKey"`
+	Key    string             `json:"key" example:"vgl_3kq9Xb2Z..."`
+}
+
+type ApiKeyCreateHandler struct {
+	ApiKeysDAO *dao.ApiKeysDAO
+	UUIDGen    clients.IUUIDGenerator
+}
+
+// ApiKeyCreateHandler godoc
+// @Summary Create an API key
+// @Description Create an API key for a machine client, sent in the X-API-Key header. Scopes are videos:read,
+// @Description videos:write and videos:delete. The key is only returned by this request. Only for the shared account.
+// @Tags apikey
+// @Accept json
+// @Produce json
+// @Param request body ApiKeyCreateRequest true "Name and scopes of the key"
+// @Success 201 {object} ApiKeyCreateResponse "Created key"
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/apikeys [post]
+func (a ApiKeyCreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("POST ApiKeyCreateHandler")
+
+	var request ApiKeyCreateRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		log.Error("Cannot decode api key request : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if request.Name == "" || len(request.Name) > MaxApiKeyNameLength {
+		log.Error("Invalid api key name : ", request.Name)
+		http.Error(w, "Name must have 1 to 64 characters", http.StatusBadRequest)
+		return
+	}
+
+	if len(request.Scopes) == 0 {
+		log.Error("Api key without scope")
+		http.Error(w, "At least one scope is required", http.StatusBadRequest)
+		return
+	}
+	scopes := []string{}
+	seen := map[string]bool{}
+	for _, scope := range request.Scopes {
+		if !models.IsValidScope(scope) {
+			log.Error("Invalid api key scope : ", scope)
+			http.Error(w, "Invalid scope "+scope, http.StatusBadRequest)
+			return
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	key, prefix, err := auth.GenerateApiKey()
+	if err != nil {
+		log.Error("Cannot generate api key : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	apiKeyID, err := a.UUIDGen.GenerateUuid()
+	if err != nil {
+		log.Error("Cannot generate new UUID : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	apiKey, err := a.ApiKeysDAO.CreateApiKey(r.Context(), apiKeyID, request.Name, prefix, auth.HashApiKey(key), scopes)
+	if err != nil {
+		log.Error("Cannot create api key : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	payload, err := json.Marshal(ApiKeyCreateResponse{ApiKey: jsonDTO.ApiKeyToApiKeyJson(apiKey), Key: key})
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write(payload)
+}
+
+type ApiKeysListHandler struct {
+	ApiKeysDAO *dao.ApiKeysDAO
+}
+
+// ApiKeysListHandler godoc
+// @Summary List API keys
+// @Description List the API keys, revoked ones included, the newest first. Only for the shared account.
+// @Tags apikey
+// @Produce json
+// @Success 200 {array} jsonDTO.ApiKeyJson "API keys"
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/apikeys [get]
+func (a ApiKeysListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GET ApiKeysListHandler")
+
+	apiKeys, err := a.ApiKeysDAO.GetApiKeys(r.Context())
+	if err != nil {
+		log.Error("Cannot get api keys : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	apiKeysJson := []jsonDTO.ApiKeyJson{}
+	for i := range apiKeys {
+		apiKeysJson = append(apiKeysJson, jsonDTO.ApiKeyToApiKeyJson(&apiKeys[i]))
+	}
+
+	payload, err := json.Marshal(apiKeysJson)
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(payload)
+}
+
+type ApiKeyRevokeHandler struct {
+	ApiKeysDAO *dao.ApiKeysDAO
+	UUIDGen    clients.IUUIDGenerator
+}
+
+// ApiKeyRevokeHandler godoc
+// @Summary Revoke an API key
+// @Description Revoke an API key : it is rejected from now on, and kept in the list. Only for the shared account.
+// @Tags apikey
+// @Produce plain
+// @Param id path string true "API key ID"
+// @Success 204 {string} string
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/apikeys/{id} [delete]
+func (a ApiKeyRevokeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("DELETE ApiKeyRevokeHandler - parameters ", vars)
+
+	id := vars["id"]
+	if !a.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	apiKey, err := a.ApiKeysDAO.GetApiKey(r.Context(), id)
+	if err != nil {
+		log.Error("Cannot find api key : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Revoking twice changes nothing
+	if apiKey.RevokedAt == nil {
+		if err := a.ApiKeysDAO.RevokeApiKey(r.Context(), id); err != nil {
+			log.Error("Cannot revoke api key "+id+" : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,398 @@
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+)
+
+// API key authenticating some requests, instead of the shared account
+const (
+	apiKeyID    = "0d4c7a2e-8f1b-4b6a-9c3d-5e2f1a7b9c40"
+	apiKeyValue = "vgl_4Zq8n1Xc7Vb2Mk9Lp3Rt6Wy0Ah5Sd8Fg2Jk4Lm7Nq1"
+)
+
+var apiKeysColumns = []string{"id", "name", "prefix", "key_hash", "scopes", "created_at", "last_used_at", "revoked_at"}
+
+// expectApiKeyLookup mocks the lookup of the API key by its hash, and the update of its last usage
+func expectApiKeyLookup(mock sqlmock.Sqlmock, scopes string, revoked bool) {
+	t1 := time.Now()
+	var revokedAt interface{}
+	if revoked {
+		revokedAt = t1
+	}
+	rows := sqlmock.NewRows(apiKeysColumns).AddRow(apiKeyID, "ci", apiKeyValue[:12], auth.HashApiKey(apiKeyValue), scopes, t1, nil, revokedAt)
+	mock.ExpectQuery(regexp.QuoteMeta(dao.ApiKeysRequests[dao.GetApiKeyFromHash])).WithArgs(auth.HashApiKey(apiKeyValue)).WillReturnRows(rows)
+	if !revoked {
+		mock.ExpectExec(regexp.QuoteMeta(dao.ApiKeysRequests[dao.UpdateApiKeyLastUsed])).WithArgs(apiKeyID).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+}
+
+func TestApiKeyCreate(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	cases := []struct {
+		name             string
+		giveBody         string
+		giveAccount      bool
+		giveApiKey       bool
+		giveDatabaseErr  bool
+		expectedScopes   []string
+		expectedHTTPCode int
+	}{
+		{
+			name:             "POST create api key",
+			giveBody:         `{"name": "ci", "scopes": ["videos:read", "videos:write", "videos:read"]}`,
+			expectedScopes:   []string{models.ScopeVideosRead, models.ScopeVideosWrite},
+			expectedHTTPCode: 201,
+		},
+		{
+			name:             "POST fails with invalid scope",
+			giveBody:         `{"name": "ci", "scopes": ["videos:admin"]}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails without scope",
+			giveBody:         `{"name": "ci", "scopes": []}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails without name",
+			giveBody:         `{"scopes": ["videos:read"]}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with user account",
+			giveBody:         `{"name": "ci", "scopes": ["videos:read"]}`,
+			giveAccount:      true,
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "POST fails with api key",
+			giveBody:         `{"name": "ci", "scopes": ["videos:read"]}`,
+			giveApiKey:       true,
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "POST fails with database error",
+			giveBody:         `{"name": "ci", "scopes": ["videos:read"]}`,
+			giveDatabaseErr:  true,
+			expectedHTTPCode: 500,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectUsersDAOCreation(mock)
+			dao_test.ExpectApiKeysDAOCreation(mock)
+
+			if tt.giveAccount {
+				expectAccountLookup(t, mock)
+			}
+			if tt.giveApiKey {
+				expectApiKeyLookup(mock, models.ScopeVideosWrite, false)
+			}
+
+			if tt.expectedHTTPCode == 201 || tt.giveDatabaseErr {
+				createApiKeyQuery := regexp.QuoteMeta(dao.ApiKeysRequests[dao.CreateApiKey])
+				if tt.giveDatabaseErr {
+					mock.ExpectExec(createApiKeyQuery).WillReturnError(fmt.Errorf("database internal error"))
+				} else {
+					mock.ExpectExec(createApiKeyQuery).WithArgs(apiKeyID, "ci", sqlmock.AnyArg(), sqlmock.AnyArg(), "videos:read,videos:write").WillReturnResult(sqlmock.NewResult(1, 1))
+					t1 := time.Now()
+					rows := sqlmock.NewRows(apiKeysColumns).AddRow(apiKeyID, "ci", "vgl_4Zq8n1Xc", "hash", "videos:read,videos:write", t1, nil, nil)
+					mock.ExpectQuery(regexp.QuoteMeta(dao.ApiKeysRequests[dao.GetApiKey])).WithArgs(apiKeyID).WillReturnRows(rows)
+				}
+			}
+
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+			apiKeysDAO, err := dao.CreateApiKeysDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &router.Clients{
+				UUIDGen: clients.NewUuidGeneratorDummy(func() (string, error) { return apiKeyID, nil }, nil),
+			}, &router.DAOs{UsersDAO: *usersDAO, ApiKeysDAO: *apiKeysDAO})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/apikeys", strings.NewReader(tt.giveBody))
+			if tt.giveAccount {
+				req.SetBasicAuth(accountUsername, accountPassword)
+			} else if tt.giveApiKey {
+				req.Header.Set(auth.ApiKeyHeader, apiKeyValue)
+			} else {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedHTTPCode == 201 {
+				var response controllers.ApiKeyCreateResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				require.True(t, strings.HasPrefix(response.Key, "vgl_"))
+				require.Equal(t, apiKeyID, response.ApiKey.ID)
+				require.Equal(t, tt.expectedScopes, response.ApiKey.Scopes)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestApiKeysList(t *testing.T) {
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	dao_test.ExpectUsersDAOCreation(mock)
+	dao_test.ExpectApiKeysDAOCreation(mock)
+
+	t1 := time.Now()
+	rows := sqlmock.NewRows(apiKeysColumns).
+		AddRow(apiKeyID, "ci", "vgl_4Zq8n1Xc", "hash1", "videos:read", t1, t1, nil).
+		AddRow("2b6e1c3d-7a8f-4e9b-b0c1-d2e3f4a5b6c7", "ingest", "vgl_8Hj2Kl5P", "hash2", "videos:read,videos:write", t1, nil, t1)
+	mock.ExpectQuery(regexp.QuoteMeta(dao.ApiKeysRequests[dao.GetApiKeys])).WillReturnRows(rows)
+
+	usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+	require.NoError(t, err)
+	apiKeysDAO, err := dao.CreateApiKeysDAO(context.Background(), db)
+	require.NoError(t, err)
+
+	r, err := router.NewRouter(config.Config{
+		UserAuth: givenUsername,
+		PwdAuth:  givenUserPwd,
+	}, &router.Clients{}, &router.DAOs{UsersDAO: *usersDAO, ApiKeysDAO: *apiKeysDAO})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/v1/apikeys", nil)
+	req.SetBasicAuth(givenUsername, givenUserPwd)
+
+	r.ServeHTTP(w, req)
+	require.Equal(t, 200, w.Code)
+	require.NotContains(t, w.Body.String(), "hash1")
+
+	var response []jsonDTO.ApiKeyJson
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response, 2)
+	require.Equal(t, []string{models.ScopeVideosRead}, response[0].Scopes)
+	require.NotNil(t, response[1].RevokedAt)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApiKeyRevoke(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	cases := []struct {
+		name             string
+		giveID           string
+		giveNotFound     bool
+		giveRevoked      bool
+		expectedHTTPCode int
+	}{
+		{
+			name:             "DELETE revoke api key",
+			giveID:           apiKeyID,
+			expectedHTTPCode: 204,
+		},
+		{
+			name:             "DELETE revoked api key",
+			giveID:           apiKeyID,
+			giveRevoked:      true,
+			expectedHTTPCode: 204,
+		},
+		{
+			name:             "DELETE fails with unknown api key",
+			giveID:           apiKeyID,
+			giveNotFound:     true,
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "DELETE fails with invalid id",
+			giveID:           "invalid",
+			expectedHTTPCode: 400,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectUsersDAOCreation(mock)
+			dao_test.ExpectApiKeysDAOCreation(mock)
+
+			if tt.expectedHTTPCode != 400 {
+				getApiKeyQuery := regexp.QuoteMeta(dao.ApiKeysRequests[dao.GetApiKey])
+				if tt.giveNotFound {
+					mock.ExpectQuery(getApiKeyQuery).WithArgs(tt.giveID).WillReturnRows(sqlmock.NewRows(apiKeysColumns))
+				} else {
+					t1 := time.Now()
+					var revokedAt interface{}
+					if tt.giveRevoked {
+						revokedAt = t1
+					}
+					rows := sqlmock.NewRows(apiKeysColumns).AddRow(apiKeyID, "ci", "vgl_4Zq8n1Xc", "hash", "videos:read", t1, nil, revokedAt)
+					mock.ExpectQuery(getApiKeyQuery).WithArgs(tt.giveID).WillReturnRows(rows)
+				}
+
+				if !tt.giveNotFound && !tt.giveRevoked {
+					mock.ExpectExec(regexp.QuoteMeta(dao.ApiKeysRequests[dao.RevokeApiKey])).WithArgs(tt.giveID).WillReturnResult(sqlmock.NewResult(0, 1))
+				}
+			}
+
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+			apiKeysDAO, err := dao.CreateApiKeysDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &router.Clients{
+				UUIDGen: clients.NewUuidGeneratorDummy(nil, func(id string) bool { return id == apiKeyID }),
+			}, &router.DAOs{UsersDAO: *usersDAO, ApiKeysDAO: *apiKeysDAO})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("DELETE", "/api/v1/apikeys/"+tt.giveID, nil)
+			req.SetBasicAuth(givenUsername, givenUserPwd)
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestApiKeyScopes(t *testing.T) {
+	cases := []struct {
+		name             string
+		giveScopes       string
+		giveRevoked      bool
+		giveMethod       string
+		giveRequest      string
+		expectedHTTPCode int
+	}{
+		{
+			name:             "GET with read scope",
+			giveScopes:       "videos:read",
+			giveMethod:       "GET",
+			giveRequest:      "/api/v1/videos/transformer/list",
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "GET fails without read scope",
+			giveScopes:       "videos:write",
+			giveMethod:       "GET",
+			giveRequest:      "/api/v1/videos/transformer/list",
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "DELETE fails without delete scope",
+			giveScopes:       "videos:read,videos:write",
+			giveMethod:       "DELETE",
+			giveRequest:      "/api/v1/videos/" + apiKeyID + "/delete",
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "PUT fails without write scope",
+			giveScopes:       "videos:read,videos:delete",
+			giveMethod:       "PUT",
+			giveRequest:      "/api/v1/videos/" + apiKeyID + "/archive",
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "PUT position fails without write scope",
+			giveScopes:       "videos:read",
+			giveMethod:       "PUT",
+			giveRequest:      "/api/v1/videos/" + apiKeyID + "/position",
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "GET videos to purge fails with api key",
+			giveScopes:       "videos:read,videos:write,videos:delete",
+			giveMethod:       "GET",
+			giveRequest:      "/api/v1/videos/retention",
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "GET fails with revoked api key",
+			giveScopes:       "videos:read",
+			giveRevoked:      true,
+			giveMethod:       "GET",
+			giveRequest:      "/api/v1/videos/transformer/list",
+			expectedHTTPCode: 401,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectApiKeysDAOCreation(mock)
+			expectApiKeyLookup(mock, tt.giveScopes, tt.giveRevoked)
+
+			apiKeysDAO, err := dao.CreateApiKeysDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			getServices := func(map[string]*clients.TransformersInstances) []models.TransformerService {
+				return []models.TransformerService{}
+			}
+			dummyServiceDiscovery := clients.NewDummyServiceDiscovery(map[string]*clients.TransformersInstances{}, nil, getServices, nil, nil)
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: "dev",
+				PwdAuth:  "test",
+			}, &router.Clients{ServiceDiscovery: dummyServiceDiscovery}, &router.DAOs{ApiKeysDAO: *apiKeysDAO})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.giveMethod, tt.giveRequest, nil)
+			req.Header.Set(auth.ApiKeyHeader, apiKeyValue)
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
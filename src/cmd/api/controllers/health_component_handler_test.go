@@ -30,11 +30,12 @@ func TestHealthComponent(t *testing.T) { //nolint:cyclop
 			givenRequest := "/health"
 
 			dummyServiceDiscovery := clients.NewDummyServiceDiscovery(nil, nil, nil, nil, nil)
-			r := router.NewRouter(
+			r, err := router.NewRouter(
 				config.Config{},
 				&router.Clients{ServiceDiscovery: dummyServiceDiscovery},
 				&router.DAOs{},
 			)
+			require.NoError(t, err)
 
 			w := httptest.NewRecorder()
 
@@ -0,0 +1,405 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/ffmpeg"
+	"github.com/Sogilis/Voogle/src/pkg/hls"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+// videosStreamsPath leads from the variant playlists of a playlist, api/v1/playlists/{id}/streams/v{level}/,
+// to the streams of the videos, api/v1/videos/
+const videosStreamsPath = "../../../../videos/"
+
+// playlistAudioGroup is the group of the audio renditions of a playlist, one per language of its videos
+const playlistAudioGroup = "audio"
+
+var errNoPlayableVideo = errors.New("no encoded video in the playlist")
+
+// videoVariants are the variant streams of an encoded video of the playlist, by increasing bandwidth, and
+// its audio renditions when its audio is not muxed with its video
+type videoVariants struct {
+	VideoID  string
+	Variants []hls.Variant
+	Audios   []hls.Rendition
+}
+
+type PlaylistGetMasterHandler struct {
+	S3Client     clients.IS3Client
+	PlaylistsDAO *dao.PlaylistsDAO
+	UUIDGen      clients.IUUIDGenerator
+	StreamSigner auth.StreamSigner
+}
+
+// PlaylistGetMasterHandler godoc
+// @Summary Get playlist master
+// @Description Get the master of the encoded videos of the playlist played one after the other. Its variant N plays
+// @Description the Nth lowest quality of each video, or its best one. Its URIs carry a stream token of the playlist.
+// @Description When a video is encoded with several audio languages, the master has an audio rendition per language
+// @Description of the videos.
+// @Tags playlist
+// @Produce plain
+// @Param id path string true "Playlist ID"
+// @Param token query string false "Stream token, instead of credentials"
+// @Success 200 {string} string "HLS playlist master"
+// @Failure 400 {string} string
+// @Failure 403 {string} string "Invalid stream token"
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/playlists/{id}/streams/master.m3u8 [get]
+func (p PlaylistGetMasterHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET PlaylistGetMasterHandler - parameters ", vars)
+
+	id := vars["id"]
+	if !p.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	videos, err := getPlaylistVariants(r.Context(), p.PlaylistsDAO, p.S3Client, id)
+	if err != nil {
+		writePlaylistStreamsError(w, err)
+		return
+	}
+
+	token, err := streamToken(r, p.StreamSigner, id)
+	if err != nil {
+		log.Error("Cannot sign stream token : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	levels := 0
+	for _, video := range videos {
+		if len(video.Variants) > levels {
+			levels = len(video.Variants)
+		}
+	}
+
+	// Each variant announces the attributes of its most demanding video
+	master := hls.MasterPlaylist{Version: 3}
+	for i, language := range playlistAudioLanguages(videos) {
+		uri := ffmpeg.AudioVariantPrefix + language + "/segment_index.m3u8"
+		if token != "" {
+			uri = hls.WithQueryParam(uri, auth.StreamTokenParam, token)
+		}
+		master.Renditions = append(master.Renditions, hls.Rendition{
+			Type:     "AUDIO",
+			GroupID:  playlistAudioGroup,
+			Name:     language,
+			Language: language,
+			Default:  i == 0,
+			URI:      uri,
+		})
+	}
+	for level := 0; level < levels; level++ {
+		var peak hls.Variant
+		for _, video := range videos {
+			if variant := variantOfLevel(video, level); variant.Bandwidth > peak.Bandwidth {
+				peak = variant
+			}
+		}
+
+		uri := "v" + strconv.Itoa(level) + "/segment_index.m3u8"
+		if token != "" {
+			uri = hls.WithQueryParam(uri, auth.StreamTokenParam, token)
+		}
+		variant := hls.Variant{Attributes: peak.Attributes, Bandwidth: peak.Bandwidth, URI: uri}
+		if len(master.Renditions) > 0 {
+			variant = variant.WithAudioGroup(playlistAudioGroup)
+		}
+		master.Variants = append(master.Variants, variant)
+	}
+
+	_, _ = w.Write(master.Encode())
+}
+
+type PlaylistGetVariantHandler struct {
+	S3Client     clients.IS3Client
+	PlaylistsDAO *dao.PlaylistsDAO
+	UUIDGen      clients.IUUIDGenerator
+	StreamSigner auth.StreamSigner
+}
+
+// PlaylistGetVariantHandler godoc
+// @Summary Get playlist variant
+// @Description Get the segments of a quality of each encoded video of the playlist, a discontinuity starting each video.
+// @Description The segments are the ones of the videos, carrying a stream token of their video.
+// @Tags playlist
+// @Produce plain
+// @Param id path string true "Playlist ID"
+// @Param level path int true "Variant of the playlist master"
+// @Param token query string false "Stream token, instead of credentials"
+// @Success 200 {string} string "HLS media playlist"
+// @Failure 400 {string} string
+// @Failure 403 {string} string "Invalid stream token"
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/playlists/{id}/streams/v{level}/segment_index.m3u8 [get]
+func (p PlaylistGetVariantHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET PlaylistGetVariantHandler - parameters ", vars)
+
+	id := vars["id"]
+	if !p.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	level, err := strconv.Atoi(vars["level"])
+	if err != nil || level < 0 {
+		log.Error("Invalid variant level ", vars["level"])
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	videos, err := getPlaylistVariants(r.Context(), p.PlaylistsDAO, p.S3Client, id)
+	if err != nil {
+		writePlaylistStreamsError(w, err)
+		return
+	}
+
+	medias := make([]hls.MediaPlaylist, 0, len(videos))
+	for _, video := range videos {
+		media, err := getVideoMedia(r.Context(), p.S3Client, p.StreamSigner, video.VideoID, variantOfLevel(video, level).URI)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		medias = append(medias, *media)
+	}
+
+	concatenated := hls.Concat(medias)
+	_, _ = w.Write(concatenated.Encode())
+}
+
+type PlaylistGetAudioHandler struct {
+	S3Client     clients.IS3Client
+	PlaylistsDAO *dao.PlaylistsDAO
+	UUIDGen      clients.IUUIDGenerator
+	StreamSigner auth.StreamSigner
+}
+
+// PlaylistGetAudioHandler godoc
+// @Summary Get playlist audio rendition
+// @Description Get the audio segments of a language of each encoded video of the playlist, a discontinuity starting
+// @Description each video. A video without this language plays its default one, a video whose audio is muxed with
+// @Description its video plays the one of its lowest quality. The segments carry a stream token of their video.
+// @Tags playlist
+// @Produce plain
+// @Param id path string true "Playlist ID"
+// @Param language path string true "Audio language of the playlist master"
+// @Param token query string false "Stream token, instead of credentials"
+// @Success 200 {string} string "HLS media playlist"
+// @Failure 400 {string} string
+// @Failure 403 {string} string "Invalid stream token"
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/playlists/{id}/streams/audio_{language}/segment_index.m3u8 [get]
+func (p PlaylistGetAudioHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET PlaylistGetAudioHandler - parameters ", vars)
+
+	id := vars["id"]
+	if !p.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	videos, err := getPlaylistVariants(r.Context(), p.PlaylistsDAO, p.S3Client, id)
+	if err != nil {
+		writePlaylistStreamsError(w, err)
+		return
+	}
+
+	language := vars["language"]
+	found := false
+	for _, playlistLanguage := range playlistAudioLanguages(videos) {
+		if playlistLanguage == language {
+			found = true
+			break
+		}
+	}
+	if !found {
+		log.Error("No audio language " + language + " in playlist " + id)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	medias := make([]hls.MediaPlaylist, 0, len(videos))
+	for _, video := range videos {
+		media, err := getVideoMedia(r.Context(), p.S3Client, p.StreamSigner, video.VideoID, audioOfLanguage(video, language))
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		medias = append(medias, *media)
+	}
+
+	concatenated := hls.Concat(medias)
+	_, _ = w.Write(concatenated.Encode())
+}
+
+// getVideoMedia returns the media playlist of the video at this URI of its master, its segment URIs leading to the
+// streams of the video
+func getVideoMedia(ctx context.Context, s3Client clients.IS3Client, streamSigner auth.StreamSigner, videoID string, mediaURI string) (*hls.MediaPlaylist, error) {
+	mediaPath := path.Clean(videoID + "/" + mediaURI)
+
+	object, err := s3Client.GetObject(ctx, mediaPath)
+	if err != nil {
+		log.Error("Failed to open video media "+mediaPath+" : ", err)
+		return nil, err
+	}
+
+	media, err := hls.ParseMedia(object)
+	if err != nil {
+		log.Error("Cannot parse video media "+mediaPath+" : ", err)
+		return nil, err
+	}
+
+	token := ""
+	if streamSigner.Enabled() {
+		if token, err = streamSigner.Sign(videoID); err != nil {
+			log.Error("Cannot sign stream token : ", err)
+			return nil, err
+		}
+	}
+
+	for i, segment := range media.Segments {
+		uri := videosStreamsPath + videoID + "/streams/" + path.Join(path.Dir(mediaURI), segment.URI)
+		if token != "" {
+			uri = hls.WithQueryParam(uri, auth.StreamTokenParam, token)
+		}
+		media.Segments[i].URI = uri
+	}
+
+	return media, nil
+}
+
+// getPlaylistVariants returns the variant streams and the audio renditions of the encoded videos of the playlist,
+// in their order
+func getPlaylistVariants(ctx context.Context, playlistsDAO *dao.PlaylistsDAO, s3Client clients.IS3Client, ID string) ([]videoVariants, error) {
+	if _, err := playlistsDAO.GetPlaylist(ctx, ID); err != nil {
+		log.Error("Cannot found playlist : ", err)
+		return nil, err
+	}
+
+	videos, err := playlistsDAO.GetPlaylistVideos(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	variants := []videoVariants{}
+	for _, video := range videos {
+		if video.Status != models.COMPLETE {
+			continue
+		}
+
+		object, err := s3Client.GetObject(ctx, video.ID+"/master.m3u8")
+		if err != nil {
+			log.Error("Failed to open video "+video.ID+"/master.m3u8 ", err)
+			return nil, err
+		}
+
+		master, err := hls.ParseMaster(object)
+		if err != nil {
+			log.Error("Cannot parse video "+video.ID+" master : ", err)
+			return nil, err
+		}
+		if len(master.Variants) == 0 {
+			continue
+		}
+
+		var audios []hls.Rendition
+		if group := master.Variants[0].AudioGroup(); group != "" {
+			for _, rendition := range master.Renditions {
+				if rendition.Type == "AUDIO" && rendition.GroupID == group && rendition.URI != "" {
+					audios = append(audios, rendition)
+				}
+			}
+		}
+
+		sort.SliceStable(master.Variants, func(i, j int) bool {
+			return master.Variants[i].Bandwidth < master.Variants[j].Bandwidth
+		})
+		variants = append(variants, videoVariants{VideoID: video.ID, Variants: master.Variants, Audios: audios})
+	}
+
+	if len(variants) == 0 {
+		return nil, errNoPlayableVideo
+	}
+
+	return variants, nil
+}
+
+// variantOfLevel returns the variant of the video for this level of the playlist, its best one if it has less
+func variantOfLevel(video videoVariants, level int) hls.Variant {
+	if level >= len(video.Variants) {
+		return video.Variants[len(video.Variants)-1]
+	}
+	return video.Variants[level]
+}
+
+// playlistAudioLanguages returns the audio languages of the videos of the playlist, in their order of appearance.
+// It is empty when the audio of every video is muxed with its video.
+func playlistAudioLanguages(videos []videoVariants) []string {
+	languages := []string{}
+	found := map[string]bool{}
+	for _, video := range videos {
+		for _, audio := range video.Audios {
+			if audio.Language != "" && !found[audio.Language] {
+				found[audio.Language] = true
+				languages = append(languages, audio.Language)
+			}
+		}
+	}
+	return languages
+}
+
+// audioOfLanguage returns the URI of the audio of the video for this language of the playlist : its rendition of
+// this language, else its default one, else its lowest quality when its audio is muxed with its video
+func audioOfLanguage(video videoVariants, language string) string {
+	if len(video.Audios) == 0 {
+		return video.Variants[0].URI
+	}
+
+	fallback := video.Audios[0]
+	for _, audio := range video.Audios {
+		if audio.Language == language {
+			return audio.URI
+		}
+		if audio.Default && !fallback.Default {
+			fallback = audio
+		}
+	}
+	return fallback.URI
+}
+
+func writePlaylistStreamsError(w http.ResponseWriter, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+	} else if errors.Is(err, errNoPlayableVideo) {
+		log.Error(err)
+		http.Error(w, "The playlist has no encoded video", http.StatusNotFound)
+	} else {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,262 @@
+package controllers_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+)
+
+func TestPlaylistStreams(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+	givenSecret := "stream-secret"
+
+	signer := auth.StreamSigner{Secret: []byte(givenSecret), Expiration: time.Hour}
+	playlistToken, err := signer.Sign(playlistID)
+	require.NoError(t, err)
+	videoToken, err := signer.Sign(playlistVideo1)
+	require.NoError(t, err)
+
+	// The first video has two qualities, the second one only one, the third one is archived. The last one, added by
+	// giveMultiAudio, has an audio rendition per language instead of muxed audio.
+	multiAudioVideo := "9c5d2b7e-41a8-4f0e-b6d3-7e2a1c4f8b90"
+	objects := map[string]string{
+		multiAudioVideo + "/master.m3u8": "#EXTM3U\n#EXT-X-VERSION:3\n" +
+			"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"group_audio\",NAME=\"audio_eng\",DEFAULT=YES,LANGUAGE=\"eng\",URI=\"audio_eng/segment_index.m3u8\"\n" +
+			"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"group_audio\",NAME=\"audio_fre\",DEFAULT=NO,LANGUAGE=\"fre\",URI=\"audio_fre/segment_index.m3u8\"\n" +
+			"#EXT-X-STREAM-INF:BANDWIDTH=1040800,RESOLUTION=640x360,CODECS=\"avc1.64001e\",AUDIO=\"group_audio\"\nv0/segment_index.m3u8\n",
+		multiAudioVideo + "/v0/segment_index.m3u8":        "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.000000,\nsegment0.ts\n#EXT-X-ENDLIST\n",
+		multiAudioVideo + "/audio_eng/segment_index.m3u8": "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.000000,\nsegment0.ts\n#EXT-X-ENDLIST\n",
+		multiAudioVideo + "/audio_fre/segment_index.m3u8": "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.000000,\nsegment0.ts\n#EXT-X-ENDLIST\n",
+		playlistVideo1 + "/master.m3u8": "#EXTM3U\n#EXT-X-VERSION:3\n" +
+			"#EXT-X-STREAM-INF:BANDWIDTH=2340800,RESOLUTION=1280x720\nv1/segment_index.m3u8\n" +
+			"#EXT-X-STREAM-INF:BANDWIDTH=1240800,RESOLUTION=640x480\nv0/segment_index.m3u8\n",
+		playlistVideo1 + "/v0/segment_index.m3u8": "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.000000,\nsegment0.ts\n#EXTINF:2.000000,\nsegment1.ts\n#EXT-X-ENDLIST\n",
+		playlistVideo1 + "/v1/segment_index.m3u8": "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.000000,\nsegment0.ts\n#EXTINF:2.000000,\nsegment1.ts\n#EXT-X-ENDLIST\n",
+		playlistVideo2 + "/master.m3u8": "#EXTM3U\n#EXT-X-VERSION:3\n" +
+			"#EXT-X-STREAM-INF:BANDWIDTH=1340800,RESOLUTION=640x360\nv0/segment_index.m3u8\n",
+		playlistVideo2 + "/v0/segment_index.m3u8": "#EXTM3U\n#EXT-X-TARGETDURATION:7\n#EXTINF:6.500000,\nsegment0.ts\n#EXT-X-ENDLIST\n",
+	}
+	getObject := func(s string) (io.Reader, error) {
+		object, ok := objects[s]
+		if !ok {
+			return nil, fmt.Errorf("no such object %v", s)
+		}
+		return strings.NewReader(object), nil
+	}
+
+	cases := []struct {
+		name             string
+		giveRequest      string
+		giveWithAuth     bool
+		giveSecret       bool
+		giveEmpty        bool
+		giveMultiAudio   bool
+		expectedHTTPCode int
+		expectedBody     string
+	}{
+		{
+			name:             "GET master",
+			giveRequest:      "/api/v1/playlists/" + playlistID + "/streams/master.m3u8",
+			giveWithAuth:     true,
+			expectedHTTPCode: 200,
+			expectedBody: "#EXTM3U\n#EXT-X-VERSION:3\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=1340800,RESOLUTION=640x360\nv0/segment_index.m3u8\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=2340800,RESOLUTION=1280x720\nv1/segment_index.m3u8\n",
+		},
+		{
+			name:             "GET variant of the best quality",
+			giveRequest:      "/api/v1/playlists/" + playlistID + "/streams/v1/segment_index.m3u8",
+			giveWithAuth:     true,
+			expectedHTTPCode: 200,
+			expectedBody: "#EXTM3U\n#EXT-X-TARGETDURATION:7\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n" +
+				"#EXTINF:6.000000,\n../../../../videos/" + playlistVideo1 + "/streams/v1/segment0.ts\n" +
+				"#EXTINF:2.000000,\n../../../../videos/" + playlistVideo1 + "/streams/v1/segment1.ts\n" +
+				"#EXT-X-DISCONTINUITY\n" +
+				"#EXTINF:6.500000,\n../../../../videos/" + playlistVideo2 + "/streams/v0/segment0.ts\n" +
+				"#EXT-X-ENDLIST\n",
+		},
+		{
+			name:             "GET master with token",
+			giveRequest:      "/api/v1/playlists/" + playlistID + "/streams/master.m3u8?token=" + playlistToken,
+			giveSecret:       true,
+			expectedHTTPCode: 200,
+			expectedBody: "#EXTM3U\n#EXT-X-VERSION:3\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=1340800,RESOLUTION=640x360\nv0/segment_index.m3u8?token=" + playlistToken + "\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=2340800,RESOLUTION=1280x720\nv1/segment_index.m3u8?token=" + playlistToken + "\n",
+		},
+		{
+			name:             "GET variant with token signs the segments of each video",
+			giveRequest:      "/api/v1/playlists/" + playlistID + "/streams/v0/segment_index.m3u8?token=" + playlistToken,
+			giveSecret:       true,
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "GET fails with token of a video",
+			giveRequest:      "/api/v1/playlists/" + playlistID + "/streams/master.m3u8?token=" + videoToken,
+			giveSecret:       true,
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "GET fails without credentials",
+			giveRequest:      "/api/v1/playlists/" + playlistID + "/streams/master.m3u8",
+			expectedHTTPCode: 401,
+		},
+		{
+			name:             "GET master with audio renditions",
+			giveRequest:      "/api/v1/playlists/" + playlistID + "/streams/master.m3u8",
+			giveWithAuth:     true,
+			giveMultiAudio:   true,
+			expectedHTTPCode: 200,
+			expectedBody: "#EXTM3U\n#EXT-X-VERSION:3\n" +
+				"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"audio\",NAME=\"eng\",LANGUAGE=\"eng\",DEFAULT=YES,AUTOSELECT=YES,URI=\"audio_eng/segment_index.m3u8\"\n" +
+				"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"audio\",NAME=\"fre\",LANGUAGE=\"fre\",DEFAULT=NO,AUTOSELECT=YES,URI=\"audio_fre/segment_index.m3u8\"\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=1340800,RESOLUTION=640x360,AUDIO=\"audio\"\nv0/segment_index.m3u8\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=2340800,RESOLUTION=1280x720,AUDIO=\"audio\"\nv1/segment_index.m3u8\n",
+		},
+		{
+			name:             "GET variant with audio renditions",
+			giveRequest:      "/api/v1/playlists/" + playlistID + "/streams/v1/segment_index.m3u8",
+			giveWithAuth:     true,
+			giveMultiAudio:   true,
+			expectedHTTPCode: 200,
+			expectedBody: "#EXTM3U\n#EXT-X-TARGETDURATION:7\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n" +
+				"#EXTINF:6.000000,\n../../../../videos/" + playlistVideo1 + "/streams/v1/segment0.ts\n" +
+				"#EXTINF:2.000000,\n../../../../videos/" + playlistVideo1 + "/streams/v1/segment1.ts\n" +
+				"#EXT-X-DISCONTINUITY\n" +
+				"#EXTINF:6.500000,\n../../../../videos/" + playlistVideo2 + "/streams/v0/segment0.ts\n" +
+				"#EXT-X-DISCONTINUITY\n" +
+				"#EXTINF:6.000000,\n../../../../videos/" + multiAudioVideo + "/streams/v0/segment0.ts\n" +
+				"#EXT-X-ENDLIST\n",
+		},
+		{
+			name:             "GET audio rendition plays the lowest quality of the videos with muxed audio",
+			giveRequest:      "/api/v1/playlists/" + playlistID + "/streams/audio_fre/segment_index.m3u8",
+			giveWithAuth:     true,
+			giveMultiAudio:   true,
+			expectedHTTPCode: 200,
+			expectedBody: "#EXTM3U\n#EXT-X-TARGETDURATION:7\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n" +
+				"#EXTINF:6.000000,\n../../../../videos/" + playlistVideo1 + "/streams/v0/segment0.ts\n" +
+				"#EXTINF:2.000000,\n../../../../videos/" + playlistVideo1 + "/streams/v0/segment1.ts\n" +
+				"#EXT-X-DISCONTINUITY\n" +
+				"#EXTINF:6.500000,\n../../../../videos/" + playlistVideo2 + "/streams/v0/segment0.ts\n" +
+				"#EXT-X-DISCONTINUITY\n" +
+				"#EXTINF:6.000000,\n../../../../videos/" + multiAudioVideo + "/streams/audio_fre/segment0.ts\n" +
+				"#EXT-X-ENDLIST\n",
+		},
+		{
+			name:             "GET audio rendition with token",
+			giveRequest:      "/api/v1/playlists/" + playlistID + "/streams/audio_eng/segment_index.m3u8?token=" + playlistToken,
+			giveSecret:       true,
+			giveMultiAudio:   true,
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "GET audio rendition fails with unknown language",
+			giveRequest:      "/api/v1/playlists/" + playlistID + "/streams/audio_ger/segment_index.m3u8",
+			giveWithAuth:     true,
+			giveMultiAudio:   true,
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "GET audio rendition fails without audio renditions",
+			giveRequest:      "/api/v1/playlists/" + playlistID + "/streams/audio_eng/segment_index.m3u8",
+			giveWithAuth:     true,
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "GET fails without encoded video",
+			giveRequest:      "/api/v1/playlists/" + playlistID + "/streams/master.m3u8",
+			giveWithAuth:     true,
+			giveEmpty:        true,
+			expectedHTTPCode: 404,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectPlaylistsDAOCreation(mock)
+
+			if tt.expectedHTTPCode != 401 && tt.expectedHTTPCode != 403 {
+				expectPlaylistLookup(mock, nil)
+
+				t1 := time.Now()
+				rows := sqlmock.NewRows(playlistVideosColumns)
+				if !tt.giveEmpty {
+					rows.AddRow(playlistVideo1, "first", int(models.COMPLETE), t1, t1, t1, "", "", nil, "", nil, nil)
+					rows.AddRow(playlistVideo2, "second", int(models.COMPLETE), t1, t1, t1, "", "", nil, "", nil, nil)
+				}
+				rows.AddRow(playlistVideo3, "third", int(models.ARCHIVE), t1, t1, t1, "", "", nil, "", nil, nil)
+				if tt.giveMultiAudio {
+					rows.AddRow(multiAudioVideo, "fourth", int(models.COMPLETE), t1, t1, t1, "", "", nil, "", nil, nil)
+				}
+				mock.ExpectQuery(regexp.QuoteMeta(dao.PlaylistsRequests[dao.GetPlaylistVideos])).WithArgs(playlistID).WillReturnRows(rows)
+			}
+
+			playlistsDAO, err := dao.CreatePlaylistsDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			cfg := config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}
+			if tt.giveSecret {
+				cfg.StreamTokenSecret = givenSecret
+				cfg.StreamTokenExpiration = time.Hour
+			}
+
+			r, err := router.NewRouter(cfg, &router.Clients{
+				S3Client: clients.NewS3ClientDummy(nil, getObject, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil),
+				UUIDGen:  clients.NewUuidGeneratorDummy(nil, func(u string) bool { _, err := uuid.Parse(u); return err == nil }),
+			}, &router.DAOs{PlaylistsDAO: *playlistsDAO})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tt.giveRequest, nil)
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedBody != "" {
+				require.Equal(t, tt.expectedBody, w.Body.String())
+			}
+			if tt.giveSecret && tt.expectedHTTPCode == 200 && strings.Contains(tt.giveRequest, "segment_index") {
+				// Each segment carries the token of its own video
+				for _, line := range strings.Split(w.Body.String(), "\n") {
+					if strings.HasPrefix(line, "../../../../videos/"+playlistVideo1) {
+						require.Contains(t, line, "?token=")
+						require.NotContains(t, line, playlistToken)
+					}
+				}
+				require.Contains(t, w.Body.String(), "#EXT-X-DISCONTINUITY\n")
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
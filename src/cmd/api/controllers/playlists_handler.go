@@ -0,0 +1,554 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+// PlaylistRequest fields are optional on update : only the given ones are updated
+type PlaylistRequest struct {
+	Title       *string `json:"title,omitempty" example:"Onboarding"`
+	Description *string `json:"description,omitempty" example:"Videos to watch the first week"`
+}
+
+// PlaylistVideoAddRequest gives the position of the video from 0, the video is appended without position
+type PlaylistVideoAddRequest struct {
+	VideoID  string `json:"videoId" example:"aaaa-b56b-..."`
+	Position *int   `json:"position,omitempty" example:"0"`
+}
+
+// PlaylistVideosReorderRequest lists all the videos of the playlist in their new order
+type PlaylistVideosReorderRequest struct {
+	VideoIDs []string `json:"videoIds" example:"aaaa-b56b-...,bbbb-c67c-..."`
+}
+
+type PlaylistsListResponse struct {
+	Playlists []jsonDTO.PlaylistJson      `json:"playlists"`
+	Links     map[string]jsonDTO.LinkJson `json:"_links"`
+}
+
+type PlaylistCreateHandler struct {
+	PlaylistsDAO *dao.PlaylistsDAO
+	UUIDGen      clients.IUUIDGenerator
+}
+
+// PlaylistCreateHandler godoc
+// @Summary Create a playlist
+// @Description Create an empty playlist, owned by the authenticated user
+// @Tags playlist
+// @Accept json
+// @Produce json
+// @Param request body PlaylistRequest true "Title and description of the playlist"
+// @Success 201 {object} jsonDTO.PlaylistJson "Created playlist"
+// @Failure 400 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/playlists [post]
+func (p PlaylistCreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("POST PlaylistCreateHandler")
+
+	request, err := decodePlaylistRequest(r)
+	if err != nil {
+		log.Error("Cannot decode playlist request : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if request.Title == nil {
+		log.Error("Playlist request without title")
+		http.Error(w, "A title is required", http.StatusBadRequest)
+		return
+	}
+	playlist := &models.Playlist{}
+	if err := applyPlaylistUpdate(playlist, request); err != nil {
+		log.Error("Invalid playlist request : ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	playlistID, err := p.UUIDGen.GenerateUuid()
+	if err != nil {
+		log.Error("Cannot generate new UUID : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	playlist, err = p.PlaylistsDAO.CreatePlaylist(r.Context(), playlistID, playlist.Title, playlist.Description, auth.OwnerID(r.Context()))
+	if err != nil {
+		log.Error("Cannot create playlist : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	playlist.Videos = []models.Video{}
+
+	writePlaylistJson(w, http.StatusCreated, playlist)
+	log.Infof("Playlist %v created", playlist.ID)
+}
+
+type PlaylistsListHandler struct {
+	PlaylistsDAO *dao.PlaylistsDAO
+}
+
+// PlaylistsListHandler godoc
+// @Summary Get list of all playlists
+// @Description Get list of all playlists sorted by title, without their videos
+// @Tags playlist
+// @Produce json
+// @Success 200 {object} PlaylistsListResponse "Playlist list and Hateoas links"
+// @Failure 500 {string} string
+// @Router /api/v1/playlists [get]
+func (p PlaylistsListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GET PlaylistsListHandler")
+
+	playlists, err := p.PlaylistsDAO.GetPlaylists(r.Context())
+	if err != nil {
+		log.Error("Unable to list playlists from database : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := PlaylistsListResponse{
+		Playlists: []jsonDTO.PlaylistJson{},
+		Links: map[string]jsonDTO.LinkJson{
+			"self":   jsonDTO.LinkToLinkJson(models.CreateLink("api/v1/playlists", "GET")),
+			"create": jsonDTO.LinkToLinkJson(models.CreateLink("api/v1/playlists", "POST")),
+		},
+	}
+	for i := range playlists {
+		response.Playlists = append(response.Playlists, jsonDTO.PlaylistToPlaylistJson(&playlists[i]))
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(payload)
+}
+
+type PlaylistGetHandler struct {
+	PlaylistsDAO *dao.PlaylistsDAO
+	UUIDGen      clients.IUUIDGenerator
+}
+
+// PlaylistGetHandler godoc
+// @Summary Get a playlist
+// @Description Get a playlist with its videos in their order
+// @Tags playlist
+// @Produce json
+// @Param id path string true "Playlist ID"
+// @Success 200 {object} jsonDTO.PlaylistJson "Playlist and its videos"
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/playlists/{id} [get]
+func (p PlaylistGetHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET PlaylistGetHandler - parameters ", vars)
+
+	playlist, ok := getPlaylist(w, r, p.PlaylistsDAO, p.UUIDGen, false)
+	if !ok {
+		return
+	}
+
+	writePlaylistWithVideos(r.Context(), w, p.PlaylistsDAO, playlist)
+}
+
+type PlaylistUpdateHandler struct {
+	PlaylistsDAO *dao.PlaylistsDAO
+	UUIDGen      clients.IUUIDGenerator
+}
+
+// PlaylistUpdateHandler godoc
+// @Summary Update a playlist
+// @Description Update the title and the description of the playlist
+// @Tags playlist
+// @Accept json
+// @Produce json
+// @Param id path string true "Playlist ID"
+// @Param request body PlaylistRequest true "Metadata to update"
+// @Success 200 {object} jsonDTO.PlaylistJson "Updated playlist"
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/playlists/{id} [patch]
+func (p PlaylistUpdateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("PATCH PlaylistUpdateHandler - parameters ", vars)
+
+	request, err := decodePlaylistRequest(r)
+	if err != nil {
+		log.Error("Cannot decode playlist request : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	playlist, ok := getPlaylist(w, r, p.PlaylistsDAO, p.UUIDGen, true)
+	if !ok {
+		return
+	}
+
+	if err := applyPlaylistUpdate(playlist, request); err != nil {
+		log.Error("Invalid playlist request : ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := p.PlaylistsDAO.UpdatePlaylist(r.Context(), playlist); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writePlaylistWithVideos(r.Context(), w, p.PlaylistsDAO, playlist)
+	log.Infof("Playlist %v updated", playlist.ID)
+}
+
+type PlaylistDeleteHandler struct {
+	PlaylistsDAO *dao.PlaylistsDAO
+	UUIDGen      clients.IUUIDGenerator
+}
+
+// PlaylistDeleteHandler godoc
+// @Summary Delete a playlist
+// @Description Delete the playlist, its videos are kept
+// @Tags playlist
+// @Param id path string true "Playlist ID"
+// @Success 204 {string} string
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/playlists/{id} [delete]
+func (p PlaylistDeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("DELETE PlaylistDeleteHandler - parameters ", vars)
+
+	playlist, ok := getPlaylist(w, r, p.PlaylistsDAO, p.UUIDGen, true)
+	if !ok {
+		return
+	}
+
+	if err := p.PlaylistsDAO.DeletePlaylist(r.Context(), playlist.ID); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	log.Infof("Playlist %v deleted", playlist.ID)
+}
+
+type PlaylistVideoAddHandler struct {
+	PlaylistsDAO *dao.PlaylistsDAO
+	VideosDAO    *dao.VideosDAO
+	UUIDGen      clients.IUUIDGenerator
+}
+
+// PlaylistVideoAddHandler godoc
+// @Summary Add a video to a playlist
+// @Description Insert the video at the position, from 0, or append it without position.
+// @Description The videos after this position move one position further.
+// @Tags playlist
+// @Accept json
+// @Produce json
+// @Param id path string true "Playlist ID"
+// @Param request body PlaylistVideoAddRequest true "Video to add"
+// @Success 200 {object} jsonDTO.PlaylistJson "Playlist and its videos"
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string "The video is already in the playlist"
+// @Failure 500 {string} string
+// @Router /api/v1/playlists/{id}/videos [post]
+func (p PlaylistVideoAddHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) { //nolint:cyclop
+	vars := mux.Vars(r)
+	log.Debug("POST PlaylistVideoAddHandler - parameters ", vars)
+
+	var request PlaylistVideoAddRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		log.Error("Cannot decode playlist video request : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !p.UUIDGen.IsValidUUID(request.VideoID) {
+		log.Error("Invalid video id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	position := -1
+	if request.Position != nil {
+		if *request.Position < 0 {
+			http.Error(w, "Position must be positive", http.StatusBadRequest)
+			return
+		}
+		position = *request.Position
+	}
+
+	playlist, ok := getPlaylist(w, r, p.PlaylistsDAO, p.UUIDGen, true)
+	if !ok {
+		return
+	}
+
+	if _, err := p.VideosDAO.GetVideo(r.Context(), request.VideoID); err != nil {
+		log.Error("Cannot found video : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Video not found", http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	videos, err := p.PlaylistsDAO.GetPlaylistVideos(r.Context(), playlist.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	for _, video := range videos {
+		if video.ID == request.VideoID {
+			log.Error("Video " + video.ID + " already in playlist " + playlist.ID)
+			http.Error(w, "The video is already in the playlist", http.StatusConflict)
+			return
+		}
+	}
+
+	if err := p.PlaylistsDAO.AddPlaylistVideo(r.Context(), playlist.ID, request.VideoID, position); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writePlaylistWithVideos(r.Context(), w, p.PlaylistsDAO, playlist)
+	log.Infof("Video %v added to playlist %v", request.VideoID, playlist.ID)
+}
+
+type PlaylistVideoRemoveHandler struct {
+	PlaylistsDAO *dao.PlaylistsDAO
+	UUIDGen      clients.IUUIDGenerator
+}
+
+// PlaylistVideoRemoveHandler godoc
+// @Summary Remove a video from a playlist
+// @Description Remove the video from the playlist, the next videos move one position back. The video is kept.
+// @Tags playlist
+// @Produce json
+// @Param id path string true "Playlist ID"
+// @Param videoId path string true "Video ID"
+// @Success 200 {object} jsonDTO.PlaylistJson "Playlist and its videos"
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/playlists/{id}/videos/{videoId} [delete]
+func (p PlaylistVideoRemoveHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("DELETE PlaylistVideoRemoveHandler - parameters ", vars)
+
+	videoID := vars["videoId"]
+	if !p.UUIDGen.IsValidUUID(videoID) {
+		log.Error("Invalid video id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	playlist, ok := getPlaylist(w, r, p.PlaylistsDAO, p.UUIDGen, true)
+	if !ok {
+		return
+	}
+
+	if err := p.PlaylistsDAO.RemovePlaylistVideo(r.Context(), playlist.ID, videoID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "The video is not in the playlist", http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writePlaylistWithVideos(r.Context(), w, p.PlaylistsDAO, playlist)
+	log.Infof("Video %v removed from playlist %v", videoID, playlist.ID)
+}
+
+type PlaylistVideosReorderHandler struct {
+	PlaylistsDAO *dao.PlaylistsDAO
+	UUIDGen      clients.IUUIDGenerator
+}
+
+// PlaylistVideosReorderHandler godoc
+// @Summary Reorder the videos of a playlist
+// @Description Give the new order of the videos. The request must list every video of the playlist, once.
+// @Tags playlist
+// @Accept json
+// @Produce json
+// @Param id path string true "Playlist ID"
+// @Param request body PlaylistVideosReorderRequest true "Videos in their new order"
+// @Success 200 {object} jsonDTO.PlaylistJson "Playlist and its videos"
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/playlists/{id}/videos [put]
+func (p PlaylistVideosReorderHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("PUT PlaylistVideosReorderHandler - parameters ", vars)
+
+	var request PlaylistVideosReorderRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		log.Error("Cannot decode playlist reorder request : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	playlist, ok := getPlaylist(w, r, p.PlaylistsDAO, p.UUIDGen, true)
+	if !ok {
+		return
+	}
+
+	videos, err := p.PlaylistsDAO.GetPlaylistVideos(r.Context(), playlist.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !isPermutation(videos, request.VideoIDs) {
+		log.Error("Reorder request of playlist " + playlist.ID + " does not match its videos")
+		http.Error(w, "The request must list every video of the playlist, once", http.StatusBadRequest)
+		return
+	}
+
+	if err := p.PlaylistsDAO.ReorderPlaylistVideos(r.Context(), playlist.ID, request.VideoIDs); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writePlaylistWithVideos(r.Context(), w, p.PlaylistsDAO, playlist)
+	log.Infof("Playlist %v reordered", playlist.ID)
+}
+
+func decodePlaylistRequest(r *http.Request) (PlaylistRequest, error) {
+	var request PlaylistRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&request)
+	return request, err
+}
+
+// applyPlaylistUpdate validates the request and sets the given metadata on the playlist
+func applyPlaylistUpdate(playlist *models.Playlist, request PlaylistRequest) error {
+	if request.Title != nil {
+		title := strings.TrimSpace(*request.Title)
+		if title == "" || utf8.RuneCountInString(title) > MaxTitleLength {
+			return fmt.Errorf("title must have between 1 and %v characters", MaxTitleLength)
+		}
+		playlist.Title = title
+	}
+
+	if request.Description != nil {
+		description := strings.TrimSpace(*request.Description)
+		if utf8.RuneCountInString(description) > MaxDescriptionLength {
+			return fmt.Errorf("description must have at most %v characters", MaxDescriptionLength)
+		}
+		playlist.Description = description
+	}
+
+	return nil
+}
+
+// getPlaylist returns the playlist of the request, or writes the error response.
+// With manage, the authenticated user must be allowed to change the playlist.
+func getPlaylist(w http.ResponseWriter, r *http.Request, playlistsDAO *dao.PlaylistsDAO, uuidGen clients.IUUIDGenerator, manage bool) (*models.Playlist, bool) {
+	id := mux.Vars(r)["id"]
+	if !uuidGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, false
+	}
+
+	playlist, err := playlistsDAO.GetPlaylist(r.Context(), id)
+	if err != nil {
+		log.Error("Cannot found playlist : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+
+	if manage && !auth.CanManagePlaylist(r.Context(), playlist) {
+		log.Error("Playlist " + playlist.ID + " belongs to another user")
+		w.WriteHeader(http.StatusForbidden)
+		return nil, false
+	}
+
+	return playlist, true
+}
+
+// isPermutation returns true if the IDs are the ones of the videos, each one once
+func isPermutation(videos []models.Video, IDs []string) bool {
+	if len(videos) != len(IDs) {
+		return false
+	}
+
+	remaining := map[string]bool{}
+	for _, video := range videos {
+		remaining[video.ID] = true
+	}
+	for _, ID := range IDs {
+		if !remaining[ID] {
+			return false
+		}
+		delete(remaining, ID)
+	}
+
+	return true
+}
+
+// writePlaylistWithVideos writes the playlist with its current videos, its cover being the one of the first video
+func writePlaylistWithVideos(ctx context.Context, w http.ResponseWriter, playlistsDAO *dao.PlaylistsDAO, playlist *models.Playlist) {
+	videos, err := playlistsDAO.GetPlaylistVideos(ctx, playlist.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	playlist.Videos = videos
+	playlist.CoverVideo = nil
+	if len(videos) > 0 {
+		playlist.CoverVideo = &videos[0].ID
+	}
+
+	writePlaylistJson(w, http.StatusOK, playlist)
+}
+
+func writePlaylistJson(w http.ResponseWriter, statusCode int, playlist *models.Playlist) {
+	payload, err := json.Marshal(jsonDTO.PlaylistToPlaylistJson(playlist))
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(payload)
+}
@@ -0,0 +1,468 @@
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+)
+
+// Playlist of the tests, and the videos it may hold
+const (
+	playlistID      = "3c1f9e2a-7d4b-4e8a-9b6c-2f0d5a8e1b73"
+	playlistVideo1  = "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	playlistVideo2  = "b7e6a0c1-5bc6-4a50-9176-ab0371aa65fe"
+	playlistVideo3  = "c2d8f4e6-1a3b-4c5d-8e9f-0a1b2c3d4e5f"
+	playlistOwnerID = "9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"
+)
+
+var (
+	playlistsColumns      = []string{"id", "title", "description", "owner_id", "created_at", "updated_at", "cover_video"}
+	playlistVideosColumns = []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
+)
+
+// expectPlaylistLookup mocks the lookup of the playlist, owned by ownerID
+func expectPlaylistLookup(mock sqlmock.Sqlmock, ownerID interface{}) {
+	t1 := time.Now()
+	rows := sqlmock.NewRows(playlistsColumns).AddRow(playlistID, "Onboarding", "", ownerID, t1, t1, nil)
+	mock.ExpectQuery(regexp.QuoteMeta(dao.PlaylistsRequests[dao.GetPlaylist])).WithArgs(playlistID).WillReturnRows(rows)
+}
+
+// expectPlaylistVideos mocks the videos of the playlist, in their order
+func expectPlaylistVideos(mock sqlmock.Sqlmock, IDs ...string) {
+	t1 := time.Now()
+	rows := sqlmock.NewRows(playlistVideosColumns)
+	for _, ID := range IDs {
+		rows.AddRow(ID, "title-"+ID, int(models.COMPLETE), t1, t1, t1, ID+"/source.mp4", ID+"/cover.png", nil, "", nil, nil)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(dao.PlaylistsRequests[dao.GetPlaylistVideos])).WithArgs(playlistID).WillReturnRows(rows)
+}
+
+func TestPlaylistCreate(t *testing.T) {
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	cases := []struct {
+		name             string
+		giveBody         string
+		giveAccount      bool
+		giveDatabaseErr  bool
+		expectedOwnerID  interface{}
+		expectedHTTPCode int
+	}{
+		{
+			name:             "POST create playlist",
+			giveBody:         `{"title": " Onboarding ", "description": "First week"}`,
+			expectedHTTPCode: 201,
+		},
+		{
+			name:             "POST create playlist owned by the user",
+			giveBody:         `{"title": "Onboarding", "description": "First week"}`,
+			giveAccount:      true,
+			expectedOwnerID:  accountID,
+			expectedHTTPCode: 201,
+		},
+		{
+			name:             "POST fails without title",
+			giveBody:         `{"description": "First week"}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with too long title",
+			giveBody:         `{"title": "` + strings.Repeat("a", 65) + `"}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with database error",
+			giveBody:         `{"title": "Onboarding"}`,
+			giveDatabaseErr:  true,
+			expectedHTTPCode: 500,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectUsersDAOCreation(mock)
+			dao_test.ExpectPlaylistsDAOCreation(mock)
+
+			if tt.giveAccount {
+				expectAccountLookup(t, mock)
+			}
+
+			if tt.expectedHTTPCode == 201 || tt.giveDatabaseErr {
+				createPlaylistQuery := regexp.QuoteMeta(dao.PlaylistsRequests[dao.CreatePlaylist])
+				if tt.giveDatabaseErr {
+					mock.ExpectExec(createPlaylistQuery).WillReturnError(fmt.Errorf("database internal error"))
+				} else {
+					mock.ExpectExec(createPlaylistQuery).WithArgs(playlistID, "Onboarding", "First week", tt.expectedOwnerID).WillReturnResult(sqlmock.NewResult(1, 1))
+					expectPlaylistLookup(mock, tt.expectedOwnerID)
+				}
+			}
+
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+			playlistsDAO, err := dao.CreatePlaylistsDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &router.Clients{
+				UUIDGen: clients.NewUuidGeneratorDummy(func() (string, error) { return playlistID, nil }, nil),
+			}, &router.DAOs{UsersDAO: *usersDAO, PlaylistsDAO: *playlistsDAO})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/playlists", strings.NewReader(tt.giveBody))
+			if tt.giveAccount {
+				req.SetBasicAuth(accountUsername, accountPassword)
+			} else {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedHTTPCode == 201 {
+				var playlist jsonDTO.PlaylistJson
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &playlist))
+				require.Equal(t, playlistID, playlist.ID)
+				require.NotNil(t, playlist.Videos)
+				require.Empty(t, *playlist.Videos)
+				require.Equal(t, "api/v1/playlists/"+playlistID, playlist.Links["self"].Href)
+				require.NotContains(t, playlist.Links, "cover")
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPlaylistVideoAdd(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	cases := []struct {
+		name             string
+		giveBody         string
+		giveAccount      bool
+		giveOwnerID      interface{}
+		giveUnknownVideo bool
+		giveDuplicate    bool
+		expectedPosition int
+		expectedShift    bool
+		expectedHTTPCode int
+	}{
+		{
+			name:             "POST append video",
+			giveBody:         `{"videoId": "` + playlistVideo3 + `"}`,
+			expectedPosition: 2,
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "POST insert video at first position",
+			giveBody:         `{"videoId": "` + playlistVideo3 + `", "position": 0}`,
+			expectedPosition: 0,
+			expectedShift:    true,
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "POST append video after the last position",
+			giveBody:         `{"videoId": "` + playlistVideo3 + `", "position": 42}`,
+			expectedPosition: 2,
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "POST fails with negative position",
+			giveBody:         `{"videoId": "` + playlistVideo3 + `", "position": -1}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with invalid video id",
+			giveBody:         `{"videoId": "invalid"}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with unknown video",
+			giveBody:         `{"videoId": "` + playlistVideo3 + `"}`,
+			giveUnknownVideo: true,
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "POST fails with video already in playlist",
+			giveBody:         `{"videoId": "` + playlistVideo2 + `"}`,
+			giveDuplicate:    true,
+			expectedHTTPCode: 409,
+		},
+		{
+			name:             "POST fails with playlist of another user",
+			giveBody:         `{"videoId": "` + playlistVideo3 + `"}`,
+			giveAccount:      true,
+			giveOwnerID:      playlistOwnerID,
+			expectedHTTPCode: 403,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectUsersDAOCreation(mock)
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectPlaylistsDAOCreation(mock)
+
+			if tt.giveAccount {
+				expectAccountLookup(t, mock)
+			}
+
+			if tt.expectedHTTPCode != 400 {
+				expectPlaylistLookup(mock, tt.giveOwnerID)
+			}
+
+			if tt.expectedHTTPCode != 400 && tt.expectedHTTPCode != 403 {
+				t1 := time.Now()
+				videoRows := sqlmock.NewRows(playlistVideosColumns)
+				if !tt.giveUnknownVideo {
+					videoID := playlistVideo3
+					if tt.giveDuplicate {
+						videoID = playlistVideo2
+					}
+					videoRows.AddRow(videoID, "title", int(models.COMPLETE), t1, t1, t1, "source.mp4", "cover.png", nil, "", nil, nil)
+				}
+				mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WillReturnRows(videoRows)
+			}
+
+			if tt.expectedHTTPCode == 200 || tt.giveDuplicate {
+				expectPlaylistVideos(mock, playlistVideo1, playlistVideo2)
+			}
+
+			if tt.expectedHTTPCode == 200 {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(dao.PlaylistsRequests[dao.LockPlaylist])).WithArgs(playlistID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(playlistID))
+				mock.ExpectQuery(regexp.QuoteMeta(dao.PlaylistsRequests[dao.GetTotalPlaylistVideos])).WithArgs(playlistID).WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(2))
+				if tt.expectedShift {
+					mock.ExpectExec(regexp.QuoteMeta(dao.PlaylistsRequests[dao.ShiftPlaylistVideos])).WithArgs(1, playlistID, tt.expectedPosition).WillReturnResult(sqlmock.NewResult(0, 2))
+				}
+				mock.ExpectExec(regexp.QuoteMeta(dao.PlaylistsRequests[dao.AddPlaylistVideo])).WithArgs(playlistID, playlistVideo3, tt.expectedPosition).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+
+				if tt.expectedShift {
+					expectPlaylistVideos(mock, playlistVideo3, playlistVideo1, playlistVideo2)
+				} else {
+					expectPlaylistVideos(mock, playlistVideo1, playlistVideo2, playlistVideo3)
+				}
+			}
+
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+			playlistsDAO, err := dao.CreatePlaylistsDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &router.Clients{
+				UUIDGen: clients.NewUuidGeneratorDummy(nil, func(u string) bool { _, err := uuid.Parse(u); return err == nil }),
+			}, &router.DAOs{UsersDAO: *usersDAO, VideosDAO: *videosDAO, PlaylistsDAO: *playlistsDAO})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/playlists/"+playlistID+"/videos", strings.NewReader(tt.giveBody))
+			if tt.giveAccount {
+				req.SetBasicAuth(accountUsername, accountPassword)
+			} else {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedHTTPCode == 200 {
+				var playlist jsonDTO.PlaylistJson
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &playlist))
+				require.Len(t, *playlist.Videos, 3)
+				require.Equal(t, playlistVideo3, (*playlist.Videos)[tt.expectedPosition].ID)
+				require.Equal(t, "api/v1/videos/"+(*playlist.Videos)[0].ID+"/cover", playlist.Links["cover"].Href)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPlaylistVideosReorder(t *testing.T) {
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	cases := []struct {
+		name             string
+		giveBody         string
+		expectedHTTPCode int
+	}{
+		{
+			name:             "PUT reorder videos",
+			giveBody:         `{"videoIds": ["` + playlistVideo2 + `", "` + playlistVideo1 + `"]}`,
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "PUT fails with missing video",
+			giveBody:         `{"videoIds": ["` + playlistVideo2 + `"]}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "PUT fails with duplicated video",
+			giveBody:         `{"videoIds": ["` + playlistVideo2 + `", "` + playlistVideo2 + `"]}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "PUT fails with video not in playlist",
+			giveBody:         `{"videoIds": ["` + playlistVideo2 + `", "` + playlistVideo3 + `"]}`,
+			expectedHTTPCode: 400,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectPlaylistsDAOCreation(mock)
+
+			expectPlaylistLookup(mock, nil)
+			expectPlaylistVideos(mock, playlistVideo1, playlistVideo2)
+
+			if tt.expectedHTTPCode == 200 {
+				updatePositionQuery := regexp.QuoteMeta(dao.PlaylistsRequests[dao.UpdatePlaylistVideoPosition])
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(dao.PlaylistsRequests[dao.LockPlaylist])).WithArgs(playlistID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(playlistID))
+				mock.ExpectExec(updatePositionQuery).WithArgs(0, playlistID, playlistVideo2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(updatePositionQuery).WithArgs(1, playlistID, playlistVideo1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				expectPlaylistVideos(mock, playlistVideo2, playlistVideo1)
+			}
+
+			playlistsDAO, err := dao.CreatePlaylistsDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &router.Clients{
+				UUIDGen: clients.NewUuidGeneratorDummy(nil, func(u string) bool { _, err := uuid.Parse(u); return err == nil }),
+			}, &router.DAOs{PlaylistsDAO: *playlistsDAO})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("PUT", "/api/v1/playlists/"+playlistID+"/videos", strings.NewReader(tt.giveBody))
+			req.SetBasicAuth(givenUsername, givenUserPwd)
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPlaylistVideoRemove(t *testing.T) {
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	cases := []struct {
+		name             string
+		giveVideoID      string
+		giveNotInList    bool
+		expectedHTTPCode int
+	}{
+		{
+			name:             "DELETE remove video",
+			giveVideoID:      playlistVideo1,
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "DELETE fails with video not in playlist",
+			giveVideoID:      playlistVideo3,
+			giveNotInList:    true,
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "DELETE fails with invalid video id",
+			giveVideoID:      "invalid",
+			expectedHTTPCode: 400,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectPlaylistsDAOCreation(mock)
+
+			if tt.expectedHTTPCode != 400 {
+				expectPlaylistLookup(mock, nil)
+
+				positionQuery := regexp.QuoteMeta(dao.PlaylistsRequests[dao.GetPlaylistVideoPosition])
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(dao.PlaylistsRequests[dao.LockPlaylist])).WithArgs(playlistID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(playlistID))
+				if tt.giveNotInList {
+					mock.ExpectQuery(positionQuery).WithArgs(playlistID, tt.giveVideoID).WillReturnRows(sqlmock.NewRows([]string{"position"}))
+					mock.ExpectRollback()
+				} else {
+					mock.ExpectQuery(positionQuery).WithArgs(playlistID, tt.giveVideoID).WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow(0))
+					mock.ExpectExec(regexp.QuoteMeta(dao.PlaylistsRequests[dao.RemovePlaylistVideo])).WithArgs(playlistID, tt.giveVideoID).WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectExec(regexp.QuoteMeta(dao.PlaylistsRequests[dao.ShiftPlaylistVideos])).WithArgs(-1, playlistID, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectCommit()
+					expectPlaylistVideos(mock, playlistVideo2)
+				}
+			}
+
+			playlistsDAO, err := dao.CreatePlaylistsDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &router.Clients{
+				UUIDGen: clients.NewUuidGeneratorDummy(nil, func(u string) bool { _, err := uuid.Parse(u); return err == nil }),
+			}, &router.DAOs{PlaylistsDAO: *playlistsDAO})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("DELETE", "/api/v1/playlists/"+playlistID+"/videos/"+tt.giveVideoID, nil)
+			req.SetBasicAuth(givenUsername, givenUserPwd)
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
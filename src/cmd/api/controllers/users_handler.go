@@ -0,0 +1,271 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+// Limits of the user credentials. Bcrypt ignores the bytes of a password after the 72th.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+var usernameRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]{3,64}$`)
+
+// UserCredentials of a user account
+type UserCredentials struct {
+	Username string `json:"username" example:"alice"`
+	Password string `json:"password" example:"a strong password"`
+}
+
+type UserRegisterHandler struct {
+	UsersDAO *dao.UsersDAO
+	UUIDGen  clients.IUUIDGenerator
+}
+
+// UserRegisterHandler godoc
+// @Summary Register a user
+// @Description Create a user account, with the shared account only. The username has 3 to 64 letters, digits, '_',
+// @Description '.' or '-', the password 8 to 72 bytes. Users authenticate with basic auth or tokens, and only manage
+// @Description their own videos.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body UserCredentials true "Credentials of the new user"
+// @Success 201 {object} jsonDTO.UserJson "Created user"
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string "Not the shared account"
+// @Failure 409 {string} string "This username already exists"
+// @Failure 500 {string} string
+// @Router /api/v1/users/register [post]
+func (u UserRegisterHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("POST UserRegisterHandler")
+
+	credentials, err := decodeCredentials(r)
+	if err != nil {
+		log.Error("Cannot decode user credentials : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !usernameRegex.MatchString(credentials.Username) {
+		log.Error("Invalid username : ", credentials.Username)
+		http.Error(w, "Username must have 3 to 64 letters, digits, '_', '.' or '-'", http.StatusBadRequest)
+		return
+	}
+
+	if len(credentials.Password) < MinPasswordLength || len(credentials.Password) > MaxPasswordLength {
+		log.Error("Invalid password length for user ", credentials.Username)
+		http.Error(w, "Password must have 8 to 72 bytes", http.StatusBadRequest)
+		return
+	}
+
+	_, err = u.UsersDAO.GetUserFromUsername(r.Context(), credentials.Username)
+	if err == nil {
+		log.Error("User " + credentials.Username + " already exists")
+		w.WriteHeader(http.StatusConflict)
+		return
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		log.Error("Cannot get user : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(credentials.Password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Error("Cannot hash password : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	userID, err := u.UUIDGen.GenerateUuid()
+	if err != nil {
+		log.Error("Cannot generate new UUID : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Another registration of the same username may have been done since the lookup
+	user, err := u.UsersDAO.CreateUser(r.Context(), userID, credentials.Username, string(passwordHash))
+	if errors.Is(err, dao.ErrDuplicateUsername) {
+		log.Error("User " + credentials.Username + " already exists")
+		w.WriteHeader(http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Error("Cannot create user : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	payload, err := json.Marshal(jsonDTO.UserToUserJson(user))
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write(payload)
+}
+
+// UserTokensResponse has no user for the shared account, and no token when no signing key is configured
+type UserTokensResponse struct {
+	User         *jsonDTO.UserJson `json:"user,omitempty"`
+	AccessToken  string            `json:"accessToken,omitempty" example:"eyJhbGciOiJIUzI1NiIs..."`
+	RefreshToken string            `json:"refreshToken,omitempty" example:"eyJhbGciOiJIUzI1NiIs..."`
+	TokenType    string            `json:"tokenType,omitempty" example:"Bearer"`
+	ExpiresIn    int               `json:"expiresIn,omitempty" example:"900"`
+}
+
+type UserLoginHandler struct {
+	Authenticator auth.Authenticator
+}
+
+// UserLoginHandler godoc
+// @Summary Log in a user
+// @Description Check the credentials of a user account, or of the shared account, and issue an access token
+// @Description to send as a bearer token, and a refresh token to get new tokens.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body UserCredentials true "Credentials of the user"
+// @Success 200 {object} UserTokensResponse "Authenticated user and its tokens"
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/users/login [post]
+func (u UserLoginHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("POST UserLoginHandler")
+
+	credentials, err := decodeCredentials(r)
+	if err != nil {
+		log.Error("Cannot decode user credentials : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	user, err := u.Authenticator.Login(r.Context(), credentials.Username, credentials.Password)
+	if err != nil {
+		log.Error("Cannot authenticate user ", credentials.Username)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	writeUserTokens(w, u.Authenticator, user)
+}
+
+// UserRefreshRequest holds the refresh token given on login
+type UserRefreshRequest struct {
+	RefreshToken string `json:"refreshToken" example:"eyJhbGciOiJIUzI1NiIs..."`
+}
+
+type UserRefreshHandler struct {
+	Authenticator auth.Authenticator
+	UsersDAO      *dao.UsersDAO
+}
+
+// UserRefreshHandler godoc
+// @Summary Refresh tokens
+// @Description Issue new access and refresh tokens from a valid refresh token
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body UserRefreshRequest true "Refresh token"
+// @Success 200 {object} UserTokensResponse "Authenticated user and its new tokens"
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/users/refresh [post]
+func (u UserRefreshHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("POST UserRefreshHandler")
+
+	var request UserRefreshRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		log.Error("Cannot decode refresh request : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	claims, err := u.Authenticator.Tokens.Verify(request.RefreshToken, auth.RefreshToken)
+	if err != nil {
+		log.Error("Invalid refresh token : ", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// The user may have been deleted since the login
+	var user *models.User
+	if claims.Subject != "" {
+		user, err = u.UsersDAO.GetUser(r.Context(), claims.Subject)
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Error("User " + claims.Subject + " does not exist anymore")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			log.Error("Cannot get user : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	} else if claims.Username != u.Authenticator.UserAuth {
+		log.Error("Refresh token of a former shared account")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	writeUserTokens(w, u.Authenticator, user)
+}
+
+func writeUserTokens(w http.ResponseWriter, authenticator auth.Authenticator, user *models.User) {
+	response := UserTokensResponse{}
+	if user != nil {
+		userJson := jsonDTO.UserToUserJson(user)
+		response.User = &userJson
+	}
+
+	if authenticator.Tokens.Enabled() {
+		tokens, err := authenticator.Tokens.NewTokens(user, authenticator.UserAuth)
+		if err != nil {
+			log.Error("Cannot sign tokens : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		response.AccessToken = tokens.AccessToken
+		response.RefreshToken = tokens.RefreshToken
+		response.TokenType = "Bearer"
+		response.ExpiresIn = int(tokens.ExpiresIn.Seconds())
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(payload)
+}
+
+func decodeCredentials(r *http.Request) (UserCredentials, error) {
+	var credentials UserCredentials
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&credentials)
+	return credentials, err
+}
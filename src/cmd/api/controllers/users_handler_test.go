@@ -0,0 +1,419 @@
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+)
+
+// User account authenticating some requests, instead of the shared account
+const (
+	accountID       = "6f0c54b1-2b9d-4c8a-a0f4-3d2e7b0f1c11"
+	accountUsername = "alice"
+	accountPassword = "alice-password"
+)
+
+var usersColumns = []string{"id", "username", "password_hash", "created_at", "updated_at"}
+
+// expectAccountLookup mocks the lookup of the user account by its username
+func expectAccountLookup(t *testing.T, mock sqlmock.Sqlmock) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(accountPassword), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	t1 := time.Now()
+	rows := sqlmock.NewRows(usersColumns).AddRow(accountID, accountUsername, string(hash), t1, t1)
+	mock.ExpectQuery(regexp.QuoteMeta(dao.UsersRequests[dao.GetUserFromUsername])).WithArgs(accountUsername).WillReturnRows(rows)
+}
+
+func TestUserRegister(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	cases := []struct {
+		name             string
+		giveBody         string
+		giveExisting     bool
+		giveDuplicate    bool
+		giveDatabaseErr  bool
+		giveNoAuth       bool
+		giveUserAuth     bool
+		expectedHTTPCode int
+	}{
+		{
+			name:             "POST register user",
+			giveBody:         `{"username": "alice", "password": "alice-password"}`,
+			expectedHTTPCode: 201,
+		},
+		{
+			name:             "POST fails with existing username",
+			giveBody:         `{"username": "alice", "password": "alice-password"}`,
+			giveExisting:     true,
+			expectedHTTPCode: 409,
+		},
+		{
+			name:             "POST fails with username registered since the lookup",
+			giveBody:         `{"username": "alice", "password": "alice-password"}`,
+			giveDuplicate:    true,
+			expectedHTTPCode: 409,
+		},
+		{
+			name:             "POST fails without credentials",
+			giveBody:         `{"username": "alice", "password": "alice-password"}`,
+			giveNoAuth:       true,
+			expectedHTTPCode: 401,
+		},
+		{
+			name:             "POST fails with a user account",
+			giveBody:         `{"username": "bob", "password": "bob-password"}`,
+			giveUserAuth:     true,
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "POST fails with invalid username",
+			giveBody:         `{"username": "a/b", "password": "alice-password"}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with too short password",
+			giveBody:         `{"username": "alice", "password": "alice"}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with too long password",
+			giveBody:         `{"username": "alice", "password": "` + strings.Repeat("a", 73) + `"}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with invalid body",
+			giveBody:         `{"username": "alice"`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with database error",
+			giveBody:         `{"username": "alice", "password": "alice-password"}`,
+			giveDatabaseErr:  true,
+			expectedHTTPCode: 500,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectUsersDAOCreation(mock)
+
+			if tt.giveUserAuth {
+				expectAccountLookup(t, mock)
+			}
+
+			if tt.expectedHTTPCode != 400 && tt.expectedHTTPCode != 401 && tt.expectedHTTPCode != 403 {
+				getUserQuery := regexp.QuoteMeta(dao.UsersRequests[dao.GetUserFromUsername])
+				if tt.giveExisting {
+					expectAccountLookup(t, mock)
+				} else {
+					mock.ExpectQuery(getUserQuery).WithArgs(accountUsername).WillReturnRows(sqlmock.NewRows(usersColumns))
+				}
+
+				if !tt.giveExisting {
+					createUserQuery := regexp.QuoteMeta(dao.UsersRequests[dao.CreateUser])
+					if tt.giveDatabaseErr {
+						mock.ExpectExec(createUserQuery).WithArgs(accountID, accountUsername, sqlmock.AnyArg()).WillReturnError(fmt.Errorf("database internal error"))
+					} else if tt.giveDuplicate {
+						mock.ExpectExec(createUserQuery).WithArgs(accountID, accountUsername, sqlmock.AnyArg()).WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+					} else {
+						mock.ExpectExec(createUserQuery).WithArgs(accountID, accountUsername, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+						t1 := time.Now()
+						rows := sqlmock.NewRows(usersColumns).AddRow(accountID, accountUsername, "hash", t1, t1)
+						mock.ExpectQuery(regexp.QuoteMeta(dao.UsersRequests[dao.GetUser])).WithArgs(accountID).WillReturnRows(rows)
+					}
+				}
+			}
+
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &router.Clients{
+				UUIDGen: clients.NewUuidGeneratorDummy(func() (string, error) { return accountID, nil }, nil),
+			}, &router.DAOs{UsersDAO: *usersDAO})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/users/register", strings.NewReader(tt.giveBody))
+			if tt.giveUserAuth {
+				req.SetBasicAuth(accountUsername, accountPassword)
+			} else if !tt.giveNoAuth {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedHTTPCode == 201 {
+				var user jsonDTO.UserJson
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &user))
+				require.Equal(t, accountID, user.ID)
+				require.Equal(t, accountUsername, user.Username)
+				require.NotContains(t, w.Body.String(), "hash")
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserLogin(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	cases := []struct {
+		name             string
+		giveBody         string
+		giveUnknown      bool
+		giveNoKeys       bool
+		expectedHTTPCode int
+		expectedSubject  string
+	}{
+		{
+			name:             "POST login user",
+			giveBody:         `{"username": "alice", "password": "alice-password"}`,
+			expectedHTTPCode: 200,
+			expectedSubject:  accountID,
+		},
+		{
+			name:             "POST login shared account",
+			giveBody:         `{"username": "dev", "password": "test"}`,
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "POST login user without signing keys",
+			giveBody:         `{"username": "alice", "password": "alice-password"}`,
+			giveNoKeys:       true,
+			expectedHTTPCode: 200,
+			expectedSubject:  accountID,
+		},
+		{
+			name:             "POST fails with wrong password",
+			giveBody:         `{"username": "alice", "password": "wrong-password"}`,
+			expectedHTTPCode: 401,
+		},
+		{
+			name:             "POST fails with unknown username",
+			giveBody:         `{"username": "alice", "password": "alice-password"}`,
+			giveUnknown:      true,
+			expectedHTTPCode: 401,
+		},
+		{
+			name:             "POST fails with invalid body",
+			giveBody:         `{"username": "alice"`,
+			expectedHTTPCode: 400,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectUsersDAOCreation(mock)
+
+			if tt.expectedHTTPCode != 400 && !strings.Contains(tt.giveBody, givenUsername) {
+				if tt.giveUnknown {
+					getUserQuery := regexp.QuoteMeta(dao.UsersRequests[dao.GetUserFromUsername])
+					mock.ExpectQuery(getUserQuery).WithArgs(accountUsername).WillReturnRows(sqlmock.NewRows(usersColumns))
+				} else {
+					expectAccountLookup(t, mock)
+				}
+			}
+
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			cfg := config.Config{
+				UserAuth:             givenUsername,
+				PwdAuth:              givenUserPwd,
+				JWTKeys:              []string{"key-1:secret"},
+				JWTKeyID:             "key-1",
+				JWTAccessExpiration:  time.Minute,
+				JWTRefreshExpiration: time.Hour,
+			}
+			if tt.giveNoKeys {
+				cfg.JWTKeys = nil
+			}
+			r, err := router.NewRouter(cfg, &router.Clients{}, &router.DAOs{UsersDAO: *usersDAO})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/users/login", strings.NewReader(tt.giveBody))
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedHTTPCode == 200 {
+				var response controllers.UserTokensResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+				if tt.expectedSubject != "" {
+					require.NotNil(t, response.User)
+					require.Equal(t, accountID, response.User.ID)
+				} else {
+					require.Nil(t, response.User)
+				}
+
+				if tt.giveNoKeys {
+					require.Empty(t, response.AccessToken)
+					require.Empty(t, response.RefreshToken)
+				} else {
+					tokens := auth.TokenManager{Keys: map[string][]byte{"key-1": []byte("secret")}}
+					claims, err := tokens.Verify(response.AccessToken, auth.AccessToken)
+					require.NoError(t, err)
+					require.Equal(t, tt.expectedSubject, claims.Subject)
+					_, err = tokens.Verify(response.RefreshToken, auth.RefreshToken)
+					require.NoError(t, err)
+					require.Equal(t, "Bearer", response.TokenType)
+					require.Equal(t, 60, response.ExpiresIn)
+				}
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRouterInvalidSigningKeys(t *testing.T) {
+	_, err := router.NewRouter(config.Config{JWTKeys: []string{"secret"}}, &router.Clients{}, &router.DAOs{})
+	require.Error(t, err)
+}
+
+func TestUserRefresh(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	tokens := auth.TokenManager{
+		Keys:              map[string][]byte{"key-1": []byte("secret")},
+		SigningKeyID:      "key-1",
+		AccessExpiration:  time.Minute,
+		RefreshExpiration: time.Hour,
+	}
+	userTokens, err := tokens.NewTokens(&models.User{ID: accountID, Username: accountUsername}, givenUsername)
+	require.NoError(t, err)
+	sharedTokens, err := tokens.NewTokens(nil, givenUsername)
+	require.NoError(t, err)
+	formerSharedTokens, err := tokens.NewTokens(nil, "former")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name             string
+		giveToken        string
+		giveDeletedUser  bool
+		expectedHTTPCode int
+		expectedLookup   bool
+	}{
+		{
+			name:             "POST refresh user tokens",
+			giveToken:        userTokens.RefreshToken,
+			expectedHTTPCode: 200,
+			expectedLookup:   true,
+		},
+		{
+			name:             "POST refresh shared account tokens",
+			giveToken:        sharedTokens.RefreshToken,
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "POST fails with deleted user",
+			giveToken:        userTokens.RefreshToken,
+			giveDeletedUser:  true,
+			expectedHTTPCode: 401,
+			expectedLookup:   true,
+		},
+		{
+			name:             "POST fails with former shared account",
+			giveToken:        formerSharedTokens.RefreshToken,
+			expectedHTTPCode: 401,
+		},
+		{
+			name:             "POST fails with access token",
+			giveToken:        userTokens.AccessToken,
+			expectedHTTPCode: 401,
+		},
+		{
+			name:             "POST fails with invalid token",
+			giveToken:        "invalid",
+			expectedHTTPCode: 401,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectUsersDAOCreation(mock)
+
+			if tt.expectedLookup {
+				rows := sqlmock.NewRows(usersColumns)
+				if !tt.giveDeletedUser {
+					t1 := time.Now()
+					rows.AddRow(accountID, accountUsername, "hash", t1, t1)
+				}
+				mock.ExpectQuery(regexp.QuoteMeta(dao.UsersRequests[dao.GetUser])).WithArgs(accountID).WillReturnRows(rows)
+			}
+
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth:             givenUsername,
+				PwdAuth:              givenUserPwd,
+				JWTKeys:              []string{"key-1:secret"},
+				JWTKeyID:             "key-1",
+				JWTAccessExpiration:  time.Minute,
+				JWTRefreshExpiration: time.Hour,
+			}, &router.Clients{}, &router.DAOs{UsersDAO: *usersDAO})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/users/refresh", strings.NewReader(`{"refreshToken": "`+tt.giveToken+`"}`))
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedHTTPCode == 200 {
+				var response controllers.UserTokensResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				_, err := tokens.Verify(response.AccessToken, auth.AccessToken)
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
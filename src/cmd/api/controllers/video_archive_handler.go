@@ -5,12 +5,14 @@ import (
 	"database/sql"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/Sogilis/Voogle/src/pkg/clients"
 
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
 	"github.com/Sogilis/Voogle/src/cmd/api/models"
 )
@@ -53,6 +55,12 @@ func (v VideoArchiveHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !auth.CanManageVideo(r.Context(), video) {
+		log.Error("Video " + video.ID + " belongs to another user")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
 	statusCode, err := v.archiveVideo(r.Context(), video)
 	if err != nil {
 		w.WriteHeader(statusCode)
@@ -68,8 +76,10 @@ func (v VideoArchiveHandler) archiveVideo(ctx context.Context, video *models.Vid
 		return http.StatusBadRequest, err
 	}
 	video.Status = models.ARCHIVE
+	archivedAt := time.Now()
+	video.ArchivedAt = &archivedAt
 
-	if err := v.VideosDAO.UpdateVideo(ctx, video); err != nil {
+	if err := v.VideosDAO.UpdateVideoArchivedAt(ctx, video); err != nil {
 		log.Error("Cannot update video "+video.ID+" : ", err)
 		return http.StatusInternalServerError, err
 	}
@@ -37,6 +37,8 @@ func TestVideoArchive(t *testing.T) { //nolint:cyclop
 		name             string
 		giveRequest      string
 		giveWithAuth     bool
+		giveAccount      bool
+		giveOwnerID      interface{}
 		giveDbGetErr     bool
 		giveDbUpdateErr  bool
 		status           models.VideoStatus
@@ -51,6 +53,23 @@ func TestVideoArchive(t *testing.T) { //nolint:cyclop
 			expectedHTTPCode: 200,
 			isValidUUID:      UUIDValidFunc,
 		},
+		{
+			name:             "PUT archive video of the user",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/archive",
+			giveAccount:      true,
+			giveOwnerID:      accountID,
+			status:           models.COMPLETE,
+			expectedHTTPCode: 200,
+			isValidUUID:      UUIDValidFunc,
+		},
+		{
+			name:             "PUT fails with video of another user",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/archive",
+			giveAccount:      true,
+			status:           models.COMPLETE,
+			expectedHTTPCode: 403,
+			isValidUUID:      UUIDValidFunc,
+		},
 		{
 			name:             "PUT fails with status not COMPLETE",
 			giveRequest:      "/api/v1/videos/" + validVideoID + "/archive",
@@ -116,17 +135,22 @@ func TestVideoArchive(t *testing.T) { //nolint:cyclop
 			}
 
 			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectUsersDAOCreation(mock)
 
-			if !tt.giveWithAuth || tt.giveRequest == "/api/v1/videos/"+invalidVideoID+"/archive" {
+			if tt.giveAccount {
+				expectAccountLookup(t, mock)
+			}
+
+			if !(tt.giveWithAuth || tt.giveAccount) || tt.giveRequest == "/api/v1/videos/"+invalidVideoID+"/archive" {
 				// All these cases will stop before modifying the database : Nothing to do
 
 			} else {
 				// Queries
 				getVideoFromIdQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])
-				updateVideoQuery := regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo])
+				updateVideoArchivedAtQuery := regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideoArchivedAt])
 
 				// Tables
-				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path"}
+				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
 				videosRows := sqlmock.NewRows(videosColumns)
 
 				// Define database response according to case
@@ -136,17 +160,17 @@ func TestVideoArchive(t *testing.T) { //nolint:cyclop
 				} else if tt.giveRequest == "/api/v1/videos/"+unknownVideoID+"/archive" {
 					mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
 				} else {
-					videosRows.AddRow(validVideoID, videoTitle, int(tt.status), t1, t1, nil, sourcePath, coverPath)
+					videosRows.AddRow(validVideoID, videoTitle, int(tt.status), t1, t1, nil, sourcePath, coverPath, nil, "", tt.giveOwnerID, nil)
 					mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
 
-					if tt.status == models.COMPLETE {
+					if tt.status == models.COMPLETE && tt.expectedHTTPCode != 403 {
 						if tt.giveDbUpdateErr {
-							mock.ExpectExec(updateVideoQuery).
-								WithArgs(videoTitle, int(models.ARCHIVE), t1, sourcePath, coverPath, validVideoID).
+							mock.ExpectExec(updateVideoArchivedAtQuery).
+								WithArgs(int(models.ARCHIVE), AnyTime{}, validVideoID).
 								WillReturnError(fmt.Errorf("Error will update db"))
 						} else {
-							mock.ExpectExec(updateVideoQuery).
-								WithArgs(videoTitle, int(models.ARCHIVE), t1, sourcePath, coverPath, validVideoID).
+							mock.ExpectExec(updateVideoArchivedAtQuery).
+								WithArgs(int(models.ARCHIVE), AnyTime{}, validVideoID).
 								WillReturnResult(sqlmock.NewResult(0, 1))
 						}
 					}
@@ -155,20 +179,26 @@ func TestVideoArchive(t *testing.T) { //nolint:cyclop
 
 			videoDAO, err := dao.CreateVideosDAO(context.Background(), db)
 			require.NoError(t, err)
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
 			routerDAO := router.DAOs{
 				VideosDAO: *videoDAO,
+				UsersDAO:  *usersDAO,
 			}
 
-			r := router.NewRouter(config.Config{
+			r, err := router.NewRouter(config.Config{
 				UserAuth: givenUsername,
 				PwdAuth:  givenUserPwd,
 			}, &routerClients, &routerDAO)
+			require.NoError(t, err)
 
 			w := httptest.NewRecorder()
 
 			req := httptest.NewRequest("PUT", tt.giveRequest, nil)
 			if tt.giveWithAuth {
 				req.SetBasicAuth(givenUsername, givenUserPwd)
+			} else if tt.giveAccount {
+				req.SetBasicAuth(accountUsername, accountPassword)
 			}
 
 			r.ServeHTTP(w, req)
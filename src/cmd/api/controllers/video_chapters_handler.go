@@ -0,0 +1,283 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/hls"
+	"github.com/Sogilis/Voogle/src/pkg/subtitles"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+const chaptersDateRangeClass = "chapter"
+
+// The variant playlists of a video are dated from the Unix epoch, so that the start date of a chapter is its
+// position in the video
+var chaptersProgramDateTime = time.Unix(0, 0)
+
+type VideoChaptersResponse struct {
+	Chapters []jsonDTO.ChapterJson       `json:"chapters"`
+	Links    map[string]jsonDTO.LinkJson `json:"_links"`
+}
+
+// VideoChaptersRequest replaces all the chapters of the video
+type VideoChaptersRequest struct {
+	Chapters []jsonDTO.ChapterJson `json:"chapters"`
+}
+
+type VideoChaptersGetHandler struct {
+	VideosDAO   *dao.VideosDAO
+	ChaptersDAO *dao.ChaptersDAO
+	UUIDGen     clients.IUUIDGenerator
+}
+
+// VideoChaptersGetHandler godoc
+// @Summary List video chapters
+// @Description List the chapters of the video, sorted on their start
+// @Tags video
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} VideoChaptersResponse "Chapters"
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/chapters [get]
+func (v VideoChaptersGetHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET VideoChaptersGetHandler - parameters ", vars)
+
+	video, ok := getRequestVideo(w, r, v.VideosDAO, v.UUIDGen, false)
+	if !ok {
+		return
+	}
+
+	chapters, err := v.ChaptersDAO.GetVideoChapters(r.Context(), video.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeChaptersJson(w, video.ID, chapters)
+}
+
+type VideoChaptersUpdateHandler struct {
+	VideosDAO   *dao.VideosDAO
+	ChaptersDAO *dao.ChaptersDAO
+	UUIDGen     clients.IUUIDGenerator
+}
+
+// VideoChaptersUpdateHandler godoc
+// @Summary Update video chapters
+// @Description Replace all the chapters of the video. Titles are trimmed and starts rounded to the millisecond.
+// @Description A chapter lasts until the start of the next one, or until the end of the video.
+// @Tags video
+// @Accept json
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param request body VideoChaptersRequest true "Chapters of the video, an empty list removing them"
+// @Success 200 {object} VideoChaptersResponse "Updated chapters"
+// @Failure 400 {string} string "Invalid chapters"
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/chapters [put]
+func (v VideoChaptersUpdateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("PUT VideoChaptersUpdateHandler - parameters ", vars)
+
+	video, ok := getRequestVideo(w, r, v.VideosDAO, v.UUIDGen, false)
+	if !ok {
+		return
+	}
+
+	if !auth.CanManageVideo(r.Context(), video) {
+		log.Error("Video " + video.ID + " belongs to another user")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	var request VideoChaptersRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		log.Error("Cannot decode video chapters request : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	chapters := make([]models.Chapter, 0, len(request.Chapters))
+	for _, chapter := range request.Chapters {
+		chapters = append(chapters, jsonDTO.ChapterJsonToChapter(chapter))
+	}
+	chapters, err := models.NormalizeChapters(chapters)
+	if err != nil {
+		log.Error("Invalid video chapters request : ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := v.ChaptersDAO.UpdateVideoChapters(r.Context(), video.ID, chapters); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeChaptersJson(w, video.ID, chapters)
+	log.Infof("Video %v chapters updated", video.ID)
+}
+
+type VideoGetChaptersHandler struct {
+	S3Client    clients.IS3Client
+	ChaptersDAO *dao.ChaptersDAO
+	UUIDGen     clients.IUUIDGenerator
+}
+
+// VideoGetChaptersHandler godoc
+// @Summary Get video chapters track
+// @Description Get the WebVTT chapters track of the video, a cue per chapter
+// @Tags video
+// @Produce plain
+// @Param id path string true "Video ID"
+// @Param token query string false "Stream token, instead of credentials"
+// @Success 200 {string} string "WebVTT chapters track"
+// @Failure 400 {string} string
+// @Failure 403 {string} string "Invalid stream token"
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/streams/chapters.vtt [get]
+func (v VideoGetChaptersHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET VideoGetChaptersHandler - parameters ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	chapters, err := v.ChaptersDAO.GetVideoChapters(r.Context(), id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// The last chapter ends with the video, as long as its first variant
+	duration := 0.0
+	if len(chapters) > 0 {
+		object, err := v.S3Client.GetObject(r.Context(), id+"/v0/segment_index.m3u8")
+		if err != nil {
+			log.Error("Failed to open video "+id+"/v0/segment_index.m3u8 ", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		media, err := hls.ParseMedia(object)
+		if err != nil {
+			log.Error("Cannot parse video variant : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		duration = media.Duration()
+	}
+
+	w.Header().Set("Content-Type", "text/vtt")
+	_, _ = w.Write(subtitles.EncodeVTT(chapterCues(chapters, duration)))
+}
+
+// addChapters returns the variant playlist of the video with its chapters as EXT-X-DATERANGE tags
+func addChapters(ctx context.Context, chaptersDAO *dao.ChaptersDAO, videoID string, playlist io.Reader) (io.Reader, error) {
+	chapters, err := chaptersDAO.GetVideoChapters(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+	if len(chapters) == 0 {
+		return playlist, nil
+	}
+
+	content, err := io.ReadAll(playlist)
+	if err != nil {
+		return nil, err
+	}
+
+	media, err := hls.ParseMedia(bytes.NewReader(content))
+	if err != nil {
+		log.Error("Cannot parse video variant : ", err)
+		return bytes.NewReader(content), nil
+	}
+
+	cues := chapterCues(chapters, media.Duration())
+	ranges := make([]hls.DateRange, 0, len(cues))
+	for _, cue := range cues {
+		ranges = append(ranges, hls.DateRange{
+			ID:       "chapter-" + cue.ID,
+			Class:    chaptersDateRangeClass,
+			Start:    chaptersProgramDateTime.Add(time.Duration(math.Round(cue.Start*1000)) * time.Millisecond),
+			Duration: cue.End - cue.Start,
+			Title:    cue.Text,
+		})
+	}
+
+	withChapters, err := hls.AddDateRanges(bytes.NewReader(content), chaptersProgramDateTime, ranges)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(withChapters), nil
+}
+
+// chapterCues returns a cue per chapter, numbered from 1, lasting until the next chapter or until the end of
+// the video. The chapters starting after the end of the video are left out.
+func chapterCues(chapters []models.Chapter, duration float64) []subtitles.Cue {
+	cues := []subtitles.Cue{}
+	for i, chapter := range chapters {
+		if chapter.Start >= duration {
+			break
+		}
+
+		end := duration
+		if i+1 < len(chapters) && chapters[i+1].Start < duration {
+			end = chapters[i+1].Start
+		}
+		cues = append(cues, subtitles.Cue{ID: strconv.Itoa(i + 1), Start: chapter.Start, End: end, Text: chapter.Title})
+	}
+
+	return cues
+}
+
+func writeChaptersJson(w http.ResponseWriter, videoID string, chapters []models.Chapter) {
+	response := VideoChaptersResponse{
+		Chapters: make([]jsonDTO.ChapterJson, 0, len(chapters)),
+		Links: map[string]jsonDTO.LinkJson{
+			"self":   jsonDTO.LinkToLinkJson(models.CreateLink("api/v1/videos/"+videoID+"/chapters", "GET")),
+			"update": jsonDTO.LinkToLinkJson(models.CreateLink("api/v1/videos/"+videoID+"/chapters", "PUT")),
+			"vtt":    jsonDTO.LinkToLinkJson(models.CreateLink("api/v1/videos/"+videoID+"/streams/chapters.vtt", "GET")),
+		},
+	}
+	for _, chapter := range chapters {
+		response.Chapters = append(response.Chapters, jsonDTO.ChapterToChapterJson(chapter))
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(payload)
+}
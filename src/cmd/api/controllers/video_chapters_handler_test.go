@@ -0,0 +1,331 @@
+package controllers_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+)
+
+var chaptersColumns = []string{"start_time", "title"}
+
+func TestVideoChapters(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	validVideoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	unknownVideoID := "0000a0a0-0aa0-0a00-0000-aa0000aa00aa"
+	t1 := time.Now()
+
+	variant := "#EXTM3U\n#EXT-X-TARGETDURATION:60\n#EXTINF:60.000000,\nsegment0.ts\n#EXTINF:40.000000,\nsegment1.ts\n#EXT-X-ENDLIST\n"
+
+	expectOwnedVideoLookup := func(mock sqlmock.Sqlmock, ID string, ownerID interface{}) {
+		videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
+		rows := sqlmock.NewRows(videosColumns)
+		if ID == validVideoID {
+			rows.AddRow(validVideoID, "lecture", int(models.COMPLETE), t1, t1, t1, "", "", nil, "", ownerID, nil)
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WithArgs(ID).WillReturnRows(rows)
+	}
+	expectVideoLookup := func(mock sqlmock.Sqlmock, ID string) {
+		expectOwnedVideoLookup(mock, ID, nil)
+	}
+	expectChaptersLookup := func(mock sqlmock.Sqlmock) {
+		rows := sqlmock.NewRows(chaptersColumns).AddRow(0.0, "Intro").AddRow(60.5, `The "end"`).AddRow(200.0, "Credits")
+		mock.ExpectQuery(regexp.QuoteMeta(dao.ChaptersRequests[dao.GetVideoChapters])).WithArgs(validVideoID).WillReturnRows(rows)
+	}
+
+	cases := []struct {
+		name             string
+		giveMethod       string
+		giveRequest      string
+		giveBody         string
+		giveAccount      bool
+		expectDB         func(mock sqlmock.Sqlmock)
+		expectedHTTPCode int
+		expectedBody     string
+	}{
+		{
+			name:        "PUT chapters",
+			giveMethod:  "PUT",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/chapters",
+			giveBody:    `{"chapters": [{"start": 60.5004, "title": "The end"}, {"start": 0, "title": " Intro "}]}`,
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, validVideoID)
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(dao.ChaptersRequests[dao.DeleteVideoChapters])).
+					WithArgs(validVideoID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(dao.ChaptersRequests[dao.AddVideoChapter])).
+					WithArgs(validVideoID, 0.0, "Intro").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(dao.ChaptersRequests[dao.AddVideoChapter])).
+					WithArgs(validVideoID, 60.5, "The end").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectedHTTPCode: 200,
+			expectedBody: `{"chapters":[{"start":0,"title":"Intro"},{"start":60.5,"title":"The end"}],"_links":{` +
+				`"self":{"href":"api/v1/videos/` + validVideoID + `/chapters","method":"GET"},` +
+				`"update":{"href":"api/v1/videos/` + validVideoID + `/chapters","method":"PUT"},` +
+				`"vtt":{"href":"api/v1/videos/` + validVideoID + `/streams/chapters.vtt","method":"GET"}}}`,
+		},
+		{
+			name:        "PUT no chapter removes them",
+			giveMethod:  "PUT",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/chapters",
+			giveBody:    `{"chapters": []}`,
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, validVideoID)
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(dao.ChaptersRequests[dao.DeleteVideoChapters])).
+					WithArgs(validVideoID).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+			expectedHTTPCode: 200,
+		},
+		{
+			name:        "PUT chapters of the user",
+			giveMethod:  "PUT",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/chapters",
+			giveBody:    `{"chapters": []}`,
+			giveAccount: true,
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectOwnedVideoLookup(mock, validVideoID, accountID)
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(dao.ChaptersRequests[dao.DeleteVideoChapters])).
+					WithArgs(validVideoID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			expectedHTTPCode: 200,
+		},
+		{
+			name:        "PUT fails with video of another user",
+			giveMethod:  "PUT",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/chapters",
+			giveBody:    `{"chapters": [{"start": 0, "title": "Intro"}]}`,
+			giveAccount: true,
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectOwnedVideoLookup(mock, validVideoID, "b7a1e0c2-0000-4000-8000-000000000000")
+			},
+			expectedHTTPCode: 403,
+		},
+		{
+			name:        "PUT fails with video without owner",
+			giveMethod:  "PUT",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/chapters",
+			giveBody:    `{"chapters": []}`,
+			giveAccount: true,
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, validVideoID)
+			},
+			expectedHTTPCode: 403,
+		},
+		{
+			name:        "PUT fails with chapters starting at the same time",
+			giveMethod:  "PUT",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/chapters",
+			giveBody:    `{"chapters": [{"start": 10, "title": "One"}, {"start": 10.0001, "title": "Two"}]}`,
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, validVideoID)
+			},
+			expectedHTTPCode: 400,
+			expectedBody:     "chapters One and Two start at the same time\n",
+		},
+		{
+			name:        "PUT fails with empty title",
+			giveMethod:  "PUT",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/chapters",
+			giveBody:    `{"chapters": [{"start": 10, "title": " "}]}`,
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, validVideoID)
+			},
+			expectedHTTPCode: 400,
+		},
+		{
+			name:        "PUT fails with negative start",
+			giveMethod:  "PUT",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/chapters",
+			giveBody:    `{"chapters": [{"start": -1, "title": "Intro"}]}`,
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, validVideoID)
+			},
+			expectedHTTPCode: 400,
+		},
+		{
+			name:        "PUT fails with unknown field",
+			giveMethod:  "PUT",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/chapters",
+			giveBody:    `{"chapters": [{"start": 0, "title": "Intro", "end": 10}]}`,
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, validVideoID)
+			},
+			expectedHTTPCode: 400,
+		},
+		{
+			name:        "PUT fails with database error",
+			giveMethod:  "PUT",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/chapters",
+			giveBody:    `{"chapters": [{"start": 0, "title": "Intro"}]}`,
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, validVideoID)
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(dao.ChaptersRequests[dao.DeleteVideoChapters])).
+					WithArgs(validVideoID).
+					WillReturnError(errors.New("database internal error"))
+				mock.ExpectRollback()
+			},
+			expectedHTTPCode: 500,
+		},
+		{
+			name:        "PUT fails with unknown video",
+			giveMethod:  "PUT",
+			giveRequest: "/api/v1/videos/" + unknownVideoID + "/chapters",
+			giveBody:    `{"chapters": []}`,
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, unknownVideoID)
+			},
+			expectedHTTPCode: 404,
+		},
+		{
+			name:        "GET chapters",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/chapters",
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, validVideoID)
+				expectChaptersLookup(mock)
+			},
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "GET fails with invalid video ID",
+			giveMethod:       "GET",
+			giveRequest:      "/api/v1/videos/invalid/chapters",
+			expectedHTTPCode: 400,
+		},
+		{
+			name:        "GET chapters track",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/streams/chapters.vtt",
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectChaptersLookup(mock)
+			},
+			expectedHTTPCode: 200,
+			expectedBody: "WEBVTT\n\n" +
+				"1\n00:00:00.000 --> 00:01:00.500\nIntro\n\n" +
+				"2\n00:01:00.500 --> 00:01:40.000\nThe \"end\"\n",
+		},
+		{
+			name:        "GET chapters track without chapter",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/streams/chapters.vtt",
+			expectDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.ChaptersRequests[dao.GetVideoChapters])).WithArgs(validVideoID).WillReturnRows(sqlmock.NewRows(chaptersColumns))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     "WEBVTT\n",
+		},
+		{
+			name:        "GET fails to chapters track of a video not encoded",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + unknownVideoID + "/streams/chapters.vtt",
+			expectDB: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(chaptersColumns).AddRow(0.0, "Intro")
+				mock.ExpectQuery(regexp.QuoteMeta(dao.ChaptersRequests[dao.GetVideoChapters])).WithArgs(unknownVideoID).WillReturnRows(rows)
+			},
+			expectedHTTPCode: 404,
+		},
+		{
+			name:        "GET variant playlist with chapters",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/streams/v0/segment_index.m3u8",
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectChaptersLookup(mock)
+			},
+			expectedHTTPCode: 200,
+			expectedBody: "#EXTM3U\n#EXT-X-TARGETDURATION:60\n" +
+				"#EXT-X-PROGRAM-DATE-TIME:1970-01-01T00:00:00.000Z\n" +
+				"#EXT-X-DATERANGE:ID=\"chapter-1\",CLASS=\"chapter\",START-DATE=\"1970-01-01T00:00:00.000Z\",DURATION=60.500,X-TITLE=\"Intro\"\n" +
+				"#EXT-X-DATERANGE:ID=\"chapter-2\",CLASS=\"chapter\",START-DATE=\"1970-01-01T00:01:00.500Z\",DURATION=39.500,X-TITLE=\"The 'end'\"\n" +
+				"#EXTINF:60.000000,\nsegment0.ts\n#EXTINF:40.000000,\nsegment1.ts\n#EXT-X-ENDLIST\n",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectPlaybackDAOCreation(mock)
+			dao_test.ExpectChaptersDAOCreation(mock)
+			dao_test.ExpectUsersDAOCreation(mock)
+			if tt.giveAccount {
+				expectAccountLookup(t, mock)
+			}
+			if tt.expectDB != nil {
+				tt.expectDB(mock)
+			}
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+			playbackDAO, err := dao.CreatePlaybackDAO(context.Background(), db)
+			require.NoError(t, err)
+			chaptersDAO, err := dao.CreateChaptersDAO(context.Background(), db)
+			require.NoError(t, err)
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			getObject := func(s string) (io.Reader, error) {
+				if s != validVideoID+"/v0/segment_index.m3u8" {
+					return nil, errors.New("Not found")
+				}
+				return strings.NewReader(variant), nil
+			}
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &router.Clients{
+				S3Client: clients.NewS3ClientDummy(nil, getObject, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil),
+				UUIDGen:  clients.NewUuidGeneratorDummy(nil, func(u string) bool { _, err := uuid.Parse(u); return err == nil }),
+			}, &router.DAOs{VideosDAO: *videosDAO, PlaybackDAO: *playbackDAO, ChaptersDAO: *chaptersDAO, UsersDAO: *usersDAO})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.giveMethod, tt.giveRequest, strings.NewReader(tt.giveBody))
+			if tt.giveAccount {
+				req.SetBasicAuth(accountUsername, accountPassword)
+			} else {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedBody != "" {
+				require.Equal(t, tt.expectedBody, w.Body.String())
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -116,7 +116,7 @@ func TestVideoCover(t *testing.T) { //nolint:cyclop
 				VideosDAO: *videoDAO,
 			}
 
-			s3Client := clients.NewS3ClientDummy(nil, tt.getObject, nil, nil, nil)
+			s3Client := clients.NewS3ClientDummy(nil, tt.getObject, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 			routerClients := router.Clients{
 				S3Client: s3Client,
 				UUIDGen:  clients.NewUuidGeneratorDummy(nil, tt.isValidUUID),
@@ -130,7 +130,7 @@ func TestVideoCover(t *testing.T) { //nolint:cyclop
 				getVideoFromIdQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])
 
 				// Tables
-				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path"}
+				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
 				videosRows := sqlmock.NewRows(videosColumns)
 
 				// Define database response according to case
@@ -140,15 +140,16 @@ func TestVideoCover(t *testing.T) { //nolint:cyclop
 				} else if tt.giveRequest == "/api/v1/videos/"+unknownVideoID+"/cover" {
 					mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
 				} else {
-					videosRows.AddRow(validVideoID, videoTitle, int(models.COMPLETE), t1, t1, nil, sourcePath, coverPath)
+					videosRows.AddRow(validVideoID, videoTitle, int(models.COMPLETE), t1, t1, nil, sourcePath, coverPath, nil, "", nil, nil)
 					mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
 				}
 			}
 
-			r := router.NewRouter(config.Config{
+			r, err := router.NewRouter(config.Config{
 				UserAuth: givenUsername,
 				PwdAuth:  givenUserPwd,
 			}, &routerClients, &routerDAO)
+			require.NoError(t, err)
 
 			w := httptest.NewRecorder()
 
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type VideoCoverUpdateHandler struct {
+	S3Client              clients.IS3Client
+	AmqpVideoStatusUpdate clients.AmqpClient
+	VideosDAO             *dao.VideosDAO
+	UUIDGen               clients.IUUIDGenerator
+	ImageConverter        clients.IImageConverter
+}
+
+// VideoCoverUpdateHandler godoc
+// @Summary Set video cover image
+// @Description Set or replace the cover image of the video. The image is cropped to a square and scaled as the encoder does.
+// @Description The cover cannot be changed while the video is uploaded or encoded.
+// @Tags video
+// @Accept multipart/form-data
+// @Param id path string true "Video ID"
+// @Param cover formData file true "JPEG or PNG cover image"
+// @Success 204 {string} string
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string "The video is uploaded or encoded"
+// @Failure 415 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/cover [put]
+func (v VideoCoverUpdateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) { //nolint:cyclop
+	vars := mux.Vars(r)
+	log.Debug("PUT VideoCoverUpdateHandler - parameters ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	fileCover, fileHandlerCover, err := r.FormFile("cover")
+	if err != nil {
+		log.Error("Missing cover file ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer fileCover.Close()
+
+	// Check if the received file cover is a supported image type
+	if !isSupportedCoverType(fileCover) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	video, err := v.VideosDAO.GetVideo(r.Context(), id)
+	if err != nil {
+		log.Error("Cannot found video : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if !auth.CanManageVideo(r.Context(), video) {
+		log.Error("Video " + video.ID + " belongs to another user")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	// The encoder converts and then removes the cover of the video it encodes
+	switch video.Status {
+	case models.UPLOADING, models.UPLOADED, models.ENCODING:
+		log.Error("Cannot change the cover of video " + id + " while it is " + strings.ToLower(video.Status.String()))
+		http.Error(w, "The cover cannot be changed while the video is "+strings.ToLower(video.Status.String()), http.StatusConflict)
+		return
+	}
+
+	coverPath, err := v.uploadConvertedCover(r.Context(), video, fileCover, fileHandlerCover)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Switch to the new cover, then remove the previous one : readers get one cover or the other
+	previousCoverPath := video.CoverPath
+	video.CoverPath = coverPath
+	if err := v.VideosDAO.UpdateVideoCoverPath(r.Context(), video); err != nil {
+		log.Error("Cannot update video "+id+" cover path : ", err)
+		if err := v.S3Client.RemoveObject(r.Context(), coverPath); err != nil {
+			log.Error("Cannot remove cover "+coverPath+" : ", err)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if previousCoverPath != "" {
+		if err := v.S3Client.RemoveObject(r.Context(), previousCoverPath); err != nil {
+			log.Error("Cannot remove previous cover "+previousCoverPath+" : ", err)
+		}
+	}
+
+	// Open clients refresh the video on status update
+	VideoUploadHandler{AmqpVideoStatusUpdate: v.AmqpVideoStatusUpdate}.publishStatus(video)
+
+	log.Infof("Video %v cover updated", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// uploadConvertedCover converts the cover and uploads it on S3 under a new path, so the current cover
+// is still available until the video is updated.
+func (v VideoCoverUpdateHandler) uploadConvertedCover(ctx context.Context, video *models.Video, cover multipart.File, fileHandler *multipart.FileHeader) (string, error) {
+	dir, err := os.MkdirTemp("", "cover-"+video.ID)
+	if err != nil {
+		log.Error("Cannot create temporary directory : ", err)
+		return "", err
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	srcPath := filepath.Join(dir, "source"+filepath.Ext(fileHandler.Filename))
+	src, err := os.Create(srcPath)
+	if err != nil {
+		log.Error("Cannot create cover file : ", err)
+		return "", err
+	}
+	_, err = io.Copy(src, cover)
+	if closeErr := src.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Error("Cannot write cover file : ", err)
+		return "", err
+	}
+
+	dstPath := filepath.Join(dir, "cover.jpeg")
+	if err := v.ImageConverter.ConvertImg(srcPath, dstPath); err != nil {
+		log.Error("Cannot convert cover : ", err)
+		return "", err
+	}
+
+	coverID, err := v.UUIDGen.GenerateUuid()
+	if err != nil {
+		log.Error("Cannot generate new cover ID : ", err)
+		return "", err
+	}
+
+	dst, err := os.Open(dstPath)
+	if err != nil {
+		log.Error("Cannot open converted cover : ", err)
+		return "", err
+	}
+	defer dst.Close()
+
+	coverPath := video.ID + "/cover-" + coverID + ".jpeg"
+	if err := v.S3Client.PutObjectInput(ctx, dst, coverPath); err != nil {
+		log.Error("Cannot upload cover : ", err)
+		return "", err
+	}
+
+	return coverPath, nil
+}
@@ -0,0 +1,282 @@
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+)
+
+func TestVideoCoverUpdate(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	validVideoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	invalidVideoID := "invalidvideoid"
+	unknownVideoID := "0000a0a0-0aa0-0a00-0000-aa0000aa00aa"
+	coverID := "b7e6a0c1-5bc6-4a50-9176-ab0371aa65fe"
+	UUIDValidFunc := func(u string) bool { _, err := uuid.Parse(u); return err == nil }
+
+	videoTitle := "title"
+	t1 := time.Now()
+	sourcePath := validVideoID + "/" + "source.mp4"
+	previousCoverPath := validVideoID + "/" + "cover.jpeg"
+	newCoverPath := validVideoID + "/cover-" + coverID + ".jpeg"
+
+	cases := []struct {
+		name                string
+		giveID              string
+		giveWithAuth        bool
+		giveAccount         bool
+		giveOwnerID         interface{}
+		giveCover           string
+		giveStatus          models.VideoStatus
+		giveCoverPath       string
+		giveConvertErr      bool
+		giveDatabaseErr     bool
+		expectedHTTPCode    int
+		expectedPut         bool
+		expectedRemoved     []string
+		expectedStatusEvent bool
+	}{
+		{
+			name:                "PUT cover",
+			giveID:              validVideoID,
+			giveWithAuth:        true,
+			giveCover:           "cover.png",
+			giveStatus:          models.COMPLETE,
+			giveCoverPath:       previousCoverPath,
+			expectedHTTPCode:    204,
+			expectedPut:         true,
+			expectedRemoved:     []string{previousCoverPath},
+			expectedStatusEvent: true,
+		},
+		{
+			name:                "PUT first cover",
+			giveID:              validVideoID,
+			giveWithAuth:        true,
+			giveCover:           "cover.jpg",
+			giveStatus:          models.ARCHIVE,
+			expectedHTTPCode:    204,
+			expectedPut:         true,
+			expectedStatusEvent: true,
+		},
+		{
+			name:                "PUT cover of a video of the user",
+			giveID:              validVideoID,
+			giveAccount:         true,
+			giveOwnerID:         accountID,
+			giveCover:           "cover.png",
+			giveStatus:          models.COMPLETE,
+			expectedHTTPCode:    204,
+			expectedPut:         true,
+			expectedStatusEvent: true,
+		},
+		{
+			name:             "PUT fails with video of another user",
+			giveID:           validVideoID,
+			giveAccount:      true,
+			giveCover:        "cover.png",
+			giveStatus:       models.COMPLETE,
+			giveCoverPath:    previousCoverPath,
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "PUT fails with no auth",
+			giveID:           validVideoID,
+			giveCover:        "cover.png",
+			expectedHTTPCode: 401,
+		},
+		{
+			name:             "PUT fails with invalid video ID",
+			giveID:           invalidVideoID,
+			giveWithAuth:     true,
+			giveCover:        "cover.png",
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "PUT fails with missing cover",
+			giveID:           validVideoID,
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "PUT fails with unsupported cover type",
+			giveID:           validVideoID,
+			giveWithAuth:     true,
+			giveCover:        "cover.gif",
+			expectedHTTPCode: 415,
+		},
+		{
+			name:             "PUT fails with unknown video ID",
+			giveID:           unknownVideoID,
+			giveWithAuth:     true,
+			giveCover:        "cover.png",
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "PUT fails while the video is encoded",
+			giveID:           validVideoID,
+			giveWithAuth:     true,
+			giveCover:        "cover.png",
+			giveStatus:       models.ENCODING,
+			expectedHTTPCode: 409,
+		},
+		{
+			name:             "PUT fails with conversion error",
+			giveID:           validVideoID,
+			giveWithAuth:     true,
+			giveCover:        "cover.png",
+			giveStatus:       models.COMPLETE,
+			giveCoverPath:    previousCoverPath,
+			giveConvertErr:   true,
+			expectedHTTPCode: 500,
+		},
+		{
+			name:             "PUT fails with database error",
+			giveID:           validVideoID,
+			giveWithAuth:     true,
+			giveCover:        "cover.png",
+			giveStatus:       models.COMPLETE,
+			giveCoverPath:    previousCoverPath,
+			giveDatabaseErr:  true,
+			expectedHTTPCode: 500,
+			expectedPut:      true,
+			expectedRemoved:  []string{newCoverPath},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectUsersDAOCreation(mock)
+
+			if tt.giveAccount {
+				expectAccountLookup(t, mock)
+			}
+
+			if (tt.giveWithAuth || tt.giveAccount) && tt.giveID != invalidVideoID && tt.giveCover != "" && tt.giveCover != "cover.gif" {
+				getVideoQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])
+				updateCoverPathQuery := regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideoCoverPath])
+
+				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
+				videosRows := sqlmock.NewRows(videosColumns)
+
+				if tt.giveID == unknownVideoID {
+					mock.ExpectQuery(getVideoQuery).WithArgs(tt.giveID).WillReturnRows(videosRows)
+				} else {
+					videosRows.AddRow(validVideoID, videoTitle, int(tt.giveStatus), t1, t1, t1, sourcePath, tt.giveCoverPath, nil, "", tt.giveOwnerID, nil)
+					mock.ExpectQuery(getVideoQuery).WithArgs(tt.giveID).WillReturnRows(videosRows)
+
+					if tt.expectedPut {
+						if tt.giveDatabaseErr {
+							mock.ExpectExec(updateCoverPathQuery).WithArgs(newCoverPath, validVideoID).WillReturnError(fmt.Errorf("unknown error"))
+						} else {
+							mock.ExpectExec(updateCoverPathQuery).WithArgs(newCoverPath, validVideoID).WillReturnResult(sqlmock.NewResult(0, 1))
+						}
+					}
+				}
+			}
+
+			var putPaths, removedPaths []string
+			s3Client := clients.NewS3ClientDummy(nil, nil,
+				func(f io.Reader, path string) error {
+					content, err := io.ReadAll(f)
+					require.NoError(t, err)
+					require.Equal(t, "converted", string(content))
+					putPaths = append(putPaths, path)
+					return nil
+				}, nil,
+				func(path string) error {
+					removedPaths = append(removedPaths, path)
+					return nil
+				}, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+			statusEvents := 0
+			amqpVideoStatusUpdate := clients.NewAmqpClientDummy(func(routingKey string, _ []byte) error {
+				require.Equal(t, videoTitle, routingKey)
+				statusEvents++
+				return nil
+			}, nil, nil)
+
+			imageConverter := clients.NewImageConverterDummy(func(srcpath, dstpath string) error {
+				if tt.giveConvertErr {
+					return fmt.Errorf("cannot convert")
+				}
+				return os.WriteFile(dstpath, []byte("converted"), 0o600)
+			})
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &router.Clients{
+				S3Client:              s3Client,
+				AmqpVideoStatusUpdate: amqpVideoStatusUpdate,
+				UUIDGen:               clients.NewUuidGeneratorDummy(func() (string, error) { return coverID, nil }, UUIDValidFunc),
+				ImageConverter:        imageConverter,
+			}, &router.DAOs{VideosDAO: *videosDAO, UsersDAO: *usersDAO})
+			require.NoError(t, err)
+
+			body := new(bytes.Buffer)
+			writer := multipart.NewWriter(body)
+			if tt.giveCover != "" {
+				fileWriter, err := writer.CreateFormFile("cover", tt.giveCover)
+				require.NoError(t, err)
+				content, err := os.ReadFile("../../../../samples/" + tt.giveCover)
+				require.NoError(t, err)
+				_, err = fileWriter.Write(content)
+				require.NoError(t, err)
+			}
+			require.NoError(t, writer.Close())
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("PUT", "/api/v1/videos/"+tt.giveID+"/cover", body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			} else if tt.giveAccount {
+				req.SetBasicAuth(accountUsername, accountPassword)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedPut {
+				require.Equal(t, []string{newCoverPath}, putPaths)
+			} else {
+				require.Empty(t, putPaths)
+			}
+			require.Equal(t, tt.expectedRemoved, removedPaths)
+			require.Equal(t, tt.expectedStatusEvent, statusEvents == 1)
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"context"
 	"database/sql"
 	"errors"
 	"net/http"
@@ -11,15 +10,17 @@ import (
 
 	"github.com/Sogilis/Voogle/src/pkg/clients"
 
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
 	"github.com/Sogilis/Voogle/src/cmd/api/models"
 )
 
 type VideoDeleteHandler struct {
-	S3Client   clients.IS3Client
-	VideosDAO  *dao.VideosDAO
-	UploadsDAO *dao.UploadsDAO
-	UUIDGen    clients.IUUIDGenerator
+	S3Client     clients.IS3Client
+	VideosDAO    *dao.VideosDAO
+	UploadsDAO   *dao.UploadsDAO
+	PlaylistsDAO *dao.PlaylistsDAO
+	UUIDGen      clients.IUUIDGenerator
 }
 
 // VideoDeleteHandler godoc
@@ -56,58 +57,26 @@ func (v VideoDeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !auth.CanManageVideo(r.Context(), video) {
+		log.Error("Video " + video.ID + " belongs to another user")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
 	if video.Status != models.ARCHIVE {
 		log.Error("Video should be archived to be deleted", err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	statusCode, err := v.deleteVideoAndUpload(r.Context(), id)
-	if err != nil {
-		w.WriteHeader(statusCode)
+	if err = dao.DeleteVideoAndUploads(r.Context(), v.VideosDAO, v.UploadsDAO, v.PlaylistsDAO, id); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	if err = v.S3Client.RemoveObject(r.Context(), id); err != nil {
+	if err = v.S3Client.RemoveObjectsWithPrefix(r.Context(), id+"/"); err != nil {
 		log.Error("Cannot remove video "+id+" from S3 : ", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 }
-
-func (v VideoDeleteHandler) deleteVideoAndUpload(ctx context.Context, id string) (int, error) {
-	tx, err := v.VideosDAO.DB.BeginTx(ctx, nil)
-	if err != nil {
-		log.Error("Cannot open new database transaction : ", err)
-		return http.StatusInternalServerError, err
-	}
-
-	if err := v.UploadsDAO.DeleteUploadTx(ctx, tx, id); err != nil {
-		log.Error("Cannot delete video "+id+" uploads : ", err)
-		if err := tx.Rollback(); err != nil {
-			log.Error("Cannot rollback : ", err)
-		}
-
-		return http.StatusInternalServerError, err
-	}
-
-	if err := v.VideosDAO.DeleteVideoTx(ctx, tx, id); err != nil {
-		log.Error("Cannot delete video "+id+" : ", err)
-		if err := tx.Rollback(); err != nil {
-			log.Error("Cannot rollback : ", err)
-		}
-
-		return http.StatusInternalServerError, err
-	}
-
-	if err := tx.Commit(); err != nil {
-		log.Error("Cannot commit database transaction")
-		if err := tx.Rollback(); err != nil {
-			log.Error("Cannot rollback : ", err)
-		}
-
-		return http.StatusInternalServerError, err
-	}
-
-	return 0, nil
-}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -30,7 +31,7 @@ func TestVideoDelete(t *testing.T) { //nolint:cyclop
 	invalidVideoID := "invalidvideoid"
 	unknownVideoID := "0000a0a0-0aa0-0a00-0000-aa0000aa00aa"
 	UUIDValidFunc := func(u string) bool { _, err := uuid.Parse(u); return err == nil }
-	removeObjectS3 := func(v string) error { return nil }
+	otherVideoID := "b7e6a0c1-5bc6-4a50-9176-ab0371aa65fe"
 
 	videoTitle := "title"
 	t1 := time.Now()
@@ -46,8 +47,8 @@ func TestVideoDelete(t *testing.T) { //nolint:cyclop
 		expectedHTTPCode     int
 		videoDeletionFails   bool
 		uploadsDeletionFails bool
+		giveS3Err            bool
 		isValidUUID          func(string) bool
-		removeObject         func(string) error
 	}{
 		{
 			name:             "DELETE video",
@@ -55,7 +56,6 @@ func TestVideoDelete(t *testing.T) { //nolint:cyclop
 			giveWithAuth:     true,
 			expectedHTTPCode: 200,
 			isValidUUID:      UUIDValidFunc,
-			removeObject:     removeObjectS3,
 		},
 		{
 			name:             "DELETE fails with invalid video ID",
@@ -63,7 +63,6 @@ func TestVideoDelete(t *testing.T) { //nolint:cyclop
 			giveWithAuth:     true,
 			expectedHTTPCode: 400,
 			isValidUUID:      UUIDValidFunc,
-			removeObject:     removeObjectS3,
 		},
 		{
 			name:             "DELETE fails with unknown video ID",
@@ -71,7 +70,6 @@ func TestVideoDelete(t *testing.T) { //nolint:cyclop
 			giveWithAuth:     true,
 			expectedHTTPCode: 404,
 			isValidUUID:      UUIDValidFunc,
-			removeObject:     removeObjectS3,
 		},
 		{
 			name:             "DELETE fails with database error",
@@ -79,22 +77,20 @@ func TestVideoDelete(t *testing.T) { //nolint:cyclop
 			giveWithAuth:     true,
 			giveDatabaseErr:  true,
 			expectedHTTPCode: 500,
-			isValidUUID:      UUIDValidFunc,
-			removeObject:     removeObjectS3},
+			isValidUUID:      UUIDValidFunc},
 		{
 			name:             "DELETE fails with no auth",
 			giveRequest:      "/api/v1/videos/" + validVideoID + "/delete",
 			giveWithAuth:     false,
 			expectedHTTPCode: 401,
-			isValidUUID:      UUIDValidFunc,
-			removeObject:     removeObjectS3},
+			isValidUUID:      UUIDValidFunc},
 		{
 			name:             "DELETE fails with S3 error",
 			giveRequest:      "/api/v1/videos/" + validVideoID + "/delete",
 			giveWithAuth:     true,
 			expectedHTTPCode: 500,
 			isValidUUID:      UUIDValidFunc,
-			removeObject:     func(v string) error { return fmt.Errorf("S3 error") },
+			giveS3Err:        true,
 		},
 		{
 			name:               "DELETE fails with video deletion fails",
@@ -102,7 +98,6 @@ func TestVideoDelete(t *testing.T) { //nolint:cyclop
 			giveWithAuth:       true,
 			expectedHTTPCode:   500,
 			isValidUUID:        UUIDValidFunc,
-			removeObject:       removeObjectS3,
 			videoDeletionFails: true,
 		},
 		{
@@ -111,7 +106,6 @@ func TestVideoDelete(t *testing.T) { //nolint:cyclop
 			giveWithAuth:         true,
 			expectedHTTPCode:     500,
 			isValidUUID:          UUIDValidFunc,
-			removeObject:         removeObjectS3,
 			uploadsDeletionFails: true,
 		},
 		{
@@ -121,14 +115,45 @@ func TestVideoDelete(t *testing.T) { //nolint:cyclop
 			giveVideoNotArchived: true,
 			expectedHTTPCode:     400,
 			isValidUUID:          UUIDValidFunc,
-			removeObject:         removeObjectS3,
 		},
 	}
 
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
 
-			s3Client := clients.NewS3ClientDummy(nil, nil, nil, nil, tt.removeObject)
+			// Objects of the video and of another one, on S3
+			objects := map[string]bool{
+				validVideoID + "/source.mp4":             true,
+				validVideoID + "/cover.png":              true,
+				validVideoID + "/master.m3u8":            true,
+				validVideoID + "/v0/segment_index.m3u8":  true,
+				validVideoID + "/v0/segment0.ts":         true,
+				validVideoID + "/v1/segment0.ts":         true,
+				validVideoID + "/thumbnails/sprite0.jpg": true,
+				otherVideoID + "/master.m3u8":            true,
+			}
+			listObjectsWithPrefix := func(prefix string) ([]string, error) {
+				var keys []string
+				for key := range objects {
+					if strings.HasPrefix(key, prefix) {
+						keys = append(keys, key)
+					}
+				}
+				return keys, nil
+			}
+			removeObjectsWithPrefix := func(prefix string) error {
+				if tt.giveS3Err {
+					return fmt.Errorf("S3 error")
+				}
+				for key := range objects {
+					if strings.HasPrefix(key, prefix) {
+						delete(objects, key)
+					}
+				}
+				return nil
+			}
+
+			s3Client := clients.NewS3ClientDummy(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, listObjectsWithPrefix, removeObjectsWithPrefix)
 
 			// Mock database
 			db, mock, err := sqlmock.New()
@@ -142,6 +167,7 @@ func TestVideoDelete(t *testing.T) { //nolint:cyclop
 
 			dao_test.ExpectVideosDAOCreation(mock)
 			dao_test.ExpectUploadsDAOCreation(mock)
+			dao_test.ExpectPlaylistsDAOCreation(mock)
 
 			if !tt.giveWithAuth || tt.giveRequest == "/api/v1/videos/"+invalidVideoID+"/delete" {
 				// All these cases will stop before modifying the database : Nothing to do
@@ -151,10 +177,13 @@ func TestVideoDelete(t *testing.T) { //nolint:cyclop
 				deleteVideo := regexp.QuoteMeta(dao.VideosRequests[dao.DeleteVideo])
 
 				deleteUpload := regexp.QuoteMeta(dao.UploadsRequests[dao.DeleteUpload])
+				getPlaylistPositions := regexp.QuoteMeta(dao.PlaylistsRequests[dao.GetVideoPlaylistPositions])
+				removeFromPlaylists := regexp.QuoteMeta(dao.PlaylistsRequests[dao.RemoveVideoFromPlaylists])
+				shiftPlaylistVideos := regexp.QuoteMeta(dao.PlaylistsRequests[dao.ShiftPlaylistVideos])
 				getVideoFromIdQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])
 
 				// Tables
-				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path"}
+				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
 				videosRows := sqlmock.NewRows(videosColumns)
 
 				if tt.giveDatabaseErr {
@@ -165,10 +194,10 @@ func TestVideoDelete(t *testing.T) { //nolint:cyclop
 
 				} else {
 					if tt.giveVideoNotArchived {
-						videosRows.AddRow(validVideoID, videoTitle, int(models.COMPLETE), t1, t1, nil, sourcePath, coverPath)
+						videosRows.AddRow(validVideoID, videoTitle, int(models.COMPLETE), t1, t1, nil, sourcePath, coverPath, nil, "", nil, nil)
 						mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
 					} else {
-						videosRows.AddRow(validVideoID, videoTitle, int(models.ARCHIVE), t1, t1, nil, sourcePath, coverPath)
+						videosRows.AddRow(validVideoID, videoTitle, int(models.ARCHIVE), t1, t1, nil, sourcePath, coverPath, nil, "", nil, nil)
 						mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
 
 						mock.ExpectBegin()
@@ -180,6 +209,12 @@ func TestVideoDelete(t *testing.T) { //nolint:cyclop
 						} else {
 							mock.ExpectExec(deleteUpload).WithArgs(validVideoID).WillReturnResult(sqlmock.NewResult(0, 1))
 
+							// The video is the third one of a playlist : the next videos move back
+							positionsRows := sqlmock.NewRows([]string{"playlist_id", "position"}).AddRow(playlistID, 2)
+							mock.ExpectQuery(getPlaylistPositions).WithArgs(validVideoID).WillReturnRows(positionsRows)
+							mock.ExpectExec(removeFromPlaylists).WithArgs(validVideoID).WillReturnResult(sqlmock.NewResult(0, 1))
+							mock.ExpectExec(shiftPlaylistVideos).WithArgs(-1, playlistID, 3).WillReturnResult(sqlmock.NewResult(0, 2))
+
 							if tt.videoDeletionFails {
 								mock.ExpectExec(deleteVideo).WithArgs(validVideoID).WillReturnError(fmt.Errorf("database internal error"))
 								mock.ExpectRollback()
@@ -199,15 +234,20 @@ func TestVideoDelete(t *testing.T) { //nolint:cyclop
 			uploadsDAO, err := dao.CreateUploadsDAO(context.Background(), db)
 			require.NoError(t, err)
 
+			playlistsDAO, err := dao.CreatePlaylistsDAO(context.Background(), db)
+			require.NoError(t, err)
+
 			routerDAO := router.DAOs{
-				VideosDAO:  *videosDAO,
-				UploadsDAO: *uploadsDAO,
+				VideosDAO:    *videosDAO,
+				UploadsDAO:   *uploadsDAO,
+				PlaylistsDAO: *playlistsDAO,
 			}
 
-			r := router.NewRouter(config.Config{
+			r, err := router.NewRouter(config.Config{
 				UserAuth: givenUsername,
 				PwdAuth:  givenUserPwd,
 			}, &routerClients, &routerDAO)
+			require.NoError(t, err)
 
 			w := httptest.NewRecorder()
 
@@ -219,6 +259,16 @@ func TestVideoDelete(t *testing.T) { //nolint:cyclop
 			r.ServeHTTP(w, req)
 			require.Equal(t, tt.expectedHTTPCode, w.Code)
 
+			// Nothing remains under the deleted video prefix, other videos are kept
+			remaining, err := s3Client.ListObjectsWithPrefix(context.Background(), validVideoID+"/")
+			require.NoError(t, err)
+			if tt.expectedHTTPCode == 200 {
+				require.Empty(t, remaining)
+			} else {
+				require.Len(t, remaining, 7)
+			}
+			require.Contains(t, objects, otherVideoID+"/master.m3u8")
+
 			// we make sure that all expectations were met
 			err = mock.ExpectationsWereMet()
 			require.NoError(t, err)
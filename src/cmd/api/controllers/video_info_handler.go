@@ -9,19 +9,23 @@ import (
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
 	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
 	"github.com/Sogilis/Voogle/src/pkg/clients"
 )
 
 type VideoGetInfoHandler struct {
-	VideosDAO *dao.VideosDAO
-	UUIDGen   clients.IUUIDGenerator
+	VideosDAO         *dao.VideosDAO
+	TagsDAO           *dao.TagsDAO
+	AudioLanguagesDAO *dao.AudioLanguagesDAO
+	WatchHistoryDAO   *dao.WatchHistoryDAO
+	UUIDGen           clients.IUUIDGenerator
 }
 
 // VideoGetInfoHandler godoc
 // @Summary Get video informations
-// @Description Get video informations
+// @Description Get video informations, with the last position of the authenticated user to resume the video
 // @Tags video
 // @Produce json
 // @Param id path string true "Video ID"
@@ -52,7 +56,35 @@ func (v VideoGetInfoHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	video.Tags, err = v.TagsDAO.GetVideoTags(r.Context(), id)
+	if err != nil {
+		log.Error("Cannot get video tags : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	video.AudioLanguages, err = v.AudioLanguagesDAO.GetVideoAudioLanguages(r.Context(), id)
+	if err != nil {
+		log.Error("Cannot get video audio languages : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	videoInfo := jsonDTO.VideoToInfoJson(video)
+
+	// Only user accounts have a watch history
+	if userID := auth.OwnerID(r.Context()); userID != nil {
+		position, err := v.WatchHistoryDAO.GetWatchPosition(r.Context(), *userID, id)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Error("Cannot get watch position : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if position != nil {
+			videoInfo.LastPosition = &position.Position
+		}
+	}
+
 	payload, err := json.Marshal(videoInfo)
 	if err != nil {
 		log.Error("Unable to parse data struct in json ", err)
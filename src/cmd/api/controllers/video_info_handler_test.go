@@ -91,6 +91,8 @@ func TestVideoInfo(t *testing.T) { //nolint:cyclop
 			}
 
 			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectTagsDAOCreation(mock)
+			dao_test.ExpectAudioLanguagesDAOCreation(mock)
 
 			if !tt.giveWithAuth || tt.giveRequest == "/api/v1/videos/"+invalidVideoID+"/info" {
 				// All these cases will stop before modifying the database : Nothing to do
@@ -98,9 +100,11 @@ func TestVideoInfo(t *testing.T) { //nolint:cyclop
 			} else {
 				// Queries
 				getVideoFromIdQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])
+				getVideoTagsQuery := regexp.QuoteMeta(dao.TagsRequests[dao.GetVideoTags])
+				getVideoAudioLanguagesQuery := regexp.QuoteMeta(dao.AudioLanguagesRequests[dao.GetVideoAudioLanguages])
 
 				// Tables
-				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path"}
+				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
 				videosRows := sqlmock.NewRows(videosColumns)
 
 				// Define database response according to case
@@ -111,21 +115,34 @@ func TestVideoInfo(t *testing.T) { //nolint:cyclop
 					mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
 
 				} else {
-					videosRows.AddRow(validVideoID, videoTitle, int(models.ENCODING), t1, t1, nil, sourcePath, coverPath)
+					videosRows.AddRow(validVideoID, videoTitle, int(models.ENCODING), t1, t1, nil, sourcePath, coverPath, nil, "", nil, nil)
 					mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
+
+					tagsRows := sqlmock.NewRows([]string{"tag"}).AddRow("mountain").AddRow("nature")
+					mock.ExpectQuery(getVideoTagsQuery).WithArgs(validVideoID).WillReturnRows(tagsRows)
+
+					languagesRows := sqlmock.NewRows([]string{"language"}).AddRow("fre").AddRow("eng")
+					mock.ExpectQuery(getVideoAudioLanguagesQuery).WithArgs(validVideoID).WillReturnRows(languagesRows)
 				}
 			}
 
 			videoDAO, err := dao.CreateVideosDAO(context.Background(), db)
 			require.NoError(t, err)
+			tagsDAO, err := dao.CreateTagsDAO(context.Background(), db)
+			require.NoError(t, err)
+			audioLanguagesDAO, err := dao.CreateAudioLanguagesDAO(context.Background(), db)
+			require.NoError(t, err)
 			routerDAO := router.DAOs{
-				VideosDAO: *videoDAO,
+				VideosDAO:         *videoDAO,
+				TagsDAO:           *tagsDAO,
+				AudioLanguagesDAO: *audioLanguagesDAO,
 			}
 
-			r := router.NewRouter(config.Config{
+			r, err := router.NewRouter(config.Config{
 				UserAuth: givenUsername,
 				PwdAuth:  givenUserPwd,
 			}, &routerClients, &routerDAO)
+			require.NoError(t, err)
 
 			w := httptest.NewRecorder()
 
@@ -137,6 +154,11 @@ func TestVideoInfo(t *testing.T) { //nolint:cyclop
 			r.ServeHTTP(w, req)
 			require.Equal(t, tt.expectedHTTPCode, w.Code)
 
+			if tt.expectedHTTPCode == 200 {
+				require.Contains(t, w.Body.String(), `"tags":["mountain","nature"]`)
+				require.Contains(t, w.Body.String(), `"audioLanguages":["fre","eng"]`)
+			}
+
 			// we make sure that all expectations were met
 			err = mock.ExpectationsWereMet()
 			require.NoError(t, err)
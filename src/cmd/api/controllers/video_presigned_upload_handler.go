@@ -0,0 +1,385 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/metrics"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type VideoPresignedUploadHandler struct {
+	S3Client              clients.IS3Client
+	AmqpVideoStatusUpdate clients.AmqpClient
+	VideosDAO             *dao.VideosDAO
+	UploadsDAO            *dao.UploadsDAO
+	UUIDGen               clients.IUUIDGenerator
+	PresignExpiration     time.Duration
+}
+
+type PresignedUploadRequest struct {
+	Title         string `json:"title"`
+	Filename      string `json:"filename"`
+	CoverFilename string `json:"coverFilename,omitempty"`
+}
+
+// VideoPresignedUploadHandler godoc
+// @Summary Create a presigned video upload
+// @Description Create the video and return presigned URLs to PUT the video source (and cover) directly on S3.
+// @Description The upload must then be completed with the 'complete' link.
+// @Tags video
+// @Accept json
+// @Produce json
+// @Param request body PresignedUploadRequest true "Video title, file names of the video and the optional cover"
+// @Success 201 {object} UploadCreatedResponse "Video, upload ID and Links (HATEOAS)"
+// @Failure 400 {string} string
+// @Failure 409 {string} string "This title already exists"
+// @Failure 500 {string} string
+// @Router /api/v1/videos/uploads/presigned [post]
+func (v VideoPresignedUploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) { //nolint:cyclop
+	log.Debug("POST VideoPresignedUploadHandler")
+
+	var request PresignedUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Error("Cannot decode presigned upload request : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if request.Title == "" || filepath.Ext(request.Filename) == "" {
+		log.Error("Missing title or filename")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	log.Infof("Receive presigned video upload request with title : '%v'", request.Title)
+
+	// Check if a video with this title already exists
+	video, err := v.VideosDAO.GetVideoFromTitle(r.Context(), request.Title)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if video != nil && (video.Status != models.FAIL_UPLOAD || !auth.CanManageVideo(r.Context(), video)) {
+		log.Error("A video with this title already exists")
+		http.Error(w, "This title already exists", http.StatusConflict)
+		return
+	}
+
+	metrics.CounterVideoUploadRequest.Inc()
+
+	// A failed upload is resumed on the existing video, otherwise a new one is created
+	if video == nil {
+		videoID, err := v.UUIDGen.GenerateUuid()
+		if err != nil {
+			log.Error("Cannot generate new video ID : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		videoPath := videoID + "/" + "source" + filepath.Ext(request.Filename)
+		coverPath := ""
+		if request.CoverFilename != "" {
+			coverPath = videoID + "/" + "cover" + filepath.Ext(request.CoverFilename)
+		}
+
+		video, err = v.VideosDAO.CreateVideo(r.Context(), videoID, request.Title, int(models.UPLOADING), videoPath, coverPath, auth.OwnerID(r.Context()))
+		if err != nil {
+			metrics.CounterVideoUploadFail.Inc()
+			log.Error("Cannot create new video : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	uploadID, err := v.UUIDGen.GenerateUuid()
+	if err != nil {
+		metrics.CounterVideoUploadFail.Inc()
+		log.Error("Cannot generate new uploadID : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	uploader := VideoUploadHandler{
+		AmqpVideoStatusUpdate: v.AmqpVideoStatusUpdate,
+		VideosDAO:             v.VideosDAO,
+		UploadsDAO:            v.UploadsDAO,
+	}
+
+	upload, err := v.UploadsDAO.CreateUpload(r.Context(), uploadID, video.ID, int(models.STARTED))
+	if err != nil {
+		metrics.CounterVideoUploadFail.Inc()
+		log.Error("Cannot insert new upload into database: ", err)
+
+		uploader.videoUploadFailed(r.Context(), video)
+		uploader.publishStatus(video)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	links, err := v.presignedLinks(r.Context(), video, upload)
+	if err != nil {
+		metrics.CounterVideoUploadFail.Inc()
+		log.Error("Cannot presign upload URLs : ", err)
+
+		if err := uploader.videoAndUploadFailed(r.Context(), video, upload); err != nil {
+			log.Error("video and upload status failed : ", err)
+		}
+		uploader.publishStatus(video)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// The video of a failed upload is uploading again
+	if video.Status == models.FAIL_UPLOAD {
+		video.Status = models.UPLOADING
+		if err := v.VideosDAO.UpdateVideo(r.Context(), video); err != nil {
+			metrics.CounterVideoUploadFail.Inc()
+			log.Errorf("Unable to update video with status  %v : %v", video.Status, err)
+
+			if err := uploader.videoAndUploadFailed(r.Context(), video, upload); err != nil {
+				log.Error("video and upload status failed : ", err)
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	response := UploadCreatedResponse{
+		Video:    jsonDTO.VideoToVideoJson(video),
+		UploadID: upload.ID,
+		Links:    links,
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write(payload)
+}
+
+func (v VideoPresignedUploadHandler) presignedLinks(ctx context.Context, video *models.Video, upload *models.Upload) (map[string]jsonDTO.LinkJson, error) {
+	sourceURL, err := v.S3Client.PresignPutObject(ctx, video.SourcePath, v.PresignExpiration)
+	if err != nil {
+		return nil, err
+	}
+
+	links := map[string]jsonDTO.LinkJson{
+		"source":   jsonDTO.LinkToLinkJson(models.CreateLink(sourceURL, "PUT")),
+		"complete": jsonDTO.LinkToLinkJson(models.CreateLink("api/v1/videos/uploads/"+upload.ID+"/complete", "POST")),
+		"status":   jsonDTO.LinkToLinkJson(models.CreateLink("api/v1/videos/"+video.ID+"/status", "GET")),
+	}
+
+	if video.CoverPath != "" {
+		coverURL, err := v.S3Client.PresignPutObject(ctx, video.CoverPath, v.PresignExpiration)
+		if err != nil {
+			return nil, err
+		}
+		links["cover"] = jsonDTO.LinkToLinkJson(models.CreateLink(coverURL, "PUT"))
+	}
+
+	return links, nil
+}
+
+type VideoPresignedUploadCompleteHandler struct {
+	S3Client              clients.IS3Client
+	AmqpClient            clients.AmqpClient
+	AmqpVideoStatusUpdate clients.AmqpClient
+	VideosDAO             *dao.VideosDAO
+	UploadsDAO            *dao.UploadsDAO
+	UUIDGen               clients.IUUIDGenerator
+}
+
+// VideoPresignedUploadCompleteHandler godoc
+// @Summary Complete a presigned video upload
+// @Description Check the video source has been sent on S3, then send it for encoding
+// @Tags video
+// @Produce json
+// @Param id path string true "Upload ID"
+// @Success 200 {object} Response "Video and Links (HATEOAS)"
+// @Failure 400 {string} string "Video source not found on S3"
+// @Failure 404 {string} string
+// @Failure 409 {string} string "Upload already completed, or this video source already exists (existing Video and Links as json)"
+// @Failure 415 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/uploads/{id}/complete [post]
+func (v VideoPresignedUploadCompleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) { //nolint:cyclop
+	vars := mux.Vars(r)
+	log.Debug("POST VideoPresignedUploadCompleteHandler - parameters ", vars)
+
+	id, exist := vars["id"]
+	if !exist || !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	upload, err := v.UploadsDAO.GetUpload(r.Context(), id)
+	if err != nil {
+		log.Error("Cannot found upload : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Resumable uploads are completed with their last chunk
+	if upload.Status != models.STARTED || upload.S3UploadID != "" {
+		log.Errorf("Upload %v cannot be completed (status %v)", upload.ID, upload.Status)
+		http.Error(w, "Upload already completed", http.StatusConflict)
+		return
+	}
+
+	video, err := v.VideosDAO.GetVideo(r.Context(), upload.VideoId)
+	if err != nil {
+		log.Error("Cannot found video : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !auth.CanManageVideo(r.Context(), video) {
+		log.Error("Video " + video.ID + " belongs to another user")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	exists, err := v.S3Client.ObjectExists(r.Context(), video.SourcePath)
+	if err != nil {
+		log.Error("Cannot check video source on S3 : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		// The upload stays STARTED : the client can still send the source and retry
+		log.Error("Video source not found on S3 : ", video.SourcePath)
+		http.Error(w, "Video source not found on S3", http.StatusBadRequest)
+		return
+	}
+
+	uploader := VideoUploadHandler{
+		S3Client:              v.S3Client,
+		AmqpClient:            v.AmqpClient,
+		AmqpVideoStatusUpdate: v.AmqpVideoStatusUpdate,
+		VideosDAO:             v.VideosDAO,
+		UploadsDAO:            v.UploadsDAO,
+		UUIDGen:               v.UUIDGen,
+	}
+
+	// Bytes did not go through the API : check the files type now
+	statusCode, err := v.checkUploadedFiles(r.Context(), video)
+	if err != nil {
+		metrics.CounterVideoUploadFail.Inc()
+		if statusCode == http.StatusUnsupportedMediaType {
+			if err := uploader.videoAndUploadFailed(r.Context(), video, upload); err != nil {
+				log.Error("video and upload status failed : ", err)
+			}
+			uploader.publishStatus(video)
+
+			if err := v.S3Client.RemoveObject(r.Context(), video.SourcePath); err != nil {
+				log.Errorf("Unable to remove uploaded video  %v : %v", video.ID, err)
+			}
+		}
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	if err := uploader.saveStoredSourceHash(r.Context(), video, upload); err != nil {
+		writeUploadError(err, w)
+		return
+	}
+
+	if err := uploader.completeUpload(r.Context(), video, upload); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	metrics.CounterVideoUploadSuccess.Inc()
+
+	if err := uploader.sendVideoForEncoding(r.Context(), video); err != nil {
+		log.Error("Cannot send video for encoding : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Include video and HATEOAS upload link into response
+	writeHTTPResponse(video, w)
+	log.Infof("Video '%v' successfully uploaded", video.Title)
+}
+
+// checkUploadedFiles checks the type of the video source and of the cover. The cover is
+// optional : if it has not been sent, it is removed from the video.
+func (v VideoPresignedUploadCompleteHandler) checkUploadedFiles(ctx context.Context, video *models.Video) (int, error) {
+	supported, err := v.isSupportedObjectType(ctx, video.SourcePath, isSupportedVideoType)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if !supported {
+		return http.StatusUnsupportedMediaType, errors.New("unsupported video type")
+	}
+
+	if video.CoverPath == "" {
+		return 0, nil
+	}
+
+	exists, err := v.S3Client.ObjectExists(ctx, video.CoverPath)
+	if err != nil {
+		log.Error("Cannot check cover on S3 : ", err)
+		return http.StatusInternalServerError, err
+	}
+	if !exists {
+		log.Debug("No cover uploaded for video ", video.ID)
+		video.CoverPath = ""
+		return 0, nil
+	}
+
+	supported, err = v.isSupportedObjectType(ctx, video.CoverPath, isSupportedCoverType)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if !supported {
+		return http.StatusUnsupportedMediaType, errors.New("unsupported cover type")
+	}
+
+	return 0, nil
+}
+
+func (v VideoPresignedUploadCompleteHandler) isSupportedObjectType(ctx context.Context, path string, isSupported func(io.ReaderAt) bool) (bool, error) {
+	object, err := v.S3Client.GetObject(ctx, path)
+	if err != nil {
+		log.Error("Cannot get object on S3 : ", err)
+		return false, err
+	}
+	if closer, ok := object.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	// 262 bytes are enough to detect the file type
+	header := make([]byte, 262)
+	n, err := io.ReadFull(object, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		log.Error("Cannot read object on S3 : ", err)
+		return false, err
+	}
+
+	return isSupported(bytes.NewReader(header[:n])), nil
+}
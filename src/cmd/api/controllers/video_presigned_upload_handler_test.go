@@ -0,0 +1,488 @@
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+)
+
+func TestVideoPresignedUpload(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	videoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	uploadID := "2508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	title := "title-of-video"
+	sourcePath := videoID + "/source.mp4"
+	coverPath := videoID + "/cover.png"
+
+	cases := []struct {
+		name               string
+		giveWithAuth       bool
+		giveBody           string
+		titleAlreadyExists bool
+		lastUploadFailed   bool
+		presignFail        bool
+		expectedHTTPCode   int
+		expectedPresigned  []string
+	}{
+		{
+			name:              "POST presigned upload",
+			giveWithAuth:      true,
+			giveBody:          `{"title":"` + title + `","filename":"4K.mp4"}`,
+			expectedHTTPCode:  201,
+			expectedPresigned: []string{sourcePath},
+		},
+		{
+			name:              "POST presigned upload with cover",
+			giveWithAuth:      true,
+			giveBody:          `{"title":"` + title + `","filename":"4K.mp4","coverFilename":"cover.png"}`,
+			expectedHTTPCode:  201,
+			expectedPresigned: []string{sourcePath, coverPath},
+		},
+		{
+			name:              "POST presigned upload with last video upload failed",
+			giveWithAuth:      true,
+			giveBody:          `{"title":"` + title + `","filename":"4K.mp4"}`,
+			lastUploadFailed:  true,
+			expectedHTTPCode:  201,
+			expectedPresigned: []string{sourcePath},
+		},
+		{
+			name:             "POST fails with invalid body",
+			giveWithAuth:     true,
+			giveBody:         `{"title":`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with missing filename",
+			giveWithAuth:     true,
+			giveBody:         `{"title":"` + title + `"}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:               "POST fails with title already exist",
+			giveWithAuth:       true,
+			giveBody:           `{"title":"` + title + `","filename":"4K.mp4"}`,
+			titleAlreadyExists: true,
+			expectedHTTPCode:   409,
+		},
+		{
+			name:              "POST fails with presign fail",
+			giveWithAuth:      true,
+			giveBody:          `{"title":"` + title + `","filename":"4K.mp4"}`,
+			presignFail:       true,
+			expectedHTTPCode:  500,
+			expectedPresigned: []string{sourcePath},
+		},
+		{
+			name:             "POST fails with no auth",
+			giveBody:         `{"title":"` + title + `","filename":"4K.mp4"}`,
+			expectedHTTPCode: 401,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			generatedIDs := []string{videoID, uploadID}
+			if tt.lastUploadFailed {
+				generatedIDs = []string{uploadID}
+			}
+			genUUID := func() (string, error) {
+				id := generatedIDs[0]
+				generatedIDs = generatedIDs[1:]
+				return id, nil
+			}
+
+			var presigned []string
+			s3Client := clients.NewS3ClientDummy(nil, nil, nil, nil, nil, nil, nil, nil, nil,
+				func(path string, expires time.Duration) (string, error) {
+					presigned = append(presigned, path)
+					if tt.presignFail {
+						return "", fmt.Errorf("S3 error")
+					}
+					return "http://s3/" + path + "?signature", nil
+				},
+				nil, nil, nil, nil,
+			)
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			routerClients := router.Clients{
+				S3Client:              s3Client,
+				AmqpVideoStatusUpdate: clients.NewAmqpClientDummy(nil, nil, nil),
+				UUIDGen:               clients.NewUuidGeneratorDummy(genUUID, nil),
+			}
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectUploadsDAOCreation(mock)
+
+			if tt.giveWithAuth && tt.expectedHTTPCode != 400 {
+				// Queries
+				createVideoQuery := regexp.QuoteMeta(dao.VideosRequests[dao.CreateVideo])
+				updateVideoQuery := regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo])
+				getVideoFromTitleQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideoFromTitle])
+				getVideoFromIdQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])
+				createUploadQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.CreateUpload])
+				updateUploadQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.UpdateUpload])
+				getUploadQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.GetUpload])
+
+				t1 := time.Now()
+				givenCoverPath := ""
+				if len(tt.expectedPresigned) > 1 {
+					givenCoverPath = coverPath
+				}
+
+				if tt.titleAlreadyExists {
+					res := sqlmock.NewRows(resumableVideosColumns).AddRow(videoID, title, models.COMPLETE, t1, t1, t1, sourcePath, "", nil, "", nil, nil)
+					mock.ExpectQuery(getVideoFromTitleQuery).WithArgs(title).WillReturnRows(res)
+
+				} else {
+					if tt.lastUploadFailed {
+						res := sqlmock.NewRows(resumableVideosColumns).AddRow(videoID, title, models.FAIL_UPLOAD, nil, t1, t1, sourcePath, givenCoverPath, nil, "", nil, nil)
+						mock.ExpectQuery(getVideoFromTitleQuery).WithArgs(title).WillReturnRows(res)
+
+					} else {
+						mock.ExpectQuery(getVideoFromTitleQuery).WithArgs(title).WillReturnRows(sqlmock.NewRows(resumableVideosColumns))
+
+						// Create Video
+						mock.ExpectExec(createVideoQuery).
+							WithArgs(videoID, title, models.UPLOADING, sourcePath, givenCoverPath, nil).
+							WillReturnResult(sqlmock.NewResult(1, 1))
+
+						res := sqlmock.NewRows(resumableVideosColumns).AddRow(videoID, title, models.UPLOADING, nil, t1, t1, sourcePath, givenCoverPath, nil, "", nil, nil)
+						mock.ExpectQuery(getVideoFromIdQuery).WithArgs(videoID).WillReturnRows(res)
+					}
+
+					// Create Upload
+					mock.ExpectExec(createUploadQuery).
+						WithArgs(uploadID, videoID, models.STARTED).
+						WillReturnResult(sqlmock.NewResult(1, 1))
+
+					res := sqlmock.NewRows(resumableUploadsColumns).AddRow(uploadID, videoID, models.STARTED, nil, t1, t1, 0, 0, 0, "", 0)
+					mock.ExpectQuery(getUploadQuery).WithArgs(uploadID).WillReturnRows(res)
+
+					if tt.presignFail {
+						mock.ExpectBegin()
+
+						// Update videos status : FAIL_UPLOAD
+						mock.ExpectExec(updateVideoQuery).
+							WithArgs(title, models.FAIL_UPLOAD, nil, sourcePath, givenCoverPath, videoID).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+
+						// Update uploads status : FAILED
+						mock.ExpectExec(updateUploadQuery).
+							WithArgs(videoID, models.FAILED, nil, uploadID).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+
+						mock.ExpectCommit()
+
+					} else if tt.lastUploadFailed {
+						// Update video status : UPLOADING
+						mock.ExpectExec(updateVideoQuery).
+							WithArgs(title, models.UPLOADING, nil, sourcePath, givenCoverPath, videoID).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+					}
+				}
+			}
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			uploadsDAO, err := dao.CreateUploadsDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			routerDAO := router.DAOs{
+				VideosDAO:  *videosDAO,
+				UploadsDAO: *uploadsDAO,
+			}
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &routerClients, &routerDAO)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/videos/uploads/presigned", strings.NewReader(tt.giveBody))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			require.Equal(t, tt.expectedPresigned, presigned)
+
+			if tt.expectedHTTPCode == 201 {
+				var response controllers.UploadCreatedResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				require.Equal(t, uploadID, response.UploadID)
+				require.Equal(t, "http://s3/"+sourcePath+"?signature", response.Links["source"].Href)
+				require.Equal(t, "api/v1/videos/uploads/"+uploadID+"/complete", response.Links["complete"].Href)
+				require.Equal(t, models.UPLOADING.String(), response.Video.Status)
+			}
+
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestVideoPresignedUploadComplete(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	videoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	uploadID := "2508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	title := "title-of-video"
+	sourcePath := videoID + "/source.mp4"
+	UUIDValidFunc := func(u string) bool { _, err := uuid.Parse(u); return err == nil }
+
+	cases := []struct {
+		name                string
+		giveID              string
+		giveWithAuth        bool
+		giveUnknownID       bool
+		giveUploadDone      bool
+		giveSourceMissing   bool
+		giveWrongMagic      bool
+		sourceAlreadyExists bool
+		expectedHTTPCode    int
+		expectRemoveSource  bool
+	}{
+		{
+			name:             "POST complete presigned upload",
+			giveID:           uploadID,
+			giveWithAuth:     true,
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "POST fails with invalid upload ID",
+			giveID:           "invalidid",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with unknown upload ID",
+			giveID:           uploadID,
+			giveWithAuth:     true,
+			giveUnknownID:    true,
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "POST fails with upload already done",
+			giveID:           uploadID,
+			giveWithAuth:     true,
+			giveUploadDone:   true,
+			expectedHTTPCode: 409,
+		},
+		{
+			name:              "POST fails with source not on S3",
+			giveID:            uploadID,
+			giveWithAuth:      true,
+			giveSourceMissing: true,
+			expectedHTTPCode:  400,
+		},
+		{
+			name:               "POST fails with wrong magic number",
+			giveID:             uploadID,
+			giveWithAuth:       true,
+			giveWrongMagic:     true,
+			expectedHTTPCode:   415,
+			expectRemoveSource: true,
+		},
+		{
+			name:                "POST fails with source already exist",
+			giveID:              uploadID,
+			giveWithAuth:        true,
+			sourceAlreadyExists: true,
+			expectedHTTPCode:    409,
+		},
+		{
+			name:             "POST fails with no auth",
+			giveID:           uploadID,
+			expectedHTTPCode: 401,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceRemoved := false
+			s3Client := clients.NewS3ClientDummy(nil,
+				func(path string) (io.Reader, error) {
+					if tt.giveWrongMagic {
+						return bytes.NewReader(make([]byte, 1000)), nil
+					}
+					return bytes.NewReader(webmChunk(1000)), nil
+				},
+				nil, nil,
+				func(path string) error { sourceRemoved = path == sourcePath; return nil },
+				nil, nil, nil, nil, nil,
+				func(path string) (bool, error) { return !tt.giveSourceMissing, nil },
+				nil, nil,
+				func(string) error { return nil },
+			)
+			amqpClient := clients.NewAmqpClientDummy(func(string, []byte) error { return nil }, nil, nil)
+			amqpVideoStatusUpdate := clients.NewAmqpClientDummy(nil, nil, nil)
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			routerClients := router.Clients{
+				S3Client:              s3Client,
+				AmqpClient:            amqpClient,
+				AmqpVideoStatusUpdate: amqpVideoStatusUpdate,
+				UUIDGen:               clients.NewUuidGeneratorDummy(nil, UUIDValidFunc),
+			}
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectUploadsDAOCreation(mock)
+
+			if tt.giveWithAuth && tt.giveID == uploadID {
+				// Queries
+				updateVideoQuery := regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo])
+				getVideoFromIdQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])
+				getUploadQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.GetUpload])
+				updateUploadQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.UpdateUpload])
+				getVideoFromSourceHashQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideoFromSourceHash])
+				updateVideoSourceHashQuery := regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideoSourceHash])
+				deleteVideoQuery := regexp.QuoteMeta(dao.VideosRequests[dao.DeleteVideo])
+				deleteUploadQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.DeleteUpload])
+
+				t1 := time.Now()
+
+				uploadRows := sqlmock.NewRows(resumableUploadsColumns)
+				if tt.giveUploadDone {
+					uploadRows.AddRow(uploadID, videoID, models.DONE, t1, t1, t1, 0, 0, 0, "", 0)
+				} else if !tt.giveUnknownID {
+					uploadRows.AddRow(uploadID, videoID, models.STARTED, nil, t1, t1, 0, 0, 0, "", 0)
+				}
+				mock.ExpectQuery(getUploadQuery).WithArgs(uploadID).WillReturnRows(uploadRows)
+
+				if !tt.giveUnknownID && !tt.giveUploadDone {
+					res := sqlmock.NewRows(resumableVideosColumns).AddRow(videoID, title, models.UPLOADING, nil, t1, t1, sourcePath, "", nil, "", nil, nil)
+					mock.ExpectQuery(getVideoFromIdQuery).WithArgs(videoID).WillReturnRows(res)
+
+					if tt.giveWrongMagic {
+						mock.ExpectBegin()
+
+						// Update videos status : FAIL_UPLOAD
+						mock.ExpectExec(updateVideoQuery).
+							WithArgs(title, models.FAIL_UPLOAD, nil, sourcePath, "", videoID).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+
+						// Update uploads status : FAILED
+						mock.ExpectExec(updateUploadQuery).
+							WithArgs(videoID, models.FAILED, nil, uploadID).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+
+						mock.ExpectCommit()
+
+					} else if tt.sourceAlreadyExists {
+						// Another video has the same source
+						res := sqlmock.NewRows(resumableVideosColumns).AddRow("AnotherId", "another-title", models.COMPLETE, t1, t1, t1, "AnotherId/source.mp4", "", "hash", "", nil, nil)
+						mock.ExpectQuery(getVideoFromSourceHashQuery).WithArgs(AnySourceHash{}).WillReturnRows(res)
+
+						// Remove the uploaded video
+						mock.ExpectBegin()
+						mock.ExpectExec(deleteUploadQuery).
+							WithArgs(videoID).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+						mock.ExpectExec(deleteVideoQuery).
+							WithArgs(videoID).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+						mock.ExpectCommit()
+
+					} else if !tt.giveSourceMissing {
+						// Source not uploaded yet
+						mock.ExpectQuery(getVideoFromSourceHashQuery).WithArgs(AnySourceHash{}).WillReturnRows(sqlmock.NewRows(resumableVideosColumns))
+						mock.ExpectExec(updateVideoSourceHashQuery).
+							WithArgs(AnySourceHash{}, videoID).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+
+						// Update videos status : UPLOADED + Upload date
+						mock.ExpectExec(updateVideoQuery).
+							WithArgs(title, models.UPLOADED, AnyTime{}, sourcePath, "", videoID).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+
+						// Update uploads status : DONE + Upload date
+						mock.ExpectExec(updateUploadQuery).
+							WithArgs(videoID, models.DONE, AnyTime{}, uploadID).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+
+						// Update video status : ENCODING
+						mock.ExpectExec(updateVideoQuery).
+							WithArgs(title, models.ENCODING, AnyTime{}, sourcePath, "", videoID).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+					}
+				}
+			}
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			uploadsDAO, err := dao.CreateUploadsDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			routerDAO := router.DAOs{
+				VideosDAO:  *videosDAO,
+				UploadsDAO: *uploadsDAO,
+			}
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &routerClients, &routerDAO)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/videos/uploads/"+tt.giveID+"/complete", nil)
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			require.Equal(t, tt.expectRemoveSource, sourceRemoved)
+
+			if tt.sourceAlreadyExists {
+				// The existing video is returned
+				require.Contains(t, w.Body.String(), `"id":"AnotherId"`)
+			}
+
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
+}
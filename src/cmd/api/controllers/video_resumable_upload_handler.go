@@ -0,0 +1,502 @@
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/metrics"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+// Resumable uploads follow the tus protocol (https://tus.io/protocols/resumable-upload.html) :
+// POST creates the upload, HEAD returns the current offset and PATCH appends a chunk.
+// The upload is finalized as soon as the last chunk is received.
+const (
+	TusVersion        = "1.0.0"
+	TusOffsetMimeType = "application/offset+octet-stream"
+
+	// S3 refuses multipart parts smaller than 5MiB, except for the last one
+	MinUploadChunkSize int64 = 5 * 1024 * 1024
+	MaxUploadChunkSize int64 = 64 * 1024 * 1024
+)
+
+type VideoResumableUploadCreateHandler struct {
+	S3Client   clients.IS3Client
+	VideosDAO  *dao.VideosDAO
+	UploadsDAO *dao.UploadsDAO
+	UUIDGen    clients.IUUIDGenerator
+}
+
+// UploadCreatedResponse is returned when an upload is created, before any byte of the video is received
+type UploadCreatedResponse struct {
+	Video    jsonDTO.VideoJson           `json:"video"`
+	UploadID string                      `json:"uploadId"`
+	Links    map[string]jsonDTO.LinkJson `json:"_links"`
+}
+
+// VideoResumableUploadCreateHandler godoc
+// @Summary Create a resumable video upload
+// @Description Create a resumable video upload (tus protocol). Chunks are then sent with PATCH requests.
+// @Tags video
+// @Produce json
+// @Param Upload-Length header int true "Size of the video file in bytes"
+// @Param Upload-Metadata header string true "tus metadata, must contain base64 encoded 'title' and 'filename'"
+// @Success 201 {object} UploadCreatedResponse "Video, upload ID and Links (HATEOAS)"
+// @Failure 400 {string} string
+// @Failure 409 {string} string "This title already exists"
+// @Failure 500 {string} string
+// @Router /api/v1/videos/uploads [post]
+func (v VideoResumableUploadCreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) { //nolint:cyclop
+	log.Debug("POST VideoResumableUploadCreateHandler")
+	w.Header().Set("Tus-Resumable", TusVersion)
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		log.Error("Missing or invalid Upload-Length header")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	metadata, err := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		log.Error("Invalid Upload-Metadata header : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	title := metadata["title"]
+	filename := metadata["filename"]
+	if title == "" || filepath.Ext(filename) == "" {
+		log.Error("Missing title or filename in upload metadata")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	log.Infof("Receive resumable video upload request with title : '%v'", title)
+
+	// Check if a video with this title already exists
+	video, err := v.VideosDAO.GetVideoFromTitle(r.Context(), title)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if video != nil && (video.Status != models.FAIL_UPLOAD || !auth.CanManageVideo(r.Context(), video)) {
+		log.Error("A video with this title already exists")
+		http.Error(w, "This title already exists", http.StatusConflict)
+		return
+	}
+
+	metrics.CounterVideoUploadRequest.Inc()
+
+	// A failed upload is resumed on the existing video, otherwise a new one is created
+	if video != nil {
+		if err := v.abortPreviousUpload(r.Context(), video); err != nil {
+			metrics.CounterVideoUploadFail.Inc()
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		// The file of the new upload may have another extension
+		video.SourcePath = video.ID + "/" + "source" + filepath.Ext(filename)
+	} else {
+		videoID, err := v.UUIDGen.GenerateUuid()
+		if err != nil {
+			log.Error("Cannot generate new video ID : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		videoPath := videoID + "/" + "source" + filepath.Ext(filename)
+		video, err = v.VideosDAO.CreateVideo(r.Context(), videoID, title, int(models.UPLOADING), videoPath, "", auth.OwnerID(r.Context()))
+		if err != nil {
+			metrics.CounterVideoUploadFail.Inc()
+			log.Error("Cannot create new video : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	uploadID, err := v.UUIDGen.GenerateUuid()
+	if err != nil {
+		metrics.CounterVideoUploadFail.Inc()
+		log.Error("Cannot generate new uploadID : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s3UploadID, err := v.S3Client.CreateMultipartUpload(r.Context(), video.SourcePath)
+	if err != nil {
+		metrics.CounterVideoUploadFail.Inc()
+		log.Error("Cannot create multipart upload on S3 : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	upload, err := v.UploadsDAO.CreateResumableUpload(r.Context(), uploadID, video.ID, int(models.STARTED), length, s3UploadID)
+	if err != nil {
+		metrics.CounterVideoUploadFail.Inc()
+		log.Error("Cannot insert new upload into database: ", err)
+		if err := v.S3Client.AbortMultipartUpload(r.Context(), video.SourcePath, s3UploadID); err != nil {
+			log.Error("Cannot abort multipart upload on S3 : ", err)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// The video of a failed upload is uploading again
+	if video.Status == models.FAIL_UPLOAD {
+		video.Status = models.UPLOADING
+		if err := v.VideosDAO.UpdateVideo(r.Context(), video); err != nil {
+			metrics.CounterVideoUploadFail.Inc()
+			log.Errorf("Unable to update video with status  %v : %v", video.Status, err)
+			if err := v.S3Client.AbortMultipartUpload(r.Context(), video.SourcePath, s3UploadID); err != nil {
+				log.Error("Cannot abort multipart upload on S3 : ", err)
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	uploadPath := "api/v1/videos/uploads/" + upload.ID
+	response := UploadCreatedResponse{
+		Video:    jsonDTO.VideoToVideoJson(video),
+		UploadID: upload.ID,
+		Links: map[string]jsonDTO.LinkJson{
+			"offset": jsonDTO.LinkToLinkJson(models.CreateLink(uploadPath, "HEAD")),
+			"upload": jsonDTO.LinkToLinkJson(models.CreateLink(uploadPath, "PATCH")),
+			"status": jsonDTO.LinkToLinkJson(models.CreateLink("api/v1/videos/"+video.ID+"/status", "GET")),
+		},
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", "/"+uploadPath)
+	w.Header().Set("Upload-Offset", "0")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write(payload)
+}
+
+// abortPreviousUpload drops the parts already sent by the previous resumable upload of a failed video,
+// and marks this upload failed if it was still started
+func (v VideoResumableUploadCreateHandler) abortPreviousUpload(ctx context.Context, video *models.Video) error {
+	upload, err := v.UploadsDAO.GetVideoLatestUpload(ctx, video.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		log.Error("Cannot get previous upload of video "+video.ID+" : ", err)
+		return err
+	}
+
+	if upload.S3UploadID == "" || upload.Status == models.DONE {
+		return nil
+	}
+
+	// The multipart upload is already gone if it was aborted when it failed
+	if err := v.S3Client.AbortMultipartUpload(ctx, video.SourcePath, upload.S3UploadID); err != nil {
+		log.Error("Cannot abort previous multipart upload on S3 : ", err)
+	}
+
+	if upload.Status == models.STARTED {
+		upload.Status = models.FAILED
+		if err := v.UploadsDAO.UpdateUpload(ctx, upload); err != nil {
+			log.Error("Cannot mark previous upload "+upload.ID+" as failed : ", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+type VideoResumableUploadOffsetHandler struct {
+	VideosDAO  *dao.VideosDAO
+	UploadsDAO *dao.UploadsDAO
+	UUIDGen    clients.IUUIDGenerator
+}
+
+// VideoResumableUploadOffsetHandler godoc
+// @Summary Get resumable upload offset
+// @Description Get the number of bytes already received for a resumable upload, in the Upload-Offset header
+// @Tags video
+// @Param id path string true "Upload ID"
+// @Success 200 {string} string
+// @Failure 400 {string} string
+// @Failure 403 {string} string "The video belongs to another user"
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/uploads/{id} [head]
+func (v VideoResumableUploadOffsetHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("HEAD VideoResumableUploadOffsetHandler - parameters ", vars)
+	w.Header().Set("Tus-Resumable", TusVersion)
+
+	upload, statusCode, err := getResumableUpload(r.Context(), v.UploadsDAO, v.UUIDGen, vars["id"])
+	if err != nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	if _, statusCode, err := getUploadVideo(r.Context(), v.VideosDAO, upload); err != nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+type VideoResumableUploadChunkHandler struct {
+	S3Client              clients.IS3Client
+	AmqpClient            clients.AmqpClient
+	AmqpVideoStatusUpdate clients.AmqpClient
+	VideosDAO             *dao.VideosDAO
+	UploadsDAO            *dao.UploadsDAO
+	UUIDGen               clients.IUUIDGenerator
+}
+
+// VideoResumableUploadChunkHandler godoc
+// @Summary Send a resumable upload chunk
+// @Description Append a chunk to a resumable upload. Chunks must be at least 5MiB, except the last one.
+// @Description The video is sent for encoding once the last chunk is received.
+// @Tags video
+// @Accept application/offset+octet-stream
+// @Param id path string true "Upload ID"
+// @Param Upload-Offset header int true "Offset of the chunk, must match the current upload offset"
+// @Success 204 {string} string
+// @Failure 400 {string} string
+// @Failure 403 {string} string "The video belongs to another user"
+// @Failure 404 {string} string
+// @Failure 409 {string} string "Upload offset mismatch, another chunk being received, or this video source already exists (existing Video and Links as json)"
+// @Failure 411 {string} string "Missing Content-Length"
+// @Failure 415 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/uploads/{id} [patch]
+func (v VideoResumableUploadChunkHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) { //nolint:cyclop
+	vars := mux.Vars(r)
+	log.Debug("PATCH VideoResumableUploadChunkHandler - parameters ", vars)
+	w.Header().Set("Tus-Resumable", TusVersion)
+
+	if r.Header.Get("Content-Type") != TusOffsetMimeType {
+		log.Error("Invalid Content-Type for upload chunk : ", r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		log.Error("Missing or invalid Upload-Offset header")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	upload, statusCode, err := getResumableUpload(r.Context(), v.UploadsDAO, v.UUIDGen, vars["id"])
+	if err != nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	video, statusCode, err := getUploadVideo(r.Context(), v.VideosDAO, upload)
+	if err != nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	if upload.Status != models.STARTED || offset != upload.Offset {
+		log.Errorf("Upload %v cannot receive chunk at offset %v (status %v, offset %v)", upload.ID, offset, upload.Status, upload.Offset)
+		w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	// The chunk is streamed to S3, which needs to know the part size before receiving it
+	chunkSize := r.ContentLength
+	if chunkSize < 0 {
+		log.Error("Missing Content-Length for upload chunk")
+		w.WriteHeader(http.StatusLengthRequired)
+		return
+	}
+
+	remaining := upload.Length - upload.Offset
+	isLastChunk := chunkSize == remaining
+	if chunkSize == 0 || chunkSize > remaining || chunkSize > MaxUploadChunkSize || (!isLastChunk && chunkSize < MinUploadChunkSize) {
+		log.Errorf("Invalid chunk size %v for upload %v", chunkSize, upload.ID)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Check if the received file is a supported video type
+	chunk := bufio.NewReader(r.Body)
+	if offset == 0 {
+		header, _ := chunk.Peek(262)
+		if !isSupportedVideoType(bytes.NewReader(header)) {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+	}
+
+	// The part is sent while the upload is locked, so that concurrent chunks cannot overwrite it
+	partNumber := upload.Parts + 1
+	received := &progressReader{reader: io.LimitReader(chunk, chunkSize)}
+	var partErr error
+	err = v.UploadsDAO.AppendUploadChunk(r.Context(), upload, offset, func() error {
+		if partErr = v.S3Client.UploadPart(r.Context(), received, chunkSize, video.SourcePath, upload.S3UploadID, partNumber); partErr != nil {
+			log.Error("Unable to upload part on S3 : ", partErr)
+			return partErr
+		}
+
+		upload.Offset += chunkSize
+		upload.Parts = partNumber
+		upload.Progress = int(upload.Offset * 100 / upload.Length)
+		return nil
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, dao.ErrUploadOffsetMismatch):
+		w.WriteHeader(http.StatusConflict)
+		return
+	case partErr != nil && received.read < chunkSize:
+		// The client sent less than its Content-Length
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !isLastChunk {
+		uploader := VideoUploadHandler{AmqpVideoStatusUpdate: v.AmqpVideoStatusUpdate}
+		uploader.publishUploadProgress(video, upload.Progress)
+	} else {
+		if err := v.finalizeUpload(r.Context(), video, upload); err != nil {
+			writeUploadError(err, w)
+			return
+		}
+		log.Infof("Video '%v' successfully uploaded", video.Title)
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// finalizeUpload assembles the S3 object, then follows the same path as a
+// single request upload : source hash saved, UPLOADED, then sent for encoding.
+func (v VideoResumableUploadChunkHandler) finalizeUpload(ctx context.Context, video *models.Video, upload *models.Upload) error {
+	uploader := VideoUploadHandler{
+		S3Client:              v.S3Client,
+		AmqpClient:            v.AmqpClient,
+		AmqpVideoStatusUpdate: v.AmqpVideoStatusUpdate,
+		VideosDAO:             v.VideosDAO,
+		UploadsDAO:            v.UploadsDAO,
+		UUIDGen:               v.UUIDGen,
+	}
+
+	if err := v.S3Client.CompleteMultipartUpload(ctx, video.SourcePath, upload.S3UploadID); err != nil {
+		metrics.CounterVideoUploadFail.Inc()
+		log.Error("Unable to complete multipart upload on S3 : ", err)
+
+		if err := uploader.videoAndUploadFailed(ctx, video, upload); err != nil {
+			log.Error("video and upload status failed : ", err)
+		}
+		uploader.publishStatus(video)
+		return err
+	}
+
+	if err := uploader.saveStoredSourceHash(ctx, video, upload); err != nil {
+		return err
+	}
+
+	if err := uploader.completeUpload(ctx, video, upload); err != nil {
+		return err
+	}
+	metrics.CounterVideoUploadSuccess.Inc()
+
+	if err := uploader.sendVideoForEncoding(ctx, video); err != nil {
+		log.Error("Cannot send video for encoding : ", err)
+		return err
+	}
+
+	return nil
+}
+
+func getResumableUpload(ctx context.Context, uploadsDAO *dao.UploadsDAO, uuidGen clients.IUUIDGenerator, id string) (*models.Upload, int, error) {
+	if !uuidGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		return nil, http.StatusBadRequest, errors.New("invalid id")
+	}
+
+	upload, err := uploadsDAO.GetUpload(ctx, id)
+	if err != nil {
+		log.Error("Cannot found upload : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, http.StatusNotFound, err
+		}
+		return nil, http.StatusInternalServerError, err
+	}
+
+	// Uploads made in a single request cannot be resumed
+	if upload.S3UploadID == "" {
+		log.Error("Upload " + id + " is not a resumable upload")
+		return nil, http.StatusNotFound, errors.New("not a resumable upload")
+	}
+
+	return upload, 0, nil
+}
+
+// getUploadVideo returns the video of the upload, if the authenticated user can upload it
+func getUploadVideo(ctx context.Context, videosDAO *dao.VideosDAO, upload *models.Upload) (*models.Video, int, error) {
+	video, err := videosDAO.GetVideo(ctx, upload.VideoId)
+	if err != nil {
+		log.Error("Cannot found video : ", err)
+		return nil, http.StatusInternalServerError, err
+	}
+
+	if !auth.CanManageVideo(ctx, video) {
+		log.Error("Video " + video.ID + " belongs to another user")
+		return nil, http.StatusForbidden, errors.New("video of another user")
+	}
+
+	return video, 0, nil
+}
+
+// parseUploadMetadata decodes a tus Upload-Metadata header : "key base64value,key2 base64value2"
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, encodedValue, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(encodedValue)
+		if err != nil {
+			return nil, err
+		}
+		metadata[key] = string(value)
+	}
+
+	return metadata, nil
+}
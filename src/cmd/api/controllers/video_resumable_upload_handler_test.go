@@ -0,0 +1,770 @@
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+)
+
+var (
+	resumableVideosColumns  = []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
+	resumableUploadsColumns = []string{"id", "video_id", "upload_status", "uploaded_at", "created_at", "updated_at", "upload_length", "upload_offset", "upload_parts", "s3_upload_id", "upload_progress"}
+)
+
+// webmChunk returns a chunk of the given size starting with a Webm magic number
+func webmChunk(size int) []byte {
+	chunk := make([]byte, size)
+	copy(chunk, []byte{
+		0x1a, 0x45, 0xdf, 0xa3, 0x9f, 0x42, 0x86, 0x81, 0x01, 0x42, 0xf7, 0x81, 0x01, 0x42, 0xf2, 0x81,
+		0x04, 0x42, 0xf3, 0x81, 0x08, 0x42, 0x82, 0x84, 0x77, 0x65, 0x62, 0x6d, 0x42, 0x87, 0x81, 0x02,
+		0x42, 0x85, 0x81, 0x02, 0x18, 0x53, 0x80, 0x67, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x4a, 0xf7,
+	})
+	return chunk
+}
+
+func TestVideoResumableUploadCreate(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	videoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	uploadID := "2508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	s3UploadID := "S3UploadID"
+	title := "title-of-video"
+	sourcePath := videoID + "/source.mp4"
+	previousUploadID := "3508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	previousSourcePath := videoID + "/source.mkv"
+	metadata := "title " + base64.StdEncoding.EncodeToString([]byte(title)) + ",filename " + base64.StdEncoding.EncodeToString([]byte("4K.mp4"))
+
+	cases := []struct {
+		name               string
+		giveWithAuth       bool
+		giveLength         string
+		giveMetadata       string
+		titleAlreadyExists bool
+		lastUploadFailed   bool
+		createUploadFail   bool
+		updateVideoFail    bool
+		expectedHTTPCode   int
+		expectedAborts     []string
+	}{
+		{
+			name:             "POST create resumable upload",
+			giveWithAuth:     true,
+			giveLength:       "1000",
+			giveMetadata:     metadata,
+			expectedHTTPCode: 201,
+		},
+		{
+			name:             "POST create resumable upload with last video upload failed",
+			giveWithAuth:     true,
+			giveLength:       "1000",
+			giveMetadata:     metadata,
+			lastUploadFailed: true,
+			expectedHTTPCode: 201,
+			expectedAborts:   []string{previousSourcePath + ":PreviousS3UploadID"},
+		},
+		{
+			name:             "POST fails with missing length",
+			giveWithAuth:     true,
+			giveMetadata:     metadata,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with missing title",
+			giveWithAuth:     true,
+			giveLength:       "1000",
+			giveMetadata:     "filename " + base64.StdEncoding.EncodeToString([]byte("4K.mp4")),
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with invalid metadata",
+			giveWithAuth:     true,
+			giveLength:       "1000",
+			giveMetadata:     "title not-base64!",
+			expectedHTTPCode: 400,
+		},
+		{
+			name:               "POST fails with title already exist",
+			giveWithAuth:       true,
+			giveLength:         "1000",
+			giveMetadata:       metadata,
+			titleAlreadyExists: true,
+			expectedHTTPCode:   409,
+		},
+		{
+			name:             "POST fails with last video upload failed and update video fail",
+			giveWithAuth:     true,
+			giveLength:       "1000",
+			giveMetadata:     metadata,
+			lastUploadFailed: true,
+			updateVideoFail:  true,
+			expectedHTTPCode: 500,
+			expectedAborts:   []string{previousSourcePath + ":PreviousS3UploadID", sourcePath + ":" + s3UploadID},
+		},
+		{
+			name:             "POST fails with create upload fail",
+			giveWithAuth:     true,
+			giveLength:       "1000",
+			giveMetadata:     metadata,
+			createUploadFail: true,
+			expectedHTTPCode: 500,
+			expectedAborts:   []string{sourcePath + ":" + s3UploadID},
+		},
+		{
+			name:             "POST fails with no auth",
+			giveWithAuth:     false,
+			expectedHTTPCode: 401,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			generatedIDs := []string{videoID, uploadID}
+			if tt.lastUploadFailed {
+				generatedIDs = []string{uploadID}
+			}
+			genUUID := func() (string, error) {
+				id := generatedIDs[0]
+				generatedIDs = generatedIDs[1:]
+				return id, nil
+			}
+
+			var aborts []string
+			s3Client := clients.NewS3ClientDummy(nil, nil, nil, nil, nil,
+				func(path string) (string, error) {
+					require.Equal(t, sourcePath, path)
+					return s3UploadID, nil
+				},
+				nil, nil,
+				func(path, uploadID string) error { aborts = append(aborts, path+":"+uploadID); return nil },
+				nil, nil, nil, nil, nil,
+			)
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			routerClients := router.Clients{
+				S3Client: s3Client,
+				UUIDGen:  clients.NewUuidGeneratorDummy(genUUID, nil),
+			}
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectUploadsDAOCreation(mock)
+
+			if tt.giveWithAuth && tt.expectedHTTPCode != 400 {
+				// Queries
+				createVideoQuery := regexp.QuoteMeta(dao.VideosRequests[dao.CreateVideo])
+				getVideoFromTitleQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideoFromTitle])
+				getVideoFromIdQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])
+				createResumableUploadQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.CreateResumableUpload])
+				getUploadQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.GetUpload])
+				getVideoLatestUploadQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.GetVideoLatestUpload])
+				updateUploadQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.UpdateUpload])
+				updateVideoQuery := regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo])
+
+				t1 := time.Now()
+
+				if tt.titleAlreadyExists {
+					res := sqlmock.NewRows(resumableVideosColumns).AddRow(videoID, title, models.COMPLETE, t1, t1, t1, sourcePath, "", nil, "", nil, nil)
+					mock.ExpectQuery(getVideoFromTitleQuery).WithArgs(title).WillReturnRows(res)
+
+				} else {
+					if tt.lastUploadFailed {
+						// The previous upload was a mkv file, abandoned before its end
+						res := sqlmock.NewRows(resumableVideosColumns).AddRow(videoID, title, models.FAIL_UPLOAD, nil, t1, t1, previousSourcePath, "", nil, "", nil, nil)
+						mock.ExpectQuery(getVideoFromTitleQuery).WithArgs(title).WillReturnRows(res)
+
+						res = sqlmock.NewRows(resumableUploadsColumns).AddRow(previousUploadID, videoID, models.STARTED, nil, t1, t1, 2000, 500, 1, "PreviousS3UploadID", 25)
+						mock.ExpectQuery(getVideoLatestUploadQuery).WithArgs(videoID).WillReturnRows(res)
+						mock.ExpectExec(updateUploadQuery).
+							WithArgs(videoID, models.FAILED, nil, previousUploadID).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+
+					} else {
+						mock.ExpectQuery(getVideoFromTitleQuery).WithArgs(title).WillReturnRows(sqlmock.NewRows(resumableVideosColumns))
+
+						// Create Video
+						mock.ExpectExec(createVideoQuery).
+							WithArgs(videoID, title, models.UPLOADING, sourcePath, "", nil).
+							WillReturnResult(sqlmock.NewResult(1, 1))
+
+						res := sqlmock.NewRows(resumableVideosColumns).AddRow(videoID, title, models.UPLOADING, nil, t1, t1, sourcePath, "", nil, "", nil, nil)
+						mock.ExpectQuery(getVideoFromIdQuery).WithArgs(videoID).WillReturnRows(res)
+					}
+
+					if tt.createUploadFail {
+						// Create Upload (fail)
+						mock.ExpectExec(createResumableUploadQuery).
+							WithArgs(uploadID, videoID, models.STARTED, 1000, s3UploadID).
+							WillReturnError(fmt.Errorf("Error while creating new upload"))
+
+					} else {
+						// Create Upload
+						mock.ExpectExec(createResumableUploadQuery).
+							WithArgs(uploadID, videoID, models.STARTED, 1000, s3UploadID).
+							WillReturnResult(sqlmock.NewResult(1, 1))
+
+						res := sqlmock.NewRows(resumableUploadsColumns).AddRow(uploadID, videoID, models.STARTED, nil, t1, t1, 1000, 0, 0, s3UploadID, 0)
+						mock.ExpectQuery(getUploadQuery).WithArgs(uploadID).WillReturnRows(res)
+
+						if tt.lastUploadFailed {
+							// Update video status : UPLOADING
+							update := mock.ExpectExec(updateVideoQuery).
+								WithArgs(title, models.UPLOADING, nil, sourcePath, "", videoID)
+							if tt.updateVideoFail {
+								update.WillReturnError(fmt.Errorf("Error while updating video"))
+							} else {
+								update.WillReturnResult(sqlmock.NewResult(0, 1))
+							}
+						}
+					}
+				}
+			}
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			uploadsDAO, err := dao.CreateUploadsDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			routerDAO := router.DAOs{
+				VideosDAO:  *videosDAO,
+				UploadsDAO: *uploadsDAO,
+			}
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &routerClients, &routerDAO)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/videos/uploads", nil)
+			req.Header.Set("Tus-Resumable", controllers.TusVersion)
+			req.Header.Set("Upload-Length", tt.giveLength)
+			req.Header.Set("Upload-Metadata", tt.giveMetadata)
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			require.Equal(t, tt.expectedAborts, aborts)
+
+			if tt.expectedHTTPCode == 201 {
+				require.Equal(t, "/api/v1/videos/uploads/"+uploadID, w.Header().Get("Location"))
+				require.Contains(t, w.Body.String(), `"status":"`+models.UPLOADING.String()+`"`)
+			}
+
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestVideoResumableUploadOffset(t *testing.T) {
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	uploadID := "2508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	videoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	UUIDValidFunc := func(u string) bool { _, err := uuid.Parse(u); return err == nil }
+
+	cases := []struct {
+		name             string
+		giveID           string
+		giveWithAuth     bool
+		giveS3UploadID   string
+		giveUnknownID    bool
+		expectedHTTPCode int
+		expectedOffset   string
+	}{
+		{
+			name:             "HEAD resumable upload offset",
+			giveID:           uploadID,
+			giveWithAuth:     true,
+			giveS3UploadID:   "S3UploadID",
+			expectedHTTPCode: 200,
+			expectedOffset:   "500",
+		},
+		{
+			name:             "HEAD fails with invalid upload ID",
+			giveID:           "invalidid",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "HEAD fails with unknown upload ID",
+			giveID:           uploadID,
+			giveWithAuth:     true,
+			giveUnknownID:    true,
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "HEAD fails with non resumable upload",
+			giveID:           uploadID,
+			giveWithAuth:     true,
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "HEAD fails with no auth",
+			giveID:           uploadID,
+			expectedHTTPCode: 401,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			routerClients := router.Clients{
+				UUIDGen: clients.NewUuidGeneratorDummy(nil, UUIDValidFunc),
+			}
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectUploadsDAOCreation(mock)
+
+			if tt.giveWithAuth && tt.expectedHTTPCode != 400 {
+				getUploadQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.GetUpload])
+				rows := sqlmock.NewRows(resumableUploadsColumns)
+				if !tt.giveUnknownID {
+					t1 := time.Now()
+					rows.AddRow(uploadID, videoID, models.STARTED, nil, t1, t1, 1000, 500, 1, tt.giveS3UploadID, 50)
+				}
+				mock.ExpectQuery(getUploadQuery).WithArgs(uploadID).WillReturnRows(rows)
+
+				if tt.expectedHTTPCode == 200 {
+					getVideoQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])
+					t1 := time.Now()
+					videoRows := sqlmock.NewRows(resumableVideosColumns).AddRow(videoID, "title-of-video", models.UPLOADING, nil, t1, t1, videoID+"/source.mp4", "", nil, "", nil, nil)
+					mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRows)
+				}
+			}
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			uploadsDAO, err := dao.CreateUploadsDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			routerDAO := router.DAOs{
+				VideosDAO:  *videosDAO,
+				UploadsDAO: *uploadsDAO,
+			}
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &routerClients, &routerDAO)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodHead, "/api/v1/videos/uploads/"+tt.giveID, nil)
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			require.Equal(t, tt.expectedOffset, w.Header().Get("Upload-Offset"))
+
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestVideoResumableUploadChunk(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	uploadID := "2508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	videoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	s3UploadID := "S3UploadID"
+	title := "title-of-video"
+	sourcePath := videoID + "/source.mp4"
+	UUIDValidFunc := func(u string) bool { _, err := uuid.Parse(u); return err == nil }
+
+	smallChunkSize := 1000
+	bigChunkSize := int(controllers.MinUploadChunkSize)
+
+	cases := []struct {
+		name                 string
+		giveWithAuth         bool
+		giveContentType      string
+		giveChunk            []byte
+		giveOffset           int64
+		giveCurrentOffset    int64
+		giveLength           int64
+		giveConcurrentUpdate bool
+		giveLocked           bool
+		completeMultipartErr bool
+		sourceAlreadyExists  bool
+		giveNoContentLength  bool
+		giveMissingBytes     int
+		expectedHTTPCode     int
+		expectedPartNumber   int32
+		expectComplete       bool
+	}{
+		{
+			name:               "PATCH first chunk",
+			giveWithAuth:       true,
+			giveContentType:    controllers.TusOffsetMimeType,
+			giveChunk:          webmChunk(bigChunkSize),
+			giveLength:         int64(bigChunkSize + smallChunkSize),
+			expectedHTTPCode:   204,
+			expectedPartNumber: 1,
+		},
+		{
+			name:               "PATCH last chunk",
+			giveWithAuth:       true,
+			giveContentType:    controllers.TusOffsetMimeType,
+			giveChunk:          make([]byte, smallChunkSize),
+			giveOffset:         int64(bigChunkSize),
+			giveCurrentOffset:  int64(bigChunkSize),
+			giveLength:         int64(bigChunkSize + smallChunkSize),
+			expectedHTTPCode:   204,
+			expectedPartNumber: 2,
+			expectComplete:     true,
+		},
+		{
+			name:               "PATCH single chunk upload",
+			giveWithAuth:       true,
+			giveContentType:    controllers.TusOffsetMimeType,
+			giveChunk:          webmChunk(smallChunkSize),
+			giveLength:         int64(smallChunkSize),
+			expectedHTTPCode:   204,
+			expectedPartNumber: 1,
+			expectComplete:     true,
+		},
+		{
+			name:                 "PATCH fails with complete multipart upload fail",
+			giveWithAuth:         true,
+			giveContentType:      controllers.TusOffsetMimeType,
+			giveChunk:            webmChunk(smallChunkSize),
+			giveLength:           int64(smallChunkSize),
+			completeMultipartErr: true,
+			expectedHTTPCode:     500,
+			expectedPartNumber:   1,
+			expectComplete:       true,
+		},
+		{
+			name:                "PATCH last chunk fails with source already exist",
+			giveWithAuth:        true,
+			giveContentType:     controllers.TusOffsetMimeType,
+			giveChunk:           webmChunk(smallChunkSize),
+			giveLength:          int64(smallChunkSize),
+			sourceAlreadyExists: true,
+			expectedHTTPCode:    409,
+			expectedPartNumber:  1,
+			expectComplete:      true,
+		},
+		{
+			name:             "PATCH fails with wrong content type",
+			giveWithAuth:     true,
+			giveContentType:  "application/octet-stream",
+			giveChunk:        webmChunk(smallChunkSize),
+			giveLength:       int64(smallChunkSize),
+			expectedHTTPCode: 415,
+		},
+		{
+			name:              "PATCH fails with offset mismatch",
+			giveWithAuth:      true,
+			giveContentType:   controllers.TusOffsetMimeType,
+			giveChunk:         make([]byte, smallChunkSize),
+			giveOffset:        0,
+			giveCurrentOffset: int64(bigChunkSize),
+			giveLength:        int64(bigChunkSize + smallChunkSize),
+			expectedHTTPCode:  409,
+		},
+		{
+			name:                "PATCH fails with missing content length",
+			giveWithAuth:        true,
+			giveContentType:     controllers.TusOffsetMimeType,
+			giveChunk:           webmChunk(smallChunkSize),
+			giveLength:          int64(smallChunkSize),
+			giveNoContentLength: true,
+			expectedHTTPCode:    411,
+		},
+		{
+			name:               "PATCH fails with chunk shorter than its content length",
+			giveWithAuth:       true,
+			giveContentType:    controllers.TusOffsetMimeType,
+			giveChunk:          webmChunk(smallChunkSize),
+			giveLength:         int64(smallChunkSize + 10),
+			giveMissingBytes:   10,
+			expectedHTTPCode:   400,
+			expectedPartNumber: 1,
+		},
+		{
+			name:             "PATCH fails with too small chunk",
+			giveWithAuth:     true,
+			giveContentType:  controllers.TusOffsetMimeType,
+			giveChunk:        webmChunk(smallChunkSize),
+			giveLength:       int64(bigChunkSize + smallChunkSize),
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "PATCH fails with wrong magic number",
+			giveWithAuth:     true,
+			giveContentType:  controllers.TusOffsetMimeType,
+			giveChunk:        make([]byte, smallChunkSize),
+			giveLength:       int64(smallChunkSize),
+			expectedHTTPCode: 415,
+		},
+		{
+			name:                 "PATCH fails with concurrent chunk",
+			giveWithAuth:         true,
+			giveContentType:      controllers.TusOffsetMimeType,
+			giveChunk:            webmChunk(bigChunkSize),
+			giveLength:           int64(bigChunkSize + smallChunkSize),
+			giveConcurrentUpdate: true,
+			expectedHTTPCode:     409,
+		},
+		{
+			name:             "PATCH fails with chunk being received by another request",
+			giveWithAuth:     true,
+			giveContentType:  controllers.TusOffsetMimeType,
+			giveChunk:        webmChunk(bigChunkSize),
+			giveLength:       int64(bigChunkSize + smallChunkSize),
+			giveLocked:       true,
+			expectedHTTPCode: 409,
+		},
+		{
+			name:             "PATCH fails with no auth",
+			giveChunk:        make([]byte, smallChunkSize),
+			expectedHTTPCode: 401,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var uploadedPart int32
+			completeCalled := false
+			s3Client := clients.NewS3ClientDummy(nil,
+				func(string) (io.Reader, error) { return bytes.NewReader(tt.giveChunk), nil },
+				nil, nil,
+				func(string) error { return nil },
+				nil,
+				func(f io.Reader, size int64, path, uploadID string, partNumber int32) error {
+					uploadedPart = partNumber
+					part, err := io.ReadAll(f)
+					if err != nil {
+						return err
+					}
+					if int64(len(part)) != size {
+						return fmt.Errorf("received %v bytes instead of %v", len(part), size)
+					}
+					require.Equal(t, tt.giveChunk, part)
+					return nil
+				},
+				func(path, uploadID string) error {
+					completeCalled = true
+					if tt.completeMultipartErr {
+						return fmt.Errorf("S3 error")
+					}
+					return nil
+				},
+				nil, nil, nil, nil, nil,
+				func(string) error { return nil },
+			)
+			amqpClient := clients.NewAmqpClientDummy(func(string, []byte) error { return nil }, nil, nil)
+			amqpVideoStatusUpdate := clients.NewAmqpClientDummy(nil, nil, nil)
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			routerClients := router.Clients{
+				S3Client:              s3Client,
+				AmqpClient:            amqpClient,
+				AmqpVideoStatusUpdate: amqpVideoStatusUpdate,
+				UUIDGen:               clients.NewUuidGeneratorDummy(nil, UUIDValidFunc),
+			}
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectUploadsDAOCreation(mock)
+
+			if tt.giveWithAuth && tt.giveContentType == controllers.TusOffsetMimeType {
+				// Queries
+				updateVideoQuery := regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo])
+				getVideoFromIdQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])
+				getUploadQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.GetUpload])
+				updateUploadQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.UpdateUpload])
+				updateUploadOffsetQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.UpdateUploadOffset])
+				lockUploadOffsetQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.LockUploadOffset])
+				getVideoFromSourceHashQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideoFromSourceHash])
+				updateVideoSourceHashQuery := regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideoSourceHash])
+				deleteVideoQuery := regexp.QuoteMeta(dao.VideosRequests[dao.DeleteVideo])
+				deleteUploadQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.DeleteUpload])
+
+				t1 := time.Now()
+				parts := tt.expectedPartNumber - 1
+				if parts < 0 {
+					parts = 0
+				}
+
+				res := sqlmock.NewRows(resumableUploadsColumns).AddRow(uploadID, videoID, models.STARTED, nil, t1, t1, tt.giveLength, tt.giveCurrentOffset, parts, s3UploadID, 0)
+				mock.ExpectQuery(getUploadQuery).WithArgs(uploadID).WillReturnRows(res)
+
+				res = sqlmock.NewRows(resumableVideosColumns).AddRow(videoID, title, models.UPLOADING, nil, t1, t1, sourcePath, "", nil, "", nil, nil)
+				mock.ExpectQuery(getVideoFromIdQuery).WithArgs(videoID).WillReturnRows(res)
+
+				if tt.expectedPartNumber != 0 || tt.giveConcurrentUpdate || tt.giveLocked {
+					// The upload is locked while its part is sent
+					mock.ExpectBegin()
+					lock := mock.ExpectQuery(lockUploadOffsetQuery).WithArgs(uploadID)
+					switch {
+					case tt.giveLocked:
+						lock.WillReturnError(&mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"})
+						mock.ExpectRollback()
+					case tt.giveConcurrentUpdate:
+						lock.WillReturnRows(sqlmock.NewRows([]string{"upload_offset"}).AddRow(tt.giveCurrentOffset + int64(bigChunkSize)))
+						mock.ExpectRollback()
+					case tt.giveMissingBytes != 0:
+						lock.WillReturnRows(sqlmock.NewRows([]string{"upload_offset"}).AddRow(tt.giveCurrentOffset))
+						mock.ExpectRollback()
+					default:
+						lock.WillReturnRows(sqlmock.NewRows([]string{"upload_offset"}).AddRow(tt.giveCurrentOffset))
+						newOffset := tt.giveCurrentOffset + int64(len(tt.giveChunk))
+						progress := int(newOffset * 100 / tt.giveLength)
+						mock.ExpectExec(updateUploadOffsetQuery).
+							WithArgs(newOffset, tt.expectedPartNumber, progress, uploadID, tt.giveCurrentOffset).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+						mock.ExpectCommit()
+					}
+				}
+
+				if tt.expectComplete && tt.completeMultipartErr {
+					mock.ExpectBegin()
+
+					// Update videos status : FAIL_UPLOAD
+					mock.ExpectExec(updateVideoQuery).
+						WithArgs(title, models.FAIL_UPLOAD, nil, sourcePath, "", videoID).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+
+					// Update uploads status : FAILED
+					mock.ExpectExec(updateUploadQuery).
+						WithArgs(videoID, models.FAILED, nil, uploadID).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+
+					mock.ExpectCommit()
+
+				} else if tt.expectComplete && tt.sourceAlreadyExists {
+					// Another video has the same source
+					res := sqlmock.NewRows(resumableVideosColumns).AddRow("AnotherId", "another-title", models.COMPLETE, t1, t1, t1, "AnotherId/source.mp4", "", "hash", "", nil, nil)
+					mock.ExpectQuery(getVideoFromSourceHashQuery).WithArgs(AnySourceHash{}).WillReturnRows(res)
+
+					// Remove the uploaded video
+					mock.ExpectBegin()
+					mock.ExpectExec(deleteUploadQuery).
+						WithArgs(videoID).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectExec(deleteVideoQuery).
+						WithArgs(videoID).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectCommit()
+
+				} else if tt.expectComplete {
+					// Source not uploaded yet
+					mock.ExpectQuery(getVideoFromSourceHashQuery).WithArgs(AnySourceHash{}).WillReturnRows(sqlmock.NewRows(resumableVideosColumns))
+					mock.ExpectExec(updateVideoSourceHashQuery).
+						WithArgs(AnySourceHash{}, videoID).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+
+					// Update videos status : UPLOADED + Upload date
+					mock.ExpectExec(updateVideoQuery).
+						WithArgs(title, models.UPLOADED, AnyTime{}, sourcePath, "", videoID).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+
+					// Update uploads status : DONE + Upload date
+					mock.ExpectExec(updateUploadQuery).
+						WithArgs(videoID, models.DONE, AnyTime{}, uploadID).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+
+					// Update video status : ENCODING
+					mock.ExpectExec(updateVideoQuery).
+						WithArgs(title, models.ENCODING, AnyTime{}, sourcePath, "", videoID).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+				}
+			}
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			uploadsDAO, err := dao.CreateUploadsDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			routerDAO := router.DAOs{
+				VideosDAO:  *videosDAO,
+				UploadsDAO: *uploadsDAO,
+			}
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &routerClients, &routerDAO)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodPatch, "/api/v1/videos/uploads/"+uploadID, bytes.NewReader(tt.giveChunk))
+			req.Header.Set("Tus-Resumable", controllers.TusVersion)
+			req.Header.Set("Content-Type", tt.giveContentType)
+			req.Header.Set("Upload-Offset", strconv.FormatInt(tt.giveOffset, 10))
+			if tt.giveNoContentLength {
+				req.ContentLength = -1
+			}
+			req.ContentLength += int64(tt.giveMissingBytes)
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			require.Equal(t, tt.expectedPartNumber, uploadedPart)
+			require.Equal(t, tt.expectComplete, completeCalled)
+
+			if tt.sourceAlreadyExists {
+				// The existing video is returned
+				require.Contains(t, w.Body.String(), `"id":"AnotherId"`)
+			}
+
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+)
+
+type VideoGetStatsHandler struct {
+	VideosDAO   *dao.VideosDAO
+	PlaybackDAO *dao.PlaybackDAO
+	UUIDGen     clients.IUUIDGenerator
+}
+
+// VideoGetStatsHandler godoc
+// @Summary Get video playback statistics
+// @Description Get the views of the video (playback sessions having watched at least one segment), its unique viewers,
+// @Description its watch time (in seconds) and the average completion of the views (between 0 and 1)
+// @Tags video
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} jsonDTO.VideoStatsJson "Video statistics"
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/stats [get]
+func (v VideoGetStatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET VideoGetStatsHandler - parameters ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if _, err := v.VideosDAO.GetVideo(r.Context(), id); err != nil {
+		log.Error("Cannot found video : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	stats, err := v.PlaybackDAO.GetVideoStats(r.Context(), id)
+	if err != nil {
+		log.Error("Cannot get video stats : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	payload, err := json.Marshal(jsonDTO.VideoStatsToVideoStatsJson(stats))
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(payload)
+}
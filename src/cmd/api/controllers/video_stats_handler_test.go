@@ -0,0 +1,136 @@
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+)
+
+func TestVideoStats(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	validVideoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	invalidVideoID := "invalidvideoid"
+	unknownVideoID := "0000a0a0-0aa0-0a00-0000-aa0000aa00aa"
+	t1 := time.Now()
+
+	cases := []struct {
+		name             string
+		giveRequest      string
+		giveWithAuth     bool
+		giveDatabaseErr  bool
+		expectedHTTPCode int
+	}{
+		{
+			name:             "GET video stats",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/stats",
+			giveWithAuth:     true,
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "GET fails with invalid video ID",
+			giveRequest:      "/api/v1/videos/" + invalidVideoID + "/stats",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "GET fails with unknown video ID",
+			giveRequest:      "/api/v1/videos/" + unknownVideoID + "/stats",
+			giveWithAuth:     true,
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "GET fails with database error",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/stats",
+			giveWithAuth:     true,
+			giveDatabaseErr:  true,
+			expectedHTTPCode: 500,
+		},
+		{
+			name:             "GET fails with no auth",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/stats",
+			expectedHTTPCode: 401,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectPlaybackDAOCreation(mock)
+
+			if tt.giveWithAuth && tt.expectedHTTPCode != 400 {
+				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
+				videosRows := sqlmock.NewRows(videosColumns)
+				if tt.expectedHTTPCode != 404 {
+					videosRows.AddRow(validVideoID, "title", models.COMPLETE, t1, t1, t1, "", "", nil, "", nil, nil)
+				}
+				mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WillReturnRows(videosRows)
+
+				if tt.expectedHTTPCode != 404 {
+					getVideoStats := mock.ExpectQuery(regexp.QuoteMeta(dao.PlaybackRequests[dao.GetVideoStats])).WithArgs(validVideoID)
+					if tt.giveDatabaseErr {
+						getVideoStats.WillReturnError(fmt.Errorf("database internal error"))
+					} else {
+						getVideoStats.WillReturnRows(sqlmock.NewRows([]string{"views", "viewers", "watch_time", "completion"}).AddRow(3, 2, 42.5, 0.75))
+					}
+				}
+			}
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+			playbackDAO, err := dao.CreatePlaybackDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &router.Clients{
+				UUIDGen: clients.NewUuidGeneratorDummy(nil, func(u string) bool { _, err := uuid.Parse(u); return err == nil }),
+			}, &router.DAOs{VideosDAO: *videosDAO, PlaybackDAO: *playbackDAO})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tt.giveRequest, nil)
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedHTTPCode == 200 {
+				var stats jsonDTO.VideoStatsJson
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+				require.Equal(t, validVideoID, stats.VideoID)
+				require.Equal(t, 3, stats.Views)
+				require.Equal(t, 2, stats.UniqueViewers)
+				require.Equal(t, 42.5, stats.WatchTime)
+				require.Equal(t, 0.75, stats.AverageCompletion)
+				require.Contains(t, stats.Links, "self")
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
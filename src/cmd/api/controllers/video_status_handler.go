@@ -11,17 +11,19 @@ import (
 
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
 	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
 	"github.com/Sogilis/Voogle/src/pkg/clients"
 )
 
 type VideoGetStatusHandler struct {
-	VideosDAO *dao.VideosDAO
-	UUIDGen   clients.IUUIDGenerator
+	VideosDAO  *dao.VideosDAO
+	UploadsDAO *dao.UploadsDAO
+	UUIDGen    clients.IUUIDGenerator
 }
 
 // VideoGetStatusHandler godoc
 // @Summary Get video status
-// @Description Get video status
+// @Description Get video status, and its upload progress (in percent)
 // @Tags video
 // @Produce plain
 // @Param id path string true "Video ID"
@@ -52,7 +54,21 @@ func (v VideoGetStatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	videoStatus := jsonDTO.VideoToStatusJson(video)
+	// The progress is only needed while the video is not uploaded yet
+	uploadProgress := 0
+	if video.Status == models.UPLOADING || video.Status == models.FAIL_UPLOAD {
+		upload, err := v.UploadsDAO.GetVideoLatestUpload(r.Context(), video.ID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Error("Cannot get video upload : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if upload != nil {
+			uploadProgress = upload.Progress
+		}
+	}
+
+	videoStatus := jsonDTO.VideoToStatusJson(video, uploadProgress)
 	payload, err := json.Marshal(videoStatus)
 	if err != nil {
 		log.Error("Unable to parse data struct in json ", err)
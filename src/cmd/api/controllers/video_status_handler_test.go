@@ -2,6 +2,7 @@ package controllers_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http/httptest"
 	"regexp"
@@ -15,6 +16,7 @@ import (
 	"github.com/Sogilis/Voogle/src/cmd/api/config"
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
 	"github.com/Sogilis/Voogle/src/cmd/api/models"
 	"github.com/Sogilis/Voogle/src/cmd/api/router"
 	"github.com/Sogilis/Voogle/src/pkg/clients"
@@ -38,7 +40,10 @@ func TestVideoStatus(t *testing.T) { //nolint:cyclop
 		giveRequest      string
 		giveWithAuth     bool
 		giveDatabaseErr  bool
+		giveUploading    bool
+		giveNoUpload     bool
 		expectedHTTPCode int
+		expectedProgress int
 		isValidUUID      func(string) bool
 	}{
 		{
@@ -46,6 +51,24 @@ func TestVideoStatus(t *testing.T) { //nolint:cyclop
 			giveRequest:      "/api/v1/videos/" + validVideoID + "/status",
 			giveWithAuth:     true,
 			expectedHTTPCode: 200,
+			expectedProgress: 100,
+			isValidUUID:      UUIDValidFunc},
+		{
+			name:             "GET uploading video status",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/status",
+			giveWithAuth:     true,
+			giveUploading:    true,
+			expectedHTTPCode: 200,
+			expectedProgress: 42,
+			isValidUUID:      UUIDValidFunc},
+		{
+			name:             "GET uploading video status without upload",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/status",
+			giveWithAuth:     true,
+			giveUploading:    true,
+			giveNoUpload:     true,
+			expectedHTTPCode: 200,
+			expectedProgress: 0,
 			isValidUUID:      UUIDValidFunc},
 		{
 			name:             "GET fails with invalid video ID",
@@ -88,6 +111,7 @@ func TestVideoStatus(t *testing.T) { //nolint:cyclop
 			}
 
 			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectUploadsDAOCreation(mock)
 
 			if !tt.giveWithAuth || tt.giveRequest == "/api/v1/videos/"+invalidVideoID+"/status" {
 				// All these cases will stop before modifying the database : Nothing to do
@@ -97,7 +121,7 @@ func TestVideoStatus(t *testing.T) { //nolint:cyclop
 				getVideoFromIdQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])
 
 				// Tables
-				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path"}
+				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
 				videosRows := sqlmock.NewRows(videosColumns)
 
 				if tt.giveDatabaseErr {
@@ -106,22 +130,37 @@ func TestVideoStatus(t *testing.T) { //nolint:cyclop
 				} else if tt.giveRequest == "/api/v1/videos/"+unknownVideoID+"/status" {
 					mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
 
+				} else if tt.giveUploading {
+					videosRows.AddRow(validVideoID, videoTitle, models.UPLOADING, nil, t1, nil, sourcePath, coverPath, nil, "", nil, nil)
+					mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
+
+					uploadsColumns := []string{"id", "video_id", "upload_status", "uploaded_at", "created_at", "updated_at", "upload_length", "upload_offset", "upload_parts", "s3_upload_id", "upload_progress"}
+					uploadsRows := sqlmock.NewRows(uploadsColumns)
+					if !tt.giveNoUpload {
+						uploadsRows.AddRow(validVideoID, validVideoID, models.STARTED, nil, t1, t1, 0, 0, 0, "", 42)
+					}
+					mock.ExpectQuery(regexp.QuoteMeta(dao.UploadsRequests[dao.GetVideoLatestUpload])).WithArgs(validVideoID).WillReturnRows(uploadsRows)
+
 				} else {
-					videosRows.AddRow(validVideoID, videoTitle, models.ENCODING, nil, t1, nil, sourcePath, coverPath)
+					videosRows.AddRow(validVideoID, videoTitle, models.ENCODING, nil, t1, nil, sourcePath, coverPath, nil, "", nil, nil)
 					mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
 				}
 			}
 
 			videoDAO, err := dao.CreateVideosDAO(context.Background(), db)
 			require.NoError(t, err)
+			uploadsDAO, err := dao.CreateUploadsDAO(context.Background(), db)
+			require.NoError(t, err)
 			routerDAO := router.DAOs{
-				VideosDAO: *videoDAO,
+				VideosDAO:  *videoDAO,
+				UploadsDAO: *uploadsDAO,
 			}
 
-			r := router.NewRouter(config.Config{
+			r, err := router.NewRouter(config.Config{
 				UserAuth: givenUsername,
 				PwdAuth:  givenUserPwd,
 			}, &routerClients, &routerDAO)
+			require.NoError(t, err)
 
 			w := httptest.NewRecorder()
 
@@ -133,6 +172,12 @@ func TestVideoStatus(t *testing.T) { //nolint:cyclop
 			r.ServeHTTP(w, req)
 			require.Equal(t, tt.expectedHTTPCode, w.Code)
 
+			if tt.expectedHTTPCode == 200 {
+				var status jsonDTO.VideoStatus
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+				require.Equal(t, tt.expectedProgress, status.UploadProgress)
+			}
+
 			// we make sure that all expectations were met
 			err = mock.ExpectationsWereMet()
 			require.NoError(t, err)
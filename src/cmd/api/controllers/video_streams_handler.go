@@ -5,6 +5,8 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,24 +15,43 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
 	"github.com/Sogilis/Voogle/src/cmd/api/metrics"
 	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/ffmpeg"
+	"github.com/Sogilis/Voogle/src/pkg/hls"
 	"github.com/Sogilis/Voogle/src/pkg/transformer/v1"
 )
 
+// PlaybackSessionParam is the query parameter holding the playback session, from the master to the segments
+const PlaybackSessionParam = "session"
+
+// Segments are named by the encoder after their index in the variant playlist
+var segmentFilenameRegex = regexp.MustCompile(`^segment(\d+)\.ts$`)
+
 type VideoGetMasterHandler struct {
-	S3Client clients.IS3Client
-	UUIDGen  clients.IUUIDGenerator
+	S3Client     clients.IS3Client
+	PlaybackDAO  *dao.PlaybackDAO
+	SubtitlesDAO *dao.SubtitlesDAO
+	UUIDGen      clients.IUUIDGenerator
+	StreamSigner auth.StreamSigner
+	ClientID     func(r *http.Request) string // Viewer of the playback session
 }
 
 // VideoGetMasterHandler godoc
 // @Summary Get video master
-// @Description Get video master
+// @Description Get video master. Its URIs carry a stream token authorizing the playlists and the segments
+// @Description of the video without credentials, until it expires. The master itself can be requested with the token.
+// @Description Each request starts a playback session, carried by the URIs too, to record the segments watched.
+// @Description The subtitles tracks of the video are published as subtitles renditions.
 // @Tags video
 // @Produce plain
 // @Param id path string true "Video ID"
+// @Param token query string false "Stream token, instead of credentials"
 // @Success 200 {string} string "HLS video master"
 // @Failure 400 {string} string
+// @Failure 403 {string} string "Invalid stream token"
 // @Failure 404 {string} string
 // @Failure 500 {string} string
 // @Router /api/v1/videos/{id}/streams/master.m3u8 [get]
@@ -45,6 +66,13 @@ func (v VideoGetMasterHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	token, err := streamToken(r, v.StreamSigner, id)
+	if err != nil {
+		log.Error("Cannot sign stream token : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	object, err := v.S3Client.GetObject(r.Context(), id+"/master.m3u8")
 	if err != nil {
 		log.Error("Failed to open video "+id+"/master.m3u8 ", err)
@@ -52,30 +80,151 @@ func (v VideoGetMasterHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if _, err = io.Copy(w, object); err != nil {
+	object, err = addSubtitles(r.Context(), v.SubtitlesDAO, id, object)
+	if err != nil {
+		log.Error("Cannot add subtitles to video master : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Playback analytics must not prevent the video from being played
+	session, err := v.UUIDGen.GenerateUuid()
+	if err == nil {
+		err = v.PlaybackDAO.CreatePlaybackSession(r.Context(), session, id, v.ClientID(r))
+	}
+	if err != nil {
+		log.Error("Cannot start playback session : ", err)
+		session = ""
+	}
+
+	if err = writePlaylist(w, object, map[string]string{auth.StreamTokenParam: token, PlaybackSessionParam: session}); err != nil {
 		log.Error("Unable to stream video master", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 }
 
+// streamToken returns the token to add to the URIs of the playlists : the one authorizing the request,
+// else a new one. It is empty if the stream tokens are disabled.
+func streamToken(r *http.Request, signer auth.StreamSigner, videoID string) (string, error) {
+	if token := auth.StreamTokenFromContext(r.Context()); token != "" {
+		return token, nil
+	}
+	if !signer.Enabled() {
+		return "", nil
+	}
+	return signer.Sign(videoID)
+}
+
+// writePlaylist writes the playlist with the query parameters, like the stream token, added to each of its URIs.
+// Empty parameters are left out.
+func writePlaylist(w io.Writer, playlist io.Reader, params map[string]string) error {
+	for key, value := range params {
+		if value == "" {
+			delete(params, key)
+		}
+	}
+	if len(params) == 0 {
+		_, err := io.Copy(w, playlist)
+		return err
+	}
+
+	rewritten, err := hls.RewriteURIs(playlist, func(uri string) string {
+		for key, value := range params {
+			uri = hls.WithQueryParam(uri, key, value)
+		}
+		return uri
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(rewritten)
+	return err
+}
+
+// Only the WebVTT track and the sprite sheets generated by the encoder can be requested
+var thumbnailsFilenameRegex = regexp.MustCompile(`^(` + regexp.QuoteMeta(ffmpeg.ThumbnailsVTT) + `|sprite\d+\.jpg)$`)
+
+type VideoGetThumbnailsHandler struct {
+	S3Client clients.IS3Client
+	UUIDGen  clients.IUUIDGenerator
+}
+
+// VideoGetThumbnailsHandler godoc
+// @Summary Get video scrubbing thumbnails
+// @Description Get the WebVTT thumbnails track (thumbnails.vtt) or one of its sprite sheets (spriteN.jpg)
+// @Tags video
+// @Produce plain
+// @Param id path string true "Video ID"
+// @Param filename path string true "thumbnails.vtt or sprite sheet name"
+// @Success 200 {string} string "WebVTT track or JPEG sprite sheet"
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/streams/thumbnails/{filename} [get]
+func (v VideoGetThumbnailsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET VideoGetThumbnailsHandler - parameters ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	filename := vars["filename"]
+	if !thumbnailsFilenameRegex.MatchString(filename) {
+		log.Error("Invalid thumbnails filename ", filename)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	object, err := v.S3Client.GetObject(r.Context(), id+"/thumbnails/"+filename)
+	if err != nil {
+		log.Error("Failed to open thumbnails "+id+"/thumbnails/"+filename+" ", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if filename == ffmpeg.ThumbnailsVTT {
+		w.Header().Set("Content-Type", "text/vtt")
+	} else {
+		w.Header().Set("Content-Type", "image/jpeg")
+	}
+
+	if _, err = io.Copy(w, object); err != nil {
+		log.Error("Unable to stream thumbnails", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
 type VideoGetSubPartHandler struct {
 	S3Client         clients.IS3Client
+	PlaybackDAO      *dao.PlaybackDAO
+	ChaptersDAO      *dao.ChaptersDAO
 	UUIDGen          clients.IUUIDGenerator
 	ServiceDiscovery clients.ServiceDiscovery
+	StreamSigner     auth.StreamSigner
 }
 
 // VideoGetSubPartHandler godoc
 // @Summary Get sub part stream video
-// @Description Get sub part stream video
+// @Description Get sub part stream video : the playlist of a quality, its segments carrying the stream token, or a segment.
+// @Description The segments requested with a playback session are recorded as watched by the session.
 // @Tags video
 // @Produce plain
 // @Param id path string true "Video ID"
 // @Param quality path string true "Video quality"
 // @Param filename path string true "Video sub part name"
 // @Param filter query []string false "List of required filters"
+// @Param token query string false "Stream token, instead of credentials"
+// @Param session query string false "Playback session, given by the master"
 // @Success 200 {string} string "Video sub part (.ts)"
 // @Failure 400 {string} string
+// @Failure 403 {string} string "Invalid stream token"
 // @Failure 404 {string} string
 // @Failure 500 {string} string
 // @Router /api/v1/videos/{id}/streams/{quality}/{filename} [get]
@@ -96,8 +245,57 @@ func (v VideoGetSubPartHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 	transformers := query["filter"]
 	s3VideoPath := id + "/" + quality + "/" + filename
 
-	if strings.Contains(filename, "segment_index") || transformers == nil {
-		object, err := v.S3Client.GetObject(r.Context(), id+"/"+quality+"/"+filename)
+	// Audio renditions of the languages are neither transformed nor counted as played segments,
+	// their video variant being requested alongside
+	audio := strings.HasPrefix(quality, ffmpeg.AudioVariantPrefix)
+	if audio {
+		transformers = nil
+	}
+
+	session := query.Get(PlaybackSessionParam)
+	if session != "" && !v.UUIDGen.IsValidUUID(session) {
+		log.Error("Invalid playback session ", session)
+		session = ""
+	}
+
+	if strings.Contains(filename, "segment_index") {
+		token, err := streamToken(r, v.StreamSigner, id)
+		if err != nil {
+			log.Error("Cannot sign stream token : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		object, err := v.S3Client.GetObject(r.Context(), s3VideoPath)
+		if err != nil {
+			log.Error("Failed to open video videoPath", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if session != "" && !audio {
+			object, err = v.recordPlaybackLength(r.Context(), session, id, object)
+			if err != nil {
+				log.Error("Unable to read subpart", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
+		object, err = addChapters(r.Context(), v.ChaptersDAO, id, object)
+		if err != nil {
+			log.Error("Cannot add chapters : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := writePlaylist(w, object, map[string]string{auth.StreamTokenParam: token, PlaybackSessionParam: session}); err != nil {
+			log.Error("Unable to stream subpart", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	} else if transformers == nil {
+		object, err := v.S3Client.GetObject(r.Context(), s3VideoPath)
 		if err != nil {
 			log.Error("Failed to open video videoPath", err)
 			w.WriteHeader(http.StatusNotFound)
@@ -109,6 +307,9 @@ func (v VideoGetSubPartHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		if !audio {
+			v.recordPlaybackSegment(r.Context(), session, id, filename)
+		}
 	} else {
 		// Add metrics (should be move into transformations service implem)
 		for _, service := range transformers {
@@ -131,7 +332,46 @@ func (v VideoGetSubPartHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		v.recordPlaybackSegment(r.Context(), session, id, filename)
+	}
+}
+
+// recordPlaybackLength records the number of segments and the duration of the variant playlist played by the session.
+// It returns the playlist to write, having been read.
+func (v VideoGetSubPartHandler) recordPlaybackLength(ctx context.Context, session, videoID string, playlist io.Reader) (io.Reader, error) {
+	content, err := io.ReadAll(playlist)
+	if err != nil {
+		return nil, err
+	}
+
+	media, err := hls.ParseMedia(bytes.NewReader(content))
+	if err != nil {
+		log.Error("Cannot parse video variant : ", err)
+		return bytes.NewReader(content), nil
+	}
+
+	_ = v.PlaybackDAO.UpdatePlaybackSessionLength(ctx, session, videoID, len(media.Segments), media.Duration())
+
+	return bytes.NewReader(content), nil
+}
+
+// recordPlaybackSegment records the segment as watched by the session, if any
+func (v VideoGetSubPartHandler) recordPlaybackSegment(ctx context.Context, session, videoID, filename string) {
+	if session == "" {
+		return
 	}
+
+	match := segmentFilenameRegex.FindStringSubmatch(filename)
+	if match == nil {
+		return
+	}
+
+	segment, err := strconv.Atoi(match[1])
+	if err != nil {
+		return
+	}
+
+	_ = v.PlaybackDAO.AddPlaybackSegment(ctx, session, videoID, segment)
 }
 
 func (v VideoGetSubPartHandler) getVideoPart(ctx context.Context, s3VideoPath string, transformers []string) (io.Reader, error) {
@@ -1,22 +1,34 @@
 package controllers_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/http/httptest"
+	"net/url"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 
 	"github.com/Sogilis/Voogle/src/pkg/clients"
 
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
 	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
 	"github.com/Sogilis/Voogle/src/cmd/api/router"
 )
 
+// sessionID is the playback session started by the requests of the masters
+const sessionID = "5d0e7b3a-91c4-4f2e-a6d8-7c3b1e9f0a24"
+
 func TestVideoStream(t *testing.T) { //nolint:cyclop
 	givenUsername := "dev"
 	givenUserPwd := "test"
@@ -108,6 +120,41 @@ func TestVideoStream(t *testing.T) { //nolint:cyclop
 			expectedHTTPCode: 404,
 			getObjectID:      func(s string) (io.Reader, error) { return nil, errors.New("Not found") },
 			isValidUUID:      UUIDValidFunc},
+		{
+			name:             "GET video thumbnails track",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/thumbnails/thumbnails.vtt",
+			giveWithAuth:     true,
+			expectedHTTPCode: 200,
+			getObjectID:      func(s string) (io.Reader, error) { return strings.NewReader("WEBVTT"), nil },
+			isValidUUID:      UUIDValidFunc},
+		{
+			name:             "GET video thumbnails sprite",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/thumbnails/sprite0.jpg",
+			giveWithAuth:     true,
+			expectedHTTPCode: 200,
+			getObjectID:      func(s string) (io.Reader, error) { return strings.NewReader(""), nil },
+			isValidUUID:      UUIDValidFunc},
+		{
+			name:             "GET fails with invalid thumbnails filename",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/thumbnails/master.m3u8",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+			getObjectID:      func(s string) (io.Reader, error) { return strings.NewReader(""), nil },
+			isValidUUID:      UUIDValidFunc},
+		{
+			name:             "GET fails to video thumbnails with invalid id",
+			giveRequest:      "/api/v1/videos/" + invalidVideoID + "/streams/thumbnails/thumbnails.vtt",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+			getObjectID:      func(s string) (io.Reader, error) { return strings.NewReader(""), nil },
+			isValidUUID:      UUIDValidFunc},
+		{
+			name:             "GET fails to video thumbnails with unknown id",
+			giveRequest:      "/api/v1/videos/" + unknownVideoID + "/streams/thumbnails/thumbnails.vtt",
+			giveWithAuth:     true,
+			expectedHTTPCode: 404,
+			getObjectID:      func(s string) (io.Reader, error) { return nil, errors.New("Not found") },
+			isValidUUID:      UUIDValidFunc},
 		{
 			name:             "GET fails with no auth",
 			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/" + validQuality + "/" + "invalidSubPart",
@@ -118,20 +165,190 @@ func TestVideoStream(t *testing.T) { //nolint:cyclop
 
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectPlaybackDAOCreation(mock)
+			dao_test.ExpectSubtitlesDAOCreation(mock)
+			if strings.HasSuffix(tt.giveRequest, "master.m3u8") && tt.expectedHTTPCode == 200 {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetVideoSubtitles])).WillReturnRows(sqlmock.NewRows(subtitlesColumns))
+				mock.ExpectExec(regexp.QuoteMeta(dao.PlaybackRequests[dao.CreatePlaybackSession])).WillReturnResult(sqlmock.NewResult(0, 1))
+			}
 
-			s3Client := clients.NewS3ClientDummy(nil, tt.getObjectID, nil, nil, nil)
+			playbackDAO, err := dao.CreatePlaybackDAO(context.Background(), db)
+			require.NoError(t, err)
+			subtitlesDAO, err := dao.CreateSubtitlesDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			s3Client := clients.NewS3ClientDummy(nil, tt.getObjectID, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 			serviceDiscovery := clients.NewDummyServiceDiscovery(nil, getServices, nil, nil, nil)
 
 			routerClients := router.Clients{
 				S3Client:         s3Client,
-				UUIDGen:          clients.NewUuidGeneratorDummy(nil, tt.isValidUUID),
+				UUIDGen:          clients.NewUuidGeneratorDummy(func() (string, error) { return sessionID, nil }, tt.isValidUUID),
 				ServiceDiscovery: serviceDiscovery,
 			}
 
-			r := router.NewRouter(config.Config{
+			r, err := router.NewRouter(config.Config{
 				UserAuth: givenUsername,
 				PwdAuth:  givenUserPwd,
-			}, &routerClients, &router.DAOs{})
+			}, &routerClients, &router.DAOs{PlaybackDAO: *playbackDAO, SubtitlesDAO: *subtitlesDAO})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+
+			req := httptest.NewRequest("GET", tt.giveRequest, nil)
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+
+	}
+
+}
+
+func TestVideoStreamToken(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+	givenSecret := "stream-secret"
+
+	validVideoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	otherVideoID := "0000a0a0-0aa0-0a00-0000-aa0000aa00aa"
+	UUIDValidFunc := func(u string) bool { _, err := uuid.Parse(u); return err == nil }
+
+	signer := auth.StreamSigner{Secret: []byte(givenSecret), Expiration: time.Hour}
+	validToken, err := signer.Sign(validVideoID)
+	require.NoError(t, err)
+	otherToken, err := signer.Sign(otherVideoID)
+	require.NoError(t, err)
+	expiredToken, err := auth.StreamSigner{Secret: []byte(givenSecret), Expiration: -time.Second}.Sign(validVideoID)
+	require.NoError(t, err)
+
+	master := "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1240800,RESOLUTION=640x480\nv0/segment_index.m3u8\n"
+	variant := "#EXTM3U\n#EXTINF:6.000000,\nsegment0.ts\n#EXT-X-ENDLIST\n"
+	getObject := func(s string) (io.Reader, error) {
+		switch {
+		case strings.HasSuffix(s, "master.m3u8"):
+			return strings.NewReader(master), nil
+		case strings.HasSuffix(s, "segment_index.m3u8"):
+			return strings.NewReader(variant), nil
+		default:
+			return strings.NewReader("segment"), nil
+		}
+	}
+
+	cases := []struct {
+		name             string
+		giveRequest      string
+		giveWithAuth     bool
+		giveNoSecret     bool
+		expectedHTTPCode int
+		expectedBody     string
+		expectedToken    bool
+	}{
+		{
+			name:             "GET master signs a token",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/master.m3u8",
+			giveWithAuth:     true,
+			expectedHTTPCode: 200,
+			expectedToken:    true,
+		},
+		{
+			name:             "GET master with token",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/master.m3u8?token=" + validToken,
+			expectedHTTPCode: 200,
+			expectedBody:     strings.Replace(master, "segment_index.m3u8", "segment_index.m3u8?session="+sessionID+"&token="+validToken, 1),
+		},
+		{
+			name:             "GET variant playlist with token",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/v0/segment_index.m3u8?token=" + validToken,
+			expectedHTTPCode: 200,
+			expectedBody:     strings.Replace(variant, "segment0.ts", "segment0.ts?token="+validToken, 1),
+		},
+		{
+			name:             "GET segment with token",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/v0/segment0.ts?token=" + validToken,
+			expectedHTTPCode: 200,
+			expectedBody:     "segment",
+		},
+		{
+			name:             "GET master without secret",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/master.m3u8",
+			giveWithAuth:     true,
+			giveNoSecret:     true,
+			expectedHTTPCode: 200,
+			expectedBody:     strings.Replace(master, "segment_index.m3u8", "segment_index.m3u8?session="+sessionID, 1),
+		},
+		{
+			name:             "GET fails with token of another video",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/v0/segment0.ts?token=" + otherToken,
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "GET fails with expired token",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/v0/segment0.ts?token=" + expiredToken,
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "GET fails with tampered token",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/v0/segment0.ts?token=9999999999." + strings.Split(validToken, ".")[1],
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "GET fails with token without secret",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/v0/segment0.ts?token=" + validToken,
+			giveNoSecret:     true,
+			expectedHTTPCode: 403,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectPlaybackDAOCreation(mock)
+			dao_test.ExpectSubtitlesDAOCreation(mock)
+			dao_test.ExpectChaptersDAOCreation(mock)
+			if strings.Contains(tt.giveRequest, "master.m3u8") && tt.expectedHTTPCode == 200 {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetVideoSubtitles])).WillReturnRows(sqlmock.NewRows(subtitlesColumns))
+				mock.ExpectExec(regexp.QuoteMeta(dao.PlaybackRequests[dao.CreatePlaybackSession])).WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			if strings.Contains(tt.giveRequest, "segment_index.m3u8") && tt.expectedHTTPCode == 200 {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.ChaptersRequests[dao.GetVideoChapters])).WillReturnRows(sqlmock.NewRows(chaptersColumns))
+			}
+
+			playbackDAO, err := dao.CreatePlaybackDAO(context.Background(), db)
+			require.NoError(t, err)
+			subtitlesDAO, err := dao.CreateSubtitlesDAO(context.Background(), db)
+			require.NoError(t, err)
+			chaptersDAO, err := dao.CreateChaptersDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			s3Client := clients.NewS3ClientDummy(nil, getObject, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+			routerClients := router.Clients{
+				S3Client: s3Client,
+				UUIDGen:  clients.NewUuidGeneratorDummy(func() (string, error) { return sessionID, nil }, UUIDValidFunc),
+			}
+
+			cfg := config.Config{
+				UserAuth:              givenUsername,
+				PwdAuth:               givenUserPwd,
+				StreamTokenSecret:     givenSecret,
+				StreamTokenExpiration: time.Hour,
+			}
+			if tt.giveNoSecret {
+				cfg.StreamTokenSecret = ""
+			}
+			r, err := router.NewRouter(cfg, &routerClients, &router.DAOs{PlaybackDAO: *playbackDAO, SubtitlesDAO: *subtitlesDAO, ChaptersDAO: *chaptersDAO})
+			require.NoError(t, err)
 
 			w := httptest.NewRecorder()
 
@@ -143,8 +360,146 @@ func TestVideoStream(t *testing.T) { //nolint:cyclop
 			r.ServeHTTP(w, req)
 			require.Equal(t, tt.expectedHTTPCode, w.Code)
 
+			if tt.expectedBody != "" {
+				require.Equal(t, tt.expectedBody, w.Body.String())
+			}
+			if tt.expectedToken {
+				lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+				uri, err := url.Parse(lines[len(lines)-1])
+				require.NoError(t, err)
+				require.Equal(t, "v0/segment_index.m3u8", uri.Path)
+				require.Equal(t, sessionID, uri.Query().Get(controllers.PlaybackSessionParam))
+				require.NoError(t, signer.Verify(uri.Query().Get(auth.StreamTokenParam), validVideoID))
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
 		})
+	}
+}
 
+func TestVideoPlaybackSession(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	validVideoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	variant := "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.000000,\nsegment0.ts\n#EXTINF:2.500000,\nsegment1.ts\n#EXT-X-ENDLIST\n"
+	getObject := func(s string) (io.Reader, error) {
+		if strings.HasSuffix(s, "segment_index.m3u8") {
+			return strings.NewReader(variant), nil
+		}
+		return strings.NewReader("segment"), nil
 	}
 
+	cases := []struct {
+		name             string
+		giveRequest      string
+		expectedHTTPCode int
+		expectedBody     string
+		expectDB         func(mock sqlmock.Sqlmock)
+	}{
+		{
+			name:             "GET variant records the length of the session",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/v0/segment_index.m3u8?session=" + sessionID,
+			expectedHTTPCode: 200,
+			expectedBody:     strings.ReplaceAll(variant, ".ts", ".ts?session="+sessionID),
+			expectDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(dao.PlaybackRequests[dao.UpdatePlaybackSessionLength])).
+					WithArgs(2, 8.5, sessionID, validVideoID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name:             "GET segment records it as watched",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/v0/segment1.ts?session=" + sessionID,
+			expectedHTTPCode: 200,
+			expectedBody:     "segment",
+			expectDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(dao.PlaybackRequests[dao.AddPlaybackSegment])).
+					WithArgs(1, sessionID, validVideoID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(dao.PlaybackRequests[dao.IncrementPlaybackSegmentsWatched])).
+					WithArgs(sessionID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name:             "GET segment already watched",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/v1/segment1.ts?session=" + sessionID,
+			expectedHTTPCode: 200,
+			expectedBody:     "segment",
+			expectDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(dao.PlaybackRequests[dao.AddPlaybackSegment])).
+					WithArgs(1, sessionID, validVideoID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+		},
+		{
+			name:             "GET segment even if it cannot be recorded",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/v0/segment0.ts?session=" + sessionID,
+			expectedHTTPCode: 200,
+			expectedBody:     "segment",
+			expectDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(dao.PlaybackRequests[dao.AddPlaybackSegment])).
+					WithArgs(0, sessionID, validVideoID).
+					WillReturnError(errors.New("database internal error"))
+			},
+		},
+		{
+			name:             "GET audio rendition does not record the length of the session",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/audio_fre/segment_index.m3u8?session=" + sessionID,
+			expectedHTTPCode: 200,
+			expectedBody:     strings.ReplaceAll(variant, ".ts", ".ts?session="+sessionID),
+		},
+		{
+			name:             "GET audio segment is not transformed nor recorded",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/audio_fre/segment1.ts?filter=gray&session=" + sessionID,
+			expectedHTTPCode: 200,
+			expectedBody:     "segment",
+		},
+		{
+			name:             "GET segment with invalid session",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/v0/segment0.ts?session=invalid",
+			expectedHTTPCode: 200,
+			expectedBody:     "segment",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectPlaybackDAOCreation(mock)
+			dao_test.ExpectChaptersDAOCreation(mock)
+			if tt.expectDB != nil {
+				tt.expectDB(mock)
+			}
+			if strings.Contains(tt.giveRequest, "segment_index.m3u8") {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.ChaptersRequests[dao.GetVideoChapters])).WithArgs(validVideoID).WillReturnRows(sqlmock.NewRows(chaptersColumns))
+			}
+
+			playbackDAO, err := dao.CreatePlaybackDAO(context.Background(), db)
+			require.NoError(t, err)
+			chaptersDAO, err := dao.CreateChaptersDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &router.Clients{
+				S3Client: clients.NewS3ClientDummy(nil, getObject, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil),
+				UUIDGen:  clients.NewUuidGeneratorDummy(nil, func(u string) bool { _, err := uuid.Parse(u); return err == nil }),
+			}, &router.DAOs{PlaybackDAO: *playbackDAO, ChaptersDAO: *chaptersDAO})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tt.giveRequest, nil)
+			req.SetBasicAuth(givenUsername, givenUserPwd)
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			require.Equal(t, tt.expectedBody, w.Body.String())
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
 }
@@ -0,0 +1,453 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/hls"
+	"github.com/Sogilis/Voogle/src/pkg/subtitles"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+const (
+	maxSubtitlesSize       = 2 << 20 // 2 MiB
+	maxSubtitleLabelLength = 64
+
+	// Duration in seconds of the segments of the subtitles playlists
+	subtitlesSegmentDuration = 30
+
+	subtitlesFilename      = "subtitles.vtt"
+	subtitlesPlaylist      = "index.m3u8"
+	subtitlesGroupID       = "subtitles"
+	subtitlesRenditionType = "SUBTITLES"
+)
+
+// BCP 47 language tag, like "en" or "pt-br", of at most 35 characters
+var languageTagRegex = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{1,8}){0,3}$`)
+
+// Only the playlist, the WebVTT file and the segments of a subtitles track can be requested
+var subtitlesFilenameRegex = regexp.MustCompile(`^(` + regexp.QuoteMeta(subtitlesPlaylist) + `|` + regexp.QuoteMeta(subtitlesFilename) + `|segment\d+\.vtt)$`)
+
+type VideoSubtitlesResponse struct {
+	Subtitles []jsonDTO.SubtitleJson      `json:"subtitles"`
+	Links     map[string]jsonDTO.LinkJson `json:"_links"`
+}
+
+type VideoSubtitleUploadHandler struct {
+	S3Client     clients.IS3Client
+	VideosDAO    *dao.VideosDAO
+	SubtitlesDAO *dao.SubtitlesDAO
+	UUIDGen      clients.IUUIDGenerator
+}
+
+// VideoSubtitleUploadHandler godoc
+// @Summary Upload video subtitles
+// @Description Set or replace the subtitles track of the video in this language. SRT files are converted to WebVTT.
+// @Description The track is published in the video master as a subtitles rendition, segmented every 30 seconds.
+// @Tags video
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param language path string true "BCP 47 language tag, like en or pt-BR"
+// @Param subtitles formData file true "SRT or WebVTT file, of at most 2 MiB"
+// @Param label formData string false "Name of the track shown by the players, the language by default"
+// @Success 200 {object} jsonDTO.SubtitleJson "Uploaded subtitles track"
+// @Failure 400 {string} string "Invalid language, label or subtitles file"
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/subtitles/{language} [put]
+func (v VideoSubtitleUploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("PUT VideoSubtitleUploadHandler - parameters ", vars)
+
+	language, ok := subtitleLanguage(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxSubtitlesSize)
+	file, _, err := r.FormFile("subtitles")
+	if err != nil {
+		log.Error("Missing subtitles file ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	label := strings.TrimSpace(r.FormValue("label"))
+	if label == "" {
+		label = language
+	}
+	if utf8.RuneCountInString(label) > maxSubtitleLabelLength || strings.ContainsAny(label, "\"\r\n") {
+		log.Error("Invalid subtitles label ", label)
+		http.Error(w, fmt.Sprintf("label must have at most %d characters, without double quote", maxSubtitleLabelLength), http.StatusBadRequest)
+		return
+	}
+
+	cues, err := subtitles.Parse(file)
+	if err != nil {
+		log.Error("Cannot parse subtitles : ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	video, ok := getRequestVideo(w, r, v.VideosDAO, v.UUIDGen, true)
+	if !ok {
+		return
+	}
+
+	if err := v.uploadSubtitles(r.Context(), video.ID, language, cues); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	subtitle, err := v.SubtitlesDAO.UpsertSubtitle(r.Context(), video.ID, language, label)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	payload, err := json.Marshal(jsonDTO.SubtitleToSubtitleJson(subtitle))
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	log.Infof("Video %v subtitles %v uploaded", video.ID, language)
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(payload)
+}
+
+// uploadSubtitles uploads on S3 the WebVTT file of the cues, its segments and their playlist. The files of
+// a previous track are replaced, then its remaining segments are removed.
+func (v VideoSubtitleUploadHandler) uploadSubtitles(ctx context.Context, videoID, language string, cues []subtitles.Cue) error {
+	prefix := subtitlesPrefix(videoID, language)
+	uploaded := map[string]bool{}
+	upload := func(filename string, content []byte) error {
+		if err := v.S3Client.PutObjectInput(ctx, bytes.NewReader(content), prefix+filename); err != nil {
+			log.Error("Cannot upload subtitles "+prefix+filename+" : ", err)
+			return err
+		}
+		uploaded[prefix+filename] = true
+		return nil
+	}
+
+	if err := upload(subtitlesFilename, subtitles.EncodeVTT(cues)); err != nil {
+		return err
+	}
+
+	segments := subtitles.Segment(cues, subtitlesSegmentDuration)
+	playlist := hls.MediaPlaylist{Version: 3, TargetDuration: subtitlesSegmentDuration}
+	for i, segment := range segments {
+		filename := "segment" + strconv.Itoa(i) + ".vtt"
+		if err := upload(filename, subtitles.EncodeVTT(segment)); err != nil {
+			return err
+		}
+		playlist.Segments = append(playlist.Segments, hls.Segment{Duration: subtitlesSegmentDuration, URI: filename})
+	}
+
+	// The playlist is uploaded last, once all its segments are available
+	if err := upload(subtitlesPlaylist, playlist.Encode()); err != nil {
+		return err
+	}
+
+	objects, err := v.S3Client.ListObjectsWithPrefix(ctx, prefix)
+	if err != nil {
+		log.Error("Cannot list previous subtitles : ", err)
+		return nil
+	}
+	for _, object := range objects {
+		if uploaded[object] {
+			continue
+		}
+		if err := v.S3Client.RemoveObject(ctx, object); err != nil {
+			log.Error("Cannot remove previous subtitles "+object+" : ", err)
+		}
+	}
+
+	return nil
+}
+
+type VideoSubtitlesListHandler struct {
+	VideosDAO    *dao.VideosDAO
+	SubtitlesDAO *dao.SubtitlesDAO
+	UUIDGen      clients.IUUIDGenerator
+}
+
+// VideoSubtitlesListHandler godoc
+// @Summary List video subtitles
+// @Description List the subtitles tracks of the video, sorted on their language
+// @Tags video
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} VideoSubtitlesResponse "Subtitles tracks"
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/subtitles [get]
+func (v VideoSubtitlesListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET VideoSubtitlesListHandler - parameters ", vars)
+
+	video, ok := getRequestVideo(w, r, v.VideosDAO, v.UUIDGen, false)
+	if !ok {
+		return
+	}
+
+	tracks, err := v.SubtitlesDAO.GetVideoSubtitles(r.Context(), video.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := VideoSubtitlesResponse{
+		Subtitles: make([]jsonDTO.SubtitleJson, 0, len(tracks)),
+		Links: map[string]jsonDTO.LinkJson{
+			"self":   jsonDTO.LinkToLinkJson(models.CreateLink("api/v1/videos/"+video.ID+"/subtitles", "GET")),
+			"master": jsonDTO.LinkToLinkJson(models.CreateLink("api/v1/videos/"+video.ID+"/streams/master.m3u8", "GET")),
+		},
+	}
+	for i := range tracks {
+		response.Subtitles = append(response.Subtitles, jsonDTO.SubtitleToSubtitleJson(&tracks[i]))
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(payload)
+}
+
+type VideoSubtitleDeleteHandler struct {
+	S3Client     clients.IS3Client
+	VideosDAO    *dao.VideosDAO
+	SubtitlesDAO *dao.SubtitlesDAO
+	UUIDGen      clients.IUUIDGenerator
+}
+
+// VideoSubtitleDeleteHandler godoc
+// @Summary Delete video subtitles
+// @Description Delete the subtitles track of the video in this language, and its files
+// @Tags video
+// @Param id path string true "Video ID"
+// @Param language path string true "BCP 47 language tag"
+// @Success 204 {string} string
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/subtitles/{language} [delete]
+func (v VideoSubtitleDeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("DELETE VideoSubtitleDeleteHandler - parameters ", vars)
+
+	language, ok := subtitleLanguage(w, r)
+	if !ok {
+		return
+	}
+
+	video, ok := getRequestVideo(w, r, v.VideosDAO, v.UUIDGen, true)
+	if !ok {
+		return
+	}
+
+	if _, err := v.SubtitlesDAO.GetSubtitle(r.Context(), video.ID, language); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// The master stops publishing the track before its files are removed
+	if err := v.SubtitlesDAO.DeleteSubtitle(r.Context(), video.ID, language); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Files left behind are removed with the video
+	prefix := subtitlesPrefix(video.ID, language)
+	if err := v.S3Client.RemoveObjectsWithPrefix(r.Context(), prefix); err != nil {
+		log.Error("Cannot remove subtitles "+prefix+" : ", err)
+	}
+
+	log.Infof("Video %v subtitles %v deleted", video.ID, language)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type VideoGetSubtitlesHandler struct {
+	S3Client     clients.IS3Client
+	UUIDGen      clients.IUUIDGenerator
+	StreamSigner auth.StreamSigner
+}
+
+// VideoGetSubtitlesHandler godoc
+// @Summary Get video subtitles stream
+// @Description Get the playlist of a subtitles track (index.m3u8), its segments carrying the stream token,
+// @Description one of its WebVTT segments (segmentN.vtt) or its whole WebVTT file (subtitles.vtt)
+// @Tags video
+// @Produce plain
+// @Param id path string true "Video ID"
+// @Param language path string true "BCP 47 language tag"
+// @Param filename path string true "index.m3u8, subtitles.vtt or segment name"
+// @Param token query string false "Stream token, instead of credentials"
+// @Success 200 {string} string "HLS subtitles playlist or WebVTT file"
+// @Failure 400 {string} string
+// @Failure 403 {string} string "Invalid stream token"
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/streams/subtitles/{language}/{filename} [get]
+func (v VideoGetSubtitlesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET VideoGetSubtitlesHandler - parameters ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	language, ok := subtitleLanguage(w, r)
+	if !ok {
+		return
+	}
+
+	filename := vars["filename"]
+	if !subtitlesFilenameRegex.MatchString(filename) {
+		log.Error("Invalid subtitles filename ", filename)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	path := subtitlesPrefix(id, language) + filename
+	object, err := v.S3Client.GetObject(r.Context(), path)
+	if err != nil {
+		log.Error("Failed to open subtitles "+path+" ", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if filename != subtitlesPlaylist {
+		w.Header().Set("Content-Type", "text/vtt")
+		if _, err = io.Copy(w, object); err != nil {
+			log.Error("Unable to stream subtitles", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	token, err := streamToken(r, v.StreamSigner, id)
+	if err != nil {
+		log.Error("Cannot sign stream token : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := writePlaylist(w, object, map[string]string{auth.StreamTokenParam: token}); err != nil {
+		log.Error("Unable to stream subtitles playlist", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// addSubtitles returns the master of the video with its subtitles tracks as subtitles renditions
+func addSubtitles(ctx context.Context, subtitlesDAO *dao.SubtitlesDAO, videoID string, master io.Reader) (io.Reader, error) {
+	tracks, err := subtitlesDAO.GetVideoSubtitles(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+	if len(tracks) == 0 {
+		return master, nil
+	}
+
+	renditions := make([]hls.Rendition, 0, len(tracks))
+	for _, track := range tracks {
+		renditions = append(renditions, hls.Rendition{
+			Type:     subtitlesRenditionType,
+			GroupID:  subtitlesGroupID,
+			Name:     track.Label,
+			Language: track.Language,
+			URI:      "subtitles/" + track.Language + "/" + subtitlesPlaylist,
+		})
+	}
+
+	withSubtitles, err := hls.AddRenditions(master, renditions)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(withSubtitles), nil
+}
+
+// subtitlesPrefix is the S3 prefix of the files of the subtitles track
+func subtitlesPrefix(videoID, language string) string {
+	return videoID + "/subtitles/" + language + "/"
+}
+
+// subtitleLanguage returns the language of the request, lower cased : language tags are case insensitive
+func subtitleLanguage(w http.ResponseWriter, r *http.Request) (string, bool) {
+	language := strings.ToLower(mux.Vars(r)["language"])
+	if len(language) > 35 || !languageTagRegex.MatchString(language) {
+		log.Error("Invalid subtitles language ", language)
+		http.Error(w, "language must be a BCP 47 language tag, like en or pt-BR", http.StatusBadRequest)
+		return "", false
+	}
+
+	return language, true
+}
+
+// getRequestVideo returns the video of the request, checking it can be managed by the user when manage is set.
+// The response is written on failure.
+func getRequestVideo(w http.ResponseWriter, r *http.Request, videosDAO *dao.VideosDAO, uuidGen clients.IUUIDGenerator, manage bool) (*models.Video, bool) {
+	id := mux.Vars(r)["id"]
+	if !uuidGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, false
+	}
+
+	video, err := videosDAO.GetVideo(r.Context(), id)
+	if err != nil {
+		log.Error("Cannot found video : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+
+	if manage && !auth.CanManageVideo(r.Context(), video) {
+		log.Error("Video " + video.ID + " belongs to another user")
+		w.WriteHeader(http.StatusForbidden)
+		return nil, false
+	}
+
+	return video, true
+}
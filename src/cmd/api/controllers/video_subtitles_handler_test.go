@@ -0,0 +1,348 @@
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+)
+
+var subtitlesColumns = []string{"video_id", "language", "label", "created_at"}
+
+func TestVideoSubtitles(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	validVideoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	unknownVideoID := "0000a0a0-0aa0-0a00-0000-aa0000aa00aa"
+	t1 := time.Now()
+
+	srt := "1\r\n00:00:01,000 --> 00:00:04,000\r\nHello\r\n\r\n2\r\n00:00:35,000 --> 00:00:36,500\r\nBye\r\n"
+
+	expectVideoLookup := func(mock sqlmock.Sqlmock, ID string) {
+		videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
+		rows := sqlmock.NewRows(videosColumns)
+		if ID == validVideoID {
+			rows.AddRow(validVideoID, "lecture", int(models.COMPLETE), t1, t1, t1, "", "", nil, "", nil, nil)
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WithArgs(ID).WillReturnRows(rows)
+	}
+
+	cases := []struct {
+		name              string
+		giveMethod        string
+		giveRequest       string
+		giveSubtitles     string
+		giveLabel         string
+		expectDB          func(mock sqlmock.Sqlmock)
+		expectedHTTPCode  int
+		expectedBody      string
+		expectedUploads   []string
+		expectedRemovals  []string
+		expectedSubtitles []string
+	}{
+		{
+			name:          "PUT SRT subtitles",
+			giveMethod:    "PUT",
+			giveRequest:   "/api/v1/videos/" + validVideoID + "/subtitles/pt-BR",
+			giveSubtitles: srt,
+			giveLabel:     "Português",
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, validVideoID)
+				mock.ExpectExec(regexp.QuoteMeta(dao.SubtitlesRequests[dao.UpsertSubtitle])).
+					WithArgs(validVideoID, "pt-br", "Português").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetSubtitle])).
+					WithArgs(validVideoID, "pt-br").
+					WillReturnRows(sqlmock.NewRows(subtitlesColumns).AddRow(validVideoID, "pt-br", "Português", t1))
+			},
+			expectedHTTPCode: 200,
+			expectedUploads: []string{
+				validVideoID + "/subtitles/pt-br/index.m3u8",
+				validVideoID + "/subtitles/pt-br/segment0.vtt",
+				validVideoID + "/subtitles/pt-br/segment1.vtt",
+				validVideoID + "/subtitles/pt-br/subtitles.vtt",
+			},
+			// A segment of the previous track
+			expectedRemovals: []string{validVideoID + "/subtitles/pt-br/segment2.vtt"},
+		},
+		{
+			name:             "PUT fails with invalid language",
+			giveMethod:       "PUT",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/subtitles/english",
+			giveSubtitles:    srt,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "PUT fails with invalid subtitles",
+			giveMethod:       "PUT",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/subtitles/en",
+			giveSubtitles:    "Hello world\n",
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "PUT fails with invalid label",
+			giveMethod:       "PUT",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/subtitles/en",
+			giveSubtitles:    srt,
+			giveLabel:        `"English"`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:          "PUT fails with unknown video",
+			giveMethod:    "PUT",
+			giveRequest:   "/api/v1/videos/" + unknownVideoID + "/subtitles/en",
+			giveSubtitles: srt,
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, unknownVideoID)
+			},
+			expectedHTTPCode: 404,
+		},
+		{
+			name:        "GET subtitles list",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/subtitles",
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, validVideoID)
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetVideoSubtitles])).
+					WithArgs(validVideoID).
+					WillReturnRows(sqlmock.NewRows(subtitlesColumns).
+						AddRow(validVideoID, "en", "English", t1).
+						AddRow(validVideoID, "fr", "Français", t1))
+			},
+			expectedHTTPCode:  200,
+			expectedSubtitles: []string{"en", "fr"},
+		},
+		{
+			name:        "GET fails with database error",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/subtitles",
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, validVideoID)
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetVideoSubtitles])).WillReturnError(fmt.Errorf("database internal error"))
+			},
+			expectedHTTPCode: 500,
+		},
+		{
+			name:        "DELETE subtitles",
+			giveMethod:  "DELETE",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/subtitles/en",
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, validVideoID)
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetSubtitle])).
+					WithArgs(validVideoID, "en").
+					WillReturnRows(sqlmock.NewRows(subtitlesColumns).AddRow(validVideoID, "en", "English", t1))
+				mock.ExpectExec(regexp.QuoteMeta(dao.SubtitlesRequests[dao.DeleteSubtitle])).
+					WithArgs(validVideoID, "en").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedHTTPCode: 204,
+			expectedRemovals: []string{validVideoID + "/subtitles/en/"},
+		},
+		{
+			name:        "DELETE fails with unknown subtitles",
+			giveMethod:  "DELETE",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/subtitles/de",
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, validVideoID)
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetSubtitle])).
+					WithArgs(validVideoID, "de").
+					WillReturnRows(sqlmock.NewRows(subtitlesColumns))
+			},
+			expectedHTTPCode: 404,
+		},
+		{
+			name:        "GET master with subtitles renditions",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/streams/master.m3u8",
+			expectDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetVideoSubtitles])).
+					WithArgs(validVideoID).
+					WillReturnRows(sqlmock.NewRows(subtitlesColumns).AddRow(validVideoID, "en", "English", t1))
+				mock.ExpectExec(regexp.QuoteMeta(dao.PlaybackRequests[dao.CreatePlaybackSession])).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedHTTPCode: 200,
+			expectedBody: "#EXTM3U\n" +
+				"#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"subtitles\",NAME=\"English\",LANGUAGE=\"en\",DEFAULT=NO,AUTOSELECT=YES,URI=\"subtitles/en/index.m3u8?session=" + sessionID + "\"\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=1240800,RESOLUTION=640x480,SUBTITLES=\"subtitles\"\n" +
+				"v0/segment_index.m3u8?session=" + sessionID + "\n",
+		},
+		{
+			name:             "GET subtitles playlist",
+			giveMethod:       "GET",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/subtitles/en/index.m3u8",
+			expectedHTTPCode: 200,
+			expectedBody:     "#EXTM3U\n#EXT-X-TARGETDURATION:30\n#EXTINF:30.000000,\nsegment0.vtt\n#EXT-X-ENDLIST\n",
+		},
+		{
+			name:             "GET subtitles segment",
+			giveMethod:       "GET",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/subtitles/en/segment0.vtt",
+			expectedHTTPCode: 200,
+			expectedBody:     "WEBVTT\n",
+		},
+		{
+			name:             "GET fails with invalid subtitles filename",
+			giveMethod:       "GET",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/subtitles/en/master.m3u8",
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "GET fails with unknown subtitles",
+			giveMethod:       "GET",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/subtitles/de/index.m3u8",
+			expectedHTTPCode: 404,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectPlaybackDAOCreation(mock)
+			dao_test.ExpectSubtitlesDAOCreation(mock)
+			if tt.expectDB != nil {
+				tt.expectDB(mock)
+			}
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+			playbackDAO, err := dao.CreatePlaybackDAO(context.Background(), db)
+			require.NoError(t, err)
+			subtitlesDAO, err := dao.CreateSubtitlesDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			objects := map[string]string{
+				validVideoID + "/master.m3u8":                  "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1240800,RESOLUTION=640x480\nv0/segment_index.m3u8\n",
+				validVideoID + "/subtitles/en/index.m3u8":      "#EXTM3U\n#EXT-X-TARGETDURATION:30\n#EXTINF:30.000000,\nsegment0.vtt\n#EXT-X-ENDLIST\n",
+				validVideoID + "/subtitles/en/segment0.vtt":    "WEBVTT\n",
+				validVideoID + "/subtitles/pt-br/segment2.vtt": "WEBVTT\n",
+			}
+			getObject := func(s string) (io.Reader, error) {
+				object, ok := objects[s]
+				if !ok {
+					return nil, errors.New("Not found")
+				}
+				return strings.NewReader(object), nil
+			}
+
+			uploads := map[string]string{}
+			var uploaded []string
+			putObject := func(f io.Reader, s string) error {
+				content, err := io.ReadAll(f)
+				require.NoError(t, err)
+				uploads[s] = string(content)
+				uploaded = append(uploaded, s)
+				return nil
+			}
+			listObjectsWithPrefix := func(prefix string) ([]string, error) {
+				keys := []string{}
+				for key := range objects {
+					if strings.HasPrefix(key, prefix) {
+						keys = append(keys, key)
+					}
+				}
+				for key := range uploads {
+					if strings.HasPrefix(key, prefix) && objects[key] == "" {
+						keys = append(keys, key)
+					}
+				}
+				return keys, nil
+			}
+			var removed []string
+			removeObject := func(s string) error {
+				removed = append(removed, s)
+				return nil
+			}
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &router.Clients{
+				S3Client: clients.NewS3ClientDummy(nil, getObject, putObject, nil, removeObject, nil, nil, nil, nil, nil, nil, nil, listObjectsWithPrefix, removeObject),
+				UUIDGen: clients.NewUuidGeneratorDummy(func() (string, error) { return sessionID, nil }, func(u string) bool {
+					_, err := uuid.Parse(u)
+					return err == nil
+				}),
+			}, &router.DAOs{VideosDAO: *videosDAO, PlaybackDAO: *playbackDAO, SubtitlesDAO: *subtitlesDAO})
+			require.NoError(t, err)
+
+			body := new(bytes.Buffer)
+			writer := multipart.NewWriter(body)
+			if tt.giveSubtitles != "" {
+				fileWriter, err := writer.CreateFormFile("subtitles", "subtitles.srt")
+				require.NoError(t, err)
+				_, err = fileWriter.Write([]byte(tt.giveSubtitles))
+				require.NoError(t, err)
+			}
+			if tt.giveLabel != "" {
+				require.NoError(t, writer.WriteField("label", tt.giveLabel))
+			}
+			require.NoError(t, writer.Close())
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.giveMethod, tt.giveRequest, body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			req.SetBasicAuth(givenUsername, givenUserPwd)
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedBody != "" {
+				require.Equal(t, tt.expectedBody, w.Body.String())
+			}
+			if tt.expectedUploads != nil {
+				sort.Strings(uploaded)
+				require.Equal(t, tt.expectedUploads, uploaded)
+				require.Equal(t, "WEBVTT\n\n1\n00:00:01.000 --> 00:00:04.000\nHello\n\n2\n00:00:35.000 --> 00:00:36.500\nBye\n",
+					uploads[validVideoID+"/subtitles/pt-br/subtitles.vtt"])
+				require.Equal(t, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:30\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n"+
+					"#EXTINF:30.000000,\nsegment0.vtt\n#EXTINF:30.000000,\nsegment1.vtt\n#EXT-X-ENDLIST\n",
+					uploads[validVideoID+"/subtitles/pt-br/index.m3u8"])
+
+				var response jsonDTO.SubtitleJson
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				require.Equal(t, "pt-br", response.Language)
+				require.Equal(t, "Português", response.Label)
+			}
+			require.Equal(t, tt.expectedRemovals, removed)
+			if tt.expectedSubtitles != nil {
+				var response controllers.VideoSubtitlesResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				languages := []string{}
+				for _, subtitle := range response.Subtitles {
+					languages = append(languages, subtitle.Language)
+				}
+				require.Equal(t, tt.expectedSubtitles, languages)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
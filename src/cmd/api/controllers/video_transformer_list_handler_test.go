@@ -53,10 +53,11 @@ func TestTransformerList(t *testing.T) { //nolint:cyclop
 			givenRequest := "/api/v1/videos/transformer/list"
 
 			dummyServiceDiscovery := clients.NewDummyServiceDiscovery(tt.adressCache, nil, getExistingServices, nil, nil)
-			r := router.NewRouter(config.Config{
+			r, err := router.NewRouter(config.Config{
 				UserAuth: givenUsername,
 				PwdAuth:  givenPassword,
 			}, &router.Clients{ServiceDiscovery: dummyServiceDiscovery}, &router.DAOs{})
+			require.NoError(t, err)
 
 			w := httptest.NewRecorder()
 
@@ -9,6 +9,7 @@ import (
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
 	"github.com/Sogilis/Voogle/src/cmd/api/models"
 	"github.com/Sogilis/Voogle/src/pkg/clients"
@@ -52,6 +53,12 @@ func (v VideoUnarchiveHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if !auth.CanManageVideo(r.Context(), video) {
+		log.Error("Video " + video.ID + " belongs to another user")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
 	statusCode, err := v.unarchiveVideo(r.Context(), video)
 	if err != nil {
 		w.WriteHeader(statusCode)
@@ -67,8 +74,9 @@ func (v VideoUnarchiveHandler) unarchiveVideo(ctx context.Context, video *models
 		return http.StatusBadRequest, err
 	}
 	video.Status = models.COMPLETE
+	video.ArchivedAt = nil
 
-	if err := v.VideosDAO.UpdateVideo(ctx, video); err != nil {
+	if err := v.VideosDAO.UpdateVideoArchivedAt(ctx, video); err != nil {
 		log.Error("Cannot update video "+video.ID+" : ", err)
 		return http.StatusInternalServerError, err
 	}
@@ -131,10 +131,10 @@ func TestVideoUnarchive(t *testing.T) { //nolint:cyclop
 			} else {
 				// Queries
 				getVideoFromIdQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])
-				updateVideoQuery := regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo])
+				updateVideoArchivedAtQuery := regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideoArchivedAt])
 
 				// Tables
-				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path"}
+				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
 				videosRows := sqlmock.NewRows(videosColumns)
 
 				// Define database response according to case
@@ -144,17 +144,17 @@ func TestVideoUnarchive(t *testing.T) { //nolint:cyclop
 				} else if tt.giveRequest == "/api/v1/videos/"+unknownVideoID+"/unarchive" {
 					mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
 				} else {
-					videosRows.AddRow(validVideoID, videoTitle, int(tt.status), t1, t1, nil, sourcePath, coverPath)
+					videosRows.AddRow(validVideoID, videoTitle, int(tt.status), t1, t1, nil, sourcePath, coverPath, nil, "", nil, nil)
 					mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
 
 					if tt.status == models.ARCHIVE {
 						if tt.giveDbUpdateErr {
-							mock.ExpectExec(updateVideoQuery).
-								WithArgs(videoTitle, int(models.COMPLETE), t1, sourcePath, coverPath, validVideoID).
+							mock.ExpectExec(updateVideoArchivedAtQuery).
+								WithArgs(int(models.COMPLETE), nil, validVideoID).
 								WillReturnError(fmt.Errorf("Error will update db"))
 						} else {
-							mock.ExpectExec(updateVideoQuery).
-								WithArgs(videoTitle, int(models.COMPLETE), t1, sourcePath, coverPath, validVideoID).
+							mock.ExpectExec(updateVideoArchivedAtQuery).
+								WithArgs(int(models.COMPLETE), nil, validVideoID).
 								WillReturnResult(sqlmock.NewResult(0, 1))
 						}
 					}
@@ -167,10 +167,11 @@ func TestVideoUnarchive(t *testing.T) { //nolint:cyclop
 				VideosDAO: *videoDAO,
 			}
 
-			r := router.NewRouter(config.Config{
+			r, err := router.NewRouter(config.Config{
 				UserAuth: givenUsername,
 				PwdAuth:  givenUserPwd,
 			}, &routerClients, &routerDAO)
+			require.NoError(t, err)
 
 			w := httptest.NewRecorder()
 
@@ -0,0 +1,248 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+// Limits of the video metadata, matching the database columns
+const (
+	MaxTitleLength       = 64
+	MaxDescriptionLength = 2048
+	MaxTagLength         = 32
+	MaxTags              = 20
+)
+
+type VideoUpdateHandler struct {
+	VideosDAO *dao.VideosDAO
+	TagsDAO   *dao.TagsDAO
+	UUIDGen   clients.IUUIDGenerator
+}
+
+// VideoUpdateRequest fields are optional : only the given ones are updated.
+// Given tags replace all the tags of the video.
+type VideoUpdateRequest struct {
+	Title       *string   `json:"title,omitempty" example:"A new title"`
+	Description *string   `json:"description,omitempty" example:"A description"`
+	Tags        *[]string `json:"tags,omitempty" example:"nature,mountain"`
+}
+
+// VideoUpdateHandler godoc
+// @Summary Update video metadata
+// @Description Update the title, the description and the tags of the video. Tags are trimmed and lower cased.
+// @Description The title cannot be changed while the video is uploaded or encoded.
+// @Tags video
+// @Accept json
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param request body VideoUpdateRequest true "Metadata to update"
+// @Success 200 {object} jsonDTO.VideoJson "Updated video"
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string "This title already exists"
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id} [patch]
+func (v VideoUpdateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) { //nolint:cyclop
+	vars := mux.Vars(r)
+	log.Debug("PATCH VideoUpdateHandler - parameters ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var request VideoUpdateRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		log.Error("Cannot decode video update request : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	video, err := v.VideosDAO.GetVideo(r.Context(), id)
+	if err != nil {
+		log.Error("Cannot found video : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if !auth.CanManageVideo(r.Context(), video) {
+		log.Error("Video " + video.ID + " belongs to another user")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	previousTitle := video.Title
+	if err := applyVideoUpdate(video, request); err != nil {
+		log.Error("Invalid video update request : ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if video.Title != previousTitle {
+		statusCode, err := v.checkTitleChange(r.Context(), video)
+		if err != nil {
+			http.Error(w, err.Error(), statusCode)
+			return
+		}
+	}
+
+	// Another video may have taken the title since the check
+	if err := v.updateVideo(r.Context(), video, request.Tags != nil); errors.Is(err, dao.ErrDuplicateTitle) {
+		http.Error(w, "This title already exists", http.StatusConflict)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if request.Tags == nil {
+		video.Tags, err = v.TagsDAO.GetVideoTags(r.Context(), video.ID)
+		if err != nil {
+			log.Error("Cannot get video tags : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	payload, err := json.Marshal(jsonDTO.VideoToVideoJson(video))
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(payload)
+	log.Infof("Video %v metadata updated", video.ID)
+}
+
+// applyVideoUpdate validates the request and sets the given metadata on the video
+func applyVideoUpdate(video *models.Video, request VideoUpdateRequest) error {
+	if request.Title != nil {
+		title := strings.TrimSpace(*request.Title)
+		if title == "" || utf8.RuneCountInString(title) > MaxTitleLength {
+			return fmt.Errorf("title must have between 1 and %v characters", MaxTitleLength)
+		}
+		video.Title = title
+	}
+
+	if request.Description != nil {
+		description := strings.TrimSpace(*request.Description)
+		if utf8.RuneCountInString(description) > MaxDescriptionLength {
+			return fmt.Errorf("description must have at most %v characters", MaxDescriptionLength)
+		}
+		video.Description = description
+	}
+
+	if request.Tags != nil {
+		tags, err := normalizeTags(*request.Tags)
+		if err != nil {
+			return err
+		}
+		video.Tags = tags
+	}
+
+	return nil
+}
+
+// normalizeTags trims, lower cases and removes duplicated tags
+func normalizeTags(requestTags []string) ([]string, error) {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, tag := range requestTags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || utf8.RuneCountInString(tag) > MaxTagLength {
+			return nil, fmt.Errorf("tags must have between 1 and %v characters", MaxTagLength)
+		}
+
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	if len(tags) > MaxTags {
+		return nil, fmt.Errorf("a video can have at most %v tags", MaxTags)
+	}
+
+	return tags, nil
+}
+
+// checkTitleChange checks the new title is free. The title is also the key of the video status updates,
+// so it cannot change while the video is uploaded or encoded.
+func (v VideoUpdateHandler) checkTitleChange(ctx context.Context, video *models.Video) (int, error) {
+	switch video.Status {
+	case models.UPLOADING, models.UPLOADED, models.ENCODING:
+		err := errors.New("The title cannot be changed while the video is " + strings.ToLower(video.Status.String()))
+		log.Error(err)
+		return http.StatusConflict, err
+	}
+
+	existing, err := v.VideosDAO.GetVideoFromTitle(ctx, video.Title)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return http.StatusInternalServerError, err
+	}
+	if existing != nil && existing.ID != video.ID {
+		log.Error("A video with this title already exists")
+		return http.StatusConflict, errors.New("This title already exists")
+	}
+
+	return 0, nil
+}
+
+func (v VideoUpdateHandler) updateVideo(ctx context.Context, video *models.Video, updateTags bool) error {
+	tx, err := v.VideosDAO.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("Cannot open new database transaction : ", err)
+		return err
+	}
+
+	// Defer a rollback in case anything fails.
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := v.VideosDAO.UpdateVideoMetadataTx(ctx, tx, video); err != nil {
+		log.Error("Cannot update video "+video.ID+" : ", err)
+		return err
+	}
+
+	if updateTags {
+		if err := v.TagsDAO.UpdateVideoTagsTx(ctx, tx, video.ID, video.Tags); err != nil {
+			log.Error("Cannot update video "+video.ID+" tags : ", err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("Cannot commit database transaction")
+		return err
+	}
+
+	return nil
+}
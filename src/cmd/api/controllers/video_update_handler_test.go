@@ -0,0 +1,348 @@
+package controllers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+)
+
+func TestVideoUpdate(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	validVideoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	otherVideoID := "4a6e6d4a-5a4b-4b8e-9a63-4c6c0f0e7e1d"
+	invalidVideoID := "invalidvideoid"
+	UUIDValidFunc := func(u string) bool { _, err := uuid.Parse(u); return err == nil }
+	videoTitle := "title"
+	t1 := time.Now()
+	sourcePath := validVideoID + "/" + "source.mp4"
+	coverPath := validVideoID + "/" + "cover.png"
+
+	cases := []struct {
+		name               string
+		giveID             string
+		giveBody           string
+		giveWithAuth       bool
+		giveAccount        bool
+		giveOwnerID        interface{}
+		giveStatus         models.VideoStatus
+		giveUnknownVideo   bool
+		titleAlreadyExists bool
+		titleTakenOnUpdate bool
+		updateFail         bool
+		expectTitle        string
+		expectDescription  string
+		expectTags         []string
+		expectedBody       string
+		expectedHTTPCode   int
+	}{
+		{
+			name:              "PATCH title, description and tags",
+			giveID:            validVideoID,
+			giveBody:          `{"title": " new title ", "description": "A description", "tags": ["Nature", "mountain", "nature "]}`,
+			giveWithAuth:      true,
+			giveStatus:        models.COMPLETE,
+			expectTitle:       "new title",
+			expectDescription: "A description",
+			expectTags:        []string{"nature", "mountain"},
+			expectedBody:      `"tags":["nature","mountain"]`,
+			expectedHTTPCode:  200,
+		},
+		{
+			name:              "PATCH description only keeps title and tags",
+			giveID:            validVideoID,
+			giveBody:          `{"description": "A description"}`,
+			giveWithAuth:      true,
+			giveStatus:        models.ENCODING,
+			expectTitle:       videoTitle,
+			expectDescription: "A description",
+			expectedBody:      `"tags":["existing"]`,
+			expectedHTTPCode:  200,
+		},
+		{
+			name:              "PATCH removes all tags",
+			giveID:            validVideoID,
+			giveBody:          `{"tags": []}`,
+			giveWithAuth:      true,
+			giveStatus:        models.COMPLETE,
+			expectTitle:       videoTitle,
+			expectDescription: "",
+			expectTags:        []string{},
+			expectedBody:      `"tags":[]`,
+			expectedHTTPCode:  200,
+		},
+		{
+			name:             "PATCH fails with title already exists",
+			giveID:           validVideoID,
+			giveBody:         `{"title": "other title"}`,
+			giveWithAuth:     true,
+			giveStatus:       models.COMPLETE,
+			expectTitle:      "other title",
+			expectedHTTPCode: 409,
+			// Title owned by another video
+			titleAlreadyExists: true,
+		},
+		{
+			name:             "PATCH fails with title taken by another video since the check",
+			giveID:           validVideoID,
+			giveBody:         `{"title": "other title"}`,
+			giveWithAuth:     true,
+			giveStatus:       models.COMPLETE,
+			expectTitle:      "other title",
+			expectedBody:     "This title already exists",
+			expectedHTTPCode: 409,
+			// The unique key of the title rejects the update
+			titleTakenOnUpdate: true,
+		},
+		{
+			name:             "PATCH fails with title change while encoding",
+			giveID:           validVideoID,
+			giveBody:         `{"title": "new title"}`,
+			giveWithAuth:     true,
+			giveStatus:       models.ENCODING,
+			expectedHTTPCode: 409,
+		},
+		{
+			name:             "PATCH fails with empty title",
+			giveID:           validVideoID,
+			giveBody:         `{"title": "  "}`,
+			giveWithAuth:     true,
+			giveStatus:       models.COMPLETE,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "PATCH fails with too long title",
+			giveID:           validVideoID,
+			giveBody:         `{"title": "` + strings.Repeat("a", controllers.MaxTitleLength+1) + `"}`,
+			giveWithAuth:     true,
+			giveStatus:       models.COMPLETE,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "PATCH fails with empty tag",
+			giveID:           validVideoID,
+			giveBody:         `{"tags": ["nature", ""]}`,
+			giveWithAuth:     true,
+			giveStatus:       models.COMPLETE,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "PATCH fails with too many tags",
+			giveID:           validVideoID,
+			giveBody:         `{"tags": ["1","2","3","4","5","6","7","8","9","10","11","12","13","14","15","16","17","18","19","20","21"]}`,
+			giveWithAuth:     true,
+			giveStatus:       models.COMPLETE,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:              "PATCH description of a video of the user",
+			giveID:            validVideoID,
+			giveBody:          `{"description": "A description"}`,
+			giveAccount:       true,
+			giveOwnerID:       accountID,
+			giveStatus:        models.COMPLETE,
+			expectTitle:       videoTitle,
+			expectDescription: "A description",
+			expectedBody:      `"tags":["existing"]`,
+			expectedHTTPCode:  200,
+		},
+		{
+			name:             "PATCH fails with video of another user",
+			giveID:           validVideoID,
+			giveBody:         `{"title": "new title", "tags": []}`,
+			giveAccount:      true,
+			giveStatus:       models.COMPLETE,
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "PATCH fails with unknown field",
+			giveID:           validVideoID,
+			giveBody:         `{"status": "Complete"}`,
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "PATCH fails with unknown video",
+			giveID:           validVideoID,
+			giveBody:         `{"description": "A description"}`,
+			giveWithAuth:     true,
+			giveUnknownVideo: true,
+			expectedHTTPCode: 404,
+		},
+		{
+			name:              "PATCH fails with database error",
+			giveID:            validVideoID,
+			giveBody:          `{"description": "A description"}`,
+			giveWithAuth:      true,
+			giveStatus:        models.COMPLETE,
+			updateFail:        true,
+			expectTitle:       videoTitle,
+			expectDescription: "A description",
+			expectedHTTPCode:  500,
+		},
+		{
+			name:             "PATCH fails with invalid video ID",
+			giveID:           invalidVideoID,
+			giveBody:         `{"description": "A description"}`,
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "PATCH fails with no auth",
+			giveID:           validVideoID,
+			giveBody:         `{"description": "A description"}`,
+			giveWithAuth:     false,
+			expectedHTTPCode: 401,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			routerClients := router.Clients{
+				UUIDGen: clients.NewUuidGeneratorDummy(nil, UUIDValidFunc),
+			}
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectTagsDAOCreation(mock)
+			dao_test.ExpectUsersDAOCreation(mock)
+
+			if tt.giveAccount {
+				expectAccountLookup(t, mock)
+			}
+
+			// Queries
+			getVideoFromIdQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])
+			getVideoFromTitleQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideoFromTitle])
+			updateVideoMetadataQuery := regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideoMetadata])
+			getVideoTagsQuery := regexp.QuoteMeta(dao.TagsRequests[dao.GetVideoTags])
+			addVideoTagQuery := regexp.QuoteMeta(dao.TagsRequests[dao.AddVideoTag])
+			deleteVideoTagsQuery := regexp.QuoteMeta(dao.TagsRequests[dao.DeleteVideoTags])
+
+			// Tables
+			videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
+
+			if !(tt.giveWithAuth || tt.giveAccount) || tt.giveID == invalidVideoID || tt.giveStatus == models.UNSPECIFIED && !tt.giveUnknownVideo {
+				// All these cases will stop before querying the database : Nothing to do
+
+			} else if tt.giveUnknownVideo {
+				mock.ExpectQuery(getVideoFromIdQuery).WithArgs(tt.giveID).WillReturnRows(sqlmock.NewRows(videosColumns))
+
+			} else {
+				videosRows := sqlmock.NewRows(videosColumns).AddRow(validVideoID, videoTitle, tt.giveStatus, t1, t1, t1, sourcePath, coverPath, nil, "", tt.giveOwnerID, nil)
+				mock.ExpectQuery(getVideoFromIdQuery).WithArgs(tt.giveID).WillReturnRows(videosRows)
+
+				if tt.expectTitle != "" && tt.expectTitle != videoTitle {
+					titleRows := sqlmock.NewRows(videosColumns)
+					if tt.titleAlreadyExists {
+						titleRows.AddRow(otherVideoID, tt.expectTitle, models.COMPLETE, t1, t1, t1, sourcePath, coverPath, nil, "", nil, nil)
+					}
+					mock.ExpectQuery(getVideoFromTitleQuery).WithArgs(tt.expectTitle).WillReturnRows(titleRows)
+				}
+
+				if tt.expectTitle != "" && !tt.titleAlreadyExists {
+					mock.ExpectBegin()
+
+					if tt.updateFail {
+						mock.ExpectExec(updateVideoMetadataQuery).
+							WithArgs(tt.expectTitle, tt.expectDescription, validVideoID).
+							WillReturnError(fmt.Errorf("Database error"))
+						mock.ExpectRollback()
+
+					} else if tt.titleTakenOnUpdate {
+						mock.ExpectExec(updateVideoMetadataQuery).
+							WithArgs(tt.expectTitle, tt.expectDescription, validVideoID).
+							WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+						mock.ExpectRollback()
+
+					} else {
+						mock.ExpectExec(updateVideoMetadataQuery).
+							WithArgs(tt.expectTitle, tt.expectDescription, validVideoID).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+
+						if tt.expectTags != nil {
+							mock.ExpectExec(deleteVideoTagsQuery).
+								WithArgs(validVideoID).
+								WillReturnResult(sqlmock.NewResult(0, 1))
+							for _, tag := range tt.expectTags {
+								mock.ExpectExec(addVideoTagQuery).
+									WithArgs(validVideoID, tag).
+									WillReturnResult(sqlmock.NewResult(1, 1))
+							}
+						}
+
+						mock.ExpectCommit()
+
+						if tt.expectTags == nil {
+							tagsRows := sqlmock.NewRows([]string{"tag"}).AddRow("existing")
+							mock.ExpectQuery(getVideoTagsQuery).WithArgs(validVideoID).WillReturnRows(tagsRows)
+						}
+					}
+				}
+			}
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+			tagsDAO, err := dao.CreateTagsDAO(context.Background(), db)
+			require.NoError(t, err)
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+			routerDAO := router.DAOs{
+				VideosDAO: *videosDAO,
+				TagsDAO:   *tagsDAO,
+				UsersDAO:  *usersDAO,
+			}
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &routerClients, &routerDAO)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodPatch, "/api/v1/videos/"+tt.giveID, strings.NewReader(tt.giveBody))
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			} else if tt.giveAccount {
+				req.SetBasicAuth(accountUsername, accountPassword)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedBody != "" {
+				require.Contains(t, w.Body.String(), tt.expectedBody)
+			}
+
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
+}
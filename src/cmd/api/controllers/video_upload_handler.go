@@ -1,8 +1,12 @@
 package controllers
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"io"
@@ -19,6 +23,7 @@ import (
 	"github.com/Sogilis/Voogle/src/pkg/clients"
 	"github.com/Sogilis/Voogle/src/pkg/events"
 
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
 	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
 	"github.com/Sogilis/Voogle/src/cmd/api/dto/protobuf"
@@ -41,117 +46,231 @@ type Response struct {
 	Links map[string]jsonDTO.LinkJson `json:"_links"`
 }
 
+// Limits of the parts of the upload form read in memory
+const (
+	maxUploadFieldSize = 1 << 10  // 1 KiB
+	maxCoverSize       = 10 << 20 // 10 MiB
+)
+
+var errFormPartTooLarge = errors.New("form part too large")
+
+// uploadForm is the multipart form of a video upload, read part by part as it is received. The title must be
+// sent before the video file, the cover before or after it.
+type uploadForm struct {
+	title     string
+	cover     []byte
+	coverName string
+
+	video      *models.Video
+	upload     *models.Upload // Upload of the video source, nil if the video is only sent again for encoding
+	sourceHash string
+}
+
 // VideoUploadHandler godoc
 // @Summary Upload video file
-// @Description Upload video file
+// @Description Upload video file. The title must be sent before the video file.
 // @Tags video
 // @Accept multipart/form-data
 // @Produce json
-// @Param file formData file true "video"
+// @Param title formData string true "Title of the video, before the video file"
+// @Param video formData file true "video"
+// @Param cover formData file false "JPEG or PNG cover image, of at most 10 MiB"
 // @Success 200 {object} Response "Video and Links (HATEOAS)"
 // @Failure 400 {string} string
-// @Failure 409 {string} string "This title already exists"
+// @Failure 409 {string} string "This title already exists, or this video source already exists (existing Video and Links as json)"
+// @Failure 413 {string} string "Cover image too large"
 // @Failure 415 {string} string
 // @Failure 500 {string} string
 // @Router /api/v1/videos/upload [post]
-func (v VideoUploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) { //nolint:cyclop
+func (v VideoUploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	log.Debug("POST VideoUploadHandler")
 
-	// Fetch title
-	title := r.FormValue("title")
-	if title == "" {
-		log.Error("Missing title file ")
+	// The form is read as it is received : the upload progress is the part of the request body received
+	received := &progressReader{reader: r.Body, total: r.ContentLength}
+	r.Body = io.NopCloser(received)
+
+	parts, err := r.MultipartReader()
+	if err != nil {
+		log.Error("Cannot read multipart form ", err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	log.Infof("Receive video upload request with title : '%v'", title)
 
-	// Fetch video
-	fileVideo, fileHandler, err := r.FormFile("video")
-	if err != nil {
-		log.Error("Missing file ", err)
+	form := &uploadForm{}
+	for {
+		part, err := parts.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			log.Error("Cannot read multipart form ", err)
+			v.uploadedSourceFailed(r.Context(), form)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		ok := v.receivePart(r.Context(), w, part, form, received)
+		_ = part.Close()
+		if !ok {
+			return
+		}
+
+		// A video failed to encode is only sent again for encoding : the rest of the form is not needed
+		if form.video != nil && form.upload == nil {
+			break
+		}
+	}
+
+	if form.video == nil {
+		log.Error("Missing video file")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	defer fileVideo.Close()
 
-	// Check if the received file is a supported video type
-	if !isSupportedVideoType(fileVideo) {
-		w.WriteHeader(http.StatusUnsupportedMediaType)
+	if form.upload != nil && !v.completeReceivedUpload(r.Context(), w, form) {
 		return
 	}
 
-	// Fetch cover image. Not mandatory
-	fileCover, fileHandlerCover, err := r.FormFile("cover")
-	if err != nil && !errors.Is(err, http.ErrMissingFile) {
-		log.Error("File cover error ", err)
-		w.WriteHeader(http.StatusBadRequest)
+	if err := v.sendVideoForEncoding(r.Context(), form.video); err != nil {
+		log.Error("Cannot send video for encoding : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	if fileCover != nil {
-		defer fileCover.Close()
+
+	// Include video and HATEOAS upload link into response
+	writeHTTPResponse(form.video, w)
+	log.Infof("Video '%v' successfully uploaded", form.video.Title)
+}
+
+// receivePart reads a part of the upload form. The response is written on failure, and the video source
+// already uploaded is removed.
+func (v VideoUploadHandler) receivePart(ctx context.Context, w http.ResponseWriter, part *multipart.Part, form *uploadForm, received *progressReader) bool {
+	switch part.FormName() {
+	case "title":
+		title, err := readFormPart(part, maxUploadFieldSize)
+		if err != nil {
+			log.Error("Cannot read title ", err)
+			v.uploadedSourceFailed(ctx, form)
+			w.WriteHeader(http.StatusBadRequest)
+			return false
+		}
+		form.title = string(title)
+
+	case "cover":
+		cover, err := readFormPart(part, maxCoverSize)
+		if err != nil {
+			log.Error("File cover error ", err)
+			v.uploadedSourceFailed(ctx, form)
+			if errors.Is(err, errFormPartTooLarge) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+			} else {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+			return false
+		}
 
 		// Check if the received file cover is a supported image type
-		if !isSupportedCoverType(fileCover) {
+		if !isSupportedCoverType(bytes.NewReader(cover)) {
+			v.uploadedSourceFailed(ctx, form)
 			w.WriteHeader(http.StatusUnsupportedMediaType)
-			return
+			return false
 		}
+		form.cover = cover
+		form.coverName = part.FileName()
+
+	case "video":
+		return v.receiveVideo(ctx, w, part, form, received)
+	}
+
+	return true
+}
+
+// receiveVideo checks the video file, then uploads it on S3 as it is received. The file of a video failed
+// to encode is not uploaded again.
+func (v VideoUploadHandler) receiveVideo(ctx context.Context, w http.ResponseWriter, part *multipart.Part, form *uploadForm, received *progressReader) bool {
+	if form.video != nil {
+		log.Error("Several video files")
+		v.uploadedSourceFailed(ctx, form)
+		w.WriteHeader(http.StatusBadRequest)
+		return false
+	}
+
+	if form.title == "" {
+		log.Error("Missing title before the video file")
+		w.WriteHeader(http.StatusBadRequest)
+		return false
+	}
+	log.Infof("Receive video upload request with title : '%v'", form.title)
+
+	// Check if the received file is a supported video type
+	source := bufio.NewReader(part)
+	head, _ := source.Peek(262) // As much as isSupportedVideoType reads
+	if !isSupportedVideoType(bytes.NewReader(head)) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return false
 	}
 
 	// Check if a video with this title already exists
-	video, err := v.VideosDAO.GetVideoFromTitle(r.Context(), title)
+	video, err := v.VideosDAO.GetVideoFromTitle(ctx, form.title)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		w.WriteHeader(http.StatusInternalServerError)
-		return
+		return false
 	}
+
+	videoID := ""
+	videoPath := ""
 	if video != nil {
 		// If a video with the same title already exists, and if its status is failed upload/encode,
 		// try to re-upload/re-encode as needed
-		if video.Status == models.FAIL_UPLOAD || video.Status == models.FAIL_ENCODE {
-			v.resumeVideoUpload(r.Context(), video, fileCover, fileVideo, fileHandlerCover, w)
-			return
-		} else {
+		if (video.Status != models.FAIL_UPLOAD && video.Status != models.FAIL_ENCODE) || !auth.CanManageVideo(ctx, video) {
 			// Title already exist, video already uploaded and encoded, return error
 			log.Error("A video with this title already uploaded and encoded")
 			http.Error(w, "This title already exists", http.StatusConflict)
-			return
+			return false
 		}
-	}
 
-	// Generate video UUID
-	videoID, err := v.UUIDGen.GenerateUuid()
-	if err != nil {
-		log.Error("Cannot generate new video ID : ", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+		if video.Status == models.FAIL_ENCODE {
+			log.Debug("Try to re-encode failed video")
+			form.video = video
+			return true
+		}
 
-	// Upload cover image (if exists) on S3, update database
-	coverPath, err := v.uploadCover(r.Context(), fileCover, videoID, fileHandlerCover)
-	if err != nil {
-		log.Error("Cannot upload cover image : ", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		// If the upload failed before the encoding started, then we have to fix the upload before resuming with the encoding.
+		log.Debug("Try to re-upload failed video")
+		videoID = video.ID
+		videoPath = video.SourcePath
+	} else {
+		// Generate video UUID
+		videoID, err = v.UUIDGen.GenerateUuid()
+		if err != nil {
+			log.Error("Cannot generate new video ID : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return false
+		}
+		videoPath = videoID + "/" + "source" + filepath.Ext(part.FileName())
 	}
 
 	// Upload video on S3, update database
-	videoPath := videoID + "/" + "source" + filepath.Ext(fileHandler.Filename)
-	videoCreated, err := v.uploadVideo(r.Context(), videoID, title, videoPath, coverPath, fileVideo, nil)
+	form.video, form.upload, form.sourceHash, err = v.uploadVideo(ctx, videoID, form.title, videoPath, source, video, received)
 	if err != nil {
 		log.Error("Cannot upload video : ", err)
 		w.WriteHeader(http.StatusInternalServerError)
-		return
+		return false
 	}
 
-	if err = v.sendVideoForEncoding(r.Context(), videoCreated); err != nil {
-		log.Error("Cannot send video for encoding : ", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+	return true
+}
 
-	// Include videoCreated and HATEOAS upload link into response
-	writeHTTPResponse(videoCreated, w)
-	log.Infof("Video '%v' successfully uploaded", title)
+// readFormPart reads a part of the form in memory, up to maxSize bytes
+func readFormPart(part io.Reader, maxSize int64) ([]byte, error) {
+	content, err := io.ReadAll(io.LimitReader(part, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > maxSize {
+		return nil, errFormPartTooLarge
+	}
+	return content, nil
 }
 
 func isSupportedVideoType(input io.ReaderAt) bool {
@@ -194,61 +313,20 @@ func isSupportedCoverType(input io.ReaderAt) bool {
 	return false
 }
 
-func (v VideoUploadHandler) resumeVideoUpload(ctx context.Context, video *models.Video, fileCover, fileVideo multipart.File, fileHandler *multipart.FileHeader, w http.ResponseWriter) {
-
-	// If the upload failed before the encoding started, then we have to fix the upload before resuming with the encoding.
-	if video.Status == models.FAIL_UPLOAD {
-		log.Debug("Try to re-upload failed video")
-		coverPath, err := v.uploadCover(ctx, fileCover, video.ID, fileHandler)
-		if err != nil {
-			log.Error("Cannot upload cover image : ", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-
-		video, err = v.uploadVideo(ctx, video.ID, video.Title, video.SourcePath, coverPath, fileVideo, video)
-		if err != nil {
-			log.Error("Cannot upload video : ", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-	}
-
-	log.Debug("Try to re-encode failed video")
-	if err := v.sendVideoForEncoding(ctx, video); err != nil {
-		log.Error("Cannot send video for encoding : ", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	// Include video and HATEOAS upload link into response
-	writeHTTPResponse(video, w)
-	log.Infof("Video '%v' successfully uploaded", video.Title)
-}
-
-func (v VideoUploadHandler) uploadCover(ctx context.Context, cover multipart.File, videoID string, fileHandler *multipart.FileHeader) (string, error) {
-	coverPath := ""
-	if cover != nil {
-		coverPath = videoID + "/" + "cover" + filepath.Ext(fileHandler.Filename)
-		if err := v.S3Client.PutObjectInput(ctx, cover, coverPath); err != nil {
-			log.Error("Cannot upload cover : ", err)
-			return "", err
-		}
-	}
-	return coverPath, nil
-}
-
-func (v VideoUploadHandler) uploadVideo(ctx context.Context, videoID, title, videoPath, coverPath string, file multipart.File, video *models.Video) (*models.Video, error) {
+// uploadVideo uploads the video source on S3 as it is received, saving the progress of the request along the
+// way, and returns the video, its upload and the SHA-256 of the source
+func (v VideoUploadHandler) uploadVideo(ctx context.Context, videoID, title, videoPath string, source io.Reader, video *models.Video, received *progressReader) (*models.Video, *models.Upload, string, error) {
 	metrics.CounterVideoUploadRequest.Inc()
 
 	// video not nil means that the video already exists. So we are in case of recover after error
 	if video == nil {
 		var err error
-		video, err = v.VideosDAO.CreateVideo(ctx, videoID, title, int(models.UPLOADING), videoPath, coverPath)
+		video, err = v.VideosDAO.CreateVideo(ctx, videoID, title, int(models.UPLOADING), videoPath, "", auth.OwnerID(ctx))
 		if err != nil {
 			metrics.CounterVideoUploadFail.Inc()
 			log.Error("Cannot generate new uploadID : ", err)
 
-			return nil, err
+			return nil, nil, "", err
 		}
 	}
 
@@ -257,7 +335,7 @@ func (v VideoUploadHandler) uploadVideo(ctx context.Context, videoID, title, vid
 		metrics.CounterVideoUploadFail.Inc()
 		log.Error("Cannot generate new uploadID : ", err)
 
-		return nil, err
+		return nil, nil, "", err
 	}
 
 	uploadCreated, err := v.UploadsDAO.CreateUpload(ctx, uploadID, video.ID, int(models.STARTED))
@@ -267,71 +345,254 @@ func (v VideoUploadHandler) uploadVideo(ctx context.Context, videoID, title, vid
 
 		v.videoUploadFailed(ctx, video)
 		v.publishStatus(video)
-		return nil, err
+		return nil, nil, "", err
 	}
 
-	// Upload video on S3
-	err = v.S3Client.PutObjectInput(ctx, file, video.SourcePath)
+	// Upload video on S3, and save the progress and compute the source hash along the way
+	hash := sha256.New()
+	received.onProgress = func(progress int) {
+		v.saveUploadProgress(ctx, video, uploadCreated, progress)
+	}
+	err = v.S3Client.PutObjectInput(ctx, io.TeeReader(source, hash), video.SourcePath)
+	received.onProgress = nil
 	if err != nil {
 		metrics.CounterVideoUploadFail.Inc()
 		log.Error("Unable to put object input on S3 ", err)
 
 		if err := v.videoAndUploadFailed(ctx, video, uploadCreated); err != nil {
 			log.Error("video and upload status failed : ", err)
-			return nil, err
+			return nil, nil, "", err
 		}
 
-		return nil, err
+		return nil, nil, "", err
 	}
 	log.Debug("Success upload video " + video.ID + " on S3")
 
+	return video, uploadCreated, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// completeReceivedUpload uploads the cover of the form, then saves the source hash and completes the upload
+func (v VideoUploadHandler) completeReceivedUpload(ctx context.Context, w http.ResponseWriter, form *uploadForm) bool {
+	if form.cover != nil {
+		coverPath := form.video.ID + "/" + "cover" + filepath.Ext(form.coverName)
+		if err := v.S3Client.PutObjectInput(ctx, bytes.NewReader(form.cover), coverPath); err != nil {
+			log.Error("Cannot upload cover image : ", err)
+			v.uploadedSourceFailed(ctx, form)
+			w.WriteHeader(http.StatusInternalServerError)
+			return false
+		}
+		form.video.CoverPath = coverPath
+	}
+
+	if err := v.saveSourceHash(ctx, form.video, form.upload, form.sourceHash); err != nil {
+		log.Error("Cannot upload video : ", err)
+		writeUploadError(err, w)
+		return false
+	}
+
+	if err := v.completeUpload(ctx, form.video, form.upload); err != nil {
+		log.Error("Cannot upload video : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+
+	metrics.CounterVideoUploadSuccess.Inc()
+	return true
+}
+
+// uploadedSourceFailed marks the upload of the form as failed and removes its source, once uploaded
+func (v VideoUploadHandler) uploadedSourceFailed(ctx context.Context, form *uploadForm) {
+	if form.upload == nil {
+		return
+	}
+
+	metrics.CounterVideoUploadFail.Inc()
+	v.sourceFailed(ctx, form.video, form.upload)
+}
+
+// duplicateSourceError is returned when the uploaded source is the same file as the source of an existing video
+type duplicateSourceError struct {
+	video *models.Video
+}
+
+func (e duplicateSourceError) Error() string {
+	return "same source as video " + e.video.ID
+}
+
+// saveSourceHash saves the hash of the uploaded source. If another video already has
+// the same source, the uploaded video is removed and a duplicateSourceError is returned.
+func (v VideoUploadHandler) saveSourceHash(ctx context.Context, video *models.Video, upload *models.Upload, sourceHash string) error {
+	existing, err := v.VideosDAO.GetVideoFromSourceHash(ctx, sourceHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		metrics.CounterVideoUploadFail.Inc()
+		v.sourceFailed(ctx, video, upload)
+		return err
+	}
+
+	if existing != nil && existing.ID != video.ID {
+		return v.duplicateSource(ctx, video, existing)
+	}
+
+	// The unique constraint on the source hash also rejects the same source uploaded twice at the same time
+	video.SourceHash = &sourceHash
+	if err := v.VideosDAO.UpdateVideoSourceHash(ctx, video); err != nil {
+		video.SourceHash = nil
+		if errors.Is(err, dao.ErrDuplicateSourceHash) {
+			// The other video saved the same hash first
+			existing, err := v.VideosDAO.GetVideoFromSourceHash(ctx, sourceHash)
+			if err == nil {
+				return v.duplicateSource(ctx, video, existing)
+			}
+			log.Error("Cannot get the video with the same source : ", err)
+		}
+
+		metrics.CounterVideoUploadFail.Inc()
+		v.sourceFailed(ctx, video, upload)
+		return err
+	}
+
+	return nil
+}
+
+// duplicateSource removes the uploaded video, which has the same source as the existing video
+func (v VideoUploadHandler) duplicateSource(ctx context.Context, video *models.Video, existing *models.Video) error {
+	log.Infof("Video %v has the same source as video %v, remove it", video.ID, existing.ID)
+	if err := v.removeDuplicateVideo(ctx, video); err != nil {
+		return err
+	}
+
+	return duplicateSourceError{video: existing}
+}
+
+// saveStoredSourceHash hashes the source of a video uploaded on S3 without going
+// through the API, then saves it as saveSourceHash does.
+func (v VideoUploadHandler) saveStoredSourceHash(ctx context.Context, video *models.Video, upload *models.Upload) error {
+	sourceHash, err := v.hashStoredSource(ctx, video)
+	if err != nil {
+		metrics.CounterVideoUploadFail.Inc()
+		v.sourceFailed(ctx, video, upload)
+		return err
+	}
+
+	return v.saveSourceHash(ctx, video, upload, sourceHash)
+}
+
+// hashStoredSource reads the whole video source from S3 to compute its hash
+func (v VideoUploadHandler) hashStoredSource(ctx context.Context, video *models.Video) (string, error) {
+	source, err := v.S3Client.GetObject(ctx, video.SourcePath)
+	if err != nil {
+		log.Error("Cannot get video source on S3 : ", err)
+		return "", err
+	}
+	if closer, ok := source.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, source); err != nil {
+		log.Error("Cannot read video source on S3 : ", err)
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// sourceFailed marks the video and its upload as failed, and removes the uploaded source
+func (v VideoUploadHandler) sourceFailed(ctx context.Context, video *models.Video, upload *models.Upload) {
+	if err := v.videoAndUploadFailed(ctx, video, upload); err != nil {
+		log.Error("video and upload status failed : ", err)
+	}
+
+	if err := v.S3Client.RemoveObject(ctx, video.SourcePath); err != nil {
+		log.Errorf("Unable to remove uploaded video  %v : %v", video.ID, err)
+	}
+}
+
+// removeDuplicateVideo removes the video, its uploads, and its files from S3
+func (v VideoUploadHandler) removeDuplicateVideo(ctx context.Context, video *models.Video) error {
+	tx, err := v.VideosDAO.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("Cannot open new database transaction : ", err)
+		return err
+	}
+
+	// Defer a rollback in case anything fails.
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := v.UploadsDAO.DeleteUploadTx(ctx, tx, video.ID); err != nil {
+		log.Error("Cannot delete video "+video.ID+" uploads : ", err)
+		return err
+	}
+
+	if err := v.VideosDAO.DeleteVideoTx(ctx, tx, video.ID); err != nil {
+		log.Error("Cannot delete video "+video.ID+" : ", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("Cannot commit database transaction")
+		return err
+	}
+
+	if err := v.S3Client.RemoveObjectsWithPrefix(ctx, video.ID+"/"); err != nil {
+		log.Error("Cannot remove video "+video.ID+" from S3 : ", err)
+		return err
+	}
+
+	return nil
+}
+
+// completeUpload marks the video as UPLOADED and its upload as DONE, once the
+// video source is on S3. On failure, the source is removed from S3.
+func (v VideoUploadHandler) completeUpload(ctx context.Context, video *models.Video, upload *models.Upload) error {
 	// Same time for videos and uploads
 	uploadDate := time.Now()
 
 	// Update videos status : UPLOADED + Upload date
 	video.Status = models.UPLOADED
 	video.UploadedAt = &uploadDate
-	if err = v.VideosDAO.UpdateVideo(ctx, video); err != nil {
+	if err := v.VideosDAO.UpdateVideo(ctx, video); err != nil {
 		metrics.CounterVideoUploadFail.Inc()
 		log.Errorf("Unable to update video with status  %v : %v", video.Status, err)
 
-		if err := v.videoAndUploadFailed(ctx, video, uploadCreated); err != nil {
+		if err := v.videoAndUploadFailed(ctx, video, upload); err != nil {
 			log.Error("video and upload status failed : ", err)
-			return nil, err
+			return err
 		}
 
 		if err := v.S3Client.RemoveObject(ctx, video.SourcePath); err != nil {
 			log.Errorf("Unable to remove uploaded video  %v : %v", video.ID, err)
-			return nil, err
+			return err
 		}
 
-		return nil, err
+		return err
 	}
 
 	v.publishStatus(video)
 
 	// Update uploads status : DONE + Upload date
-	uploadCreated.Status = models.DONE
-	uploadCreated.UploadedAt = &uploadDate
-	if err = v.UploadsDAO.UpdateUpload(ctx, uploadCreated); err != nil {
+	upload.Status = models.DONE
+	upload.UploadedAt = &uploadDate
+	if err := v.UploadsDAO.UpdateUpload(ctx, upload); err != nil {
 		metrics.CounterVideoUploadFail.Inc()
-		log.Errorf("Unable to update upload with status  %v: %v", uploadCreated.Status, err)
+		log.Errorf("Unable to update upload with status  %v: %v", upload.Status, err)
 
-		if err := v.videoAndUploadFailed(ctx, video, uploadCreated); err != nil {
+		if err := v.videoAndUploadFailed(ctx, video, upload); err != nil {
 			log.Error("video and upload status failed : ", err)
-			return nil, err
+			return err
 		}
 
 		if err := v.S3Client.RemoveObject(ctx, video.SourcePath); err != nil {
 			log.Errorf("Unable to remove uploaded video  %v : %v", video.ID, err)
-			return nil, err
+			return err
 		}
 
-		return nil, err
+		return err
 	}
 
-	metrics.CounterVideoUploadSuccess.Inc()
-	return video, nil
+	return nil
 }
 
 func (v VideoUploadHandler) sendVideoForEncoding(ctx context.Context, video *models.Video) error {
@@ -416,7 +677,22 @@ func (v VideoUploadHandler) videoAndUploadFailed(ctx context.Context, video *mod
 }
 
 func writeHTTPResponse(video *models.Video, w http.ResponseWriter) {
-	// Include videoCreated and status link into response (HATEOAS)
+	writeVideoResponse(video, http.StatusOK, w)
+}
+
+// writeUploadError answers with the existing video when the source is a duplicate
+func writeUploadError(err error, w http.ResponseWriter) {
+	var duplicate duplicateSourceError
+	if errors.As(err, &duplicate) {
+		writeVideoResponse(duplicate.video, http.StatusConflict, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusInternalServerError)
+}
+
+func writeVideoResponse(video *models.Video, statusCode int, w http.ResponseWriter) {
+	// Include video and status link into response (HATEOAS)
 	links := map[string]jsonDTO.LinkJson{
 		"status": jsonDTO.LinkToLinkJson(&models.Link{Href: "api/v1/videos/" + video.ID + "/status", Method: "GET"}),
 		"stream": jsonDTO.LinkToLinkJson(&models.Link{Href: "api/v1/videos/" + video.ID + "/streams/master.m3u8", Method: "GET"}),
@@ -433,11 +709,19 @@ func writeHTTPResponse(video *models.Video, w http.ResponseWriter) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	w.WriteHeader(statusCode)
 	_, _ = w.Write(payload)
 }
 
 func (v VideoUploadHandler) publishStatus(video *models.Video) {
-	msg, err := proto.Marshal(protobuf.VideoToVideoProtobuf(video))
+	v.publishUploadProgress(video, 0)
+}
+
+func (v VideoUploadHandler) publishUploadProgress(video *models.Video, progress int) {
+	videoProto := protobuf.VideoToVideoProtobuf(video)
+	videoProto.UploadProgress = int32(progress)
+
+	msg, err := proto.Marshal(videoProto)
 	if err != nil {
 		log.Error("Failed to Marshal status", err)
 		return
@@ -447,3 +731,41 @@ func (v VideoUploadHandler) publishStatus(video *models.Video) {
 		log.Error("Unable to publish status update", err)
 	}
 }
+
+// saveUploadProgress is best effort : the upload goes on even if the progress cannot be saved
+func (v VideoUploadHandler) saveUploadProgress(ctx context.Context, video *models.Video, upload *models.Upload, progress int) {
+	upload.Progress = progress
+	if err := v.UploadsDAO.UpdateUploadProgress(ctx, upload); err != nil {
+		log.Error("Unable to save upload progress : ", err)
+	}
+
+	v.publishUploadProgress(video, progress)
+}
+
+// UploadProgressStep is the minimum progress (in percent) between two saves of the upload progress
+const UploadProgressStep = 5
+
+// progressReader calls onProgress, when set, each time the progress moves forward of at least
+// UploadProgressStep. 100% is never reported : the end of the upload is saved with its status.
+type progressReader struct {
+	reader     io.Reader
+	total      int64
+	read       int64
+	progress   int
+	onProgress func(progress int)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	p.read += int64(n)
+
+	if p.total > 0 && p.onProgress != nil {
+		progress := int(p.read * 100 / p.total)
+		if progress < 100 && progress >= p.progress+UploadProgressStep {
+			p.progress = progress
+			p.onProgress(progress)
+		}
+	}
+
+	return n, err
+}
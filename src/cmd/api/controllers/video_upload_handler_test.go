@@ -11,15 +11,20 @@ import (
 	"net/http/httptest"
 	"os"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/Sogilis/Voogle/src/pkg/clients"
+	contracts "github.com/Sogilis/Voogle/src/pkg/contracts/v1"
 
 	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
 	"github.com/Sogilis/Voogle/src/cmd/api/models"
@@ -37,6 +42,15 @@ func (a AnyTime) Match(v driver.Value) bool {
 	return ok
 }
 
+// Used to mock the SHA-256 of the uploaded source, computed while it is sent to S3
+type AnySourceHash struct{}
+
+// Match satisfies sqlmock.Argument interface
+func (a AnySourceHash) Match(v driver.Value) bool {
+	hash, ok := v.(string)
+	return ok && regexp.MustCompile("^[0-9a-f]{64}$").MatchString(hash)
+}
+
 func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 	givenUsername := "dev"
 	givenUserPwd := "test"
@@ -56,9 +70,12 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 		lastUploadFailed        bool
 		lastEncodeFailed        bool
 		titleAlreadyExists      bool
+		sourceAlreadyExists     bool
+		sourceSavedConcurrently bool
 		createVideoFail         bool
 		createUploadFail        bool
 		uploadVideoOnS3fail     bool
+		uploadCoverOnS3fail     bool
 		videoUpdateUploadedFail bool
 		uploadUpdateDoneFail    bool
 		publishToEncoderFail    bool
@@ -241,6 +258,34 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 			putObject:          func(f io.Reader, s string) error { _, err := io.ReadAll(f); return err },
 			amqpClientPublish:  func(string, []byte) error { return nil },
 		},
+		{
+			name:                "POST fails with source already exist",
+			giveRequest:         "/api/v1/videos/upload",
+			giveWithAuth:        true,
+			giveTitle:           "title-of-video",
+			giveFieldVideo:      "video",
+			giveCover:           "cover.jpg",
+			giveFieldCover:      "cover",
+			sourceAlreadyExists: true,
+			expectedHTTPCode:    409,
+			genUUID:             func() (string, error) { return "AUniqueId", nil },
+			putObject:           func(f io.Reader, s string) error { _, err := io.ReadAll(f); return err },
+			amqpClientPublish:   func(string, []byte) error { return nil },
+		},
+		{
+			name:                    "POST fails with same source saved concurrently",
+			giveRequest:             "/api/v1/videos/upload",
+			giveWithAuth:            true,
+			giveTitle:               "title-of-video",
+			giveFieldVideo:          "video",
+			giveCover:               "cover.jpg",
+			giveFieldCover:          "cover",
+			sourceSavedConcurrently: true,
+			expectedHTTPCode:        409,
+			genUUID:                 func() (string, error) { return "AUniqueId", nil },
+			putObject:               func(f io.Reader, s string) error { _, err := io.ReadAll(f); return err },
+			amqpClientPublish:       func(string, []byte) error { return nil },
+		},
 		{
 			name:              "POST fails with create video fail",
 			giveRequest:       "/api/v1/videos/upload",
@@ -320,10 +365,16 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 			giveCover:           "cover.jpg",
 			giveFieldCover:      "cover",
 			expectedHTTPCode:    500,
-			uploadVideoOnS3fail: true,
+			uploadCoverOnS3fail: true,
 			genUUID:             func() (string, error) { return "AUniqueId", nil },
-			putObject:           func(f io.Reader, s string) error { return fmt.Errorf("Cannot upload on S3") },
-			amqpClientPublish:   func(string, []byte) error { return nil },
+			putObject: func(f io.Reader, s string) error {
+				if strings.HasSuffix(s, "/cover.jpg") {
+					return fmt.Errorf("Cannot upload on S3")
+				}
+				_, err := io.ReadAll(f)
+				return err
+			},
+			amqpClientPublish: func(string, []byte) error { return nil },
 		},
 		{
 			name:                 "POST fails with publish encode request fail",
@@ -351,7 +402,7 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
 
-			s3Client := clients.NewS3ClientDummy(nil, nil, tt.putObject, nil, removeObject)
+			s3Client := clients.NewS3ClientDummy(nil, nil, tt.putObject, nil, removeObject, nil, nil, nil, nil, nil, nil, nil, nil, removeObject)
 			amqpClient := clients.NewAmqpClientDummy(tt.amqpClientPublish, nil, nil)
 			amqpVideoStatusUpdate := clients.NewAmqpClientDummy(nil, nil, nil)
 
@@ -371,7 +422,7 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 			dao_test.ExpectUploadsDAOCreation(mock)
 
 			if tt.giveTitle == "" || tt.giveEmptyBody || tt.giveFieldVideo == "NOT-video" ||
-				tt.giveWrongMagic || !tt.giveWithAuth || tt.giveCover == "cover.gif" {
+				tt.giveWrongMagic || !tt.giveWithAuth {
 				// All these cases will stop before modifying the database : Nothing to do
 
 			} else {
@@ -381,14 +432,18 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 				updateVideoQuery := regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo])
 				getVideoFromTitleQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideoFromTitle])
 				getVideoFromIdQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])
+				getVideoFromSourceHashQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideoFromSourceHash])
+				updateVideoSourceHashQuery := regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideoSourceHash])
+				deleteVideoQuery := regexp.QuoteMeta(dao.VideosRequests[dao.DeleteVideo])
 
 				createUploadQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.CreateUpload])
 				updateUploadQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.UpdateUpload])
 				getUploadQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.GetUpload])
+				deleteUploadQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.DeleteUpload])
 
 				// Tables
-				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path"}
-				uploadsColumns := []string{"id", "video_id", "upload_status", "uploaded_at", "created_at", "updated_at"}
+				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
+				uploadsColumns := []string{"id", "video_id", "upload_status", "uploaded_at", "created_at", "updated_at", "upload_length", "upload_offset", "upload_parts", "s3_upload_id", "upload_progress"}
 				videosRows := sqlmock.NewRows(videosColumns)
 				uploadRows := sqlmock.NewRows(uploadsColumns)
 
@@ -404,44 +459,43 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 				}
 
 				if tt.titleAlreadyExists {
-					res := sqlmock.NewRows(videosColumns).AddRow(VideoID, tt.giveTitle, models.UPLOADING, nil, t1, t1, sourcePath, coverPath)
+					res := sqlmock.NewRows(videosColumns).AddRow(VideoID, tt.giveTitle, models.UPLOADING, nil, t1, t1, sourcePath, coverPath, nil, "", nil, nil)
 					mock.ExpectQuery(getVideoFromTitleQuery).WithArgs(tt.giveTitle).WillReturnRows(res)
 
-				} else if tt.uploadVideoOnS3fail {
+				} else if tt.uploadVideoOnS3fail || tt.uploadCoverOnS3fail || tt.giveCover == "cover.gif" {
+					// The video is uploaded before the cover is checked and uploaded
 					mock.ExpectQuery(getVideoFromTitleQuery).WithArgs(tt.giveTitle).WillReturnRows(videosRows)
 
-					if tt.giveCover == "" {
-						// Create Video
-						mock.ExpectExec(createVideoQuery).
-							WithArgs(VideoID, tt.giveTitle, models.UPLOADING, sourcePath, coverPath).
-							WillReturnResult(sqlmock.NewResult(1, 1))
+					// Create Video
+					mock.ExpectExec(createVideoQuery).
+						WithArgs(VideoID, tt.giveTitle, models.UPLOADING, sourcePath, "", nil).
+						WillReturnResult(sqlmock.NewResult(1, 1))
 
-						res := sqlmock.NewRows(videosColumns).AddRow(VideoID, tt.giveTitle, models.UPLOADING, nil, t1, t1, sourcePath, coverPath)
-						mock.ExpectQuery(getVideoFromIdQuery).WithArgs(VideoID).WillReturnRows(res)
+					res := sqlmock.NewRows(videosColumns).AddRow(VideoID, tt.giveTitle, models.UPLOADING, nil, t1, t1, sourcePath, "", nil, "", nil, nil)
+					mock.ExpectQuery(getVideoFromIdQuery).WithArgs(VideoID).WillReturnRows(res)
 
-						// Create Upload
-						mock.ExpectExec(createUploadQuery).
-							WithArgs(UploadID, VideoID, models.STARTED).
-							WillReturnResult(sqlmock.NewResult(1, 1))
+					// Create Upload
+					mock.ExpectExec(createUploadQuery).
+						WithArgs(UploadID, VideoID, models.STARTED).
+						WillReturnResult(sqlmock.NewResult(1, 1))
 
-						uploadRows.AddRow(UploadID, VideoID, models.STARTED, nil, t1, t1)
-						mock.ExpectQuery(getUploadQuery).WithArgs(VideoID).WillReturnRows(uploadRows)
+					uploadRows.AddRow(UploadID, VideoID, models.STARTED, nil, t1, t1, 0, 0, 0, "", 0)
+					mock.ExpectQuery(getUploadQuery).WithArgs(VideoID).WillReturnRows(uploadRows)
 
-						// Expect transaction
-						mock.ExpectBegin()
+					// Expect transaction
+					mock.ExpectBegin()
 
-						// Update video status : FAIL_UPLOAD
-						mock.ExpectExec(updateVideoQuery).
-							WithArgs(tt.giveTitle, models.FAIL_UPLOAD, nil, sourcePath, coverPath, VideoID).
-							WillReturnResult(sqlmock.NewResult(0, 1))
+					// Update video status : FAIL_UPLOAD
+					mock.ExpectExec(updateVideoQuery).
+						WithArgs(tt.giveTitle, models.FAIL_UPLOAD, nil, sourcePath, "", VideoID).
+						WillReturnResult(sqlmock.NewResult(0, 1))
 
-						// Update uploads status : DONE + Upload date
-						mock.ExpectExec(updateUploadQuery).
-							WithArgs(VideoID, models.FAILED, nil, UploadID).
-							WillReturnResult(sqlmock.NewResult(0, 1))
+					// Update uploads status : FAILED
+					mock.ExpectExec(updateUploadQuery).
+						WithArgs(VideoID, models.FAILED, nil, UploadID).
+						WillReturnResult(sqlmock.NewResult(0, 1))
 
-						mock.ExpectCommit()
-					}
+					mock.ExpectCommit()
 
 				} else if errVideoID != nil || errUploadID != nil {
 					mock.ExpectQuery(getVideoFromTitleQuery).WithArgs(tt.giveTitle).WillReturnRows(videosRows)
@@ -451,11 +505,11 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 
 					// Create Video (fail)
 					mock.ExpectExec(createVideoQuery).
-						WithArgs(VideoID, tt.giveTitle, models.UPLOADING, sourcePath, coverPath).
+						WithArgs(VideoID, tt.giveTitle, models.UPLOADING, sourcePath, "", nil).
 						WillReturnError(fmt.Errorf("Error while creating new video"))
 
 				} else if tt.lastEncodeFailed {
-					res := sqlmock.NewRows(videosColumns).AddRow(VideoID, tt.giveTitle, models.FAIL_ENCODE, nil, t1, t1, sourcePath, coverPath)
+					res := sqlmock.NewRows(videosColumns).AddRow(VideoID, tt.giveTitle, models.FAIL_ENCODE, nil, t1, t1, sourcePath, coverPath, nil, "", nil, nil)
 					mock.ExpectQuery(getVideoFromTitleQuery).WithArgs(tt.giveTitle).WillReturnRows(res)
 
 					// Update video status : ENCODING
@@ -465,18 +519,18 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 
 				} else {
 					if tt.lastUploadFailed {
-						res := sqlmock.NewRows(videosColumns).AddRow(VideoID, tt.giveTitle, models.FAIL_UPLOAD, nil, t1, t1, sourcePath, coverPath)
+						res := sqlmock.NewRows(videosColumns).AddRow(VideoID, tt.giveTitle, models.FAIL_UPLOAD, nil, t1, t1, sourcePath, coverPath, nil, "", nil, nil)
 						mock.ExpectQuery(getVideoFromTitleQuery).WithArgs(tt.giveTitle).WillReturnRows(res)
 
 					} else {
 						mock.ExpectQuery(getVideoFromTitleQuery).WithArgs(tt.giveTitle).WillReturnRows(videosRows)
 
-						// Create Video
+						// Create Video, its cover being uploaded after it
 						mock.ExpectExec(createVideoQuery).
-							WithArgs(VideoID, tt.giveTitle, models.UPLOADING, sourcePath, coverPath).
+							WithArgs(VideoID, tt.giveTitle, models.UPLOADING, sourcePath, "", nil).
 							WillReturnResult(sqlmock.NewResult(1, 1))
 
-						res := sqlmock.NewRows(videosColumns).AddRow(VideoID, tt.giveTitle, models.UPLOADING, nil, t1, t1, sourcePath, coverPath)
+						res := sqlmock.NewRows(videosColumns).AddRow(VideoID, tt.giveTitle, models.UPLOADING, nil, t1, t1, sourcePath, "", nil, "", nil, nil)
 						mock.ExpectQuery(getVideoFromIdQuery).WithArgs(VideoID).WillReturnRows(res)
 					}
 
@@ -488,7 +542,7 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 
 						// Update videos status : FAIL_UPLOAD
 						mock.ExpectExec(updateVideoQuery).
-							WithArgs(tt.giveTitle, models.FAIL_UPLOAD, nil, sourcePath, coverPath, VideoID).
+							WithArgs(tt.giveTitle, models.FAIL_UPLOAD, nil, sourcePath, "", VideoID).
 							WillReturnResult(sqlmock.NewResult(0, 1))
 
 					} else {
@@ -497,10 +551,56 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 							WithArgs(UploadID, VideoID, models.STARTED).
 							WillReturnResult(sqlmock.NewResult(1, 1))
 
-						uploadRows.AddRow(UploadID, VideoID, models.STARTED, nil, t1, t1)
+						uploadRows.AddRow(UploadID, VideoID, models.STARTED, nil, t1, t1, 0, 0, 0, "", 0)
 						mock.ExpectQuery(getUploadQuery).WithArgs(VideoID).WillReturnRows(uploadRows)
 
-						if tt.videoUpdateUploadedFail {
+						if tt.sourceAlreadyExists {
+							// Another video has the same source
+							res := sqlmock.NewRows(videosColumns).AddRow("AnotherId", "another-title", models.COMPLETE, t1, t1, t1, "AnotherId/source.mp4", "", "hash", "", nil, nil)
+							mock.ExpectQuery(getVideoFromSourceHashQuery).WithArgs(AnySourceHash{}).WillReturnRows(res)
+
+							// Remove the uploaded video
+							mock.ExpectBegin()
+							mock.ExpectExec(deleteUploadQuery).
+								WithArgs(VideoID).
+								WillReturnResult(sqlmock.NewResult(0, 1))
+							mock.ExpectExec(deleteVideoQuery).
+								WithArgs(VideoID).
+								WillReturnResult(sqlmock.NewResult(0, 1))
+							mock.ExpectCommit()
+
+						} else if tt.sourceSavedConcurrently {
+							// Another video with the same source saves its hash first
+							mock.ExpectQuery(getVideoFromSourceHashQuery).WithArgs(AnySourceHash{}).WillReturnRows(sqlmock.NewRows(videosColumns))
+							mock.ExpectExec(updateVideoSourceHashQuery).
+								WithArgs(AnySourceHash{}, VideoID).
+								WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+
+							res := sqlmock.NewRows(videosColumns).AddRow("AnotherId", "another-title", models.UPLOADED, t1, t1, t1, "AnotherId/source.mp4", "", "hash", "", nil, nil)
+							mock.ExpectQuery(getVideoFromSourceHashQuery).WithArgs(AnySourceHash{}).WillReturnRows(res)
+
+							// Remove the uploaded video
+							mock.ExpectBegin()
+							mock.ExpectExec(deleteUploadQuery).
+								WithArgs(VideoID).
+								WillReturnResult(sqlmock.NewResult(0, 1))
+							mock.ExpectExec(deleteVideoQuery).
+								WithArgs(VideoID).
+								WillReturnResult(sqlmock.NewResult(0, 1))
+							mock.ExpectCommit()
+
+						} else {
+							// Source not uploaded yet
+							mock.ExpectQuery(getVideoFromSourceHashQuery).WithArgs(AnySourceHash{}).WillReturnRows(sqlmock.NewRows(videosColumns))
+							mock.ExpectExec(updateVideoSourceHashQuery).
+								WithArgs(AnySourceHash{}, VideoID).
+								WillReturnResult(sqlmock.NewResult(0, 1))
+						}
+
+						if tt.sourceAlreadyExists || tt.sourceSavedConcurrently {
+							// The video has been removed : Nothing to do
+
+						} else if tt.videoUpdateUploadedFail {
 							// Update videos status : UPLOADED + Upload date
 							mock.ExpectExec(updateVideoQuery).
 								WithArgs(tt.giveTitle, models.UPLOADED, AnyTime{}, sourcePath, coverPath, VideoID).
@@ -651,10 +751,11 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 				UploadsDAO: *uploadsDAO,
 			}
 
-			r := router.NewRouter(config.Config{
+			r, err := router.NewRouter(config.Config{
 				UserAuth: givenUsername,
 				PwdAuth:  givenUserPwd,
 			}, &routerClients, &routerDAO)
+			require.NoError(t, err)
 
 			w := httptest.NewRecorder()
 
@@ -667,9 +768,150 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 			r.ServeHTTP(w, req)
 			require.Equal(t, tt.expectedHTTPCode, w.Code)
 
+			if tt.sourceAlreadyExists || tt.sourceSavedConcurrently {
+				// The existing video is returned
+				require.Contains(t, w.Body.String(), `"id":"AnotherId"`)
+			}
+
 			// we make sure that all expectations were met
 			err = mock.ExpectationsWereMet()
 			require.NoError(t, err)
 		})
 	}
 }
+
+// chunkedReader returns at most size bytes per read, like a request body received over time
+type chunkedReader struct {
+	reader io.Reader
+	size   int
+	read   int
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if len(p) > c.size {
+		p = p[:c.size]
+	}
+	n, err := c.reader.Read(p)
+	c.read += n
+	return n, err
+}
+
+func TestVideoUploadProgress(t *testing.T) {
+	givenUsername := "dev"
+	givenUserPwd := "test"
+	givenTitle := "title-of-video"
+	videoID := "AUniqueId"
+	sourcePath := videoID + "/source.webm"
+
+	// Webm magic number, then the content of the video
+	video := append([]byte{
+		0x1a, 0x45, 0xdf, 0xa3, 0x9f, 0x42, 0x86, 0x81, 0x01, 0x42, 0xf7, 0x81, 0x01, 0x42, 0xf2, 0x81,
+		0x04, 0x42, 0xf3, 0x81, 0x08, 0x42, 0x82, 0x84, 0x77, 0x65, 0x62, 0x6d, 0x42, 0x87, 0x81, 0x02,
+		0x42, 0x85, 0x81, 0x02, 0x18, 0x53, 0x80, 0x67, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x4a, 0xf7,
+	}, make([]byte, 100000)...)
+
+	form := new(bytes.Buffer)
+	writer := multipart.NewWriter(form)
+	require.NoError(t, writer.WriteField("title", givenTitle))
+	fileWriter, err := writer.CreateFormFile("video", "video.webm")
+	require.NoError(t, err)
+	_, err = fileWriter.Write(video)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	// The body is received by reads of at most 1% : the progress goes through each multiple of 5%
+	bodySize := form.Len()
+	body := &chunkedReader{reader: form, size: bodySize / 100}
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	dao_test.ExpectVideosDAOCreation(mock)
+	dao_test.ExpectUploadsDAOCreation(mock)
+
+	videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
+	uploadsColumns := []string{"id", "video_id", "upload_status", "uploaded_at", "created_at", "updated_at", "upload_length", "upload_offset", "upload_parts", "s3_upload_id", "upload_progress"}
+	t1 := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideoFromTitle])).WithArgs(givenTitle).WillReturnRows(sqlmock.NewRows(videosColumns))
+	mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.CreateVideo])).
+		WithArgs(videoID, givenTitle, models.UPLOADING, sourcePath, "", nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WithArgs(videoID).
+		WillReturnRows(sqlmock.NewRows(videosColumns).AddRow(videoID, givenTitle, models.UPLOADING, nil, t1, t1, sourcePath, "", nil, "", nil, nil))
+	mock.ExpectExec(regexp.QuoteMeta(dao.UploadsRequests[dao.CreateUpload])).
+		WithArgs(videoID, videoID, models.STARTED).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(dao.UploadsRequests[dao.GetUpload])).WithArgs(videoID).
+		WillReturnRows(sqlmock.NewRows(uploadsColumns).AddRow(videoID, videoID, models.STARTED, nil, t1, t1, 0, 0, 0, "", 0))
+
+	expectedProgress := []int{}
+	for progress := controllers.UploadProgressStep; progress < 100; progress += controllers.UploadProgressStep {
+		expectedProgress = append(expectedProgress, progress)
+		mock.ExpectExec(regexp.QuoteMeta(dao.UploadsRequests[dao.UpdateUploadProgress])).
+			WithArgs(progress, videoID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideoFromSourceHash])).WithArgs(AnySourceHash{}).WillReturnRows(sqlmock.NewRows(videosColumns))
+	mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideoSourceHash])).
+		WithArgs(AnySourceHash{}, videoID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo])).
+		WithArgs(givenTitle, models.UPLOADED, AnyTime{}, sourcePath, "", videoID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(dao.UploadsRequests[dao.UpdateUpload])).
+		WithArgs(videoID, models.DONE, AnyTime{}, videoID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo])).
+		WithArgs(givenTitle, models.ENCODING, AnyTime{}, sourcePath, "", videoID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Each progress pushed is the part of the request body received
+	pushedProgress := []int{}
+	amqpVideoStatusUpdate := clients.NewAmqpClientDummy(func(_ string, msg []byte) error {
+		status := &contracts.Video{}
+		require.NoError(t, proto.Unmarshal(msg, status))
+		if status.UploadProgress > 0 {
+			require.Equal(t, body.read*100/bodySize, int(status.UploadProgress))
+			pushedProgress = append(pushedProgress, int(status.UploadProgress))
+		}
+		return nil
+	}, nil, nil)
+
+	var uploaded []byte
+	s3Client := clients.NewS3ClientDummy(nil, nil, func(f io.Reader, s string) error {
+		uploaded, err = io.ReadAll(f)
+		return err
+	}, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+	require.NoError(t, err)
+	uploadsDAO, err := dao.CreateUploadsDAO(context.Background(), db)
+	require.NoError(t, err)
+
+	r, err := router.NewRouter(config.Config{
+		UserAuth: givenUsername,
+		PwdAuth:  givenUserPwd,
+	}, &router.Clients{
+		S3Client:              s3Client,
+		AmqpClient:            clients.NewAmqpClientDummy(func(string, []byte) error { return nil }, nil, nil),
+		AmqpVideoStatusUpdate: amqpVideoStatusUpdate,
+		UUIDGen:               clients.NewUuidGeneratorDummy(func() (string, error) { return videoID, nil }, nil),
+	}, &router.DAOs{VideosDAO: *videosDAO, UploadsDAO: *uploadsDAO})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/videos/upload", body)
+	req.ContentLength = int64(bodySize)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.SetBasicAuth(givenUsername, givenUserPwd)
+
+	r.ServeHTTP(w, req)
+	require.Equal(t, 200, w.Code)
+	require.Equal(t, video, uploaded)
+	require.Equal(t, expectedProgress, pushedProgress)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -29,7 +29,8 @@ type VideoListResponse struct {
 }
 
 type VideosListHandler struct {
-	VideosDAO *dao.VideosDAO
+	VideosDAO   *dao.VideosDAO
+	PlaybackDAO *dao.PlaybackDAO
 }
 
 // VideosListHandler godoc
@@ -37,7 +38,7 @@ type VideosListHandler struct {
 // @Description Get list of all videos
 // @Tags video
 // @Produce json
-// @Param attribute path string true "Sort attribute"
+// @Param attribute path string true "Sort attribute : title, upload_date, creation_date, update_date or views"
 // @Param order path string true "Sort order"
 // @Param page path string true "Page number"
 // @Param limit path string true "Video per page"
@@ -66,8 +67,13 @@ func (v VideosListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	//Initialize the response
 	response := VideoListResponse{}
 
-	//Get videos to be returned
-	videos, err := v.VideosDAO.GetVideos(r.Context(), attribute, order, page, limit, int(status))
+	//Get videos to be returned, the views being counted from the playback sessions
+	var videos []models.Video
+	if attribute == models.VIEWS {
+		videos, err = v.PlaybackDAO.GetVideosByViews(r.Context(), order, page, limit, int(status))
+	} else {
+		videos, err = v.VideosDAO.GetVideos(r.Context(), attribute, order, page, limit, int(status))
+	}
 	if err != nil {
 		log.Error("Unable to list objects from database: ", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -84,7 +90,7 @@ func (v VideosListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	//Add total number of page to the response
-	totalvideos, err := v.VideosDAO.GetTotalVideos(r.Context(), int(models.COMPLETE))
+	totalvideos, err := v.VideosDAO.GetTotalVideos(r.Context(), int(status))
 	if err != nil {
 		log.Error("Unable to get number of videos: ", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -138,17 +144,9 @@ func checkRequest(vars map[string]string) (map[string]interface{}, error) {
 	var err error
 
 	//Check variables and are propers
-	switch vars["attribute"] {
-	case "title":
-		values["attribute"] = models.TITLE
-	case "upload_date":
-		values["attribute"] = models.UPLOADEDAT
-	case "creation_date":
-		values["attribute"] = models.CREATEDAT
-	case "update_date":
-		values["attribute"] = models.UPDATEDAT
-	default:
-		return values, errors.New("Wrong attribute")
+	values["attribute"], err = stringToPaginationAttribute(vars["attribute"])
+	if err != nil {
+		return values, err
 	}
 
 	values["order"], err = strconv.ParseBool(vars["order"])
@@ -172,3 +170,20 @@ func checkRequest(vars map[string]string) (map[string]interface{}, error) {
 	}
 	return values, err
 }
+
+func stringToPaginationAttribute(attribute string) (models.PaginationAttribute, error) {
+	switch attribute {
+	case "title":
+		return models.TITLE, nil
+	case "upload_date":
+		return models.UPLOADEDAT, nil
+	case "creation_date":
+		return models.CREATEDAT, nil
+	case "update_date":
+		return models.UPDATEDAT, nil
+	case "views":
+		return models.VIEWS, nil
+	default:
+		return models.TITLE, errors.New("Wrong attribute")
+	}
+}
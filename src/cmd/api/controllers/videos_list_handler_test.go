@@ -2,6 +2,7 @@ package controllers_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http/httptest"
 	"regexp"
@@ -14,6 +15,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
 	"github.com/Sogilis/Voogle/src/cmd/api/models"
@@ -179,7 +181,7 @@ func TestVideosList(t *testing.T) { //nolint:cyclop
 				getVideoTotal := regexp.QuoteMeta(dao.VideosRequests[dao.GetTotalVideos])
 
 				// Tables
-				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path"}
+				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
 				videosRows := sqlmock.NewRows(videosColumns)
 
 				if tt.databaseHasError {
@@ -187,9 +189,9 @@ func TestVideosList(t *testing.T) { //nolint:cyclop
 				} else {
 					sourcePathVideo := validVideoId + "/" + "source.mp4"
 					coverPath := validVideoId + "/" + "cover.png"
-					videosRows.AddRow(validVideoId, "title", int(models.ENCODING), t1, t1, nil, sourcePathVideo, coverPath)
+					videosRows.AddRow(validVideoId, "title", int(models.ENCODING), t1, t1, nil, sourcePathVideo, coverPath, nil, "", nil, nil)
 					mock.ExpectQuery(getVideoListQuery).WithArgs(int(tt.status), (pagenum-1)*limitnum, limitnum).WillReturnRows(videosRows)
-					mock.ExpectQuery(getVideoTotal).WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(1))
+					mock.ExpectQuery(getVideoTotal).WithArgs(int(tt.status)).WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(1))
 				}
 			}
 
@@ -200,10 +202,11 @@ func TestVideosList(t *testing.T) { //nolint:cyclop
 				VideosDAO: *VideosDAO,
 			}
 
-			r := router.NewRouter(config.Config{
+			r, err := router.NewRouter(config.Config{
 				UserAuth: givenUsername,
 				PwdAuth:  givenPassword,
 			}, &routerClients, &routerDAO)
+			require.NoError(t, err)
 
 			w := httptest.NewRecorder()
 
@@ -221,3 +224,48 @@ func TestVideosList(t *testing.T) { //nolint:cyclop
 		})
 	}
 }
+
+func TestVideosListByViews(t *testing.T) {
+	givenUsername := "dev"
+	givenPassword := "test"
+	t1 := time.Now()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	dao_test.ExpectVideosDAOCreation(mock)
+	dao_test.ExpectPlaybackDAOCreation(mock)
+
+	videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
+	videosRows := sqlmock.NewRows(videosColumns).
+		AddRow("1508e7d5-5bc6-4a50-9176-ab0371aa65fe", "most viewed", int(models.COMPLETE), t1, t1, t1, "", "", nil, "", nil, nil).
+		AddRow("b7e6a0c1-5bc6-4a50-9176-ab0371aa65fe", "less viewed", int(models.COMPLETE), t1, t1, t1, "", "", nil, "", nil, nil)
+	mock.ExpectQuery(regexp.QuoteMeta(dao.PlaybackRequests[dao.GetVideosViewsDesc])).WithArgs(int(models.COMPLETE), 0, 10).WillReturnRows(videosRows)
+	mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetTotalVideos])).WithArgs(int(models.COMPLETE)).WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(2))
+
+	videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+	require.NoError(t, err)
+	playbackDAO, err := dao.CreatePlaybackDAO(context.Background(), db)
+	require.NoError(t, err)
+
+	r, err := router.NewRouter(config.Config{
+		UserAuth: givenUsername,
+		PwdAuth:  givenPassword,
+	}, &router.Clients{UUIDGen: clients.NewUuidGeneratorDummy(nil, nil)}, &router.DAOs{VideosDAO: *videosDAO, PlaybackDAO: *playbackDAO})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/v1/videos/list/views/false/1/10/"+models.COMPLETE.String(), nil)
+	req.SetBasicAuth(givenUsername, givenPassword)
+
+	r.ServeHTTP(w, req)
+	require.Equal(t, 200, w.Code)
+
+	var response controllers.VideoListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Videos, 2)
+	require.Equal(t, "most viewed", response.Videos[0].Title)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
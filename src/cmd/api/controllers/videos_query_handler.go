@@ -0,0 +1,340 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+const (
+	defaultVideosLimit = 10
+	maxVideosLimit     = 100
+)
+
+type VideoPageResponse struct {
+	Videos []VideoInfo                 `json:"videos"`
+	Links  map[string]jsonDTO.LinkJson `json:"_links"`
+	Total  int                         `json:"_total"`
+}
+
+type VideosQueryHandler struct {
+	VideosDAO   *dao.VideosDAO
+	PlaybackDAO *dao.PlaybackDAO
+}
+
+// videosQuery is a checked videos list request
+type videosQuery struct {
+	Sort      string
+	Attribute models.PaginationAttribute
+	Ascending bool
+	Statuses  []models.VideoStatus
+	Limit     int
+	Cursor    *pageCursor
+}
+
+// pageCursor is the opaque cursor of the videos list links. It holds the sort of the list, to
+// reject cursors used with another one, and the position of the first (Before) or last video of
+// the previous page. Without position, the list starts from its end (Before) or its beginning.
+type pageCursor struct {
+	Sort      string `json:"s"`
+	Ascending bool   `json:"a"`
+	Before    bool   `json:"b,omitempty"`
+	Value     string `json:"v,omitempty"`
+	Title     string `json:"t,omitempty"`
+	ID        string `json:"i,omitempty"`
+}
+
+// VideosQueryHandler godoc
+// @Summary Get list of videos
+// @Description Get list of videos with one of the statuses, paginated with cursors : follow the Hateoas links
+// @Description to get the other pages. Videos are sorted on the attribute, then on their ID (on their title then their ID for views).
+// @Tags video
+// @Produce json
+// @Param status query []string false "Video statuses (Complete by default)" collectionFormat(multi)
+// @Param sort query string false "Sort attribute : title, upload_date, creation_date, update_date or views" default(upload_date)
+// @Param order query string false "Sort order : asc or desc" default(desc)
+// @Param limit query int false "Video per page" default(10)
+// @Param cursor query string false "Page cursor, from the Hateoas links"
+// @Param mine query bool false "Only the videos of the authenticated user" default(false)
+// @Success 200 {object} VideoPageResponse "Video list and Hateoas links"
+// @Failure 400 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos [get]
+func (v VideosQueryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) { //nolint:cyclop
+	log.Debug("GET VideosQueryHandler - parameters ", r.URL.Query())
+
+	query, err := checkVideosQuery(r.URL.Query())
+	if err != nil {
+		log.Error("Request cannot be treated: ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ownerID, err := ownerFilter(r)
+	if err != nil {
+		log.Error("Request cannot be treated: ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Pages before the cursor are read in the reverse order
+	before := query.Cursor != nil && query.Cursor.Before
+	var position *models.VideoCursor
+	if query.Cursor != nil && query.Cursor.ID != "" {
+		position, err = cursorPosition(query.Cursor, query.Attribute)
+		if err != nil {
+			log.Error("Request cannot be treated: ", err)
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// One more video tells if there is another page
+	videos, cursors, err := v.videosAfter(r.Context(), query, query.Ascending != before, ownerID, position)
+	if err != nil {
+		log.Error("Unable to list objects from database: ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	hasMore := len(videos) > query.Limit
+	if hasMore {
+		videos, cursors = videos[:query.Limit], cursors[:query.Limit]
+	}
+	if before {
+		for i, j := 0, len(videos)-1; i < j; i, j = i+1, j-1 {
+			videos[i], videos[j] = videos[j], videos[i]
+			cursors[i], cursors[j] = cursors[j], cursors[i]
+		}
+	}
+
+	response := VideoPageResponse{Videos: []VideoInfo{}}
+	for _, video := range videos {
+		response.Videos = append(response.Videos, VideoInfo{
+			Id:        video.ID,
+			Title:     video.Title,
+			CoverLink: jsonDTO.LinkToLinkJson(models.CreateLink("api/v1/videos/"+video.ID+"/cover", "GET")),
+		})
+	}
+
+	response.Total, err = v.VideosDAO.GetTotalVideosWithStatuses(r.Context(), query.Statuses, ownerID)
+	if err != nil {
+		log.Error("Unable to get number of videos: ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	//Populate links response, keeping the request parameters
+	response.Links = map[string]jsonDTO.LinkJson{}
+	pagePath := func(cursor *pageCursor) string {
+		values := url.Values{}
+		for _, status := range query.Statuses {
+			values.Add("status", status.String())
+		}
+		values.Set("sort", query.Sort)
+		values.Set("order", "desc")
+		if query.Ascending {
+			values.Set("order", "asc")
+		}
+		values.Set("limit", strconv.Itoa(query.Limit))
+		if ownerID != nil {
+			values.Set("mine", "true")
+		}
+		if cursor != nil {
+			values.Set("cursor", encodeCursor(*cursor))
+		}
+		return "api/v1/videos?" + values.Encode()
+	}
+	newCursor := func(position models.VideoCursor, toPrevious bool) *pageCursor {
+		cursor := pageCursor{Sort: query.Sort, Ascending: query.Ascending, Before: toPrevious, Title: position.Title, ID: position.ID}
+		switch value := position.Value.(type) {
+		case string:
+			cursor.Value = value
+		case int:
+			cursor.Value = strconv.Itoa(value)
+		case time.Time:
+			cursor.Value = value.Format(time.RFC3339Nano)
+		}
+		return &cursor
+	}
+
+	response.Links["first"] = jsonDTO.LinkToLinkJson(models.CreateLink(pagePath(nil), "GET"))
+	response.Links["last"] = jsonDTO.LinkToLinkJson(models.CreateLink(pagePath(&pageCursor{Sort: query.Sort, Ascending: query.Ascending, Before: true}), "GET"))
+
+	if len(videos) > 0 {
+		if (before && hasMore) || (!before && position != nil) {
+			response.Links["previous"] = jsonDTO.LinkToLinkJson(models.CreateLink(pagePath(newCursor(cursors[0], true)), "GET"))
+		}
+		if (!before && hasMore) || (before && position != nil) {
+			response.Links["next"] = jsonDTO.LinkToLinkJson(models.CreateLink(pagePath(newCursor(cursors[len(cursors)-1], false)), "GET"))
+		}
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(payload)
+}
+
+// videosAfter returns the videos after the position in the list, with their own position. Views are counted
+// from the playback sessions.
+func (v VideosQueryHandler) videosAfter(ctx context.Context, query videosQuery, ascending bool, ownerID *string, position *models.VideoCursor) ([]models.Video, []models.VideoCursor, error) {
+	videos := []models.Video{}
+	cursors := []models.VideoCursor{}
+
+	if query.Attribute == models.VIEWS {
+		viewed, err := v.PlaybackDAO.GetVideosAfterViews(ctx, ascending, query.Statuses, ownerID, position, query.Limit+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, video := range viewed {
+			videos = append(videos, video.Video)
+			cursors = append(cursors, models.NewVideoViewsCursor(video))
+		}
+		return videos, cursors, nil
+	}
+
+	videos, err := v.VideosDAO.GetVideosAfter(ctx, query.Attribute, ascending, query.Statuses, ownerID, position, query.Limit+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, video := range videos {
+		cursors = append(cursors, models.NewVideoCursor(video, query.Attribute))
+	}
+	return videos, cursors, nil
+}
+
+func checkVideosQuery(values url.Values) (videosQuery, error) { //nolint:cyclop
+	query := videosQuery{
+		Sort:      "upload_date",
+		Attribute: models.UPLOADEDAT,
+		Limit:     defaultVideosLimit,
+	}
+	var err error
+
+	// Statuses can be repeated or comma separated
+	for _, value := range values["status"] {
+		for _, name := range strings.Split(value, ",") {
+			status, err := models.StringToVideoStatus(strings.TrimSpace(name))
+			if err != nil {
+				return query, errors.New("Status is not a valid string")
+			}
+			query.Statuses = append(query.Statuses, status)
+		}
+	}
+	if len(query.Statuses) == 0 {
+		query.Statuses = []models.VideoStatus{models.COMPLETE}
+	}
+
+	if values.Get("sort") != "" {
+		query.Sort = values.Get("sort")
+		query.Attribute, err = stringToPaginationAttribute(query.Sort)
+		if err != nil {
+			return query, err
+		}
+	}
+
+	switch values.Get("order") {
+	case "asc":
+		query.Ascending = true
+	case "", "desc":
+		query.Ascending = false
+	default:
+		return query, errors.New("Order must be asc or desc")
+	}
+
+	if values.Get("limit") != "" {
+		query.Limit, err = strconv.Atoi(values.Get("limit"))
+		if err != nil || query.Limit < 1 || query.Limit > maxVideosLimit {
+			return query, errors.New("Limit must be between 1 and " + strconv.Itoa(maxVideosLimit))
+		}
+	}
+
+	if values.Get("cursor") != "" {
+		query.Cursor, err = decodeCursor(values.Get("cursor"))
+		if err != nil {
+			return query, errors.New("Invalid cursor")
+		}
+		if query.Cursor.Sort != query.Sort || query.Cursor.Ascending != query.Ascending {
+			return query, errors.New("Cursor does not match the sort of the list")
+		}
+	}
+
+	return query, nil
+}
+
+// ownerFilter returns the ID of the authenticated user when only its videos are requested (mine=true),
+// or nil for every video
+func ownerFilter(r *http.Request) (*string, error) {
+	switch r.URL.Query().Get("mine") {
+	case "", "false":
+		return nil, nil
+	case "true":
+		ownerID := auth.OwnerID(r.Context())
+		if ownerID == nil {
+			return nil, errors.New("Only user accounts own videos")
+		}
+		return ownerID, nil
+	default:
+		return nil, errors.New("Mine must be true or false")
+	}
+}
+
+func encodeCursor(cursor pageCursor) string {
+	// Cannot fail : the cursor only has strings and booleans
+	payload, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func decodeCursor(value string) (*pageCursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor pageCursor
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// cursorPosition returns the position of the cursor, its value typed as the sort attribute
+func cursorPosition(cursor *pageCursor, attribute models.PaginationAttribute) (*models.VideoCursor, error) {
+	position := models.VideoCursor{ID: cursor.ID, Value: cursor.Value}
+	switch attribute {
+	case models.TITLE:
+		// The value is already the title
+	case models.VIEWS:
+		views, err := strconv.Atoi(cursor.Value)
+		if err != nil {
+			return nil, err
+		}
+		position.Value = views
+		position.Title = cursor.Title
+	default:
+		date, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return nil, err
+		}
+		position.Value = date
+	}
+	return &position, nil
+}
@@ -0,0 +1,381 @@
+package controllers_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+)
+
+func TestVideosQuery(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenPassword := "test"
+	t1 := time.Now()
+	cursor := func(payload string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(payload))
+	}
+
+	cases := []struct {
+		name             string
+		giveRequest      string
+		giveWithAuth     bool
+		giveAccount      bool
+		giveDatabaseErr  bool
+		giveTitles       []string
+		expectedQuery    dao.VideosRequestName
+		expectedArgs     []driver.Value
+		expectedStatuses string
+		expectedOwner    driver.Value
+		expectedHTTPCode int
+		expectedTitles   []string
+		expectedLinks    []string
+	}{
+		{
+			name:             "GET first page",
+			giveRequest:      "/api/v1/videos?limit=2",
+			giveWithAuth:     true,
+			giveTitles:       []string{"c", "b", "a"},
+			expectedQuery:    dao.GetVideosAfterUploadedAtDesc,
+			expectedArgs:     []driver.Value{"4", nil, nil, nil, nil, nil, 3},
+			expectedStatuses: "4",
+			expectedHTTPCode: 200,
+			expectedTitles:   []string{"c", "b"},
+			expectedLinks:    []string{"first", "last", "next"},
+		},
+		{
+			name:             "GET next page with several statuses",
+			giveRequest:      "/api/v1/videos?status=Complete,Archive&sort=title&order=asc&limit=2&cursor=" + cursor(`{"s":"title","a":true,"v":"b","i":"id-b"}`),
+			giveWithAuth:     true,
+			giveTitles:       []string{"c", "d"},
+			expectedQuery:    dao.GetVideosAfterTitleAsc,
+			expectedArgs:     []driver.Value{"4,5", nil, nil, "b", "b", "id-b", 3},
+			expectedStatuses: "4,5",
+			expectedHTTPCode: 200,
+			expectedTitles:   []string{"c", "d"},
+			expectedLinks:    []string{"first", "last", "previous"},
+		},
+		{
+			name:             "GET last page",
+			giveRequest:      "/api/v1/videos?status=Complete&status=Archive&sort=title&order=asc&limit=2&cursor=" + cursor(`{"s":"title","a":true,"b":true}`),
+			giveWithAuth:     true,
+			giveTitles:       []string{"d", "c", "b"},
+			expectedQuery:    dao.GetVideosAfterTitleDesc,
+			expectedArgs:     []driver.Value{"4,5", nil, nil, nil, nil, nil, 3},
+			expectedStatuses: "4,5",
+			expectedHTTPCode: 200,
+			expectedTitles:   []string{"c", "d"},
+			expectedLinks:    []string{"first", "last", "previous"},
+		},
+		{
+			name:             "GET previous page",
+			giveRequest:      "/api/v1/videos?sort=title&order=asc&limit=2&cursor=" + cursor(`{"s":"title","a":true,"b":true,"v":"c","i":"id-c"}`),
+			giveWithAuth:     true,
+			giveTitles:       []string{"b", "a"},
+			expectedQuery:    dao.GetVideosAfterTitleDesc,
+			expectedArgs:     []driver.Value{"4", nil, nil, "c", "c", "id-c", 3},
+			expectedStatuses: "4",
+			expectedHTTPCode: 200,
+			expectedTitles:   []string{"a", "b"},
+			expectedLinks:    []string{"first", "last", "next"},
+		},
+		{
+			name:             "GET my videos",
+			giveRequest:      "/api/v1/videos?mine=true&limit=2",
+			giveAccount:      true,
+			giveTitles:       []string{"b", "a"},
+			expectedQuery:    dao.GetVideosAfterUploadedAtDesc,
+			expectedArgs:     []driver.Value{"4", accountID, accountID, nil, nil, nil, 3},
+			expectedStatuses: "4",
+			expectedOwner:    accountID,
+			expectedHTTPCode: 200,
+			expectedTitles:   []string{"b", "a"},
+			expectedLinks:    []string{"first", "last"},
+		},
+		{
+			name:             "GET fails with my videos of shared account",
+			giveRequest:      "/api/v1/videos?mine=true",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "GET fails with invalid mine",
+			giveRequest:      "/api/v1/videos?mine=invalid",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "GET fails with no auth",
+			giveRequest:      "/api/v1/videos",
+			expectedHTTPCode: 401,
+		},
+		{
+			name:             "GET fails with invalid status",
+			giveRequest:      "/api/v1/videos?status=Complete,invalid",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "GET fails with invalid sort",
+			giveRequest:      "/api/v1/videos?sort=invalid",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "GET fails with invalid order",
+			giveRequest:      "/api/v1/videos?order=invalid",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "GET fails with invalid limit",
+			giveRequest:      "/api/v1/videos?limit=0",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "GET fails with invalid cursor",
+			giveRequest:      "/api/v1/videos?cursor=invalid",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "GET fails with cursor of another sort",
+			giveRequest:      "/api/v1/videos?sort=upload_date&cursor=" + cursor(`{"s":"title","a":true,"v":"b","i":"id-b"}`),
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "GET fails with database error",
+			giveRequest:      "/api/v1/videos",
+			giveWithAuth:     true,
+			giveDatabaseErr:  true,
+			expectedQuery:    dao.GetVideosAfterUploadedAtDesc,
+			expectedArgs:     []driver.Value{"4", nil, nil, nil, nil, nil, 11},
+			expectedHTTPCode: 500,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectUsersDAOCreation(mock)
+
+			if tt.giveAccount {
+				expectAccountLookup(t, mock)
+			}
+
+			if tt.expectedArgs != nil {
+				listQuery := regexp.QuoteMeta(dao.VideosRequests[tt.expectedQuery])
+				totalQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetTotalVideosWithStatuses])
+
+				if tt.giveDatabaseErr {
+					mock.ExpectQuery(listQuery).WithArgs(tt.expectedArgs...).WillReturnError(fmt.Errorf("unknown error"))
+				} else {
+					videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
+					videosRows := sqlmock.NewRows(videosColumns)
+					for _, title := range tt.giveTitles {
+						videosRows.AddRow("id-"+title, title, int(models.COMPLETE), t1, t1, t1, "id-"+title+"/source.mp4", "id-"+title+"/cover.png", nil, "", nil, nil)
+					}
+					mock.ExpectQuery(listQuery).WithArgs(tt.expectedArgs...).WillReturnRows(videosRows)
+					mock.ExpectQuery(totalQuery).WithArgs(tt.expectedStatuses, tt.expectedOwner, tt.expectedOwner).WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(4))
+				}
+			}
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenPassword,
+			}, &router.Clients{UUIDGen: clients.NewUuidGeneratorDummy(nil, nil)}, &router.DAOs{VideosDAO: *videosDAO, UsersDAO: *usersDAO})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tt.giveRequest, nil)
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenPassword)
+			} else if tt.giveAccount {
+				req.SetBasicAuth(accountUsername, accountPassword)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedHTTPCode == 200 {
+				var response controllers.VideoPageResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				require.Equal(t, 4, response.Total)
+
+				titles := []string{}
+				for _, video := range response.Videos {
+					titles = append(titles, video.Title)
+				}
+				require.Equal(t, tt.expectedTitles, titles)
+
+				require.Len(t, response.Links, len(tt.expectedLinks))
+				for _, link := range tt.expectedLinks {
+					require.Contains(t, response.Links, link)
+				}
+
+				// Links keep the request parameters
+				requestQuery, err := url.ParseQuery(strings.SplitN(tt.giveRequest, "?", 2)[1])
+				require.NoError(t, err)
+				linkQuery, err := url.ParseQuery(strings.SplitN(response.Links["first"].Href, "?", 2)[1])
+				require.NoError(t, err)
+				require.Equal(t, requestQuery.Get("limit"), linkQuery.Get("limit"))
+				require.Equal(t, requestQuery.Get("mine"), linkQuery.Get("mine"))
+				require.Empty(t, linkQuery.Get("cursor"))
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestVideosQueryByViews(t *testing.T) {
+	givenUsername := "dev"
+	givenPassword := "test"
+	t1 := time.Now()
+	cursor := func(payload string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(payload))
+	}
+
+	cases := []struct {
+		name             string
+		giveRequest      string
+		giveVideos       []string
+		giveViews        []int
+		expectedQuery    dao.PlaybackRequestName
+		expectedArgs     []driver.Value
+		expectedHTTPCode int
+		expectedTitles   []string
+		expectedCursors  map[string]string
+	}{
+		{
+			name:             "GET first page",
+			giveRequest:      "/api/v1/videos?sort=views&limit=2",
+			giveVideos:       []string{"b", "a", "c"},
+			giveViews:        []int{3, 3, 1},
+			expectedQuery:    dao.GetVideosAfterViewsDesc,
+			expectedArgs:     []driver.Value{"4", nil, nil, nil, nil, nil, nil, 3},
+			expectedHTTPCode: 200,
+			expectedTitles:   []string{"b", "a"},
+			expectedCursors:  map[string]string{"next": `{"s":"views","a":false,"v":"3","t":"a","i":"id-a"}`},
+		},
+		{
+			name:             "GET next page",
+			giveRequest:      "/api/v1/videos?sort=views&limit=2&cursor=" + cursor(`{"s":"views","a":false,"v":"3","t":"a","i":"id-a"}`),
+			giveVideos:       []string{"c"},
+			giveViews:        []int{1},
+			expectedQuery:    dao.GetVideosAfterViewsDesc,
+			expectedArgs:     []driver.Value{"4", nil, nil, 3, 3, "a", "id-a", 3},
+			expectedHTTPCode: 200,
+			expectedTitles:   []string{"c"},
+			expectedCursors:  map[string]string{"previous": `{"s":"views","a":false,"b":true,"v":"1","t":"c","i":"id-c"}`},
+		},
+		{
+			name:             "GET previous page",
+			giveRequest:      "/api/v1/videos?sort=views&order=asc&limit=2&cursor=" + cursor(`{"s":"views","a":true,"b":true,"v":"3","t":"b","i":"id-b"}`),
+			giveVideos:       []string{"a", "c"},
+			giveViews:        []int{3, 1},
+			expectedQuery:    dao.GetVideosAfterViewsDesc,
+			expectedArgs:     []driver.Value{"4", nil, nil, 3, 3, "b", "id-b", 3},
+			expectedHTTPCode: 200,
+			expectedTitles:   []string{"c", "a"},
+			expectedCursors:  map[string]string{"next": `{"s":"views","a":true,"v":"3","t":"a","i":"id-a"}`},
+		},
+		{
+			name:             "GET fails with invalid views cursor",
+			giveRequest:      "/api/v1/videos?sort=views&cursor=" + cursor(`{"s":"views","a":false,"v":"many","t":"a","i":"id-a"}`),
+			expectedHTTPCode: 400,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectPlaybackDAOCreation(mock)
+
+			if tt.expectedArgs != nil {
+				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at", "views"}
+				videosRows := sqlmock.NewRows(videosColumns)
+				for i, title := range tt.giveVideos {
+					videosRows.AddRow("id-"+title, title, int(models.COMPLETE), t1, t1, t1, "id-"+title+"/source.mp4", "id-"+title+"/cover.png", nil, "", nil, nil, tt.giveViews[i])
+				}
+				mock.ExpectQuery(regexp.QuoteMeta(dao.PlaybackRequests[tt.expectedQuery])).WithArgs(tt.expectedArgs...).WillReturnRows(videosRows)
+				mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetTotalVideosWithStatuses])).WithArgs("4", nil, nil).WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(3))
+			}
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			playbackDAO, err := dao.CreatePlaybackDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenPassword,
+			}, &router.Clients{UUIDGen: clients.NewUuidGeneratorDummy(nil, nil)}, &router.DAOs{VideosDAO: *videosDAO, PlaybackDAO: *playbackDAO})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tt.giveRequest, nil)
+			req.SetBasicAuth(givenUsername, givenPassword)
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedHTTPCode == 200 {
+				var response controllers.VideoPageResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				require.Equal(t, 3, response.Total)
+
+				titles := []string{}
+				for _, video := range response.Videos {
+					titles = append(titles, video.Title)
+				}
+				require.Equal(t, tt.expectedTitles, titles)
+
+				// Cursors hold the views, the title and the ID of the video
+				for link, expectedCursor := range tt.expectedCursors {
+					require.Contains(t, response.Links, link)
+					linkQuery, err := url.ParseQuery(strings.SplitN(response.Links[link].Href, "?", 2)[1])
+					require.NoError(t, err)
+					require.Equal(t, "views", linkQuery.Get("sort"))
+					require.Equal(t, cursor(expectedCursor), linkQuery.Get("cursor"))
+				}
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
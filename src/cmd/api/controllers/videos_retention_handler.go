@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/retention"
+)
+
+type ExpiredVideoInfo struct {
+	Id         string     `json:"id" example:"1"`
+	Title      string     `json:"title" example:"my title"`
+	ArchivedAt *time.Time `json:"archivedAt" example:"2022-04-15T12:59:52Z"`
+	Size       int64      `json:"size" example:"1048576"`
+}
+
+type RetentionResponse struct {
+	Enabled bool               `json:"enabled" example:"true"`
+	Period  string             `json:"period" example:"720h0m0s"`
+	Videos  []ExpiredVideoInfo `json:"videos"`
+	Size    int64              `json:"size" example:"1048576"`
+}
+
+type VideosRetentionHandler struct {
+	Purger retention.Purger
+}
+
+// VideosRetentionHandler godoc
+// @Summary List videos to purge
+// @Description Dry run of the retention policy : archived videos that would be purged now, the oldest archive first. Only for the shared account.
+// @Tags video
+// @Produce json
+// @Success 200 {object} RetentionResponse "Videos to purge and their size in bytes"
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/retention [get]
+func (v VideosRetentionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GET VideosRetentionHandler")
+
+	expired, err := v.Purger.ExpiredVideos(r.Context())
+	if err != nil {
+		log.Error("Unable to get videos to purge: ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := RetentionResponse{
+		Enabled: v.Purger.Enabled(),
+		Period:  v.Purger.Period.String(),
+		Videos:  []ExpiredVideoInfo{},
+	}
+	for _, e := range expired {
+		response.Videos = append(response.Videos, ExpiredVideoInfo{
+			Id:         e.Video.ID,
+			Title:      e.Video.Title,
+			ArchivedAt: e.Video.ArchivedAt,
+			Size:       e.Size,
+		})
+		response.Size += e.Size
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(payload)
+}
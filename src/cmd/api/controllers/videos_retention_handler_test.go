@@ -0,0 +1,155 @@
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+)
+
+func TestVideosRetention(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	video1ID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	video2ID := "b7e6a0c1-5bc6-4a50-9176-ab0371aa65fe"
+	archivedAt := time.Now().Add(-1000 * time.Hour)
+
+	cases := []struct {
+		name             string
+		giveWithAuth     bool
+		givePeriod       time.Duration
+		giveVideos       []string
+		giveDatabaseErr  bool
+		giveSizeErr      bool
+		expectedHTTPCode int
+		expectedVideos   int
+		expectedSize     int64
+	}{
+		{
+			name:             "GET videos to purge",
+			giveWithAuth:     true,
+			givePeriod:       720 * time.Hour,
+			giveVideos:       []string{video1ID, video2ID},
+			expectedHTTPCode: 200,
+			expectedVideos:   2,
+			expectedSize:     2048,
+		},
+		{
+			name:             "GET no video to purge",
+			giveWithAuth:     true,
+			givePeriod:       720 * time.Hour,
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "GET videos to purge with unknown size",
+			giveWithAuth:     true,
+			givePeriod:       720 * time.Hour,
+			giveVideos:       []string{video1ID},
+			giveSizeErr:      true,
+			expectedHTTPCode: 200,
+			expectedVideos:   1,
+		},
+		{
+			name:             "GET nothing to purge with retention disabled",
+			giveWithAuth:     true,
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "GET fails with no auth",
+			givePeriod:       720 * time.Hour,
+			expectedHTTPCode: 401,
+		},
+		{
+			name:             "GET fails with database error",
+			giveWithAuth:     true,
+			givePeriod:       720 * time.Hour,
+			giveDatabaseErr:  true,
+			expectedHTTPCode: 500,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectUploadsDAOCreation(mock)
+
+			if tt.giveWithAuth && tt.givePeriod > 0 {
+				archivedBeforeQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideosArchivedBefore])
+
+				if tt.giveDatabaseErr {
+					mock.ExpectQuery(archivedBeforeQuery).WillReturnError(fmt.Errorf("database internal error"))
+				} else {
+					videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
+					videosRows := sqlmock.NewRows(videosColumns)
+					for _, id := range tt.giveVideos {
+						videosRows.AddRow(id, "title-"+id, int(models.ARCHIVE), archivedAt, archivedAt, time.Now(), id+"/source.mp4", id+"/cover.jpeg", nil, "", nil, archivedAt)
+					}
+					mock.ExpectQuery(archivedBeforeQuery).WithArgs(int(models.ARCHIVE), sqlmock.AnyArg()).WillReturnRows(videosRows)
+				}
+			}
+
+			s3Client := clients.NewS3ClientDummy(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil,
+				func(path string) (int64, error) {
+					if tt.giveSizeErr {
+						return 0, fmt.Errorf("cannot list objects")
+					}
+					return 1024, nil
+				}, nil, nil)
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			uploadsDAO, err := dao.CreateUploadsDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth:        givenUsername,
+				PwdAuth:         givenUserPwd,
+				RetentionPeriod: tt.givePeriod,
+			}, &router.Clients{S3Client: s3Client}, &router.DAOs{VideosDAO: *videosDAO, UploadsDAO: *uploadsDAO})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/api/v1/videos/retention", nil)
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedHTTPCode == 200 {
+				var response controllers.RetentionResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				require.Equal(t, tt.givePeriod > 0, response.Enabled)
+				require.Len(t, response.Videos, tt.expectedVideos)
+				require.Equal(t, tt.expectedSize, response.Size)
+				for _, video := range response.Videos {
+					require.WithinDuration(t, archivedAt, *video.ArchivedAt, time.Second)
+				}
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
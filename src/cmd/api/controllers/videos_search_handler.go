@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 100
+)
+
+type VideosSearchHandler struct {
+	VideosDAO *dao.VideosDAO
+}
+
+// VideosSearchHandler godoc
+// @Summary Search videos
+// @Description Full-text search over the title, the description and the tags of the videos, the most relevant first.
+// @Description Words match their beginning : 'mount' matches 'mountain'.
+// @Tags video
+// @Produce json
+// @Param q query string true "Searched words"
+// @Param status query string false "Video status (Complete by default)"
+// @Param from query string false "Uploaded from this date (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "Uploaded until this date included (YYYY-MM-DD or RFC3339)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Video per page" default(10)
+// @Param mine query bool false "Only the videos of the authenticated user" default(false)
+// @Success 200 {object} VideoListResponse "Video list and Hateoas links"
+// @Failure 400 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/search [get]
+func (v VideosSearchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	log.Debug("GET VideosSearchHandler - parameters ", query)
+
+	search, page, limit, err := checkSearchRequest(query)
+	if err != nil {
+		log.Error("Request cannot be treated: ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	search.OwnerID, err = ownerFilter(r)
+	if err != nil {
+		log.Error("Request cannot be treated: ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	//Initialize the response
+	response := VideoListResponse{Videos: []VideoInfo{}}
+
+	videos, err := v.VideosDAO.SearchVideos(r.Context(), search, page, limit)
+	if err != nil {
+		log.Error("Unable to search videos in database: ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	for _, video := range videos {
+		response.Videos = append(response.Videos, VideoInfo{
+			Id:        video.ID,
+			Title:     video.Title,
+			CoverLink: jsonDTO.LinkToLinkJson(models.CreateLink("api/v1/videos/"+video.ID+"/cover", "GET")),
+		})
+	}
+
+	totalvideos, err := v.VideosDAO.GetTotalSearchVideos(r.Context(), search)
+	if err != nil {
+		log.Error("Unable to get number of videos: ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response.LastPage = totalvideos / limit
+	if (totalvideos%limit) != 0 || response.LastPage == 0 {
+		response.LastPage++
+	}
+
+	//Populate links response, keeping the search parameters
+	response.Links = map[string]jsonDTO.LinkJson{}
+	pagePath := func(page int) string {
+		query.Set("page", strconv.Itoa(page))
+		query.Set("limit", strconv.Itoa(limit))
+		return "api/v1/videos/search?" + query.Encode()
+	}
+
+	response.Links["first"] = jsonDTO.LinkToLinkJson(models.CreateLink(pagePath(1), "GET"))
+	response.Links["last"] = jsonDTO.LinkToLinkJson(models.CreateLink(pagePath(response.LastPage), "GET"))
+
+	if page != 1 && page <= response.LastPage {
+		response.Links["previous"] = jsonDTO.LinkToLinkJson(models.CreateLink(pagePath(page-1), "GET"))
+	}
+
+	if page < response.LastPage {
+		response.Links["next"] = jsonDTO.LinkToLinkJson(models.CreateLink(pagePath(page+1), "GET"))
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(payload)
+}
+
+func checkSearchRequest(query url.Values) (models.VideoSearch, int, int, error) { //nolint:cyclop
+	search := models.VideoSearch{Query: strings.TrimSpace(query.Get("q"))}
+	page, limit := 1, defaultSearchLimit
+	var err error
+
+	if search.Query == "" {
+		return search, page, limit, errors.New("Missing search")
+	}
+
+	if query.Get("status") != "" {
+		status, err := models.StringToVideoStatus(query.Get("status"))
+		if err != nil {
+			return search, page, limit, errors.New("Status is not a valid string")
+		}
+		search.Status = &status
+	}
+
+	if query.Get("from") != "" {
+		from, _, err := parseSearchDate(query.Get("from"))
+		if err != nil {
+			return search, page, limit, errors.New("From is not a valid date")
+		}
+		search.UploadedAfter = &from
+	}
+
+	if query.Get("to") != "" {
+		to, isDay, err := parseSearchDate(query.Get("to"))
+		if err != nil {
+			return search, page, limit, errors.New("To is not a valid date")
+		}
+		// The whole day is included
+		if isDay {
+			to = to.AddDate(0, 0, 1)
+		} else {
+			to = to.Add(time.Nanosecond)
+		}
+		search.UploadedBefore = &to
+	}
+
+	if query.Get("page") != "" {
+		page, err = strconv.Atoi(query.Get("page"))
+		if err != nil || page < 1 {
+			return search, page, limit, errors.New("Page is not a positive number")
+		}
+	}
+
+	if query.Get("limit") != "" {
+		limit, err = strconv.Atoi(query.Get("limit"))
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			return search, page, limit, errors.New("Limit must be between 1 and " + strconv.Itoa(maxSearchLimit))
+		}
+	}
+
+	return search, page, limit, nil
+}
+
+// parseSearchDate accepts a day (YYYY-MM-DD) or a RFC3339 date, and tells if it was a day
+func parseSearchDate(value string) (time.Time, bool, error) {
+	if day, err := time.Parse("2006-01-02", value); err == nil {
+		return day, true, nil
+	}
+
+	date, err := time.Parse(time.RFC3339, value)
+	return date, false, err
+}
@@ -0,0 +1,196 @@
+package controllers_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+)
+
+func TestVideosSearch(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenPassword := "test"
+	validVideoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	t1 := time.Now()
+	from := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)
+	complete := int(models.COMPLETE)
+	archive := int(models.ARCHIVE)
+
+	cases := []struct {
+		name             string
+		giveRequest      string
+		giveWithAuth     bool
+		giveAccount      bool
+		giveDatabaseErr  bool
+		expectedArgs     []driver.Value
+		expectedTotal    int
+		expectedHTTPCode int
+		expectedLinks    []string
+	}{
+		{
+			name:             "GET search",
+			giveRequest:      "/api/v1/videos/search?q=mount%20ski",
+			giveWithAuth:     true,
+			expectedArgs:     []driver.Value{complete, nil, nil, nil, nil, nil, nil},
+			expectedTotal:    1,
+			expectedHTTPCode: 200,
+			expectedLinks:    []string{"first", "last"},
+		},
+		{
+			name:             "GET search with filters",
+			giveRequest:      "/api/v1/videos/search?q=mount%20ski&status=Archive&from=2022-01-01&to=2022-01-31&page=2&limit=1",
+			giveWithAuth:     true,
+			expectedArgs:     []driver.Value{archive, from, from, to, to, nil, nil},
+			expectedTotal:    3,
+			expectedHTTPCode: 200,
+			expectedLinks:    []string{"first", "last", "previous", "next"},
+		},
+		{
+			name:             "GET search my videos",
+			giveRequest:      "/api/v1/videos/search?q=mount%20ski&mine=true",
+			giveAccount:      true,
+			expectedArgs:     []driver.Value{complete, nil, nil, nil, nil, accountID, accountID},
+			expectedTotal:    1,
+			expectedHTTPCode: 200,
+			expectedLinks:    []string{"first", "last"},
+		},
+		{
+			name:             "GET fails with my videos of shared account",
+			giveRequest:      "/api/v1/videos/search?q=mount&mine=true",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "GET fails with no auth",
+			giveRequest:      "/api/v1/videos/search?q=mount",
+			expectedHTTPCode: 401,
+		},
+		{
+			name:             "GET fails with missing search",
+			giveRequest:      "/api/v1/videos/search?q=%20",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "GET fails with invalid status",
+			giveRequest:      "/api/v1/videos/search?q=mount&status=invalid",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "GET fails with invalid date",
+			giveRequest:      "/api/v1/videos/search?q=mount&from=yesterday",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "GET fails with invalid limit",
+			giveRequest:      "/api/v1/videos/search?q=mount&limit=1000",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "GET fails with database error",
+			giveRequest:      "/api/v1/videos/search?q=mount",
+			giveWithAuth:     true,
+			giveDatabaseErr:  true,
+			expectedArgs:     []driver.Value{complete, nil, nil, nil, nil, nil, nil},
+			expectedHTTPCode: 500,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectUsersDAOCreation(mock)
+
+			if tt.giveAccount {
+				expectAccountLookup(t, mock)
+			}
+
+			if tt.expectedArgs != nil {
+				searchQuery := regexp.QuoteMeta(dao.VideosRequests[dao.SearchVideos])
+				totalQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetTotalSearchVideos])
+				fullText := "mount*"
+				if !tt.giveDatabaseErr {
+					fullText = "mount* ski*"
+				}
+
+				searchArgs := append([]driver.Value{fullText, fullText, fullText, fullText}, tt.expectedArgs...)
+				totalArgs := append([]driver.Value{fullText, fullText}, tt.expectedArgs...)
+
+				if tt.giveDatabaseErr {
+					mock.ExpectQuery(searchQuery).WithArgs(append(searchArgs, 0, 10)...).WillReturnError(fmt.Errorf("unknown error"))
+				} else {
+					videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at", "relevance"}
+					videosRows := sqlmock.NewRows(videosColumns).
+						AddRow(validVideoID, "Mountain skiing", complete, t1, t1, t1, validVideoID+"/source.mp4", validVideoID+"/cover.png", nil, "", nil, nil, 2.5)
+
+					offset, limit := 0, 10
+					if tt.expectedTotal == 3 {
+						offset, limit = 1, 1
+					}
+					mock.ExpectQuery(searchQuery).WithArgs(append(searchArgs, offset, limit)...).WillReturnRows(videosRows)
+					mock.ExpectQuery(totalQuery).WithArgs(totalArgs...).WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(tt.expectedTotal))
+				}
+			}
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenPassword,
+			}, &router.Clients{UUIDGen: clients.NewUuidGeneratorDummy(nil, nil)}, &router.DAOs{VideosDAO: *videosDAO, UsersDAO: *usersDAO})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tt.giveRequest, nil)
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenPassword)
+			} else if tt.giveAccount {
+				req.SetBasicAuth(accountUsername, accountPassword)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedHTTPCode == 200 {
+				var response controllers.VideoListResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				require.Len(t, response.Videos, 1)
+				require.Equal(t, validVideoID, response.Videos[0].Id)
+				require.Equal(t, tt.expectedTotal, response.LastPage)
+				require.Len(t, response.Links, len(tt.expectedLinks))
+				for _, link := range tt.expectedLinks {
+					require.Contains(t, response.Links[link].Href, "q=mount+ski")
+				}
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+const (
+	defaultWatchHistoryLimit = 20
+	maxWatchHistoryLimit     = 100
+)
+
+var errNoWatchHistory = errors.New("Only user accounts have a watch history")
+
+// VideoPositionRequest gives the playback position in seconds from the start of the video
+type VideoPositionRequest struct {
+	Position *float64 `json:"position" example:"754.2"`
+}
+
+type WatchHistoryResponse struct {
+	History []jsonDTO.WatchPositionJson `json:"history"`
+	Links   map[string]jsonDTO.LinkJson `json:"_links"`
+}
+
+type VideoPositionUpdateHandler struct {
+	VideosDAO       *dao.VideosDAO
+	WatchHistoryDAO *dao.WatchHistoryDAO
+	UUIDGen         clients.IUUIDGenerator
+}
+
+// VideoPositionUpdateHandler godoc
+// @Summary Report the playback position
+// @Description Record the position of the authenticated user in the video, replacing the previous one
+// @Tags video
+// @Accept json
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param request body VideoPositionRequest true "Position in seconds"
+// @Success 200 {object} jsonDTO.WatchPositionJson "Recorded position"
+// @Failure 400 {string} string "Invalid position, or not a user account"
+// @Failure 403 {string} string "API key without the videos:write scope"
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/position [put]
+func (v VideoPositionUpdateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("PUT VideoPositionUpdateHandler - parameters ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	userID := auth.OwnerID(r.Context())
+	if userID == nil {
+		log.Error(errNoWatchHistory)
+		http.Error(w, errNoWatchHistory.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var request VideoPositionRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		log.Error("Cannot decode position request : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if request.Position == nil || *request.Position < 0 {
+		log.Error("Invalid position request")
+		http.Error(w, "position must be a number of seconds from 0", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := v.VideosDAO.GetVideo(r.Context(), id); err != nil {
+		log.Error("Cannot found video : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	position, err := v.WatchHistoryDAO.UpsertWatchPosition(r.Context(), *userID, id, *request.Position)
+	if err != nil {
+		log.Error("Cannot record watch position : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeWatchPositionJson(w, position)
+}
+
+type VideoPositionGetHandler struct {
+	WatchHistoryDAO *dao.WatchHistoryDAO
+	UUIDGen         clients.IUUIDGenerator
+}
+
+// VideoPositionGetHandler godoc
+// @Summary Get the playback position
+// @Description Get the last position reported by the authenticated user in the video
+// @Tags video
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} jsonDTO.WatchPositionJson "Last position"
+// @Failure 400 {string} string "Not a user account"
+// @Failure 404 {string} string "Video never watched"
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/position [get]
+func (v VideoPositionGetHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET VideoPositionGetHandler - parameters ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	userID := auth.OwnerID(r.Context())
+	if userID == nil {
+		log.Error(errNoWatchHistory)
+		http.Error(w, errNoWatchHistory.Error(), http.StatusBadRequest)
+		return
+	}
+
+	position, err := v.WatchHistoryDAO.GetWatchPosition(r.Context(), *userID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeWatchPositionJson(w, position)
+}
+
+type WatchHistoryHandler struct {
+	WatchHistoryDAO *dao.WatchHistoryDAO
+}
+
+// WatchHistoryHandler godoc
+// @Summary Get the watch history
+// @Description Get the last videos watched by the authenticated user with their position, most recent first
+// @Tags video
+// @Produce json
+// @Param limit query int false "Videos to return, at most 100" default(20)
+// @Success 200 {object} WatchHistoryResponse "Watch history"
+// @Failure 400 {string} string "Invalid limit, or not a user account"
+// @Failure 500 {string} string
+// @Router /api/v1/history [get]
+func (v WatchHistoryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GET WatchHistoryHandler")
+
+	userID := auth.OwnerID(r.Context())
+	if userID == nil {
+		log.Error(errNoWatchHistory)
+		http.Error(w, errNoWatchHistory.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultWatchHistoryLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxWatchHistoryLimit {
+			log.Error("Invalid limit ", value)
+			http.Error(w, "Limit must be between 1 and "+strconv.Itoa(maxWatchHistoryLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	history, err := v.WatchHistoryDAO.GetWatchHistory(r.Context(), *userID, limit)
+	if err != nil {
+		log.Error("Cannot get watch history : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := WatchHistoryResponse{
+		History: make([]jsonDTO.WatchPositionJson, 0, len(history)),
+		Links: map[string]jsonDTO.LinkJson{
+			"self": jsonDTO.LinkToLinkJson(models.CreateLink("api/v1/history", "GET")),
+		},
+	}
+	for i := range history {
+		response.History = append(response.History, jsonDTO.WatchPositionToWatchPositionJson(&history[i]))
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(payload)
+}
+
+func writeWatchPositionJson(w http.ResponseWriter, position *models.WatchPosition) {
+	payload, err := json.Marshal(jsonDTO.WatchPositionToWatchPositionJson(position))
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(payload)
+}
@@ -0,0 +1,284 @@
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+)
+
+var watchHistoryColumns = []string{"user_id", "video_id", "position", "watched_at"}
+
+func TestWatchHistory(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	validVideoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	unknownVideoID := "0000a0a0-0aa0-0a00-0000-aa0000aa00aa"
+	t1 := time.Now()
+
+	expectVideoLookup := func(mock sqlmock.Sqlmock, ID string) {
+		videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
+		rows := sqlmock.NewRows(videosColumns)
+		if ID == validVideoID {
+			rows.AddRow(validVideoID, "lecture", int(models.COMPLETE), t1, t1, t1, "", "", nil, "", nil, nil)
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WithArgs(ID).WillReturnRows(rows)
+	}
+	expectPositionLookup := func(mock sqlmock.Sqlmock, position *float64) {
+		rows := sqlmock.NewRows(watchHistoryColumns)
+		if position != nil {
+			rows.AddRow(accountID, validVideoID, *position, t1)
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(dao.WatchHistoryRequests[dao.GetWatchPosition])).WithArgs(accountID, validVideoID).WillReturnRows(rows)
+	}
+	position := 754.2
+
+	cases := []struct {
+		name             string
+		giveMethod       string
+		giveRequest      string
+		giveBody         string
+		giveAccount      bool
+		expectDB         func(mock sqlmock.Sqlmock)
+		expectedHTTPCode int
+		expectedPosition *float64
+	}{
+		{
+			name:        "PUT position",
+			giveMethod:  "PUT",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/position",
+			giveBody:    `{"position": 754.2}`,
+			giveAccount: true,
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, validVideoID)
+				mock.ExpectExec(regexp.QuoteMeta(dao.WatchHistoryRequests[dao.UpsertWatchPosition])).
+					WithArgs(accountID, validVideoID, position).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectPositionLookup(mock, &position)
+			},
+			expectedHTTPCode: 200,
+			expectedPosition: &position,
+		},
+		{
+			name:             "PUT fails with shared account",
+			giveMethod:       "PUT",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/position",
+			giveBody:         `{"position": 754.2}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "PUT fails with negative position",
+			giveMethod:       "PUT",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/position",
+			giveBody:         `{"position": -1}`,
+			giveAccount:      true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "PUT fails without position",
+			giveMethod:       "PUT",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/position",
+			giveBody:         `{}`,
+			giveAccount:      true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:        "PUT fails with unknown video",
+			giveMethod:  "PUT",
+			giveRequest: "/api/v1/videos/" + unknownVideoID + "/position",
+			giveBody:    `{"position": 754.2}`,
+			giveAccount: true,
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, unknownVideoID)
+			},
+			expectedHTTPCode: 404,
+		},
+		{
+			name:        "PUT fails with database error",
+			giveMethod:  "PUT",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/position",
+			giveBody:    `{"position": 754.2}`,
+			giveAccount: true,
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, validVideoID)
+				mock.ExpectExec(regexp.QuoteMeta(dao.WatchHistoryRequests[dao.UpsertWatchPosition])).WillReturnError(fmt.Errorf("database internal error"))
+			},
+			expectedHTTPCode: 500,
+		},
+		{
+			name:        "GET position",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/position",
+			giveAccount: true,
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectPositionLookup(mock, &position)
+			},
+			expectedHTTPCode: 200,
+			expectedPosition: &position,
+		},
+		{
+			name:        "GET fails with video never watched",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/position",
+			giveAccount: true,
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectPositionLookup(mock, nil)
+			},
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "GET fails with invalid video ID",
+			giveMethod:       "GET",
+			giveRequest:      "/api/v1/videos/invalid/position",
+			giveAccount:      true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:        "GET video info with last position",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/info",
+			giveAccount: true,
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, validVideoID)
+				mock.ExpectQuery(regexp.QuoteMeta(dao.TagsRequests[dao.GetVideoTags])).WithArgs(validVideoID).WillReturnRows(sqlmock.NewRows([]string{"tag"}))
+				mock.ExpectQuery(regexp.QuoteMeta(dao.AudioLanguagesRequests[dao.GetVideoAudioLanguages])).WithArgs(validVideoID).WillReturnRows(sqlmock.NewRows([]string{"language"}))
+				expectPositionLookup(mock, &position)
+			},
+			expectedHTTPCode: 200,
+			expectedPosition: &position,
+		},
+		{
+			name:        "GET video info never watched",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + validVideoID + "/info",
+			giveAccount: true,
+			expectDB: func(mock sqlmock.Sqlmock) {
+				expectVideoLookup(mock, validVideoID)
+				mock.ExpectQuery(regexp.QuoteMeta(dao.TagsRequests[dao.GetVideoTags])).WithArgs(validVideoID).WillReturnRows(sqlmock.NewRows([]string{"tag"}))
+				mock.ExpectQuery(regexp.QuoteMeta(dao.AudioLanguagesRequests[dao.GetVideoAudioLanguages])).WithArgs(validVideoID).WillReturnRows(sqlmock.NewRows([]string{"language"}))
+				expectPositionLookup(mock, nil)
+			},
+			expectedHTTPCode: 200,
+		},
+		{
+			name:        "GET history",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/history?limit=2",
+			giveAccount: true,
+			expectDB: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(watchHistoryColumns).
+					AddRow(accountID, validVideoID, position, t1).
+					AddRow(accountID, unknownVideoID, 12.0, t1.Add(-time.Hour))
+				mock.ExpectQuery(regexp.QuoteMeta(dao.WatchHistoryRequests[dao.GetWatchHistory])).WithArgs(accountID, 2).WillReturnRows(rows)
+			},
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "GET history fails with invalid limit",
+			giveMethod:       "GET",
+			giveRequest:      "/api/v1/history?limit=1000",
+			giveAccount:      true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "GET history fails with shared account",
+			giveMethod:       "GET",
+			giveRequest:      "/api/v1/history",
+			expectedHTTPCode: 400,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectUsersDAOCreation(mock)
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectTagsDAOCreation(mock)
+			dao_test.ExpectAudioLanguagesDAOCreation(mock)
+			dao_test.ExpectWatchHistoryDAOCreation(mock)
+
+			if tt.giveAccount {
+				expectAccountLookup(t, mock)
+			}
+			if tt.expectDB != nil {
+				tt.expectDB(mock)
+			}
+
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+			tagsDAO, err := dao.CreateTagsDAO(context.Background(), db)
+			require.NoError(t, err)
+			audioLanguagesDAO, err := dao.CreateAudioLanguagesDAO(context.Background(), db)
+			require.NoError(t, err)
+			watchHistoryDAO, err := dao.CreateWatchHistoryDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r, err := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &router.Clients{
+				UUIDGen: clients.NewUuidGeneratorDummy(nil, func(u string) bool { _, err := uuid.Parse(u); return err == nil }),
+			}, &router.DAOs{UsersDAO: *usersDAO, VideosDAO: *videosDAO, TagsDAO: *tagsDAO, AudioLanguagesDAO: *audioLanguagesDAO, WatchHistoryDAO: *watchHistoryDAO})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.giveMethod, tt.giveRequest, strings.NewReader(tt.giveBody))
+			if tt.giveAccount {
+				req.SetBasicAuth(accountUsername, accountPassword)
+			} else {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedHTTPCode == 200 {
+				switch {
+				case strings.HasSuffix(tt.giveRequest, "/position"):
+					var response jsonDTO.WatchPositionJson
+					require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+					require.Equal(t, validVideoID, response.VideoID)
+					require.Equal(t, *tt.expectedPosition, response.Position)
+
+				case strings.HasSuffix(tt.giveRequest, "/info"):
+					var response jsonDTO.VideoInfo
+					require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+					require.Equal(t, tt.expectedPosition, response.LastPosition)
+
+				default:
+					var response controllers.WatchHistoryResponse
+					require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+					require.Len(t, response.History, 2)
+					require.Equal(t, validVideoID, response.History[0].VideoID)
+					require.Equal(t, unknownVideoID, response.History[1].VideoID)
+				}
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -15,13 +18,13 @@ import (
 	"github.com/Sogilis/Voogle/src/pkg/clients"
 	contracts "github.com/Sogilis/Voogle/src/pkg/contracts/v1"
 
-	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
 	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
 	"github.com/Sogilis/Voogle/src/cmd/api/dto/protobuf"
 )
 
 type WSHandler struct {
-	Config                config.Config
+	Authenticator         auth.Authenticator
 	AmqpVideoStatusUpdate clients.AmqpClient
 }
 
@@ -31,7 +34,9 @@ type WSHandler struct {
 // @Tags websocket
 // @Accept plain
 // @Produce plain
-// @Param Cookie header string true "Authentication cookie"
+// @Param Authorization header string false "Bearer access token"
+// @Param access_token query string false "Access token, for the clients that cannot set headers"
+// @Param Cookie header string false "Authentication cookie with basic auth credentials"
 // @Success 101 {string} string
 // @Failure 400 {string} string
 // @Failure 401 {string} string
@@ -44,21 +49,11 @@ func (wsh WSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	upgrader := websocket.Upgrader{}
 
 	upgrader.CheckOrigin = func(r *http.Request) bool {
-
-		decodedData, err := decodeAuthorization(r)
-		if err != nil {
-			log.Error("Could not decode data", err)
+		if err := wsh.authenticate(r); err != nil {
+			log.Error("Cannot authenticate websocket : ", err)
 			return false
 		}
-
-		givenUser, givenPass := extractCredentials(decodedData)
-
-		// We check the data match our expectation.
-		if givenUser == wsh.Config.UserAuth &&
-			givenPass == wsh.Config.PwdAuth {
-			return true
-		}
-		return false
+		return true
 	}
 
 	conn, err := upgrader.Upgrade(w, r, nil)
@@ -99,26 +94,56 @@ var HandleMessage = func(ctx context.Context, wsh *WSHandler, randomQueueName st
 	conn.Close()
 }
 
+// authenticate checks the access token, given as a bearer token or as the access_token query parameter
+// (browsers cannot set headers on websockets), or else the basic auth credentials of the Authorization cookie
+func (wsh WSHandler) authenticate(r *http.Request) error {
+	token, ok := auth.BearerToken(r)
+	if !ok {
+		token = r.URL.Query().Get("access_token")
+	}
+	if token != "" {
+		_, err := wsh.Authenticator.AuthenticateToken(r.Context(), token)
+		return err
+	}
+
+	decodedData, err := decodeAuthorization(r)
+	if err != nil {
+		return err
+	}
+
+	givenUser, givenPass, err := extractCredentials(decodedData)
+	if err != nil {
+		return err
+	}
+
+	_, err = wsh.Authenticator.AuthenticateCredentials(r.Context(), givenUser, givenPass)
+	return err
+}
+
 func decodeAuthorization(r *http.Request) (decodedData []byte, err error) {
 	authCookie, err := r.Cookie("Authorization")
 	if err != nil {
-		log.Error("No such cookie", err)
+		return nil, err
 	}
-	strCookie := authCookie.String()
-	auth := strCookie[len("Authorization="):]
-	decodedData, err = base64.StdEncoding.DecodeString(auth[len("Basic%20"):])
+
+	// The webapp sets the header value, URL encoded
+	value, err := url.QueryUnescape(authCookie.Value)
 	if err != nil {
 		return nil, err
 	}
+	if !strings.HasPrefix(value, "Basic ") {
+		return nil, errors.New("authorization cookie is not basic auth")
+	}
 
-	return decodedData, nil
+	return base64.StdEncoding.DecodeString(value[len("Basic "):])
 }
 
-func extractCredentials(data []byte) (username string, password string) {
+func extractCredentials(data []byte) (username string, password string, err error) {
 	creds := bytes.SplitN(data, []byte(":"), 2)
-	givenUser := string(creds[0])
-	givenPass := string(creds[1])
-	return givenUser, givenPass
+	if len(creds) != 2 {
+		return "", "", errors.New("credentials must be username:password")
+	}
+	return string(creds[0]), string(creds[1]), nil
 }
 
 func (wsh *WSHandler) handleClientMessage(ctx context.Context, clear context.CancelFunc, randomQueueName string, conn *websocket.Conn) {
@@ -166,7 +191,7 @@ func (wsh *WSHandler) handleUpdateMessage(ctx context.Context, randomQueueName s
 				}
 				video := protobuf.VideoProtobufToVideo(videoProto)
 				video.Title = d.RoutingKey
-				msg, err := json.Marshal(jsonDTO.VideoToStatusJson(video))
+				msg, err := json.Marshal(jsonDTO.VideoToStatusJson(video, int(videoProto.UploadProgress)))
 				if err != nil {
 					log.Error("Failed to marshall response to front :", err)
 				}
@@ -5,13 +5,19 @@ import (
 	"encoding/base64"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	hijack "github.com/getlantern/httptest"
 	"github.com/gorilla/websocket"
 
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
 	"github.com/Sogilis/Voogle/src/cmd/api/config"
 	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
 	"github.com/Sogilis/Voogle/src/cmd/api/router"
 	"github.com/Sogilis/Voogle/src/pkg/clients"
 	"github.com/stretchr/testify/require"
@@ -21,10 +27,21 @@ func TestWebsocket(t *testing.T) { //nolint:cyclop
 	requiredUsername := "valid"
 	requiredPassword := "valid"
 
+	tokens := auth.TokenManager{
+		Keys:             map[string][]byte{"key-1": []byte("secret")},
+		SigningKeyID:     "key-1",
+		AccessExpiration: time.Minute,
+	}
+	validTokens, err := tokens.NewTokens(nil, requiredUsername)
+	require.NoError(t, err)
+
 	cases := []struct {
 		name             string
 		givenUsername    string
 		givenPassword    string
+		givenNoCookie    bool
+		givenHeaderToken string
+		givenQueryToken  string
 		expectedResponse int
 	}{
 		{
@@ -45,6 +62,35 @@ func TestWebsocket(t *testing.T) { //nolint:cyclop
 			givenPassword:    "invalid",
 			expectedResponse: 403,
 		},
+		{
+			name:             "Authentication Fail with no cookie",
+			givenNoCookie:    true,
+			expectedResponse: 403,
+		},
+		{
+			name:             "Authentication Succeed with bearer token",
+			givenNoCookie:    true,
+			givenHeaderToken: validTokens.AccessToken,
+			expectedResponse: 200,
+		},
+		{
+			name:             "Authentication Succeed with query token",
+			givenNoCookie:    true,
+			givenQueryToken:  validTokens.AccessToken,
+			expectedResponse: 200,
+		},
+		{
+			name:             "Authentication Fail with refresh token",
+			givenNoCookie:    true,
+			givenQueryToken:  validTokens.RefreshToken,
+			expectedResponse: 403,
+		},
+		{
+			name:             "Authentication Fail with invalid token",
+			givenNoCookie:    true,
+			givenHeaderToken: "invalid",
+			expectedResponse: 403,
+		},
 	}
 
 	for _, tt := range cases {
@@ -53,17 +99,34 @@ func TestWebsocket(t *testing.T) { //nolint:cyclop
 			controllers.HandleMessage = func(ctx context.Context, wsh *controllers.WSHandler, randomQueueName string, conn *websocket.Conn) {
 			}
 
-			encodedAuth := "Basic%20" + base64.StdEncoding.EncodeToString([]byte(tt.givenUsername+":"+tt.givenPassword))
-			authCookie := http.Cookie{Name: "Authorization", Value: encodedAuth}
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectUsersDAOCreation(mock)
+			if !tt.givenNoCookie && (tt.givenUsername != requiredUsername || tt.givenPassword != requiredPassword) {
+				getUserQuery := regexp.QuoteMeta(dao.UsersRequests[dao.GetUserFromUsername])
+				mock.ExpectQuery(getUserQuery).WithArgs(tt.givenUsername).WillReturnRows(sqlmock.NewRows(usersColumns))
+			}
+
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
 
 			givenRequest := "/ws"
+			if tt.givenQueryToken != "" {
+				givenRequest += "?access_token=" + tt.givenQueryToken
+			}
 
 			amqpDummy := clients.NewAmqpClientDummy(nil, nil, nil)
 
-			r := router.NewRouter(config.Config{
-				UserAuth: requiredUsername,
-				PwdAuth:  requiredPassword,
-			}, &router.Clients{AmqpVideoStatusUpdate: amqpDummy}, &router.DAOs{})
+			r, err := router.NewRouter(config.Config{
+				UserAuth:            requiredUsername,
+				PwdAuth:             requiredPassword,
+				JWTKeys:             []string{"key-1:secret"},
+				JWTKeyID:            "key-1",
+				JWTAccessExpiration: time.Minute,
+			}, &router.Clients{AmqpVideoStatusUpdate: amqpDummy}, &router.DAOs{UsersDAO: *usersDAO})
+			require.NoError(t, err)
 
 			w := hijack.NewRecorder(nil)
 
@@ -73,11 +136,18 @@ func TestWebsocket(t *testing.T) { //nolint:cyclop
 			req.Header.Set("Sec-WebSocket-Version", "13")
 			req.Header.Set("Sec-WebSocket-Key", "42")
 			req.Header.Set("Sec-WebSocket-Extensions", "permessage-deflate")
-			req.AddCookie(&authCookie)
+			if !tt.givenNoCookie {
+				encodedAuth := "Basic%20" + base64.StdEncoding.EncodeToString([]byte(tt.givenUsername+":"+tt.givenPassword))
+				req.AddCookie(&http.Cookie{Name: "Authorization", Value: encodedAuth})
+			}
+			if tt.givenHeaderToken != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.givenHeaderToken)
+			}
 
 			r.ServeHTTP(w, req)
 			require.Equal(t, tt.expectedResponse, w.Code())
 
+			require.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
 
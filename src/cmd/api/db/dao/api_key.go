@@ -0,0 +1,262 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type ApiKeysRequestName int
+
+const (
+	CreateTableApiKeysReq ApiKeysRequestName = iota
+	CreateApiKey
+	GetApiKey
+	GetApiKeyFromHash
+	GetApiKeys
+	RevokeApiKey
+	UpdateApiKeyLastUsed
+)
+
+var ApiKeysRequests = map[ApiKeysRequestName]string{
+	CreateTableApiKeysReq: `CREATE TABLE IF NOT EXISTS api_keys (
+			id              VARCHAR(36) NOT NULL,
+			name            VARCHAR(64) NOT NULL,
+			prefix          VARCHAR(16) NOT NULL,
+			key_hash        CHAR(64) NOT NULL,
+			scopes          VARCHAR(255) NOT NULL,
+			created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			last_used_at    DATETIME DEFAULT NULL,
+			revoked_at      DATETIME DEFAULT NULL,
+
+			CONSTRAINT pk PRIMARY KEY (id),
+			CONSTRAINT unique_key_hash UNIQUE (key_hash)
+		);`,
+
+	CreateApiKey:         "INSERT INTO api_keys (id, name, prefix, key_hash, scopes) VALUES (?, ?, ?, ?, ?)",
+	GetApiKey:            "SELECT * FROM api_keys WHERE id = ?",
+	GetApiKeyFromHash:    "SELECT * FROM api_keys WHERE key_hash = ?",
+	GetApiKeys:           "SELECT * FROM api_keys ORDER BY created_at DESC",
+	RevokeApiKey:         "UPDATE api_keys SET revoked_at = NOW() WHERE id = ? AND revoked_at IS NULL",
+	UpdateApiKeyLastUsed: "UPDATE api_keys SET last_used_at = NOW() WHERE id = ?",
+}
+
+type ApiKeysDAO struct {
+	DB                       *sql.DB
+	stmtCreateApiKey         *sql.Stmt
+	stmtGetApiKey            *sql.Stmt
+	stmtGetApiKeyFromHash    *sql.Stmt
+	stmtGetApiKeys           *sql.Stmt
+	stmtRevokeApiKey         *sql.Stmt
+	stmtUpdateApiKeyLastUsed *sql.Stmt
+}
+
+func prepareApiKeyStmts(ctx context.Context, db *sql.DB) (*ApiKeysDAO, error) {
+	stmts := ApiKeysDAO{}
+
+	// CreateApiKey
+	var err error
+	stmts.stmtCreateApiKey, err = db.PrepareContext(ctx, ApiKeysRequests[CreateApiKey])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetApiKey
+	stmts.stmtGetApiKey, err = db.PrepareContext(ctx, ApiKeysRequests[GetApiKey])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetApiKeyFromHash
+	stmts.stmtGetApiKeyFromHash, err = db.PrepareContext(ctx, ApiKeysRequests[GetApiKeyFromHash])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetApiKeys
+	stmts.stmtGetApiKeys, err = db.PrepareContext(ctx, ApiKeysRequests[GetApiKeys])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// RevokeApiKey
+	stmts.stmtRevokeApiKey, err = db.PrepareContext(ctx, ApiKeysRequests[RevokeApiKey])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// UpdateApiKeyLastUsed
+	stmts.stmtUpdateApiKeyLastUsed, err = db.PrepareContext(ctx, ApiKeysRequests[UpdateApiKeyLastUsed])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	return &stmts, nil
+}
+
+func createTableApiKeys(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, ApiKeysRequests[CreateTableApiKeysReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+
+	log.Debug("Table api_keys created (or existed already)")
+	return nil
+}
+
+func CreateApiKeysDAO(ctx context.Context, db *sql.DB) (*ApiKeysDAO, error) {
+	if err := createTableApiKeys(ctx, db); err != nil {
+		log.Error("Cannot create table api_keys : ", err)
+		return nil, err
+	}
+
+	apiKeyDAO, err := prepareApiKeyStmts(ctx, db)
+	if err != nil {
+		log.Error("Cannot prepare api_keys statements : ", err)
+		return nil, err
+	}
+
+	apiKeyDAO.DB = db
+
+	return apiKeyDAO, nil
+}
+
+// CreateApiKey stores the key from its hash, its scopes are stored comma separated
+func (a ApiKeysDAO) CreateApiKey(ctx context.Context, ID, name, prefix, keyHash string, scopes []string) (*models.ApiKey, error) {
+	res, err := a.stmtCreateApiKey.ExecContext(ctx, ID, name, prefix, keyHash, strings.Join(scopes, ","))
+	if err != nil {
+		log.Error("Error while insert into api_keys : ", err)
+		return nil, err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return nil, err
+	}
+
+	// Check if one and only one rows has been affected
+	if nbRowAff != 1 {
+		err := fmt.Errorf("wrong number of row affected (%d) while creating api key id : %v", nbRowAff, ID)
+		log.Error(err)
+		return nil, err
+	}
+
+	return a.GetApiKey(ctx, ID)
+}
+
+func (a ApiKeysDAO) GetApiKey(ctx context.Context, ID string) (*models.ApiKey, error) {
+	return scanApiKey(a.stmtGetApiKey.QueryRowContext(ctx, ID))
+}
+
+func (a ApiKeysDAO) GetApiKeyFromHash(ctx context.Context, keyHash string) (*models.ApiKey, error) {
+	return scanApiKey(a.stmtGetApiKeyFromHash.QueryRowContext(ctx, keyHash))
+}
+
+// GetApiKeys returns all the keys, revoked ones included, the newest first
+func (a ApiKeysDAO) GetApiKeys(ctx context.Context) ([]models.ApiKey, error) {
+	rows, err := a.stmtGetApiKeys.QueryContext(ctx)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	apiKeys := []models.ApiKey{}
+	for rows.Next() {
+		apiKey, err := scanApiKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		apiKeys = append(apiKeys, *apiKey)
+	}
+
+	return apiKeys, nil
+}
+
+func (a ApiKeysDAO) RevokeApiKey(ctx context.Context, ID string) error {
+	res, err := a.stmtRevokeApiKey.ExecContext(ctx, ID)
+	if err != nil {
+		log.Error("Error while update api_keys : ", err)
+		return err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return err
+	}
+
+	// Check if one and only one rows has been affected
+	if nbRowAff != 1 {
+		err := fmt.Errorf("wrong number of row affected (%d) while revoking api key id : %v", nbRowAff, ID)
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (a ApiKeysDAO) UpdateApiKeyLastUsed(ctx context.Context, ID string) error {
+	if _, err := a.stmtUpdateApiKeyLastUsed.ExecContext(ctx, ID); err != nil {
+		log.Error("Error while update api_keys : ", err)
+		return err
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanApiKey(row rowScanner) (*models.ApiKey, error) {
+	var apiKey models.ApiKey
+	var scopes string
+	err := row.Scan(
+		&apiKey.ID,
+		&apiKey.Name,
+		&apiKey.Prefix,
+		&apiKey.KeyHash,
+		&scopes,
+		&apiKey.CreatedAt,
+		&apiKey.LastUsedAt,
+		&apiKey.RevokedAt,
+	)
+	if err != nil {
+		log.Error("Error, api key not found : ", err)
+		return nil, err
+	}
+
+	apiKey.Scopes = []string{}
+	if scopes != "" {
+		apiKey.Scopes = strings.Split(scopes, ",")
+	}
+
+	return &apiKey, nil
+}
+
+func (a ApiKeysDAO) Close() {
+	_ = a.stmtCreateApiKey.Close()
+	_ = a.stmtGetApiKey.Close()
+	_ = a.stmtGetApiKeyFromHash.Close()
+	_ = a.stmtGetApiKeys.Close()
+	_ = a.stmtRevokeApiKey.Close()
+	_ = a.stmtUpdateApiKeyLastUsed.Close()
+}
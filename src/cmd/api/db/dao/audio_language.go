@@ -0,0 +1,172 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+
+	_ "github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+)
+
+type AudioLanguagesRequestName int
+
+const (
+	CreateTableAudioLanguagesReq AudioLanguagesRequestName = iota
+	GetVideoAudioLanguages
+	AddVideoAudioLanguage
+	DeleteVideoAudioLanguages
+)
+
+var AudioLanguagesRequests = map[AudioLanguagesRequestName]string{
+	CreateTableAudioLanguagesReq: `CREATE TABLE IF NOT EXISTS video_audio_languages (
+			video_id        VARCHAR(36) NOT NULL,
+			language        VARCHAR(8) NOT NULL,
+			position        INT NOT NULL,
+
+			CONSTRAINT pk PRIMARY KEY (video_id, language),
+			CONSTRAINT fk_al_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE
+		);`,
+
+	// Languages of the audio renditions, the default one first
+	GetVideoAudioLanguages:    "SELECT language FROM video_audio_languages WHERE video_id = ? ORDER BY position ASC",
+	AddVideoAudioLanguage:     "INSERT INTO video_audio_languages (video_id, language, position) VALUES (?, ?, ?)",
+	DeleteVideoAudioLanguages: "DELETE FROM video_audio_languages WHERE video_id = ?",
+}
+
+type AudioLanguagesDAO struct {
+	DB                            *sql.DB
+	stmtGetVideoAudioLanguages    *sql.Stmt
+	stmtAddVideoAudioLanguage     *sql.Stmt
+	stmtDeleteVideoAudioLanguages *sql.Stmt
+}
+
+func prepareAudioLanguageStmts(ctx context.Context, db *sql.DB) (*AudioLanguagesDAO, error) {
+	stmts := AudioLanguagesDAO{}
+
+	// GetVideoAudioLanguages
+	var err error
+	stmts.stmtGetVideoAudioLanguages, err = db.PrepareContext(ctx, AudioLanguagesRequests[GetVideoAudioLanguages])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// AddVideoAudioLanguage
+	stmts.stmtAddVideoAudioLanguage, err = db.PrepareContext(ctx, AudioLanguagesRequests[AddVideoAudioLanguage])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// DeleteVideoAudioLanguages
+	stmts.stmtDeleteVideoAudioLanguages, err = db.PrepareContext(ctx, AudioLanguagesRequests[DeleteVideoAudioLanguages])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	return &stmts, nil
+}
+
+func createTableAudioLanguages(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, AudioLanguagesRequests[CreateTableAudioLanguagesReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+
+	log.Debug("Table video_audio_languages created (or existed already)")
+	return nil
+}
+
+func CreateAudioLanguagesDAO(ctx context.Context, db *sql.DB) (*AudioLanguagesDAO, error) {
+	if err := createTableAudioLanguages(ctx, db); err != nil {
+		log.Error("Cannot create table video_audio_languages : ", err)
+		return nil, err
+	}
+
+	audioLanguageDAO, err := prepareAudioLanguageStmts(ctx, db)
+	if err != nil {
+		log.Error("Cannot prepare video_audio_languages statements : ", err)
+		return nil, err
+	}
+
+	audioLanguageDAO.DB = db
+
+	return audioLanguageDAO, nil
+}
+
+func (a AudioLanguagesDAO) GetVideoAudioLanguages(ctx context.Context, videoID string) ([]string, error) {
+	rows, err := a.stmtGetVideoAudioLanguages.QueryContext(ctx, videoID)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	languages := []string{}
+	for rows.Next() {
+		var language string
+		if err := rows.Scan(&language); err != nil {
+			log.Error("Cannot read rows : ", err)
+			return nil, err
+		}
+		languages = append(languages, language)
+	}
+
+	return languages, nil
+}
+
+// UpdateVideoAudioLanguages replaces all the audio languages of the video, in their order
+func (a AudioLanguagesDAO) UpdateVideoAudioLanguages(ctx context.Context, videoID string, languages []string) error {
+	tx, err := a.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("Cannot open new database transaction : ", err)
+		return err
+	}
+
+	if err = a.updateVideoAudioLanguagesTx(ctx, tx, videoID, languages); err != nil {
+		if err := tx.Rollback(); err != nil {
+			log.Error("Cannot rollback : ", err)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("Cannot commit database transaction")
+		if err := tx.Rollback(); err != nil {
+			log.Error("Cannot rollback : ", err)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (a AudioLanguagesDAO) updateVideoAudioLanguagesTx(ctx context.Context, tx *sql.Tx, videoID string, languages []string) error {
+	if _, err := tx.StmtContext(ctx, a.stmtDeleteVideoAudioLanguages).ExecContext(ctx, videoID); err != nil {
+		log.Error("Error while delete from video_audio_languages : ", err)
+		return err
+	}
+
+	stmt := tx.StmtContext(ctx, a.stmtAddVideoAudioLanguage)
+	for position, language := range languages {
+		if _, err := stmt.ExecContext(ctx, videoID, language, position); err != nil {
+			log.Error("Error while insert into video_audio_languages : ", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a AudioLanguagesDAO) Close() {
+	_ = a.stmtGetVideoAudioLanguages.Close()
+	_ = a.stmtAddVideoAudioLanguage.Close()
+	_ = a.stmtDeleteVideoAudioLanguages.Close()
+}
@@ -0,0 +1,174 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+
+	_ "github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type ChaptersRequestName int
+
+const (
+	CreateTableChaptersReq ChaptersRequestName = iota
+	GetVideoChapters
+	AddVideoChapter
+	DeleteVideoChapters
+)
+
+var ChaptersRequests = map[ChaptersRequestName]string{
+	CreateTableChaptersReq: `CREATE TABLE IF NOT EXISTS chapters (
+			video_id        VARCHAR(36) NOT NULL,
+			start_time      DOUBLE NOT NULL,
+			title           VARCHAR(128) NOT NULL,
+
+			CONSTRAINT pk PRIMARY KEY (video_id, start_time),
+			CONSTRAINT fk_c_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE
+		);`,
+
+	GetVideoChapters:    "SELECT start_time, title FROM chapters WHERE video_id = ? ORDER BY start_time ASC",
+	AddVideoChapter:     "INSERT INTO chapters (video_id, start_time, title) VALUES (?, ?, ?)",
+	DeleteVideoChapters: "DELETE FROM chapters WHERE video_id = ?",
+}
+
+type ChaptersDAO struct {
+	DB                      *sql.DB
+	stmtGetVideoChapters    *sql.Stmt
+	stmtAddVideoChapter     *sql.Stmt
+	stmtDeleteVideoChapters *sql.Stmt
+}
+
+func prepareChapterStmts(ctx context.Context, db *sql.DB) (*ChaptersDAO, error) {
+	stmts := ChaptersDAO{}
+
+	// GetVideoChapters
+	var err error
+	stmts.stmtGetVideoChapters, err = db.PrepareContext(ctx, ChaptersRequests[GetVideoChapters])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// AddVideoChapter
+	stmts.stmtAddVideoChapter, err = db.PrepareContext(ctx, ChaptersRequests[AddVideoChapter])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// DeleteVideoChapters
+	stmts.stmtDeleteVideoChapters, err = db.PrepareContext(ctx, ChaptersRequests[DeleteVideoChapters])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	return &stmts, nil
+}
+
+func createTableChapters(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, ChaptersRequests[CreateTableChaptersReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+
+	log.Debug("Table chapters created (or existed already)")
+	return nil
+}
+
+func CreateChaptersDAO(ctx context.Context, db *sql.DB) (*ChaptersDAO, error) {
+	if err := createTableChapters(ctx, db); err != nil {
+		log.Error("Cannot create table chapters : ", err)
+		return nil, err
+	}
+
+	chapterDAO, err := prepareChapterStmts(ctx, db)
+	if err != nil {
+		log.Error("Cannot prepare chapters statements : ", err)
+		return nil, err
+	}
+
+	chapterDAO.DB = db
+
+	return chapterDAO, nil
+}
+
+// GetVideoChapters returns the chapters of the video, sorted on their start
+func (c ChaptersDAO) GetVideoChapters(ctx context.Context, videoID string) ([]models.Chapter, error) {
+	rows, err := c.stmtGetVideoChapters.QueryContext(ctx, videoID)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	chapters := []models.Chapter{}
+	for rows.Next() {
+		var chapter models.Chapter
+		if err := rows.Scan(&chapter.Start, &chapter.Title); err != nil {
+			log.Error("Cannot read rows : ", err)
+			return nil, err
+		}
+		chapters = append(chapters, chapter)
+	}
+
+	return chapters, nil
+}
+
+// UpdateVideoChapters replaces all the chapters of the video
+func (c ChaptersDAO) UpdateVideoChapters(ctx context.Context, videoID string, chapters []models.Chapter) error {
+	tx, err := c.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("Cannot open new database transaction : ", err)
+		return err
+	}
+
+	if err = c.updateVideoChaptersTx(ctx, tx, videoID, chapters); err != nil {
+		if err := tx.Rollback(); err != nil {
+			log.Error("Cannot rollback : ", err)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("Cannot commit database transaction")
+		if err := tx.Rollback(); err != nil {
+			log.Error("Cannot rollback : ", err)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (c ChaptersDAO) updateVideoChaptersTx(ctx context.Context, tx *sql.Tx, videoID string, chapters []models.Chapter) error {
+	if _, err := tx.StmtContext(ctx, c.stmtDeleteVideoChapters).ExecContext(ctx, videoID); err != nil {
+		log.Error("Error while delete from chapters : ", err)
+		return err
+	}
+
+	stmt := tx.StmtContext(ctx, c.stmtAddVideoChapter)
+	for _, chapter := range chapters {
+		if _, err := stmt.ExecContext(ctx, videoID, chapter.Start, chapter.Title); err != nil {
+			log.Error("Error while insert into chapters : ", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c ChaptersDAO) Close() {
+	_ = c.stmtGetVideoChapters.Close()
+	_ = c.stmtAddVideoChapter.Close()
+	_ = c.stmtDeleteVideoChapters.Close()
+}
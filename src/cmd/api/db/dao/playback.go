@@ -0,0 +1,384 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type PlaybackRequestName int
+
+const (
+	CreateTablePlaybackSessionsReq PlaybackRequestName = iota
+	CreateTablePlaybackSegmentsReq
+	CreatePlaybackSession
+	UpdatePlaybackSessionLength
+	AddPlaybackSegment
+	IncrementPlaybackSegmentsWatched
+	GetVideoStats
+	GetVideosViewsAsc
+	GetVideosViewsDesc
+	GetVideosAfterViewsAsc
+	GetVideosAfterViewsDesc
+)
+
+var PlaybackRequests = map[PlaybackRequestName]string{
+	CreateTablePlaybackSessionsReq: `CREATE TABLE IF NOT EXISTS playback_sessions (
+			id                  VARCHAR(36) NOT NULL,
+			video_id            VARCHAR(36) NOT NULL,
+			viewer              VARCHAR(128) NOT NULL,
+			segments_total      INT NOT NULL DEFAULT 0,
+			duration            DOUBLE NOT NULL DEFAULT 0,
+			segments_watched    INT NOT NULL DEFAULT 0,
+			started_at          DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at          DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+			CONSTRAINT pk PRIMARY KEY (id),
+			CONSTRAINT fk_ps_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE,
+			INDEX idx_video_watched (video_id, segments_watched)
+		);`,
+
+	CreateTablePlaybackSegmentsReq: `CREATE TABLE IF NOT EXISTS playback_segments (
+			session_id      VARCHAR(36) NOT NULL,
+			segment         INT NOT NULL,
+			watched_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+			CONSTRAINT pk PRIMARY KEY (session_id, segment),
+			CONSTRAINT fk_psg_ps_id FOREIGN KEY (session_id) REFERENCES playback_sessions (id) ON DELETE CASCADE
+		);`,
+
+	CreatePlaybackSession:       "INSERT INTO playback_sessions (id, video_id, viewer) VALUES (?, ?, ?)",
+	UpdatePlaybackSessionLength: "UPDATE playback_sessions SET segments_total = ?, duration = ? WHERE id = ? AND video_id = ?",
+	// The segment is only recorded for a session of the video
+	AddPlaybackSegment:               "INSERT IGNORE INTO playback_segments (session_id, segment) SELECT id, ? FROM playback_sessions WHERE id = ? AND video_id = ?",
+	IncrementPlaybackSegmentsWatched: "UPDATE playback_sessions SET segments_watched = segments_watched + 1 WHERE id = ?",
+	GetVideoStats: "SELECT COUNT(*), COUNT(DISTINCT viewer), " +
+		"COALESCE(SUM(IF(segments_total > 0, duration * LEAST(segments_watched, segments_total) / segments_total, 0)), 0), " +
+		"COALESCE(AVG(IF(segments_total > 0, LEAST(segments_watched, segments_total) / segments_total, 0)), 0) " +
+		"FROM playback_sessions WHERE video_id = ? AND segments_watched > 0",
+	GetVideosViewsAsc:       "SELECT v.* FROM videos v LEFT JOIN " + videoViews + " s ON s.video_id = v.id WHERE v.video_status = ? ORDER BY COALESCE(s.views, 0) ASC, v.title ASC LIMIT ?,?",
+	GetVideosViewsDesc:      "SELECT v.* FROM videos v LEFT JOIN " + videoViews + " s ON s.video_id = v.id WHERE v.video_status = ? ORDER BY COALESCE(s.views, 0) DESC, v.title ASC LIMIT ?,?",
+	GetVideosAfterViewsAsc:  videosAfterViewsRequest(true),
+	GetVideosAfterViewsDesc: videosAfterViewsRequest(false),
+}
+
+// videoViews counts the views of each video : its sessions having watched at least one segment
+const videoViews = "(SELECT video_id, COUNT(*) AS views FROM playback_sessions WHERE segments_watched > 0 GROUP BY video_id)"
+
+// videosAfterViewsRequest lists the videos with their number of views, after a cursor on (views, title, id)
+func videosAfterViewsRequest(ascending bool) string {
+	order, comparison := "ASC", ">"
+	if !ascending {
+		order, comparison = "DESC", "<"
+	}
+
+	return "SELECT v.*, COALESCE(s.views, 0) FROM videos v LEFT JOIN " + videoViews + " s ON s.video_id = v.id " +
+		"WHERE FIND_IN_SET(v.video_status, ?) AND (? IS NULL OR v.owner_id = ?) AND (? IS NULL OR (COALESCE(s.views, 0), v.title, v.id) " + comparison + " (?, ?, ?)) " +
+		"ORDER BY COALESCE(s.views, 0) " + order + ", v.title " + order + ", v.id " + order + " LIMIT ?"
+}
+
+type PlaybackDAO struct {
+	DB                                   *sql.DB
+	stmtCreatePlaybackSession            *sql.Stmt
+	stmtUpdatePlaybackSessionLength      *sql.Stmt
+	stmtAddPlaybackSegment               *sql.Stmt
+	stmtIncrementPlaybackSegmentsWatched *sql.Stmt
+	stmtGetVideoStats                    *sql.Stmt
+	stmtGetVideosViewsAsc                *sql.Stmt
+	stmtGetVideosViewsDesc               *sql.Stmt
+	stmtGetVideosAfterViewsAsc           *sql.Stmt
+	stmtGetVideosAfterViewsDesc          *sql.Stmt
+}
+
+func preparePlaybackStmts(ctx context.Context, db *sql.DB) (*PlaybackDAO, error) {
+	stmts := PlaybackDAO{}
+
+	// CreatePlaybackSession
+	var err error
+	stmts.stmtCreatePlaybackSession, err = db.PrepareContext(ctx, PlaybackRequests[CreatePlaybackSession])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// UpdatePlaybackSessionLength
+	stmts.stmtUpdatePlaybackSessionLength, err = db.PrepareContext(ctx, PlaybackRequests[UpdatePlaybackSessionLength])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// AddPlaybackSegment
+	stmts.stmtAddPlaybackSegment, err = db.PrepareContext(ctx, PlaybackRequests[AddPlaybackSegment])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// IncrementPlaybackSegmentsWatched
+	stmts.stmtIncrementPlaybackSegmentsWatched, err = db.PrepareContext(ctx, PlaybackRequests[IncrementPlaybackSegmentsWatched])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideoStats
+	stmts.stmtGetVideoStats, err = db.PrepareContext(ctx, PlaybackRequests[GetVideoStats])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideosViewsAsc
+	stmts.stmtGetVideosViewsAsc, err = db.PrepareContext(ctx, PlaybackRequests[GetVideosViewsAsc])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideosViewsDesc
+	stmts.stmtGetVideosViewsDesc, err = db.PrepareContext(ctx, PlaybackRequests[GetVideosViewsDesc])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideosAfterViewsAsc
+	stmts.stmtGetVideosAfterViewsAsc, err = db.PrepareContext(ctx, PlaybackRequests[GetVideosAfterViewsAsc])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideosAfterViewsDesc
+	stmts.stmtGetVideosAfterViewsDesc, err = db.PrepareContext(ctx, PlaybackRequests[GetVideosAfterViewsDesc])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	return &stmts, nil
+}
+
+func createTablePlayback(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, PlaybackRequests[CreateTablePlaybackSessionsReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, PlaybackRequests[CreateTablePlaybackSegmentsReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+
+	log.Debug("Tables playback_sessions and playback_segments created (or existed already)")
+	return nil
+}
+
+func CreatePlaybackDAO(ctx context.Context, db *sql.DB) (*PlaybackDAO, error) {
+	if err := createTablePlayback(ctx, db); err != nil {
+		log.Error("Cannot create table playback_sessions : ", err)
+		return nil, err
+	}
+
+	playbackDAO, err := preparePlaybackStmts(ctx, db)
+	if err != nil {
+		log.Error("Cannot prepare playback statements : ", err)
+		return nil, err
+	}
+
+	playbackDAO.DB = db
+
+	return playbackDAO, nil
+}
+
+func (p PlaybackDAO) CreatePlaybackSession(ctx context.Context, ID, videoID, viewer string) error {
+	res, err := p.stmtCreatePlaybackSession.ExecContext(ctx, ID, videoID, viewer)
+	if err != nil {
+		log.Error("Error while insert into playback_sessions : ", err)
+		return err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return err
+	}
+
+	// Check if one and only one rows has been affected
+	if nbRowAff != 1 {
+		err := fmt.Errorf("wrong number of row affected (%d) while creating playback session id : %v", nbRowAff, ID)
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdatePlaybackSessionLength records the length of the variant played by the session of the video,
+// the last one requested if the player switched of quality
+func (p PlaybackDAO) UpdatePlaybackSessionLength(ctx context.Context, ID, videoID string, segmentsTotal int, duration float64) error {
+	if _, err := p.stmtUpdatePlaybackSessionLength.ExecContext(ctx, segmentsTotal, duration, ID, videoID); err != nil {
+		log.Error("Cannot update playback session : ", err)
+		return err
+	}
+
+	return nil
+}
+
+// AddPlaybackSegment records that the session of the video watched this segment. A segment requested
+// again, in another quality for instance, is only counted once.
+func (p PlaybackDAO) AddPlaybackSegment(ctx context.Context, ID, videoID string, segment int) error {
+	res, err := p.stmtAddPlaybackSegment.ExecContext(ctx, segment, ID, videoID)
+	if err != nil {
+		log.Error("Error while insert into playback_segments : ", err)
+		return err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return err
+	}
+
+	// Already watched, or not a session of the video
+	if nbRowAff == 0 {
+		return nil
+	}
+
+	if _, err := p.stmtIncrementPlaybackSegmentsWatched.ExecContext(ctx, ID); err != nil {
+		log.Error("Cannot update playback session : ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (p PlaybackDAO) GetVideoStats(ctx context.Context, videoID string) (*models.VideoStats, error) {
+	stats := models.VideoStats{VideoID: videoID}
+	err := p.stmtGetVideoStats.QueryRowContext(ctx, videoID).Scan(
+		&stats.Views,
+		&stats.UniqueViewers,
+		&stats.WatchTime,
+		&stats.AverageCompletion,
+	)
+	if err != nil {
+		log.Error("Cannot read video stats : ", err)
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// GetVideosByViews returns a page of the videos with this status, sorted on their number of views
+func (p PlaybackDAO) GetVideosByViews(ctx context.Context, ascending bool, page, limit, status int) ([]models.Video, error) {
+	stmt := p.stmtGetVideosViewsDesc
+	if ascending {
+		stmt = p.stmtGetVideosViewsAsc
+	}
+
+	rows, err := stmt.QueryContext(ctx, status, (page-1)*limit, limit)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	var videos []models.Video
+	for rows.Next() {
+		var row models.Video
+		if err := rows.Scan(
+			&row.ID,
+			&row.Title,
+			&row.Status,
+			&row.UploadedAt,
+			&row.CreatedAt,
+			&row.UpdatedAt,
+			&row.SourcePath,
+			&row.CoverPath,
+			&row.SourceHash,
+			&row.Description,
+			&row.OwnerID,
+			&row.ArchivedAt,
+		); err != nil {
+			log.Error("Cannot read rows : ", err)
+			return nil, err
+		}
+		videos = append(videos, row)
+	}
+
+	return videos, nil
+}
+
+// GetVideosAfterViews returns the videos with one of the statuses, and of the owner if any, after the cursor
+// in the list sorted on their number of views, then on their title and their ID. Without cursor, the list starts
+// from its beginning.
+func (p PlaybackDAO) GetVideosAfterViews(ctx context.Context, ascending bool, statuses []models.VideoStatus, ownerID *string, cursor *models.VideoCursor, limit int) ([]models.VideoViews, error) {
+	stmt := p.stmtGetVideosAfterViewsDesc
+	if ascending {
+		stmt = p.stmtGetVideosAfterViewsAsc
+	}
+
+	var cursorViews, cursorTitle, cursorID interface{}
+	if cursor != nil {
+		cursorViews, cursorTitle, cursorID = cursor.Value, cursor.Title, cursor.ID
+	}
+
+	rows, err := stmt.QueryContext(ctx, statusSet(statuses), ownerID, ownerID, cursorViews, cursorViews, cursorTitle, cursorID, limit)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	var videos []models.VideoViews
+	for rows.Next() {
+		var row models.VideoViews
+		if err := rows.Scan(
+			&row.Video.ID,
+			&row.Video.Title,
+			&row.Video.Status,
+			&row.Video.UploadedAt,
+			&row.Video.CreatedAt,
+			&row.Video.UpdatedAt,
+			&row.Video.SourcePath,
+			&row.Video.CoverPath,
+			&row.Video.SourceHash,
+			&row.Video.Description,
+			&row.Video.OwnerID,
+			&row.Video.ArchivedAt,
+			&row.Views,
+		); err != nil {
+			log.Error("Cannot read rows : ", err)
+			return nil, err
+		}
+		videos = append(videos, row)
+	}
+
+	return videos, nil
+}
+
+func (p PlaybackDAO) Close() {
+	_ = p.stmtCreatePlaybackSession.Close()
+	_ = p.stmtUpdatePlaybackSessionLength.Close()
+	_ = p.stmtAddPlaybackSegment.Close()
+	_ = p.stmtIncrementPlaybackSegmentsWatched.Close()
+	_ = p.stmtGetVideoStats.Close()
+	_ = p.stmtGetVideosViewsAsc.Close()
+	_ = p.stmtGetVideosViewsDesc.Close()
+	_ = p.stmtGetVideosAfterViewsAsc.Close()
+	_ = p.stmtGetVideosAfterViewsDesc.Close()
+}
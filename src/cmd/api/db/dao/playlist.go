@@ -0,0 +1,547 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type PlaylistsRequestName int
+
+const (
+	CreateTablePlaylistsReq PlaylistsRequestName = iota
+	CreateTablePlaylistVideosReq
+	CreatePlaylist
+	GetPlaylist
+	GetPlaylists
+	UpdatePlaylist
+	DeletePlaylist
+	LockPlaylist
+	GetPlaylistVideos
+	GetTotalPlaylistVideos
+	GetPlaylistVideoPosition
+	ShiftPlaylistVideos
+	AddPlaylistVideo
+	RemovePlaylistVideo
+	UpdatePlaylistVideoPosition
+	GetVideoPlaylistPositions
+	RemoveVideoFromPlaylists
+)
+
+var PlaylistsRequests = map[PlaylistsRequestName]string{
+	CreateTablePlaylistsReq: `CREATE TABLE IF NOT EXISTS playlists (
+			id              VARCHAR(36) NOT NULL,
+			title           VARCHAR(64) NOT NULL,
+			description     VARCHAR(2048) NOT NULL DEFAULT '',
+			owner_id        VARCHAR(36),
+			created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+			CONSTRAINT pk PRIMARY KEY (id),
+			CONSTRAINT fk_p_owner_id FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE SET NULL
+		);`,
+
+	CreateTablePlaylistVideosReq: `CREATE TABLE IF NOT EXISTS playlist_videos (
+			playlist_id     VARCHAR(36) NOT NULL,
+			video_id        VARCHAR(36) NOT NULL,
+			position        INT NOT NULL,
+			added_at        DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+			CONSTRAINT pk PRIMARY KEY (playlist_id, video_id),
+			CONSTRAINT fk_pv_p_id FOREIGN KEY (playlist_id) REFERENCES playlists (id) ON DELETE CASCADE,
+			CONSTRAINT fk_pv_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE,
+			INDEX idx_playlist_position (playlist_id, position)
+		);`,
+
+	CreatePlaylist:              "INSERT INTO playlists (id, title, description, owner_id) VALUES (?, ?, ?, ?)",
+	GetPlaylist:                 "SELECT p.*, " + playlistCoverVideo + " FROM playlists p WHERE p.id = ?",
+	GetPlaylists:                "SELECT p.*, " + playlistCoverVideo + " FROM playlists p ORDER BY p.title ASC",
+	UpdatePlaylist:              "UPDATE playlists SET title = ?, description = ? WHERE id = ?",
+	DeletePlaylist:              "DELETE FROM playlists WHERE id = ?",
+	LockPlaylist:                "SELECT id FROM playlists WHERE id = ? FOR UPDATE",
+	GetPlaylistVideos:           "SELECT v.* FROM playlist_videos pv JOIN videos v ON v.id = pv.video_id WHERE pv.playlist_id = ? ORDER BY pv.position ASC",
+	GetTotalPlaylistVideos:      "SELECT COUNT(*) FROM playlist_videos WHERE playlist_id = ?",
+	GetPlaylistVideoPosition:    "SELECT position FROM playlist_videos WHERE playlist_id = ? AND video_id = ?",
+	ShiftPlaylistVideos:         "UPDATE playlist_videos SET position = position + ? WHERE playlist_id = ? AND position >= ?",
+	AddPlaylistVideo:            "INSERT INTO playlist_videos (playlist_id, video_id, position) VALUES (?, ?, ?)",
+	RemovePlaylistVideo:         "DELETE FROM playlist_videos WHERE playlist_id = ? AND video_id = ?",
+	UpdatePlaylistVideoPosition: "UPDATE playlist_videos SET position = ? WHERE playlist_id = ? AND video_id = ?",
+	GetVideoPlaylistPositions:   "SELECT playlist_id, position FROM playlist_videos WHERE video_id = ?",
+	RemoveVideoFromPlaylists:    "DELETE FROM playlist_videos WHERE video_id = ?",
+}
+
+// playlistCoverVideo selects the first video of the playlist p
+const playlistCoverVideo = "(SELECT pv.video_id FROM playlist_videos pv WHERE pv.playlist_id = p.id ORDER BY pv.position ASC LIMIT 1)"
+
+type PlaylistsDAO struct {
+	DB                              *sql.DB
+	stmtCreatePlaylist              *sql.Stmt
+	stmtGetPlaylist                 *sql.Stmt
+	stmtGetPlaylists                *sql.Stmt
+	stmtUpdatePlaylist              *sql.Stmt
+	stmtDeletePlaylist              *sql.Stmt
+	stmtLockPlaylist                *sql.Stmt
+	stmtGetPlaylistVideos           *sql.Stmt
+	stmtGetTotalPlaylistVideos      *sql.Stmt
+	stmtGetPlaylistVideoPosition    *sql.Stmt
+	stmtShiftPlaylistVideos         *sql.Stmt
+	stmtAddPlaylistVideo            *sql.Stmt
+	stmtRemovePlaylistVideo         *sql.Stmt
+	stmtUpdatePlaylistVideoPosition *sql.Stmt
+	stmtGetVideoPlaylistPositions   *sql.Stmt
+	stmtRemoveVideoFromPlaylists    *sql.Stmt
+}
+
+func preparePlaylistStmts(ctx context.Context, db *sql.DB) (*PlaylistsDAO, error) {
+	stmts := PlaylistsDAO{}
+
+	// CreatePlaylist
+	var err error
+	stmts.stmtCreatePlaylist, err = db.PrepareContext(ctx, PlaylistsRequests[CreatePlaylist])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetPlaylist
+	stmts.stmtGetPlaylist, err = db.PrepareContext(ctx, PlaylistsRequests[GetPlaylist])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetPlaylists
+	stmts.stmtGetPlaylists, err = db.PrepareContext(ctx, PlaylistsRequests[GetPlaylists])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// UpdatePlaylist
+	stmts.stmtUpdatePlaylist, err = db.PrepareContext(ctx, PlaylistsRequests[UpdatePlaylist])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// DeletePlaylist
+	stmts.stmtDeletePlaylist, err = db.PrepareContext(ctx, PlaylistsRequests[DeletePlaylist])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// LockPlaylist
+	stmts.stmtLockPlaylist, err = db.PrepareContext(ctx, PlaylistsRequests[LockPlaylist])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetPlaylistVideos
+	stmts.stmtGetPlaylistVideos, err = db.PrepareContext(ctx, PlaylistsRequests[GetPlaylistVideos])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetTotalPlaylistVideos
+	stmts.stmtGetTotalPlaylistVideos, err = db.PrepareContext(ctx, PlaylistsRequests[GetTotalPlaylistVideos])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetPlaylistVideoPosition
+	stmts.stmtGetPlaylistVideoPosition, err = db.PrepareContext(ctx, PlaylistsRequests[GetPlaylistVideoPosition])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// ShiftPlaylistVideos
+	stmts.stmtShiftPlaylistVideos, err = db.PrepareContext(ctx, PlaylistsRequests[ShiftPlaylistVideos])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// AddPlaylistVideo
+	stmts.stmtAddPlaylistVideo, err = db.PrepareContext(ctx, PlaylistsRequests[AddPlaylistVideo])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// RemovePlaylistVideo
+	stmts.stmtRemovePlaylistVideo, err = db.PrepareContext(ctx, PlaylistsRequests[RemovePlaylistVideo])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// UpdatePlaylistVideoPosition
+	stmts.stmtUpdatePlaylistVideoPosition, err = db.PrepareContext(ctx, PlaylistsRequests[UpdatePlaylistVideoPosition])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideoPlaylistPositions
+	stmts.stmtGetVideoPlaylistPositions, err = db.PrepareContext(ctx, PlaylistsRequests[GetVideoPlaylistPositions])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// RemoveVideoFromPlaylists
+	stmts.stmtRemoveVideoFromPlaylists, err = db.PrepareContext(ctx, PlaylistsRequests[RemoveVideoFromPlaylists])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	return &stmts, nil
+}
+
+func createTablePlaylists(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, PlaylistsRequests[CreateTablePlaylistsReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+	log.Debug("Table playlists created (or existed already)")
+
+	if _, err := db.ExecContext(ctx, PlaylistsRequests[CreateTablePlaylistVideosReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+	log.Debug("Table playlist_videos created (or existed already)")
+
+	return nil
+}
+
+// CreatePlaylistsDAO needs the videos table, its videos reference them
+func CreatePlaylistsDAO(ctx context.Context, db *sql.DB) (*PlaylistsDAO, error) {
+	if err := createTablePlaylists(ctx, db); err != nil {
+		log.Error("Cannot create table playlists : ", err)
+		return nil, err
+	}
+
+	playlistDAO, err := preparePlaylistStmts(ctx, db)
+	if err != nil {
+		log.Error("Cannot prepare playlists statements : ", err)
+		return nil, err
+	}
+
+	playlistDAO.DB = db
+
+	return playlistDAO, nil
+}
+
+func (p PlaylistsDAO) CreatePlaylist(ctx context.Context, ID, title, description string, ownerID *string) (*models.Playlist, error) {
+	res, err := p.stmtCreatePlaylist.ExecContext(ctx, ID, title, description, ownerID)
+	if err != nil {
+		log.Error("Error while insert into playlists : ", err)
+		return nil, err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return nil, err
+	}
+
+	// Check if one and only one rows has been affected
+	if nbRowAff != 1 {
+		err := fmt.Errorf("wrong number of row affected (%d) while creating playlist id : %v", nbRowAff, ID)
+		log.Error(err)
+		return nil, err
+	}
+
+	return p.GetPlaylist(ctx, ID)
+}
+
+func (p PlaylistsDAO) GetPlaylist(ctx context.Context, ID string) (*models.Playlist, error) {
+	var playlist models.Playlist
+	err := p.stmtGetPlaylist.QueryRowContext(ctx, ID).Scan(
+		&playlist.ID,
+		&playlist.Title,
+		&playlist.Description,
+		&playlist.OwnerID,
+		&playlist.CreatedAt,
+		&playlist.UpdatedAt,
+		&playlist.CoverVideo,
+	)
+	if err != nil {
+		log.Error("Error, playlist not found : ", err)
+		return nil, err
+	}
+
+	return &playlist, nil
+}
+
+// GetPlaylists returns every playlist sorted by title, without their videos
+func (p PlaylistsDAO) GetPlaylists(ctx context.Context) ([]models.Playlist, error) {
+	rows, err := p.stmtGetPlaylists.QueryContext(ctx)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	playlists := []models.Playlist{}
+	for rows.Next() {
+		var playlist models.Playlist
+		if err := rows.Scan(
+			&playlist.ID,
+			&playlist.Title,
+			&playlist.Description,
+			&playlist.OwnerID,
+			&playlist.CreatedAt,
+			&playlist.UpdatedAt,
+			&playlist.CoverVideo,
+		); err != nil {
+			log.Error("Cannot read rows : ", err)
+			return nil, err
+		}
+		playlists = append(playlists, playlist)
+	}
+
+	return playlists, nil
+}
+
+func (p PlaylistsDAO) UpdatePlaylist(ctx context.Context, playlist *models.Playlist) error {
+	if _, err := p.stmtUpdatePlaylist.ExecContext(ctx, playlist.Title, playlist.Description, playlist.ID); err != nil {
+		log.Error("Error while update playlist : ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (p PlaylistsDAO) DeletePlaylist(ctx context.Context, ID string) error {
+	res, err := p.stmtDeletePlaylist.ExecContext(ctx, ID)
+	if err != nil {
+		log.Error("Error while delete from playlists : ", err)
+		return err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return err
+	}
+
+	// Check if one and only one rows has been affected
+	if nbRowAff != 1 {
+		err := fmt.Errorf("wrong number of row affected (%d) while deleting playlist id : %v", nbRowAff, ID)
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// GetPlaylistVideos returns the videos of the playlist, in their order
+func (p PlaylistsDAO) GetPlaylistVideos(ctx context.Context, ID string) ([]models.Video, error) {
+	rows, err := p.stmtGetPlaylistVideos.QueryContext(ctx, ID)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	videos := []models.Video{}
+	for rows.Next() {
+		var video models.Video
+		if err := rows.Scan(
+			&video.ID,
+			&video.Title,
+			&video.Status,
+			&video.UploadedAt,
+			&video.CreatedAt,
+			&video.UpdatedAt,
+			&video.SourcePath,
+			&video.CoverPath,
+			&video.SourceHash,
+			&video.Description,
+			&video.OwnerID,
+			&video.ArchivedAt,
+		); err != nil {
+			log.Error("Cannot read rows : ", err)
+			return nil, err
+		}
+		videos = append(videos, video)
+	}
+
+	return videos, nil
+}
+
+// AddPlaylistVideo inserts the video at this position, the next videos move one position further.
+// A negative position, or one after the last video, appends the video.
+func (p PlaylistsDAO) AddPlaylistVideo(ctx context.Context, ID, videoID string, position int) error {
+	return p.inPlaylistTx(ctx, ID, func(tx *sql.Tx) error {
+		var total int
+		if err := tx.StmtContext(ctx, p.stmtGetTotalPlaylistVideos).QueryRowContext(ctx, ID).Scan(&total); err != nil {
+			log.Error("Cannot count playlist videos : ", err)
+			return err
+		}
+
+		if position < 0 || position > total {
+			position = total
+		} else if _, err := tx.StmtContext(ctx, p.stmtShiftPlaylistVideos).ExecContext(ctx, 1, ID, position); err != nil {
+			log.Error("Cannot shift playlist videos : ", err)
+			return err
+		}
+
+		if _, err := tx.StmtContext(ctx, p.stmtAddPlaylistVideo).ExecContext(ctx, ID, videoID, position); err != nil {
+			log.Error("Error while insert into playlist_videos : ", err)
+			return err
+		}
+
+		return nil
+	})
+}
+
+// RemovePlaylistVideo removes the video, the next videos move one position back
+func (p PlaylistsDAO) RemovePlaylistVideo(ctx context.Context, ID, videoID string) error {
+	return p.inPlaylistTx(ctx, ID, func(tx *sql.Tx) error {
+		var position int
+		if err := tx.StmtContext(ctx, p.stmtGetPlaylistVideoPosition).QueryRowContext(ctx, ID, videoID).Scan(&position); err != nil {
+			log.Error("Cannot get video position in playlist : ", err)
+			return err
+		}
+
+		if _, err := tx.StmtContext(ctx, p.stmtRemovePlaylistVideo).ExecContext(ctx, ID, videoID); err != nil {
+			log.Error("Error while delete from playlist_videos : ", err)
+			return err
+		}
+
+		if _, err := tx.StmtContext(ctx, p.stmtShiftPlaylistVideos).ExecContext(ctx, -1, ID, position+1); err != nil {
+			log.Error("Cannot shift playlist videos : ", err)
+			return err
+		}
+
+		return nil
+	})
+}
+
+// ReorderPlaylistVideos gives each video its index in videoIDs as position. The IDs must be the ones of the videos
+// of the playlist.
+func (p PlaylistsDAO) ReorderPlaylistVideos(ctx context.Context, ID string, videoIDs []string) error {
+	return p.inPlaylistTx(ctx, ID, func(tx *sql.Tx) error {
+		stmt := tx.StmtContext(ctx, p.stmtUpdatePlaylistVideoPosition)
+		for position, videoID := range videoIDs {
+			if _, err := stmt.ExecContext(ctx, position, ID, videoID); err != nil {
+				log.Error("Error while update playlist_videos : ", err)
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// RemoveVideoFromPlaylistsTx removes the video from every playlist, the next videos of each one move one position back
+func (p PlaylistsDAO) RemoveVideoFromPlaylistsTx(ctx context.Context, tx *sql.Tx, videoID string) error {
+	rows, err := tx.StmtContext(ctx, p.stmtGetVideoPlaylistPositions).QueryContext(ctx, videoID)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return err
+	}
+
+	positions := []models.PlaylistPosition{}
+	for rows.Next() {
+		var position models.PlaylistPosition
+		if err := rows.Scan(&position.PlaylistID, &position.Position); err != nil {
+			log.Error("Cannot read rows : ", err)
+			_ = rows.Close()
+			return err
+		}
+		positions = append(positions, position)
+	}
+	// Rows must be closed before the next statements of the transaction
+	if err := rows.Close(); err != nil {
+		log.Error("Error while closing database Rows", err)
+		return err
+	}
+
+	if _, err := tx.StmtContext(ctx, p.stmtRemoveVideoFromPlaylists).ExecContext(ctx, videoID); err != nil {
+		log.Error("Error while delete from playlist_videos : ", err)
+		return err
+	}
+
+	stmt := tx.StmtContext(ctx, p.stmtShiftPlaylistVideos)
+	for _, position := range positions {
+		if _, err := stmt.ExecContext(ctx, -1, position.PlaylistID, position.Position+1); err != nil {
+			log.Error("Cannot shift playlist videos : ", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// inPlaylistTx runs fn in a transaction, the playlist row being locked to serialize the changes of its videos
+func (p PlaylistsDAO) inPlaylistTx(ctx context.Context, ID string, fn func(tx *sql.Tx) error) error {
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("Cannot open new database transaction : ", err)
+		return err
+	}
+
+	var lockedID string
+	err = tx.StmtContext(ctx, p.stmtLockPlaylist).QueryRowContext(ctx, ID).Scan(&lockedID)
+	if err == nil {
+		err = fn(tx)
+	}
+	if err != nil {
+		if err := tx.Rollback(); err != nil {
+			log.Error("Cannot rollback : ", err)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("Cannot commit database transaction")
+		if err := tx.Rollback(); err != nil {
+			log.Error("Cannot rollback : ", err)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (p PlaylistsDAO) Close() {
+	_ = p.stmtCreatePlaylist.Close()
+	_ = p.stmtGetPlaylist.Close()
+	_ = p.stmtGetPlaylists.Close()
+	_ = p.stmtUpdatePlaylist.Close()
+	_ = p.stmtDeletePlaylist.Close()
+	_ = p.stmtLockPlaylist.Close()
+	_ = p.stmtGetPlaylistVideos.Close()
+	_ = p.stmtGetTotalPlaylistVideos.Close()
+	_ = p.stmtGetPlaylistVideoPosition.Close()
+	_ = p.stmtShiftPlaylistVideos.Close()
+	_ = p.stmtAddPlaylistVideo.Close()
+	_ = p.stmtRemovePlaylistVideo.Close()
+	_ = p.stmtUpdatePlaylistVideoPosition.Close()
+	_ = p.stmtGetVideoPlaylistPositions.Close()
+	_ = p.stmtRemoveVideoFromPlaylists.Close()
+}
@@ -0,0 +1,196 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type SubtitlesRequestName int
+
+const (
+	CreateTableSubtitlesReq SubtitlesRequestName = iota
+	UpsertSubtitle
+	GetSubtitle
+	GetVideoSubtitles
+	DeleteSubtitle
+)
+
+var SubtitlesRequests = map[SubtitlesRequestName]string{
+	CreateTableSubtitlesReq: `CREATE TABLE IF NOT EXISTS subtitles (
+			video_id        VARCHAR(36) NOT NULL,
+			language        VARCHAR(35) NOT NULL,
+			label           VARCHAR(64) NOT NULL,
+			created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+			CONSTRAINT pk PRIMARY KEY (video_id, language),
+			CONSTRAINT fk_st_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE
+		);`,
+
+	UpsertSubtitle:    "INSERT INTO subtitles (video_id, language, label) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE label = VALUES(label), created_at = NOW()",
+	GetSubtitle:       "SELECT * FROM subtitles WHERE video_id = ? AND language = ?",
+	GetVideoSubtitles: "SELECT * FROM subtitles WHERE video_id = ? ORDER BY language ASC",
+	DeleteSubtitle:    "DELETE FROM subtitles WHERE video_id = ? AND language = ?",
+}
+
+type SubtitlesDAO struct {
+	DB                    *sql.DB
+	stmtUpsertSubtitle    *sql.Stmt
+	stmtGetSubtitle       *sql.Stmt
+	stmtGetVideoSubtitles *sql.Stmt
+	stmtDeleteSubtitle    *sql.Stmt
+}
+
+func prepareSubtitlesStmts(ctx context.Context, db *sql.DB) (*SubtitlesDAO, error) {
+	stmts := SubtitlesDAO{}
+
+	// UpsertSubtitle
+	var err error
+	stmts.stmtUpsertSubtitle, err = db.PrepareContext(ctx, SubtitlesRequests[UpsertSubtitle])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetSubtitle
+	stmts.stmtGetSubtitle, err = db.PrepareContext(ctx, SubtitlesRequests[GetSubtitle])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideoSubtitles
+	stmts.stmtGetVideoSubtitles, err = db.PrepareContext(ctx, SubtitlesRequests[GetVideoSubtitles])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// DeleteSubtitle
+	stmts.stmtDeleteSubtitle, err = db.PrepareContext(ctx, SubtitlesRequests[DeleteSubtitle])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	return &stmts, nil
+}
+
+func createTableSubtitles(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, SubtitlesRequests[CreateTableSubtitlesReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+
+	log.Debug("Table subtitles created (or existed already)")
+	return nil
+}
+
+func CreateSubtitlesDAO(ctx context.Context, db *sql.DB) (*SubtitlesDAO, error) {
+	if err := createTableSubtitles(ctx, db); err != nil {
+		log.Error("Cannot create table subtitles : ", err)
+		return nil, err
+	}
+
+	subtitlesDAO, err := prepareSubtitlesStmts(ctx, db)
+	if err != nil {
+		log.Error("Cannot prepare subtitles statements : ", err)
+		return nil, err
+	}
+
+	subtitlesDAO.DB = db
+
+	return subtitlesDAO, nil
+}
+
+// UpsertSubtitle records the subtitles track of the video in this language, replacing the previous one
+func (s SubtitlesDAO) UpsertSubtitle(ctx context.Context, videoID, language, label string) (*models.Subtitle, error) {
+	if _, err := s.stmtUpsertSubtitle.ExecContext(ctx, videoID, language, label); err != nil {
+		log.Error("Error while insert into subtitles : ", err)
+		return nil, err
+	}
+
+	return s.GetSubtitle(ctx, videoID, language)
+}
+
+func (s SubtitlesDAO) GetSubtitle(ctx context.Context, videoID, language string) (*models.Subtitle, error) {
+	var subtitle models.Subtitle
+	err := s.stmtGetSubtitle.QueryRowContext(ctx, videoID, language).Scan(
+		&subtitle.VideoID,
+		&subtitle.Language,
+		&subtitle.Label,
+		&subtitle.CreatedAt,
+	)
+	if err != nil {
+		log.Error("Error, subtitle not found : ", err)
+		return nil, err
+	}
+
+	return &subtitle, nil
+}
+
+// GetVideoSubtitles returns the subtitles tracks of the video, sorted on their language
+func (s SubtitlesDAO) GetVideoSubtitles(ctx context.Context, videoID string) ([]models.Subtitle, error) {
+	rows, err := s.stmtGetVideoSubtitles.QueryContext(ctx, videoID)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	subtitles := []models.Subtitle{}
+	for rows.Next() {
+		var subtitle models.Subtitle
+		if err := rows.Scan(
+			&subtitle.VideoID,
+			&subtitle.Language,
+			&subtitle.Label,
+			&subtitle.CreatedAt,
+		); err != nil {
+			log.Error("Cannot read rows : ", err)
+			return nil, err
+		}
+		subtitles = append(subtitles, subtitle)
+	}
+
+	return subtitles, nil
+}
+
+func (s SubtitlesDAO) DeleteSubtitle(ctx context.Context, videoID, language string) error {
+	res, err := s.stmtDeleteSubtitle.ExecContext(ctx, videoID, language)
+	if err != nil {
+		log.Error("Error while delete from subtitles : ", err)
+		return err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return err
+	}
+
+	// Check if one and only one rows has been affected
+	if nbRowAff != 1 {
+		err := fmt.Errorf("wrong number of row affected (%d) while deleting subtitle %v of video id : %v", nbRowAff, language, videoID)
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s SubtitlesDAO) Close() {
+	_ = s.stmtUpsertSubtitle.Close()
+	_ = s.stmtGetSubtitle.Close()
+	_ = s.stmtGetVideoSubtitles.Close()
+	_ = s.stmtDeleteSubtitle.Close()
+}
@@ -0,0 +1,155 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+
+	_ "github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+)
+
+type TagsRequestName int
+
+const (
+	CreateTableTagsReq TagsRequestName = iota
+	AlterTableTagsReq
+	GetVideoTags
+	AddVideoTag
+	DeleteVideoTags
+)
+
+var TagsRequests = map[TagsRequestName]string{
+	CreateTableTagsReq: `CREATE TABLE IF NOT EXISTS video_tags (
+			video_id        VARCHAR(36) NOT NULL,
+			tag             VARCHAR(32) NOT NULL,
+
+			CONSTRAINT pk PRIMARY KEY (video_id, tag),
+			CONSTRAINT fk_t_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE,
+			FULLTEXT INDEX ft_tag (tag)
+		);`,
+	// The index of the tags became a full-text index, for the databases created before it
+	AlterTableTagsReq: `ALTER TABLE video_tags
+			DROP INDEX IF EXISTS idx_tag,
+			ADD FULLTEXT INDEX IF NOT EXISTS ft_tag (tag);`,
+
+	GetVideoTags:    "SELECT tag FROM video_tags WHERE video_id = ? ORDER BY tag ASC",
+	AddVideoTag:     "INSERT INTO video_tags (video_id, tag) VALUES (?, ?)",
+	DeleteVideoTags: "DELETE FROM video_tags WHERE video_id = ?",
+}
+
+type TagsDAO struct {
+	DB                  *sql.DB
+	stmtGetVideoTags    *sql.Stmt
+	stmtAddVideoTag     *sql.Stmt
+	stmtDeleteVideoTags *sql.Stmt
+}
+
+func prepareTagStmts(ctx context.Context, db *sql.DB) (*TagsDAO, error) {
+	stmts := TagsDAO{}
+
+	// GetVideoTags
+	var err error
+	stmts.stmtGetVideoTags, err = db.PrepareContext(ctx, TagsRequests[GetVideoTags])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// AddVideoTag
+	stmts.stmtAddVideoTag, err = db.PrepareContext(ctx, TagsRequests[AddVideoTag])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// DeleteVideoTags
+	stmts.stmtDeleteVideoTags, err = db.PrepareContext(ctx, TagsRequests[DeleteVideoTags])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	return &stmts, nil
+}
+
+func createTableTags(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, TagsRequests[CreateTableTagsReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+	log.Debug("Table video_tags created (or existed already)")
+
+	if _, err := db.ExecContext(ctx, TagsRequests[AlterTableTagsReq]); err != nil {
+		log.Error("Cannot alter table : ", err)
+		return err
+	}
+	log.Debug("Table video_tags up to date")
+
+	return nil
+}
+
+func CreateTagsDAO(ctx context.Context, db *sql.DB) (*TagsDAO, error) {
+	if err := createTableTags(ctx, db); err != nil {
+		log.Error("Cannot create table video_tags : ", err)
+		return nil, err
+	}
+
+	tagDAO, err := prepareTagStmts(ctx, db)
+	if err != nil {
+		log.Error("Cannot prepare video_tags statements : ", err)
+		return nil, err
+	}
+
+	tagDAO.DB = db
+
+	return tagDAO, nil
+}
+
+func (t TagsDAO) GetVideoTags(ctx context.Context, videoID string) ([]string, error) {
+	rows, err := t.stmtGetVideoTags.QueryContext(ctx, videoID)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			log.Error("Cannot read rows : ", err)
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+// UpdateVideoTagsTx replaces all the tags of the video
+func (t TagsDAO) UpdateVideoTagsTx(ctx context.Context, tx *sql.Tx, videoID string, tags []string) error {
+	if _, err := tx.StmtContext(ctx, t.stmtDeleteVideoTags).ExecContext(ctx, videoID); err != nil {
+		log.Error("Error while delete from video_tags : ", err)
+		return err
+	}
+
+	stmt := tx.StmtContext(ctx, t.stmtAddVideoTag)
+	for _, tag := range tags {
+		if _, err := stmt.ExecContext(ctx, videoID, tag); err != nil {
+			log.Error("Error while insert into video_tags : ", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t TagsDAO) Close() {
+	_ = t.stmtGetVideoTags.Close()
+	_ = t.stmtAddVideoTag.Close()
+	_ = t.stmtDeleteVideoTags.Close()
+}
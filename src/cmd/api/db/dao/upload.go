@@ -3,23 +3,38 @@ package dao
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/Sogilis/Voogle/src/cmd/api/models"
 )
 
+var ErrUploadOffsetMismatch = errors.New("upload offset mismatch")
+
+// Error numbers of MariaDB and MySQL when a row locked with NOWAIT is already locked
+const (
+	mysqlErrLockWaitTimeout = 1205
+	mysqlErrLockNowait      = 3572
+)
+
 type UploadsRequestName int
 
 const (
 	CreateTableUploadsReq UploadsRequestName = iota
+	AlterTableUploadsReq
 	CreateUpload
 	UpdateUpload
 	GetUpload
 	GetUploads
 	DeleteUpload
+	CreateResumableUpload
+	UpdateUploadOffset
+	UpdateUploadProgress
+	GetVideoLatestUpload
+	LockUploadOffset
 )
 
 var UploadsRequests = map[UploadsRequestName]string{
@@ -30,25 +45,50 @@ var UploadsRequests = map[UploadsRequestName]string{
 			uploaded_at     DATETIME,
 			created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 			updated_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-		
+			upload_length   BIGINT NOT NULL DEFAULT 0,
+			upload_offset   BIGINT NOT NULL DEFAULT 0,
+			upload_parts    INT NOT NULL DEFAULT 0,
+			s3_upload_id    VARCHAR(1024) NOT NULL DEFAULT '',
+			upload_progress INT NOT NULL DEFAULT 0,
+
 			CONSTRAINT pk PRIMARY KEY (id),
 			CONSTRAINT fk_v_id FOREIGN KEY (video_id) REFERENCES videos (id)
 		);`,
+	// Columns added since the first version of the table, for the databases created before them
+	AlterTableUploadsReq: `ALTER TABLE uploads
+			ADD COLUMN IF NOT EXISTS upload_length BIGINT NOT NULL DEFAULT 0 AFTER updated_at,
+			ADD COLUMN IF NOT EXISTS upload_offset BIGINT NOT NULL DEFAULT 0 AFTER upload_length,
+			ADD COLUMN IF NOT EXISTS upload_parts INT NOT NULL DEFAULT 0 AFTER upload_offset,
+			ADD COLUMN IF NOT EXISTS s3_upload_id VARCHAR(1024) NOT NULL DEFAULT '' AFTER upload_parts,
+			ADD COLUMN IF NOT EXISTS upload_progress INT NOT NULL DEFAULT 0 AFTER s3_upload_id;`,
 
 	CreateUpload: "INSERT INTO uploads (id, video_id, upload_status) VALUES ( ? , ?, ?)",
 	UpdateUpload: "UPDATE uploads SET video_id = ?, upload_status = ?, uploaded_at = ? WHERE id = ?",
 	GetUpload:    "SELECT * FROM uploads WHERE id = ?",
 	GetUploads:   "SELECT * FROM uploads",
 	DeleteUpload: "DELETE FROM uploads WHERE video_id = ?",
+
+	CreateResumableUpload: "INSERT INTO uploads (id, video_id, upload_status, upload_length, s3_upload_id) VALUES (?, ?, ?, ?, ?)",
+	// The offset is checked to reject concurrent writes on the same upload
+	UpdateUploadOffset:   "UPDATE uploads SET upload_offset = ?, upload_parts = ?, upload_progress = ? WHERE id = ? AND upload_offset = ?",
+	UpdateUploadProgress: "UPDATE uploads SET upload_progress = ? WHERE id = ?",
+	GetVideoLatestUpload: "SELECT * FROM uploads WHERE video_id = ? ORDER BY created_at DESC LIMIT 1",
+	// Concurrent chunks are rejected instead of waiting for the one being sent
+	LockUploadOffset: "SELECT upload_offset FROM uploads WHERE id = ? FOR UPDATE NOWAIT",
 }
 
 type UploadsDAO struct {
-	DB               *sql.DB
-	stmtCreateUpload *sql.Stmt
-	stmtUpdateUpload *sql.Stmt
-	stmtGetUpload    *sql.Stmt
-	stmtGetUploads   *sql.Stmt
-	stmtDeleteUpload *sql.Stmt
+	DB                        *sql.DB
+	stmtCreateUpload          *sql.Stmt
+	stmtUpdateUpload          *sql.Stmt
+	stmtGetUpload             *sql.Stmt
+	stmtGetUploads            *sql.Stmt
+	stmtDeleteUpload          *sql.Stmt
+	stmtCreateResumableUpload *sql.Stmt
+	stmtUpdateUploadOffset    *sql.Stmt
+	stmtUpdateUploadProgress  *sql.Stmt
+	stmtGetVideoLatestUpload  *sql.Stmt
+	stmtLockUploadOffset      *sql.Stmt
 }
 
 func prepareUploadStmts(ctx context.Context, db *sql.DB) (*UploadsDAO, error) {
@@ -90,6 +130,41 @@ func prepareUploadStmts(ctx context.Context, db *sql.DB) (*UploadsDAO, error) {
 		return nil, err
 	}
 
+	// CreateResumableUpload
+	stmts.stmtCreateResumableUpload, err = db.PrepareContext(ctx, UploadsRequests[CreateResumableUpload])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// UpdateUploadOffset
+	stmts.stmtUpdateUploadOffset, err = db.PrepareContext(ctx, UploadsRequests[UpdateUploadOffset])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// UpdateUploadProgress
+	stmts.stmtUpdateUploadProgress, err = db.PrepareContext(ctx, UploadsRequests[UpdateUploadProgress])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideoLatestUpload
+	stmts.stmtGetVideoLatestUpload, err = db.PrepareContext(ctx, UploadsRequests[GetVideoLatestUpload])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// LockUploadOffset
+	stmts.stmtLockUploadOffset, err = db.PrepareContext(ctx, UploadsRequests[LockUploadOffset])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
 	return &stmts, nil
 }
 
@@ -98,8 +173,14 @@ func createTableUploads(ctx context.Context, db *sql.DB) error {
 		log.Error("Cannot create table : ", err)
 		return err
 	}
-
 	log.Debug("Table uploads created (or existed already)")
+
+	if _, err := db.ExecContext(ctx, UploadsRequests[AlterTableUploadsReq]); err != nil {
+		log.Error("Cannot alter table : ", err)
+		return err
+	}
+	log.Debug("Table uploads up to date")
+
 	return nil
 }
 
@@ -143,6 +224,110 @@ func (u UploadsDAO) CreateUpload(ctx context.Context, ID, videoID string, status
 	return u.GetUpload(ctx, ID)
 }
 
+func (u UploadsDAO) CreateResumableUpload(ctx context.Context, ID, videoID string, status int, length int64, s3UploadID string) (*models.Upload, error) {
+	res, err := u.stmtCreateResumableUpload.ExecContext(ctx, ID, videoID, status, length, s3UploadID)
+	if err != nil {
+		log.Error("Error while insert into uploads : ", err)
+		return nil, err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return nil, err
+	}
+
+	// Check if one and only one rows has been affected
+	if nbRowAff != 1 {
+		err := fmt.Errorf("wrong number of row affected (%d) while creating upload id : %v", nbRowAff, ID)
+		log.Error(err)
+		return nil, err
+	}
+
+	return u.GetUpload(ctx, ID)
+}
+
+// AppendUploadChunk sends a chunk of the upload at previousOffset with send, then moves the upload offset forward
+// to the one of the upload. The upload stays locked meanwhile : a concurrent chunk, such as the retry of a client
+// after a timeout, gets ErrUploadOffsetMismatch instead of sending the same S3 part again.
+func (u UploadsDAO) AppendUploadChunk(ctx context.Context, upload *models.Upload, previousOffset int64, send func() error) error {
+	tx, err := u.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("Cannot open new database transaction : ", err)
+		return err
+	}
+
+	var offset int64
+	err = tx.StmtContext(ctx, u.stmtLockUploadOffset).QueryRowContext(ctx, upload.ID).Scan(&offset)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && (mysqlErr.Number == mysqlErrLockWaitTimeout || mysqlErr.Number == mysqlErrLockNowait) {
+		log.Errorf("Upload %v is receiving another chunk", upload.ID)
+		err = ErrUploadOffsetMismatch
+	} else if err == nil && offset != previousOffset {
+		log.Errorf("Upload %v offset is no longer %v", upload.ID, previousOffset)
+		err = ErrUploadOffsetMismatch
+	}
+	if err == nil {
+		err = send()
+	}
+	if err == nil {
+		err = u.updateUploadOffsetTx(ctx, tx, upload, previousOffset)
+	}
+	if err != nil {
+		if err := tx.Rollback(); err != nil {
+			log.Error("Cannot rollback : ", err)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("Cannot commit database transaction")
+		if err := tx.Rollback(); err != nil {
+			log.Error("Cannot rollback : ", err)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// updateUploadOffsetTx moves the upload offset forward, only if it still equals previousOffset.
+// It returns ErrUploadOffsetMismatch if another request already moved the offset.
+func (u UploadsDAO) updateUploadOffsetTx(ctx context.Context, tx *sql.Tx, upload *models.Upload, previousOffset int64) error {
+	stmt := tx.StmtContext(ctx, u.stmtUpdateUploadOffset)
+	res, err := stmt.ExecContext(ctx, upload.Offset, upload.Parts, upload.Progress, upload.ID, previousOffset)
+	if err != nil {
+		log.Error("Error while update upload offset : ", err)
+		return err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return err
+	}
+
+	if nbRowAff != 1 {
+		log.Errorf("Upload %v offset is no longer %v", upload.ID, previousOffset)
+		return ErrUploadOffsetMismatch
+	}
+
+	return nil
+}
+
+// UpdateUploadProgress only saves the upload progress, in percent. The number of affected
+// rows is not checked : saving the same progress twice does not change the row.
+func (u UploadsDAO) UpdateUploadProgress(ctx context.Context, upload *models.Upload) error {
+	if _, err := u.stmtUpdateUploadProgress.ExecContext(ctx, upload.Progress, upload.ID); err != nil {
+		log.Error("Error while update upload progress : ", err)
+		return err
+	}
+
+	return nil
+}
+
 func (u UploadsDAO) DeleteUpload(ctx context.Context, ID string) error {
 	res, err := u.stmtDeleteUpload.ExecContext(ctx, ID)
 	if err != nil {
@@ -246,6 +431,35 @@ func (u UploadsDAO) GetUpload(ctx context.Context, id string) (*models.Upload, e
 		&upload.UploadedAt,
 		&upload.CreatedAt,
 		&upload.UpdatedAt,
+		&upload.Length,
+		&upload.Offset,
+		&upload.Parts,
+		&upload.S3UploadID,
+		&upload.Progress,
+	)
+	if err != nil {
+		log.Error("Error, upload not found : ", err)
+		return nil, err
+	}
+
+	return &upload, nil
+}
+
+// GetVideoLatestUpload returns the last upload started for the video
+func (u UploadsDAO) GetVideoLatestUpload(ctx context.Context, videoID string) (*models.Upload, error) {
+	var upload models.Upload
+	err := u.stmtGetVideoLatestUpload.QueryRowContext(ctx, videoID).Scan(
+		&upload.ID,
+		&upload.VideoId,
+		&upload.Status,
+		&upload.UploadedAt,
+		&upload.CreatedAt,
+		&upload.UpdatedAt,
+		&upload.Length,
+		&upload.Offset,
+		&upload.Parts,
+		&upload.S3UploadID,
+		&upload.Progress,
 	)
 	if err != nil {
 		log.Error("Error, upload not found : ", err)
@@ -278,6 +492,11 @@ func (u UploadsDAO) GetUploads(ctx context.Context, db *sql.DB) ([]models.Upload
 			&row.UploadedAt,
 			&row.CreatedAt,
 			&row.UpdatedAt,
+			&row.Length,
+			&row.Offset,
+			&row.Parts,
+			&row.S3UploadID,
+			&row.Progress,
 		); err != nil {
 			log.Error("Cannot read rows : ", err)
 			return nil, err
@@ -294,4 +513,9 @@ func (u UploadsDAO) Close() {
 	_ = u.stmtGetUpload.Close()
 	_ = u.stmtGetUploads.Close()
 	_ = u.stmtDeleteUpload.Close()
+	_ = u.stmtCreateResumableUpload.Close()
+	_ = u.stmtUpdateUploadOffset.Close()
+	_ = u.stmtUpdateUploadProgress.Close()
+	_ = u.stmtGetVideoLatestUpload.Close()
+	_ = u.stmtLockUploadOffset.Close()
 }
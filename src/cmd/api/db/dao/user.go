@@ -0,0 +1,162 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+var ErrDuplicateUsername = errors.New("username already used by another user")
+
+type UsersRequestName int
+
+const (
+	CreateTableUsersReq UsersRequestName = iota
+	CreateUser
+	GetUser
+	GetUserFromUsername
+)
+
+var UsersRequests = map[UsersRequestName]string{
+	CreateTableUsersReq: `CREATE TABLE IF NOT EXISTS users (
+			id              VARCHAR(36) NOT NULL,
+			username        VARCHAR(64) NOT NULL,
+			password_hash   CHAR(60) NOT NULL,
+			created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+			CONSTRAINT pk PRIMARY KEY (id),
+			CONSTRAINT unique_username UNIQUE (username)
+		);`,
+
+	CreateUser:          "INSERT INTO users (id, username, password_hash) VALUES (?, ?, ?)",
+	GetUser:             "SELECT * FROM users WHERE id = ?",
+	GetUserFromUsername: "SELECT * FROM users WHERE username = ?",
+}
+
+type UsersDAO struct {
+	DB                      *sql.DB
+	stmtCreateUser          *sql.Stmt
+	stmtGetUser             *sql.Stmt
+	stmtGetUserFromUsername *sql.Stmt
+}
+
+func prepareUserStmts(ctx context.Context, db *sql.DB) (*UsersDAO, error) {
+	stmts := UsersDAO{}
+
+	// CreateUser
+	var err error
+	stmts.stmtCreateUser, err = db.PrepareContext(ctx, UsersRequests[CreateUser])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetUser
+	stmts.stmtGetUser, err = db.PrepareContext(ctx, UsersRequests[GetUser])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetUserFromUsername
+	stmts.stmtGetUserFromUsername, err = db.PrepareContext(ctx, UsersRequests[GetUserFromUsername])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	return &stmts, nil
+}
+
+func createTableUsers(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, UsersRequests[CreateTableUsersReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+
+	log.Debug("Table users created (or existed already)")
+	return nil
+}
+
+func CreateUsersDAO(ctx context.Context, db *sql.DB) (*UsersDAO, error) {
+	if err := createTableUsers(ctx, db); err != nil {
+		log.Error("Cannot create table users : ", err)
+		return nil, err
+	}
+
+	userDAO, err := prepareUserStmts(ctx, db)
+	if err != nil {
+		log.Error("Cannot prepare users statements : ", err)
+		return nil, err
+	}
+
+	userDAO.DB = db
+
+	return userDAO, nil
+}
+
+func (u UsersDAO) CreateUser(ctx context.Context, ID, username, passwordHash string) (*models.User, error) {
+	res, err := u.stmtCreateUser.ExecContext(ctx, ID, username, passwordHash)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+			log.Error("Username already used by another user : ", err)
+			return nil, ErrDuplicateUsername
+		}
+		log.Error("Error while insert into users : ", err)
+		return nil, err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return nil, err
+	}
+
+	// Check if one and only one rows has been affected
+	if nbRowAff != 1 {
+		err := fmt.Errorf("wrong number of row affected (%d) while creating user id : %v", nbRowAff, ID)
+		log.Error(err)
+		return nil, err
+	}
+
+	return u.GetUser(ctx, ID)
+}
+
+func (u UsersDAO) GetUser(ctx context.Context, ID string) (*models.User, error) {
+	return scanUser(u.stmtGetUser.QueryRowContext(ctx, ID))
+}
+
+func (u UsersDAO) GetUserFromUsername(ctx context.Context, username string) (*models.User, error) {
+	return scanUser(u.stmtGetUserFromUsername.QueryRowContext(ctx, username))
+}
+
+func scanUser(row *sql.Row) (*models.User, error) {
+	var user models.User
+	err := row.Scan(
+		&user.ID,
+		&user.Username,
+		&user.PasswordHash,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		log.Error("Error, user not found : ", err)
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (u UsersDAO) Close() {
+	_ = u.stmtCreateUser.Close()
+	_ = u.stmtGetUser.Close()
+	_ = u.stmtGetUserFromUsername.Close()
+}
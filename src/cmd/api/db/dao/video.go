@@ -3,18 +3,30 @@ package dao
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/Sogilis/Voogle/src/cmd/api/models"
 )
 
+var ErrDuplicateSourceHash = errors.New("source hash already used by another video")
+var ErrDuplicateTitle = errors.New("title already used by another video")
+
+// Error number of MariaDB when a unique key already exists
+const mysqlErrDuplicateEntry = 1062
+
 type VideosRequestName int
 
 const (
 	CreateTableVideosReq VideosRequestName = iota
+	AlterTableVideosReq
 	CreateVideo
 	UpdateVideo
 	GetVideo
@@ -25,6 +37,23 @@ const (
 	GetVideosUploadedAtDesc
 	GetTotalVideos
 	DeleteVideo
+	GetVideoFromSourceHash
+	UpdateVideoSourceHash
+	UpdateVideoMetadata
+	SearchVideos
+	GetTotalSearchVideos
+	GetVideosAfterTitleAsc
+	GetVideosAfterTitleDesc
+	GetVideosAfterUploadedAtAsc
+	GetVideosAfterUploadedAtDesc
+	GetVideosAfterCreatedAtAsc
+	GetVideosAfterCreatedAtDesc
+	GetVideosAfterUpdatedAtAsc
+	GetVideosAfterUpdatedAtDesc
+	GetTotalVideosWithStatuses
+	UpdateVideoCoverPath
+	GetVideosArchivedBefore
+	UpdateVideoArchivedAt
 )
 
 var VideosRequests = map[VideosRequestName]string{
@@ -37,12 +66,28 @@ var VideosRequests = map[VideosRequestName]string{
 			updated_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 			source_path     VARCHAR(64) NOT NULL,
 			cover_path      VARCHAR(64),
+			source_hash     CHAR(64),
+			description     VARCHAR(2048) NOT NULL DEFAULT '',
+			owner_id        VARCHAR(36),
+			archived_at     DATETIME,
 
 			CONSTRAINT pk PRIMARY KEY (id),
-			CONSTRAINT unique_title UNIQUE (title)
+			CONSTRAINT unique_title UNIQUE (title),
+			CONSTRAINT unique_source_hash UNIQUE (source_hash),
+			CONSTRAINT fk_v_owner_id FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE SET NULL,
+			FULLTEXT INDEX ft_title_description (title, description)
 		);`,
+	// Columns and keys added since the first version of the table, for the databases created before them
+	AlterTableVideosReq: `ALTER TABLE videos
+			ADD COLUMN IF NOT EXISTS source_hash CHAR(64) AFTER cover_path,
+			ADD COLUMN IF NOT EXISTS description VARCHAR(2048) NOT NULL DEFAULT '' AFTER source_hash,
+			ADD COLUMN IF NOT EXISTS owner_id VARCHAR(36) AFTER description,
+			ADD COLUMN IF NOT EXISTS archived_at DATETIME AFTER owner_id,
+			ADD UNIQUE INDEX IF NOT EXISTS unique_source_hash (source_hash),
+			ADD CONSTRAINT fk_v_owner_id FOREIGN KEY IF NOT EXISTS (owner_id) REFERENCES users (id) ON DELETE SET NULL,
+			ADD FULLTEXT INDEX IF NOT EXISTS ft_title_description (title, description);`,
 
-	CreateVideo:             "INSERT INTO videos (id, title, video_status, source_path, cover_path) VALUES (?, ? , ?, ?, ?)",
+	CreateVideo:             "INSERT INTO videos (id, title, video_status, source_path, cover_path, owner_id) VALUES (?, ? , ?, ?, ?, ?)",
 	UpdateVideo:             "UPDATE videos SET title = ?, video_status = ?, uploaded_at = ?, source_path = ?, cover_path = ? WHERE id = ?",
 	GetVideo:                "SELECT * FROM videos WHERE id = ?",
 	GetVideoFromTitle:       "SELECT * FROM videos WHERE title = ?",
@@ -52,20 +97,93 @@ var VideosRequests = map[VideosRequestName]string{
 	GetVideosUploadedAtDesc: "SELECT * FROM videos WHERE video_status = ? ORDER BY uploaded_at DESC LIMIT ?,?",
 	GetTotalVideos:          "SELECT COUNT(*) FROM videos WHERE video_status = ?",
 	DeleteVideo:             "DELETE FROM videos WHERE id = ?",
+	GetVideoFromSourceHash:  "SELECT * FROM videos WHERE source_hash = ?",
+	UpdateVideoSourceHash:   "UPDATE videos SET source_hash = ? WHERE id = ?",
+	UpdateVideoMetadata:     "UPDATE videos SET title = ?, description = ? WHERE id = ?",
+
+	// Videos with the status matching the search in their title, description or tags, the most relevant first.
+	// Other filters are ignored when NULL.
+	SearchVideos: `SELECT v.*, MATCH (v.title, v.description) AGAINST (? IN BOOLEAN MODE) + COALESCE(t.relevance, 0) AS relevance
+		FROM videos v
+		LEFT JOIN (SELECT video_id, SUM(MATCH (tag) AGAINST (? IN BOOLEAN MODE)) AS relevance FROM video_tags
+			WHERE MATCH (tag) AGAINST (? IN BOOLEAN MODE) GROUP BY video_id) t ON t.video_id = v.id
+		WHERE (MATCH (v.title, v.description) AGAINST (? IN BOOLEAN MODE) OR t.video_id IS NOT NULL)
+			AND v.video_status = ?
+			AND (? IS NULL OR v.uploaded_at >= ?)
+			AND (? IS NULL OR v.uploaded_at < ?)
+			AND (? IS NULL OR v.owner_id = ?)
+		ORDER BY relevance DESC, v.title ASC LIMIT ?,?`,
+	GetTotalSearchVideos: `SELECT COUNT(*)
+		FROM videos v
+		LEFT JOIN (SELECT DISTINCT video_id FROM video_tags
+			WHERE MATCH (tag) AGAINST (? IN BOOLEAN MODE)) t ON t.video_id = v.id
+		WHERE (MATCH (v.title, v.description) AGAINST (? IN BOOLEAN MODE) OR t.video_id IS NOT NULL)
+			AND v.video_status = ?
+			AND (? IS NULL OR v.uploaded_at >= ?)
+			AND (? IS NULL OR v.uploaded_at < ?)
+			AND (? IS NULL OR v.owner_id = ?)`,
+
+	// Keyset pagination : videos with one of the statuses (comma separated list) and of the owner (any owner
+	// when NULL), after the position of the cursor (sort value, id), or from the beginning when the cursor is NULL.
+	GetVideosAfterTitleAsc:       videosAfterRequest("title", true),
+	GetVideosAfterTitleDesc:      videosAfterRequest("title", false),
+	GetVideosAfterUploadedAtAsc:  videosAfterRequest(uploadedAtSortKey, true),
+	GetVideosAfterUploadedAtDesc: videosAfterRequest(uploadedAtSortKey, false),
+	GetVideosAfterCreatedAtAsc:   videosAfterRequest("created_at", true),
+	GetVideosAfterCreatedAtDesc:  videosAfterRequest("created_at", false),
+	GetVideosAfterUpdatedAtAsc:   videosAfterRequest("updated_at", true),
+	GetVideosAfterUpdatedAtDesc:  videosAfterRequest("updated_at", false),
+	GetTotalVideosWithStatuses:   "SELECT COUNT(*) FROM videos WHERE FIND_IN_SET(video_status, ?) AND (? IS NULL OR owner_id = ?)",
+
+	UpdateVideoCoverPath: "UPDATE videos SET cover_path = ? WHERE id = ?",
+
+	// Videos archived before the given date
+	GetVideosArchivedBefore: "SELECT * FROM videos WHERE video_status = ? AND archived_at < ? ORDER BY archived_at ASC",
+	UpdateVideoArchivedAt:   "UPDATE videos SET video_status = ?, archived_at = ? WHERE id = ?",
+}
+
+// Videos not uploaded yet come first, as NULL values do when sorting
+const uploadedAtSortKey = "IFNULL(uploaded_at, TIMESTAMP '1000-01-01 00:00:00')"
+
+func videosAfterRequest(sortKey string, ascending bool) string {
+	order, comparison := "ASC", ">"
+	if !ascending {
+		order, comparison = "DESC", "<"
+	}
+
+	return "SELECT * FROM videos WHERE FIND_IN_SET(video_status, ?) AND (? IS NULL OR owner_id = ?) AND (? IS NULL OR (" + sortKey + ", id) " + comparison + " (?, ?)) " +
+		"ORDER BY " + sortKey + " " + order + ", id " + order + " LIMIT ?"
 }
 
 type VideosDAO struct {
-	DB                          *sql.DB
-	stmtCreate                  *sql.Stmt
-	stmtUpdate                  *sql.Stmt
-	stmtGetVideo                *sql.Stmt
-	stmtGetVideoFromTitle       *sql.Stmt
-	stmtGetVideosTitleAsc       *sql.Stmt
-	stmtGetVideosTitleDesc      *sql.Stmt
-	stmtGetVideosUploadedAtAsc  *sql.Stmt
-	stmtGetVideosUploadedAtDesc *sql.Stmt
-	stmtGetTotalVideos          *sql.Stmt
-	stmtDeleteVideo             *sql.Stmt
+	DB                               *sql.DB
+	stmtCreate                       *sql.Stmt
+	stmtUpdate                       *sql.Stmt
+	stmtGetVideo                     *sql.Stmt
+	stmtGetVideoFromTitle            *sql.Stmt
+	stmtGetVideosTitleAsc            *sql.Stmt
+	stmtGetVideosTitleDesc           *sql.Stmt
+	stmtGetVideosUploadedAtAsc       *sql.Stmt
+	stmtGetVideosUploadedAtDesc      *sql.Stmt
+	stmtGetTotalVideos               *sql.Stmt
+	stmtDeleteVideo                  *sql.Stmt
+	stmtGetVideoFromSourceHash       *sql.Stmt
+	stmtUpdateVideoSourceHash        *sql.Stmt
+	stmtUpdateVideoMetadata          *sql.Stmt
+	stmtSearchVideos                 *sql.Stmt
+	stmtGetTotalSearchVideos         *sql.Stmt
+	stmtGetVideosAfterTitleAsc       *sql.Stmt
+	stmtGetVideosAfterTitleDesc      *sql.Stmt
+	stmtGetVideosAfterUploadedAtAsc  *sql.Stmt
+	stmtGetVideosAfterUploadedAtDesc *sql.Stmt
+	stmtGetVideosAfterCreatedAtAsc   *sql.Stmt
+	stmtGetVideosAfterCreatedAtDesc  *sql.Stmt
+	stmtGetVideosAfterUpdatedAtAsc   *sql.Stmt
+	stmtGetVideosAfterUpdatedAtDesc  *sql.Stmt
+	stmtGetTotalVideosWithStatuses   *sql.Stmt
+	stmtUpdateVideoCoverPath         *sql.Stmt
+	stmtGetVideosArchivedBefore      *sql.Stmt
+	stmtUpdateVideoArchivedAt        *sql.Stmt
 }
 
 func prepareVideoStmts(ctx context.Context, db *sql.DB) (*VideosDAO, error) {
@@ -142,6 +260,125 @@ func prepareVideoStmts(ctx context.Context, db *sql.DB) (*VideosDAO, error) {
 		return nil, err
 	}
 
+	// GetVideoFromSourceHash
+	stmts.stmtGetVideoFromSourceHash, err = db.PrepareContext(ctx, VideosRequests[GetVideoFromSourceHash])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// UpdateVideoSourceHash
+	stmts.stmtUpdateVideoSourceHash, err = db.PrepareContext(ctx, VideosRequests[UpdateVideoSourceHash])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// UpdateVideoMetadata
+	stmts.stmtUpdateVideoMetadata, err = db.PrepareContext(ctx, VideosRequests[UpdateVideoMetadata])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// SearchVideos
+	stmts.stmtSearchVideos, err = db.PrepareContext(ctx, VideosRequests[SearchVideos])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetTotalSearchVideos
+	stmts.stmtGetTotalSearchVideos, err = db.PrepareContext(ctx, VideosRequests[GetTotalSearchVideos])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideosAfterTitleAsc
+	stmts.stmtGetVideosAfterTitleAsc, err = db.PrepareContext(ctx, VideosRequests[GetVideosAfterTitleAsc])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideosAfterTitleDesc
+	stmts.stmtGetVideosAfterTitleDesc, err = db.PrepareContext(ctx, VideosRequests[GetVideosAfterTitleDesc])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideosAfterUploadedAtAsc
+	stmts.stmtGetVideosAfterUploadedAtAsc, err = db.PrepareContext(ctx, VideosRequests[GetVideosAfterUploadedAtAsc])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideosAfterUploadedAtDesc
+	stmts.stmtGetVideosAfterUploadedAtDesc, err = db.PrepareContext(ctx, VideosRequests[GetVideosAfterUploadedAtDesc])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideosAfterCreatedAtAsc
+	stmts.stmtGetVideosAfterCreatedAtAsc, err = db.PrepareContext(ctx, VideosRequests[GetVideosAfterCreatedAtAsc])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideosAfterCreatedAtDesc
+	stmts.stmtGetVideosAfterCreatedAtDesc, err = db.PrepareContext(ctx, VideosRequests[GetVideosAfterCreatedAtDesc])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideosAfterUpdatedAtAsc
+	stmts.stmtGetVideosAfterUpdatedAtAsc, err = db.PrepareContext(ctx, VideosRequests[GetVideosAfterUpdatedAtAsc])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideosAfterUpdatedAtDesc
+	stmts.stmtGetVideosAfterUpdatedAtDesc, err = db.PrepareContext(ctx, VideosRequests[GetVideosAfterUpdatedAtDesc])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetTotalVideosWithStatuses
+	stmts.stmtGetTotalVideosWithStatuses, err = db.PrepareContext(ctx, VideosRequests[GetTotalVideosWithStatuses])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// UpdateVideoCoverPath
+	stmts.stmtUpdateVideoCoverPath, err = db.PrepareContext(ctx, VideosRequests[UpdateVideoCoverPath])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideosArchivedBefore
+	stmts.stmtGetVideosArchivedBefore, err = db.PrepareContext(ctx, VideosRequests[GetVideosArchivedBefore])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// UpdateVideoArchivedAt
+	stmts.stmtUpdateVideoArchivedAt, err = db.PrepareContext(ctx, VideosRequests[UpdateVideoArchivedAt])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
 	return &stmts, nil
 }
 
@@ -150,8 +387,14 @@ func createTableVideos(ctx context.Context, db *sql.DB) error {
 		log.Error("Cannot create table : ", err)
 		return err
 	}
-
 	log.Debug("Table videos created (or existed already)")
+
+	if _, err := db.ExecContext(ctx, VideosRequests[AlterTableVideosReq]); err != nil {
+		log.Error("Cannot alter table : ", err)
+		return err
+	}
+	log.Debug("Table videos up to date")
+
 	return nil
 }
 
@@ -172,8 +415,8 @@ func CreateVideosDAO(ctx context.Context, db *sql.DB) (*VideosDAO, error) {
 	return videoDAO, nil
 }
 
-func (v VideosDAO) CreateVideo(ctx context.Context, ID, title string, status int, sourcePath string, coverPath string) (*models.Video, error) {
-	res, err := v.stmtCreate.ExecContext(ctx, ID, title, status, sourcePath, coverPath)
+func (v VideosDAO) CreateVideo(ctx context.Context, ID, title string, status int, sourcePath string, coverPath string, ownerID *string) (*models.Video, error) {
+	res, err := v.stmtCreate.ExecContext(ctx, ID, title, status, sourcePath, coverPath, ownerID)
 	if err != nil {
 		log.Error("Error while insert into videos : ", err)
 		return nil, err
@@ -242,6 +485,53 @@ func (v VideosDAO) DeleteVideoTx(ctx context.Context, tx *sql.Tx, ID string) err
 	return nil
 }
 
+// DeleteVideoAndUploads deletes the video and its uploads, and removes it from the playlists, in the same transaction
+func DeleteVideoAndUploads(ctx context.Context, videosDAO *VideosDAO, uploadsDAO *UploadsDAO, playlistsDAO *PlaylistsDAO, ID string) error {
+	tx, err := videosDAO.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("Cannot open new database transaction : ", err)
+		return err
+	}
+
+	if err := uploadsDAO.DeleteUploadTx(ctx, tx, ID); err != nil {
+		log.Error("Cannot delete video "+ID+" uploads : ", err)
+		if err := tx.Rollback(); err != nil {
+			log.Error("Cannot rollback : ", err)
+		}
+
+		return err
+	}
+
+	if err := playlistsDAO.RemoveVideoFromPlaylistsTx(ctx, tx, ID); err != nil {
+		log.Error("Cannot remove video "+ID+" from playlists : ", err)
+		if err := tx.Rollback(); err != nil {
+			log.Error("Cannot rollback : ", err)
+		}
+
+		return err
+	}
+
+	if err := videosDAO.DeleteVideoTx(ctx, tx, ID); err != nil {
+		log.Error("Cannot delete video "+ID+" : ", err)
+		if err := tx.Rollback(); err != nil {
+			log.Error("Cannot rollback : ", err)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("Cannot commit database transaction")
+		if err := tx.Rollback(); err != nil {
+			log.Error("Cannot rollback : ", err)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
 func (v VideosDAO) UpdateVideo(ctx context.Context, video *models.Video) error {
 	res, err := v.stmtUpdate.ExecContext(ctx, video.Title, video.Status, video.UploadedAt, video.SourcePath, video.CoverPath, video.ID)
 	if err != nil {
@@ -299,6 +589,10 @@ func (v VideosDAO) GetVideo(ctx context.Context, ID string) (*models.Video, erro
 		&video.UpdatedAt,
 		&video.SourcePath,
 		&video.CoverPath,
+		&video.SourceHash,
+		&video.Description,
+		&video.OwnerID,
+		&video.ArchivedAt,
 	)
 	if err != nil {
 		log.Error("Error, video not found : ", err)
@@ -319,6 +613,34 @@ func (v VideosDAO) GetVideoFromTitle(ctx context.Context, title string) (*models
 		&video.UpdatedAt,
 		&video.SourcePath,
 		&video.CoverPath,
+		&video.SourceHash,
+		&video.Description,
+		&video.OwnerID,
+		&video.ArchivedAt,
+	)
+	if err != nil {
+		log.Error("Error, video not found : ", err)
+		return nil, err
+	}
+
+	return &video, nil
+}
+
+func (v VideosDAO) GetVideoFromSourceHash(ctx context.Context, sourceHash string) (*models.Video, error) {
+	var video models.Video
+	err := v.stmtGetVideoFromSourceHash.QueryRowContext(ctx, sourceHash).Scan(
+		&video.ID,
+		&video.Title,
+		&video.Status,
+		&video.UploadedAt,
+		&video.CreatedAt,
+		&video.UpdatedAt,
+		&video.SourcePath,
+		&video.CoverPath,
+		&video.SourceHash,
+		&video.Description,
+		&video.OwnerID,
+		&video.ArchivedAt,
 	)
 	if err != nil {
 		log.Error("Error, video not found : ", err)
@@ -328,6 +650,111 @@ func (v VideosDAO) GetVideoFromTitle(ctx context.Context, title string) (*models
 	return &video, nil
 }
 
+func (v VideosDAO) UpdateVideoCoverPath(ctx context.Context, video *models.Video) error {
+	res, err := v.stmtUpdateVideoCoverPath.ExecContext(ctx, video.CoverPath, video.ID)
+	if err != nil {
+		log.Error("Error while update video cover path : ", err)
+		return err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return err
+	}
+
+	// Check if one and only one rows has been affected
+	if nbRowAff != 1 {
+		err := fmt.Errorf("wrong number of row affected (%d) while update id : %v in table videos", nbRowAff, video.ID)
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateVideoArchivedAt saves the status of the video with its archive date, nil when it is not archived
+func (v VideosDAO) UpdateVideoArchivedAt(ctx context.Context, video *models.Video) error {
+	res, err := v.stmtUpdateVideoArchivedAt.ExecContext(ctx, video.Status, video.ArchivedAt, video.ID)
+	if err != nil {
+		log.Error("Error while update video archive date : ", err)
+		return err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return err
+	}
+
+	// Check if one and only one rows has been affected
+	if nbRowAff != 1 {
+		err := fmt.Errorf("wrong number of row affected (%d) while update id : %v in table videos", nbRowAff, video.ID)
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (v VideosDAO) UpdateVideoSourceHash(ctx context.Context, video *models.Video) error {
+	res, err := v.stmtUpdateVideoSourceHash.ExecContext(ctx, video.SourceHash, video.ID)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+			log.Error("Source hash already used by another video : ", err)
+			return ErrDuplicateSourceHash
+		}
+		log.Error("Error while update video source hash : ", err)
+		return err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return err
+	}
+
+	// Check if one and only one rows has been affected
+	if nbRowAff != 1 {
+		err := fmt.Errorf("wrong number of row affected (%d) while update id : %v in table videos", nbRowAff, video.ID)
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateVideoMetadataTx updates the title and the description of the video
+func (v VideosDAO) UpdateVideoMetadataTx(ctx context.Context, tx *sql.Tx, video *models.Video) error {
+	stmt := tx.StmtContext(ctx, v.stmtUpdateVideoMetadata)
+	res, err := stmt.ExecContext(ctx, video.Title, video.Description, video.ID)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+			log.Error("Title already used by another video : ", err)
+			return ErrDuplicateTitle
+		}
+		log.Error("Error while update video metadata : ", err)
+		return err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return err
+	}
+
+	// No row affected when the metadata did not change : only check the video has not been deleted
+	if nbRowAff > 1 {
+		err := fmt.Errorf("wrong number of row affected (%d) while update id : %v in table videos", nbRowAff, video.ID)
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
 func (v VideosDAO) GetVideos(ctx context.Context, attribute interface{}, ascending bool, page, limit, status int) ([]models.Video, error) {
 
 	var stmt *sql.Stmt
@@ -382,6 +809,10 @@ func (v VideosDAO) GetVideos(ctx context.Context, attribute interface{}, ascendi
 			&row.UpdatedAt,
 			&row.SourcePath,
 			&row.CoverPath,
+			&row.SourceHash,
+			&row.Description,
+			&row.OwnerID,
+			&row.ArchivedAt,
 		); err != nil {
 			log.Error("Cannot read rows : ", err)
 			return nil, err
@@ -402,6 +833,228 @@ func (v VideosDAO) GetTotalVideos(ctx context.Context, status int) (int, error)
 	return total, nil
 }
 
+// GetVideosAfter returns the videos with one of the statuses, sorted on the attribute (then on the ID), coming
+// after the cursor. Without cursor, the videos are returned from the beginning.
+func (v VideosDAO) GetVideosAfter(ctx context.Context, attribute models.PaginationAttribute, ascending bool, statuses []models.VideoStatus, ownerID *string, cursor *models.VideoCursor, limit int) ([]models.Video, error) { //nolint:cyclop
+	var stmt *sql.Stmt
+	switch attribute {
+	case models.TITLE:
+		stmt = v.stmtGetVideosAfterTitleDesc
+		if ascending {
+			stmt = v.stmtGetVideosAfterTitleAsc
+		}
+
+	case models.UPLOADEDAT:
+		stmt = v.stmtGetVideosAfterUploadedAtDesc
+		if ascending {
+			stmt = v.stmtGetVideosAfterUploadedAtAsc
+		}
+
+	case models.CREATEDAT:
+		stmt = v.stmtGetVideosAfterCreatedAtDesc
+		if ascending {
+			stmt = v.stmtGetVideosAfterCreatedAtAsc
+		}
+
+	case models.UPDATEDAT:
+		stmt = v.stmtGetVideosAfterUpdatedAtDesc
+		if ascending {
+			stmt = v.stmtGetVideosAfterUpdatedAtAsc
+		}
+
+	default:
+		err := fmt.Errorf("no such attribute")
+		return nil, err
+	}
+
+	var cursorValue, cursorID interface{}
+	if cursor != nil {
+		cursorValue, cursorID = cursor.Value, cursor.ID
+	}
+
+	rows, err := stmt.QueryContext(ctx, statusSet(statuses), ownerID, ownerID, cursorValue, cursorValue, cursorID, limit)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	var videos []models.Video
+	for rows.Next() {
+		var row models.Video
+		if err := rows.Scan(
+			&row.ID,
+			&row.Title,
+			&row.Status,
+			&row.UploadedAt,
+			&row.CreatedAt,
+			&row.UpdatedAt,
+			&row.SourcePath,
+			&row.CoverPath,
+			&row.SourceHash,
+			&row.Description,
+			&row.OwnerID,
+			&row.ArchivedAt,
+		); err != nil {
+			log.Error("Cannot read rows : ", err)
+			return nil, err
+		}
+		videos = append(videos, row)
+	}
+
+	return videos, nil
+}
+
+func (v VideosDAO) GetTotalVideosWithStatuses(ctx context.Context, statuses []models.VideoStatus, ownerID *string) (int, error) {
+	var total int
+	err := v.stmtGetTotalVideosWithStatuses.QueryRowContext(ctx, statusSet(statuses), ownerID, ownerID).Scan(&total)
+	if err != nil {
+		log.Error("Cannot read rows : ", err)
+		return -1, err
+	}
+	return total, nil
+}
+
+// GetVideosArchivedBefore returns the videos archived before the date, the oldest first
+func (v VideosDAO) GetVideosArchivedBefore(ctx context.Context, date time.Time) ([]models.Video, error) {
+	rows, err := v.stmtGetVideosArchivedBefore.QueryContext(ctx, int(models.ARCHIVE), date)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	var videos []models.Video
+	for rows.Next() {
+		var row models.Video
+		if err := rows.Scan(
+			&row.ID,
+			&row.Title,
+			&row.Status,
+			&row.UploadedAt,
+			&row.CreatedAt,
+			&row.UpdatedAt,
+			&row.SourcePath,
+			&row.CoverPath,
+			&row.SourceHash,
+			&row.Description,
+			&row.OwnerID,
+			&row.ArchivedAt,
+		); err != nil {
+			log.Error("Cannot read rows : ", err)
+			return nil, err
+		}
+		videos = append(videos, row)
+	}
+
+	return videos, nil
+}
+
+// statusSet returns the statuses as a FIND_IN_SET list
+func statusSet(statuses []models.VideoStatus) string {
+	set := make([]string, len(statuses))
+	for i, status := range statuses {
+		set[i] = strconv.Itoa(int(status))
+	}
+	return strings.Join(set, ",")
+}
+
+func (v VideosDAO) SearchVideos(ctx context.Context, search models.VideoSearch, page, limit int) ([]models.Video, error) {
+	query := fullTextQuery(search.Query)
+	status, uploadedAfter, uploadedBefore := searchFilters(search)
+
+	rows, err := v.stmtSearchVideos.QueryContext(ctx, query, query, query, query,
+		status, uploadedAfter, uploadedAfter, uploadedBefore, uploadedBefore, search.OwnerID, search.OwnerID, (page-1)*limit, limit)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	var videos []models.Video
+	for rows.Next() {
+		var row models.Video
+		var relevance float64
+		if err := rows.Scan(
+			&row.ID,
+			&row.Title,
+			&row.Status,
+			&row.UploadedAt,
+			&row.CreatedAt,
+			&row.UpdatedAt,
+			&row.SourcePath,
+			&row.CoverPath,
+			&row.SourceHash,
+			&row.Description,
+			&row.OwnerID,
+			&row.ArchivedAt,
+			&relevance,
+		); err != nil {
+			log.Error("Cannot read rows : ", err)
+			return nil, err
+		}
+		videos = append(videos, row)
+	}
+
+	return videos, nil
+}
+
+func (v VideosDAO) GetTotalSearchVideos(ctx context.Context, search models.VideoSearch) (int, error) {
+	query := fullTextQuery(search.Query)
+	status, uploadedAfter, uploadedBefore := searchFilters(search)
+
+	var total int
+	err := v.stmtGetTotalSearchVideos.QueryRowContext(ctx, query, query,
+		status, uploadedAfter, uploadedAfter, uploadedBefore, uploadedBefore, search.OwnerID, search.OwnerID).Scan(&total)
+	if err != nil {
+		log.Error("Cannot read rows : ", err)
+		return -1, err
+	}
+	return total, nil
+}
+
+// fullTextQuery turns the search into a boolean mode query matching the beginning of each word :
+// 'mount ski' matches 'mountain' or 'skiing'. Boolean operators of the search are dropped.
+func fullTextQuery(search string) string {
+	words := strings.FieldsFunc(search, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i, word := range words {
+		words[i] = word + "*"
+	}
+
+	return strings.Join(words, " ")
+}
+
+// searchFilters returns the filters of the search, nil when not set
+// searchFilters returns the filters of the search, the complete videos when it has no status
+func searchFilters(search models.VideoSearch) (status, uploadedAfter, uploadedBefore interface{}) {
+	status = int(models.COMPLETE)
+	if search.Status != nil {
+		status = int(*search.Status)
+	}
+	if search.UploadedAfter != nil {
+		uploadedAfter = *search.UploadedAfter
+	}
+	if search.UploadedBefore != nil {
+		uploadedBefore = *search.UploadedBefore
+	}
+	return status, uploadedAfter, uploadedBefore
+}
+
 func (v VideosDAO) Close() {
 	_ = v.stmtCreate.Close()
 	_ = v.stmtUpdate.Close()
@@ -413,4 +1066,21 @@ func (v VideosDAO) Close() {
 	_ = v.stmtGetVideosTitleDesc.Close()
 	_ = v.stmtGetVideosUploadedAtAsc.Close()
 	_ = v.stmtGetVideosUploadedAtDesc.Close()
+	_ = v.stmtGetVideoFromSourceHash.Close()
+	_ = v.stmtUpdateVideoSourceHash.Close()
+	_ = v.stmtUpdateVideoMetadata.Close()
+	_ = v.stmtSearchVideos.Close()
+	_ = v.stmtGetTotalSearchVideos.Close()
+	_ = v.stmtGetVideosAfterTitleAsc.Close()
+	_ = v.stmtGetVideosAfterTitleDesc.Close()
+	_ = v.stmtGetVideosAfterUploadedAtAsc.Close()
+	_ = v.stmtGetVideosAfterUploadedAtDesc.Close()
+	_ = v.stmtGetVideosAfterCreatedAtAsc.Close()
+	_ = v.stmtGetVideosAfterCreatedAtDesc.Close()
+	_ = v.stmtGetVideosAfterUpdatedAtAsc.Close()
+	_ = v.stmtGetVideosAfterUpdatedAtDesc.Close()
+	_ = v.stmtGetTotalVideosWithStatuses.Close()
+	_ = v.stmtUpdateVideoCoverPath.Close()
+	_ = v.stmtGetVideosArchivedBefore.Close()
+	_ = v.stmtUpdateVideoArchivedAt.Close()
 }
@@ -0,0 +1,163 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+
+	_ "github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type WatchHistoryRequestName int
+
+const (
+	CreateTableWatchHistoryReq WatchHistoryRequestName = iota
+	UpsertWatchPosition
+	GetWatchPosition
+	GetWatchHistory
+)
+
+var WatchHistoryRequests = map[WatchHistoryRequestName]string{
+	CreateTableWatchHistoryReq: `CREATE TABLE IF NOT EXISTS watch_history (
+			user_id         VARCHAR(36) NOT NULL,
+			video_id        VARCHAR(36) NOT NULL,
+			position        DOUBLE NOT NULL DEFAULT 0,
+			watched_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+			CONSTRAINT pk PRIMARY KEY (user_id, video_id),
+			CONSTRAINT fk_wh_u_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+			CONSTRAINT fk_wh_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE,
+			INDEX idx_user_watched_at (user_id, watched_at)
+		);`,
+
+	UpsertWatchPosition: "INSERT INTO watch_history (user_id, video_id, position) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE position = VALUES(position), watched_at = NOW()",
+	GetWatchPosition:    "SELECT * FROM watch_history WHERE user_id = ? AND video_id = ?",
+	GetWatchHistory:     "SELECT * FROM watch_history WHERE user_id = ? ORDER BY watched_at DESC, video_id ASC LIMIT ?",
+}
+
+type WatchHistoryDAO struct {
+	DB                      *sql.DB
+	stmtUpsertWatchPosition *sql.Stmt
+	stmtGetWatchPosition    *sql.Stmt
+	stmtGetWatchHistory     *sql.Stmt
+}
+
+func prepareWatchHistoryStmts(ctx context.Context, db *sql.DB) (*WatchHistoryDAO, error) {
+	stmts := WatchHistoryDAO{}
+
+	// UpsertWatchPosition
+	var err error
+	stmts.stmtUpsertWatchPosition, err = db.PrepareContext(ctx, WatchHistoryRequests[UpsertWatchPosition])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetWatchPosition
+	stmts.stmtGetWatchPosition, err = db.PrepareContext(ctx, WatchHistoryRequests[GetWatchPosition])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetWatchHistory
+	stmts.stmtGetWatchHistory, err = db.PrepareContext(ctx, WatchHistoryRequests[GetWatchHistory])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	return &stmts, nil
+}
+
+func createTableWatchHistory(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, WatchHistoryRequests[CreateTableWatchHistoryReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+
+	log.Debug("Table watch_history created (or existed already)")
+	return nil
+}
+
+func CreateWatchHistoryDAO(ctx context.Context, db *sql.DB) (*WatchHistoryDAO, error) {
+	if err := createTableWatchHistory(ctx, db); err != nil {
+		log.Error("Cannot create table watch_history : ", err)
+		return nil, err
+	}
+
+	watchHistoryDAO, err := prepareWatchHistoryStmts(ctx, db)
+	if err != nil {
+		log.Error("Cannot prepare watch history statements : ", err)
+		return nil, err
+	}
+
+	watchHistoryDAO.DB = db
+
+	return watchHistoryDAO, nil
+}
+
+// UpsertWatchPosition records the position of the user in the video, replacing the previous one
+func (w WatchHistoryDAO) UpsertWatchPosition(ctx context.Context, userID, videoID string, position float64) (*models.WatchPosition, error) {
+	if _, err := w.stmtUpsertWatchPosition.ExecContext(ctx, userID, videoID, position); err != nil {
+		log.Error("Error while insert into watch_history : ", err)
+		return nil, err
+	}
+
+	return w.GetWatchPosition(ctx, userID, videoID)
+}
+
+func (w WatchHistoryDAO) GetWatchPosition(ctx context.Context, userID, videoID string) (*models.WatchPosition, error) {
+	var position models.WatchPosition
+	err := w.stmtGetWatchPosition.QueryRowContext(ctx, userID, videoID).Scan(
+		&position.UserID,
+		&position.VideoID,
+		&position.Position,
+		&position.WatchedAt,
+	)
+	if err != nil {
+		log.Error("Error, watch position not found : ", err)
+		return nil, err
+	}
+
+	return &position, nil
+}
+
+// GetWatchHistory returns the last videos watched by the user, most recent first
+func (w WatchHistoryDAO) GetWatchHistory(ctx context.Context, userID string, limit int) ([]models.WatchPosition, error) {
+	rows, err := w.stmtGetWatchHistory.QueryContext(ctx, userID, limit)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+	defer func() {
+		if err = rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	history := []models.WatchPosition{}
+	for rows.Next() {
+		var position models.WatchPosition
+		if err := rows.Scan(
+			&position.UserID,
+			&position.VideoID,
+			&position.Position,
+			&position.WatchedAt,
+		); err != nil {
+			log.Error("Cannot read rows : ", err)
+			return nil, err
+		}
+		history = append(history, position)
+	}
+
+	return history, nil
+}
+
+func (w WatchHistoryDAO) Close() {
+	_ = w.stmtUpsertWatchPosition.Close()
+	_ = w.stmtGetWatchPosition.Close()
+	_ = w.stmtGetWatchHistory.Close()
+}
@@ -9,6 +9,7 @@ import (
 
 func ExpectVideosDAOCreation(mock sqlmock.Sqlmock) {
 	mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.CreateTableVideosReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.AlterTableVideosReq])).WillReturnResult(sqlmock.NewResult(0, 0))
 	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.CreateVideo]))
 	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo]))
 	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo]))
@@ -19,13 +20,124 @@ func ExpectVideosDAOCreation(mock sqlmock.Sqlmock) {
 	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideosUploadedAtDesc]))
 	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.GetTotalVideos]))
 	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.DeleteVideo]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideoFromSourceHash]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideoSourceHash]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideoMetadata]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.SearchVideos]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.GetTotalSearchVideos]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideosAfterTitleAsc]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideosAfterTitleDesc]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideosAfterUploadedAtAsc]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideosAfterUploadedAtDesc]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideosAfterCreatedAtAsc]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideosAfterCreatedAtDesc]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideosAfterUpdatedAtAsc]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideosAfterUpdatedAtDesc]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.GetTotalVideosWithStatuses]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideoCoverPath]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideosArchivedBefore]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideoArchivedAt]))
+}
+
+func ExpectTagsDAOCreation(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(dao.TagsRequests[dao.CreateTableTagsReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(dao.TagsRequests[dao.AlterTableTagsReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.TagsRequests[dao.GetVideoTags]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.TagsRequests[dao.AddVideoTag]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.TagsRequests[dao.DeleteVideoTags]))
+}
+
+func ExpectAudioLanguagesDAOCreation(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(dao.AudioLanguagesRequests[dao.CreateTableAudioLanguagesReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.AudioLanguagesRequests[dao.GetVideoAudioLanguages]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.AudioLanguagesRequests[dao.AddVideoAudioLanguage]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.AudioLanguagesRequests[dao.DeleteVideoAudioLanguages]))
+}
+
+func ExpectChaptersDAOCreation(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(dao.ChaptersRequests[dao.CreateTableChaptersReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.ChaptersRequests[dao.GetVideoChapters]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.ChaptersRequests[dao.AddVideoChapter]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.ChaptersRequests[dao.DeleteVideoChapters]))
 }
 
 func ExpectUploadsDAOCreation(mock sqlmock.Sqlmock) {
 	mock.ExpectExec(regexp.QuoteMeta(dao.UploadsRequests[dao.CreateTableUploadsReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(dao.UploadsRequests[dao.AlterTableUploadsReq])).WillReturnResult(sqlmock.NewResult(0, 0))
 	mock.ExpectPrepare(regexp.QuoteMeta(dao.UploadsRequests[dao.CreateUpload]))
 	mock.ExpectPrepare(regexp.QuoteMeta(dao.UploadsRequests[dao.UpdateUpload]))
 	mock.ExpectPrepare(regexp.QuoteMeta(dao.UploadsRequests[dao.GetUpload]))
 	mock.ExpectPrepare(regexp.QuoteMeta(dao.UploadsRequests[dao.GetUploads]))
 	mock.ExpectPrepare(regexp.QuoteMeta(dao.UploadsRequests[dao.DeleteUpload]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.UploadsRequests[dao.CreateResumableUpload]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.UploadsRequests[dao.UpdateUploadOffset]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.UploadsRequests[dao.UpdateUploadProgress]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.UploadsRequests[dao.GetVideoLatestUpload]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.UploadsRequests[dao.LockUploadOffset]))
+}
+
+func ExpectUsersDAOCreation(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(dao.UsersRequests[dao.CreateTableUsersReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.UsersRequests[dao.CreateUser]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.UsersRequests[dao.GetUser]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.UsersRequests[dao.GetUserFromUsername]))
+}
+
+func ExpectApiKeysDAOCreation(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(dao.ApiKeysRequests[dao.CreateTableApiKeysReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.ApiKeysRequests[dao.CreateApiKey]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.ApiKeysRequests[dao.GetApiKey]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.ApiKeysRequests[dao.GetApiKeyFromHash]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.ApiKeysRequests[dao.GetApiKeys]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.ApiKeysRequests[dao.RevokeApiKey]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.ApiKeysRequests[dao.UpdateApiKeyLastUsed]))
+}
+
+func ExpectPlaylistsDAOCreation(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(dao.PlaylistsRequests[dao.CreateTablePlaylistsReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(dao.PlaylistsRequests[dao.CreateTablePlaylistVideosReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaylistsRequests[dao.CreatePlaylist]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaylistsRequests[dao.GetPlaylist]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaylistsRequests[dao.GetPlaylists]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaylistsRequests[dao.UpdatePlaylist]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaylistsRequests[dao.DeletePlaylist]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaylistsRequests[dao.LockPlaylist]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaylistsRequests[dao.GetPlaylistVideos]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaylistsRequests[dao.GetTotalPlaylistVideos]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaylistsRequests[dao.GetPlaylistVideoPosition]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaylistsRequests[dao.ShiftPlaylistVideos]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaylistsRequests[dao.AddPlaylistVideo]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaylistsRequests[dao.RemovePlaylistVideo]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaylistsRequests[dao.UpdatePlaylistVideoPosition]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaylistsRequests[dao.GetVideoPlaylistPositions]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaylistsRequests[dao.RemoveVideoFromPlaylists]))
+}
+
+func ExpectPlaybackDAOCreation(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(dao.PlaybackRequests[dao.CreateTablePlaybackSessionsReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(dao.PlaybackRequests[dao.CreateTablePlaybackSegmentsReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaybackRequests[dao.CreatePlaybackSession]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaybackRequests[dao.UpdatePlaybackSessionLength]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaybackRequests[dao.AddPlaybackSegment]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaybackRequests[dao.IncrementPlaybackSegmentsWatched]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaybackRequests[dao.GetVideoStats]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaybackRequests[dao.GetVideosViewsAsc]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaybackRequests[dao.GetVideosViewsDesc]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaybackRequests[dao.GetVideosAfterViewsAsc]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.PlaybackRequests[dao.GetVideosAfterViewsDesc]))
+}
+
+func ExpectWatchHistoryDAOCreation(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(dao.WatchHistoryRequests[dao.CreateTableWatchHistoryReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.WatchHistoryRequests[dao.UpsertWatchPosition]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.WatchHistoryRequests[dao.GetWatchPosition]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.WatchHistoryRequests[dao.GetWatchHistory]))
+}
+
+func ExpectSubtitlesDAOCreation(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(dao.SubtitlesRequests[dao.CreateTableSubtitlesReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.SubtitlesRequests[dao.UpsertSubtitle]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetSubtitle]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetVideoSubtitles]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.SubtitlesRequests[dao.DeleteSubtitle]))
 }
@@ -16,62 +16,28 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/videos/list/{attribute}/{order}/{page}/{limit}/{status}": {
+        "/api/v1/apikeys": {
             "get": {
-                "description": "Get list of all videos",
+                "description": "List the API keys, revoked ones included, the newest first. Only for the shared account.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "video"
-                ],
-                "summary": "Get list of all videos",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Sort attribute",
-                        "name": "attribute",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Sort order",
-                        "name": "order",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Video per page",
-                        "name": "limit",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Video status ",
-                        "name": "status",
-                        "in": "path",
-                        "required": true
-                    }
+                    "apikey"
                 ],
+                "summary": "List API keys",
                 "responses": {
                     "200": {
-                        "description": "Video list and Hateoas links",
+                        "description": "API keys",
                         "schema": {
-                            "$ref": "#/definitions/controllers.VideoListResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/json.ApiKeyJson"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "string"
                         }
@@ -83,55 +49,35 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/videos/transformer": {
-            "get": {
-                "description": "Get list of existing services",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "services"
-                ],
-                "summary": "Get list of existing services",
-                "responses": {
-                    "200": {
-                        "description": "Service list",
-                        "schema": {
-                            "$ref": "#/definitions/controllers.TransformerServiceListResponse"
-                        }
-                    }
-                }
-            }
-        },
-        "/api/v1/videos/upload": {
+            },
             "post": {
-                "description": "Upload video file",
+                "description": "Create an API key for a machine client, sent in the X-API-Key header. Scopes are videos:read,\nvideos:write and videos:delete. The key is only returned by this request. Only for the shared account.",
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "video"
+                    "apikey"
                 ],
-                "summary": "Upload video file",
+                "summary": "Create an API key",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "video",
-                        "name": "file",
-                        "in": "formData",
-                        "required": true
+                        "description": "Name and scopes of the key",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.ApiKeyCreateRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Video and Links (HATEOAS)",
+                    "201": {
+                        "description": "Created key",
                         "schema": {
-                            "$ref": "#/definitions/controllers.Response"
+                            "$ref": "#/definitions/controllers.ApiKeyCreateResponse"
                         }
                     },
                     "400": {
@@ -140,14 +86,8 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
-                    "409": {
-                        "description": "This title already exists",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "415": {
-                        "description": "Unsupported Media Type",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "string"
                         }
@@ -161,28 +101,28 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/videos/{id}/archive": {
-            "put": {
-                "description": "Archive video",
+        "/api/v1/apikeys/{id}": {
+            "delete": {
+                "description": "Revoke an API key : it is rejected from now on, and kept in the list. Only for the shared account.",
                 "produces": [
                     "text/plain"
                 ],
                 "tags": [
-                    "video"
+                    "apikey"
                 ],
-                "summary": "Archive video",
+                "summary": "Revoke an API key",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Video ID",
+                        "description": "API key ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "204": {
+                        "description": "No Content",
                         "schema": {
                             "type": "string"
                         }
@@ -193,6 +133,12 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -208,46 +154,63 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/videos/{id}/cover": {
+        "/api/v1/history": {
             "get": {
-                "description": "Get video cover image in base64",
-                "consumes": [
-                    "text/plain"
-                ],
+                "description": "Get the last videos watched by the authenticated user with their position, most recent first",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
                     "video"
                 ],
-                "summary": "Get video cover image in base64",
+                "summary": "Get the watch history",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Video ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Videos to return, at most 100",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "video cover image in base64",
+                        "description": "Watch history",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/controllers.WatchHistoryResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid limit, or not a user account",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "type": "string"
                         }
+                    }
+                }
+            }
+        },
+        "/api/v1/playlists": {
+            "get": {
+                "description": "Get list of all playlists sorted by title, without their videos",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "Get list of all playlists",
+                "responses": {
+                    "200": {
+                        "description": "Playlist list and Hateoas links",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.PlaylistsListResponse"
+                        }
                     },
                     "500": {
                         "description": "Internal Server Error",
@@ -256,32 +219,35 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/videos/{id}/delete": {
-            "delete": {
-                "description": "Delete video",
+            },
+            "post": {
+                "description": "Create an empty playlist, owned by the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "video"
+                    "playlist"
                 ],
-                "summary": "Delete video",
+                "summary": "Create a playlist",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Video ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Title and description of the playlist",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.PlaylistRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created playlist",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/json.PlaylistJson"
                         }
                     },
                     "400": {
@@ -290,12 +256,6 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -305,20 +265,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/videos/{id}/info": {
+        "/api/v1/playlists/{id}": {
             "get": {
-                "description": "Get video informations",
+                "description": "Get a playlist with its videos in their order",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "video"
+                    "playlist"
                 ],
-                "summary": "Get video informations",
+                "summary": "Get a playlist",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Video ID",
+                        "description": "Playlist ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -326,9 +286,9 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Video Informations",
+                        "description": "Playlist and its videos",
                         "schema": {
-                            "$ref": "#/definitions/json.VideoInfo"
+                            "$ref": "#/definitions/json.PlaylistJson"
                         }
                     },
                     "400": {
@@ -350,32 +310,27 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/videos/{id}/status": {
-            "get": {
-                "description": "Get video status",
-                "produces": [
-                    "text/plain"
-                ],
+            },
+            "delete": {
+                "description": "Delete the playlist, its videos are kept",
                 "tags": [
-                    "video"
+                    "playlist"
                 ],
-                "summary": "Get video status",
+                "summary": "Delete a playlist",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Video ID",
+                        "description": "Playlist ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Describe video status",
+                    "204": {
+                        "description": "No Content",
                         "schema": {
-                            "$ref": "#/definitions/json.VideoStatus"
+                            "type": "string"
                         }
                     },
                     "400": {
@@ -384,6 +339,12 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -397,32 +358,42 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/videos/{id}/streams/master.m3u8": {
-            "get": {
-                "description": "Get video master",
+            },
+            "patch": {
+                "description": "Update the title and the description of the playlist",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "video"
+                    "playlist"
                 ],
-                "summary": "Get video master",
+                "summary": "Update a playlist",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Video ID",
+                        "description": "Playlist ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Metadata to update",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.PlaylistRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "HLS video master",
+                        "description": "Updated playlist",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/json.PlaylistJson"
                         }
                     },
                     "400": {
@@ -431,6 +402,12 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -446,51 +423,41 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/videos/{id}/streams/{quality}/{filename}": {
+        "/api/v1/playlists/{id}/streams/audio_{language}/segment_index.m3u8": {
             "get": {
-                "description": "Get sub part stream video",
+                "description": "Get the audio segments of a language of each encoded video of the playlist, a discontinuity starting\neach video. A video without this language plays its default one, a video whose audio is muxed with\nits video plays the one of its lowest quality. The segments carry a stream token of their video.",
                 "produces": [
                     "text/plain"
                 ],
                 "tags": [
-                    "video"
+                    "playlist"
                 ],
-                "summary": "Get sub part stream video",
+                "summary": "Get playlist audio rendition",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Video ID",
+                        "description": "Playlist ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Video quality",
-                        "name": "quality",
+                        "description": "Audio language of the playlist master",
+                        "name": "language",
                         "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Video sub part name",
-                        "name": "filename",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "description": "List of required filters",
-                        "name": "filter",
+                        "description": "Stream token, instead of credentials",
+                        "name": "token",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Video sub part (.ts)",
+                        "description": "HLS media playlist",
                         "schema": {
                             "type": "string"
                         }
@@ -501,6 +468,12 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "403": {
+                        "description": "Invalid stream token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -516,28 +489,34 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/videos/{id}/unarchive": {
-            "put": {
-                "description": "Unarchive video",
+        "/api/v1/playlists/{id}/streams/master.m3u8": {
+            "get": {
+                "description": "Get the master of the encoded videos of the playlist played one after the other. Its variant N plays\nthe Nth lowest quality of each video, or its best one. Its URIs carry a stream token of the playlist.\nWhen a video is encoded with several audio languages, the master has an audio rendition per language\nof the videos.",
                 "produces": [
                     "text/plain"
                 ],
                 "tags": [
-                    "video"
+                    "playlist"
                 ],
-                "summary": "Unarchive video",
+                "summary": "Get playlist master",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Video ID",
+                        "description": "Playlist ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Stream token, instead of credentials",
+                        "name": "token",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "HLS playlist master",
                         "schema": {
                             "type": "string"
                         }
@@ -548,6 +527,12 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "403": {
+                        "description": "Invalid stream token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -563,17 +548,2294 @@ const docTemplate = `{
                 }
             }
         },
-        "/health": {
+        "/api/v1/playlists/{id}/streams/v{level}/segment_index.m3u8": {
             "get": {
-                "description": "Get component health",
+                "description": "Get the segments of a quality of each encoded video of the playlist, a discontinuity starting each video.\nThe segments are the ones of the videos, carrying a stream token of their video.",
                 "produces": [
                     "text/plain"
                 ],
                 "tags": [
-                    "health"
+                    "playlist"
                 ],
-                "summary": "Get component health",
-                "responses": {
+                "summary": "Get playlist variant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Playlist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Variant of the playlist master",
+                        "name": "level",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Stream token, instead of credentials",
+                        "name": "token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "HLS media playlist",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Invalid stream token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/playlists/{id}/videos": {
+            "put": {
+                "description": "Give the new order of the videos. The request must list every video of the playlist, once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "Reorder the videos of a playlist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Playlist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Videos in their new order",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.PlaylistVideosReorderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Playlist and its videos",
+                        "schema": {
+                            "$ref": "#/definitions/json.PlaylistJson"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Insert the video at the position, from 0, or append it without position.\nThe videos after this position move one position further.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "Add a video to a playlist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Playlist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Video to add",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.PlaylistVideoAddRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Playlist and its videos",
+                        "schema": {
+                            "$ref": "#/definitions/json.PlaylistJson"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "The video is already in the playlist",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/playlists/{id}/videos/{videoId}": {
+            "delete": {
+                "description": "Remove the video from the playlist, the next videos move one position back. The video is kept.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "Remove a video from a playlist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Playlist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "videoId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Playlist and its videos",
+                        "schema": {
+                            "$ref": "#/definitions/json.PlaylistJson"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/login": {
+            "post": {
+                "description": "Check the credentials of a user account, or of the shared account, and issue an access token\nto send as a bearer token, and a refresh token to get new tokens.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Log in a user",
+                "parameters": [
+                    {
+                        "description": "Credentials of the user",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.UserCredentials"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Authenticated user and its tokens",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.UserTokensResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/refresh": {
+            "post": {
+                "description": "Issue new access and refresh tokens from a valid refresh token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Refresh tokens",
+                "parameters": [
+                    {
+                        "description": "Refresh token",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.UserRefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Authenticated user and its new tokens",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.UserTokensResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/register": {
+            "post": {
+                "description": "Create a user account, with the shared account only. The username has 3 to 64 letters, digits, '_',\n'.' or '-', the password 8 to 72 bytes. Users authenticate with basic auth or tokens, and only manage\ntheir own videos.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Register a user",
+                "parameters": [
+                    {
+                        "description": "Credentials of the new user",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.UserCredentials"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created user",
+                        "schema": {
+                            "$ref": "#/definitions/json.UserJson"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Not the shared account",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "This username already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos": {
+            "get": {
+                "description": "Get list of videos with one of the statuses, paginated with cursors : follow the Hateoas links\nto get the other pages. Videos are sorted on the attribute, then on their ID (on their title then their ID for views).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get list of videos",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Video statuses (Complete by default)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "upload_date",
+                        "description": "Sort attribute : title, upload_date, creation_date, update_date or views",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "desc",
+                        "description": "Sort order : asc or desc",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Video per page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Page cursor, from the Hateoas links",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Only the videos of the authenticated user",
+                        "name": "mine",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Video list and Hateoas links",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.VideoPageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/list/{attribute}/{order}/{page}/{limit}/{status}": {
+            "get": {
+                "description": "Get list of all videos",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get list of all videos",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sort attribute : title, upload_date, creation_date, update_date or views",
+                        "name": "attribute",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort order",
+                        "name": "order",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Video per page",
+                        "name": "limit",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Video status ",
+                        "name": "status",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Video list and Hateoas links",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.VideoListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/retention": {
+            "get": {
+                "description": "Dry run of the retention policy : archived videos that would be purged now, the oldest archive first. Only for the shared account.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "List videos to purge",
+                "responses": {
+                    "200": {
+                        "description": "Videos to purge and their size in bytes",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.RetentionResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/search": {
+            "get": {
+                "description": "Full-text search over the title, the description and the tags of the videos, the most relevant first.\nWords match their beginning : 'mount' matches 'mountain'.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Search videos",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Searched words",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Video status (Complete by default)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Uploaded from this date (YYYY-MM-DD or RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Uploaded until this date included (YYYY-MM-DD or RFC3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Video per page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Only the videos of the authenticated user",
+                        "name": "mine",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Video list and Hateoas links",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.VideoListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/transformer/list": {
+            "get": {
+                "description": "Get list of existing services",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "services"
+                ],
+                "summary": "Get list of existing services",
+                "responses": {
+                    "200": {
+                        "description": "Service list",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.TransformerServiceListResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/upload": {
+            "post": {
+                "description": "Upload video file. The title must be sent before the video file.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Upload video file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Title of the video, before the video file",
+                        "name": "title",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "video",
+                        "name": "video",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "JPEG or PNG cover image, of at most 10 MiB",
+                        "name": "cover",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Video and Links (HATEOAS)",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "This title already exists, or this video source already exists (existing Video and Links as json)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "Cover image too large",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/uploads": {
+            "post": {
+                "description": "Create a resumable video upload (tus protocol). Chunks are then sent with PATCH requests.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Create a resumable video upload",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Size of the video file in bytes",
+                        "name": "Upload-Length",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "tus metadata, must contain base64 encoded 'title' and 'filename'",
+                        "name": "Upload-Metadata",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Video, upload ID and Links (HATEOAS)",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.UploadCreatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "This title already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/uploads/presigned": {
+            "post": {
+                "description": "Create the video and return presigned URLs to PUT the video source (and cover) directly on S3.\nThe upload must then be completed with the 'complete' link.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Create a presigned video upload",
+                "parameters": [
+                    {
+                        "description": "Video title, file names of the video and the optional cover",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.PresignedUploadRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Video, upload ID and Links (HATEOAS)",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.UploadCreatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "This title already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/uploads/{id}": {
+            "head": {
+                "description": "Get the number of bytes already received for a resumable upload, in the Upload-Offset header",
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get resumable upload offset",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Upload ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "The video belongs to another user",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Append a chunk to a resumable upload. Chunks must be at least 5MiB, except the last one.\nThe video is sent for encoding once the last chunk is received.",
+                "consumes": [
+                    "application/offset+octet-stream"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Send a resumable upload chunk",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Upload ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset of the chunk, must match the current upload offset",
+                        "name": "Upload-Offset",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "The video belongs to another user",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Upload offset mismatch, another chunk being received, or this video source already exists (existing Video and Links as json)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "411": {
+                        "description": "Missing Content-Length",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/uploads/{id}/complete": {
+            "post": {
+                "description": "Check the video source has been sent on S3, then send it for encoding",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Complete a presigned video upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Upload ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Video and Links (HATEOAS)",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Video source not found on S3",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Upload already completed, or this video source already exists (existing Video and Links as json)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}": {
+            "patch": {
+                "description": "Update the title, the description and the tags of the video. Tags are trimmed and lower cased.\nThe title cannot be changed while the video is uploaded or encoded.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Update video metadata",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Metadata to update",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.VideoUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated video",
+                        "schema": {
+                            "$ref": "#/definitions/json.VideoJson"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "This title already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/archive": {
+            "put": {
+                "description": "Archive video",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Archive video",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/chapters": {
+            "get": {
+                "description": "List the chapters of the video, sorted on their start",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "List video chapters",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Chapters",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.VideoChaptersResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace all the chapters of the video. Titles are trimmed and starts rounded to the millisecond.\nA chapter lasts until the start of the next one, or until the end of the video.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Update video chapters",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Chapters of the video, an empty list removing them",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.VideoChaptersRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated chapters",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.VideoChaptersResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid chapters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/cover": {
+            "get": {
+                "description": "Get video cover image in base64",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get video cover image in base64",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "video cover image in base64",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Set or replace the cover image of the video. The image is cropped to a square and scaled as the encoder does.\nThe cover cannot be changed while the video is uploaded or encoded.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Set video cover image",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "JPEG or PNG cover image",
+                        "name": "cover",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "The video is uploaded or encoded",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/delete": {
+            "delete": {
+                "description": "Delete video",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Delete video",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/info": {
+            "get": {
+                "description": "Get video informations, with the last position of the authenticated user to resume the video",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get video informations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Video Informations",
+                        "schema": {
+                            "$ref": "#/definitions/json.VideoInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/position": {
+            "get": {
+                "description": "Get the last position reported by the authenticated user in the video",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get the playback position",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Last position",
+                        "schema": {
+                            "$ref": "#/definitions/json.WatchPositionJson"
+                        }
+                    },
+                    "400": {
+                        "description": "Not a user account",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Video never watched",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Record the position of the authenticated user in the video, replacing the previous one",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Report the playback position",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Position in seconds",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.VideoPositionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Recorded position",
+                        "schema": {
+                            "$ref": "#/definitions/json.WatchPositionJson"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid position, or not a user account",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key without the videos:write scope",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/stats": {
+            "get": {
+                "description": "Get the views of the video (playback sessions having watched at least one segment), its unique viewers,\nits watch time (in seconds) and the average completion of the views (between 0 and 1)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get video playback statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Video statistics",
+                        "schema": {
+                            "$ref": "#/definitions/json.VideoStatsJson"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/status": {
+            "get": {
+                "description": "Get video status, and its upload progress (in percent)",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get video status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Describe video status",
+                        "schema": {
+                            "$ref": "#/definitions/json.VideoStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/streams/chapters.vtt": {
+            "get": {
+                "description": "Get the WebVTT chapters track of the video, a cue per chapter",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get video chapters track",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Stream token, instead of credentials",
+                        "name": "token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "WebVTT chapters track",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Invalid stream token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/streams/master.m3u8": {
+            "get": {
+                "description": "Get video master. Its URIs carry a stream token authorizing the playlists and the segments\nof the video without credentials, until it expires. The master itself can be requested with the token.\nEach request starts a playback session, carried by the URIs too, to record the segments watched.\nThe subtitles tracks of the video are published as subtitles renditions.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get video master",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Stream token, instead of credentials",
+                        "name": "token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "HLS video master",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Invalid stream token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/streams/subtitles/{language}/{filename}": {
+            "get": {
+                "description": "Get the playlist of a subtitles track (index.m3u8), its segments carrying the stream token,\none of its WebVTT segments (segmentN.vtt) or its whole WebVTT file (subtitles.vtt)",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get video subtitles stream",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "BCP 47 language tag",
+                        "name": "language",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "index.m3u8, subtitles.vtt or segment name",
+                        "name": "filename",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Stream token, instead of credentials",
+                        "name": "token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "HLS subtitles playlist or WebVTT file",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Invalid stream token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/streams/thumbnails/{filename}": {
+            "get": {
+                "description": "Get the WebVTT thumbnails track (thumbnails.vtt) or one of its sprite sheets (spriteN.jpg)",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get video scrubbing thumbnails",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "thumbnails.vtt or sprite sheet name",
+                        "name": "filename",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "WebVTT track or JPEG sprite sheet",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/streams/{quality}/{filename}": {
+            "get": {
+                "description": "Get sub part stream video : the playlist of a quality, its segments carrying the stream token, or a segment.\nThe segments requested with a playback session are recorded as watched by the session.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get sub part stream video",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Video quality",
+                        "name": "quality",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Video sub part name",
+                        "name": "filename",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "description": "List of required filters",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Stream token, instead of credentials",
+                        "name": "token",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Playback session, given by the master",
+                        "name": "session",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Video sub part (.ts)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Invalid stream token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/subtitles": {
+            "get": {
+                "description": "List the subtitles tracks of the video, sorted on their language",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "List video subtitles",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Subtitles tracks",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.VideoSubtitlesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/subtitles/{language}": {
+            "put": {
+                "description": "Set or replace the subtitles track of the video in this language. SRT files are converted to WebVTT.\nThe track is published in the video master as a subtitles rendition, segmented every 30 seconds.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Upload video subtitles",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "BCP 47 language tag, like en or pt-BR",
+                        "name": "language",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "SRT or WebVTT file, of at most 2 MiB",
+                        "name": "subtitles",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of the track shown by the players, the language by default",
+                        "name": "label",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Uploaded subtitles track",
+                        "schema": {
+                            "$ref": "#/definitions/json.SubtitleJson"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid language, label or subtitles file",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete the subtitles track of the video in this language, and its files",
+                "tags": [
+                    "video"
+                ],
+                "summary": "Delete video subtitles",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "BCP 47 language tag",
+                        "name": "language",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/unarchive": {
+            "put": {
+                "description": "Unarchive video",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Unarchive video",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Get component health",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Get component health",
+                "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
@@ -599,10 +2861,21 @@ const docTemplate = `{
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Authentication cookie",
+                        "description": "Bearer access token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Access token, for the clients that cannot set headers",
+                        "name": "access_token",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authentication cookie with basic auth credentials",
                         "name": "Cookie",
-                        "in": "header",
-                        "required": true
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -632,10 +2905,256 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "controllers.Response": {
+        }
+    },
+    "definitions": {
+        "controllers.ApiKeyCreateRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "ci-pipeline"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "videos:read",
+                        "videos:write"
+                    ]
+                }
+            }
+        },
+        "controllers.ApiKeyCreateResponse": {
+            "type": "object",
+            "properties": {
+                "apiKey": {
+                    "$ref": "#/definitions/json.ApiKeyJson"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "vgl_3kq9Xb2Z..."
+                }
+            }
+        },
+        "controllers.ExpiredVideoInfo": {
+            "type": "object",
+            "properties": {
+                "archivedAt": {
+                    "type": "string",
+                    "example": "2022-04-15T12:59:52Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "1"
+                },
+                "size": {
+                    "type": "integer",
+                    "example": 1048576
+                },
+                "title": {
+                    "type": "string",
+                    "example": "my title"
+                }
+            }
+        },
+        "controllers.PlaylistRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Videos to watch the first week"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Onboarding"
+                }
+            }
+        },
+        "controllers.PlaylistVideoAddRequest": {
+            "type": "object",
+            "properties": {
+                "position": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "videoId": {
+                    "type": "string",
+                    "example": "aaaa-b56b-..."
+                }
+            }
+        },
+        "controllers.PlaylistVideosReorderRequest": {
+            "type": "object",
+            "properties": {
+                "videoIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "aaaa-b56b-...",
+                        "bbbb-c67c-..."
+                    ]
+                }
+            }
+        },
+        "controllers.PlaylistsListResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/json.LinkJson"
+                    }
+                },
+                "playlists": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/json.PlaylistJson"
+                    }
+                }
+            }
+        },
+        "controllers.PresignedUploadRequest": {
+            "type": "object",
+            "properties": {
+                "coverFilename": {
+                    "type": "string"
+                },
+                "filename": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.Response": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/json.LinkJson"
+                    }
+                },
+                "video": {
+                    "$ref": "#/definitions/json.VideoJson"
+                }
+            }
+        },
+        "controllers.RetentionResponse": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "period": {
+                    "type": "string",
+                    "example": "720h0m0s"
+                },
+                "size": {
+                    "type": "integer",
+                    "example": 1048576
+                },
+                "videos": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controllers.ExpiredVideoInfo"
+                    }
+                }
+            }
+        },
+        "controllers.TransformerServiceListResponse": {
+            "type": "object",
+            "properties": {
+                "services": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/json.TransformerServiceJson"
+                    }
+                }
+            }
+        },
+        "controllers.UploadCreatedResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/json.LinkJson"
+                    }
+                },
+                "uploadId": {
+                    "type": "string"
+                },
+                "video": {
+                    "$ref": "#/definitions/json.VideoJson"
+                }
+            }
+        },
+        "controllers.UserCredentials": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "a strong password"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "alice"
+                }
+            }
+        },
+        "controllers.UserRefreshRequest": {
+            "type": "object",
+            "properties": {
+                "refreshToken": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                }
+            }
+        },
+        "controllers.UserTokensResponse": {
+            "type": "object",
+            "properties": {
+                "accessToken": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "expiresIn": {
+                    "type": "integer",
+                    "example": 900
+                },
+                "refreshToken": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "tokenType": {
+                    "type": "string",
+                    "example": "Bearer"
+                },
+                "user": {
+                    "$ref": "#/definitions/json.UserJson"
+                }
+            }
+        },
+        "controllers.VideoChaptersRequest": {
+            "type": "object",
+            "properties": {
+                "chapters": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/json.ChapterJson"
+                    }
+                }
+            }
+        },
+        "controllers.VideoChaptersResponse": {
             "type": "object",
             "properties": {
                 "_links": {
@@ -644,18 +3163,10 @@ const docTemplate = `{
                         "$ref": "#/definitions/json.LinkJson"
                     }
                 },
-                "video": {
-                    "$ref": "#/definitions/json.VideoJson"
-                }
-            }
-        },
-        "controllers.TransformerServiceListResponse": {
-            "type": "object",
-            "properties": {
-                "services": {
+                "chapters": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/json.TransformerServiceJson"
+                        "$ref": "#/definitions/json.ChapterJson"
                     }
                 }
             }
@@ -696,6 +3207,145 @@ const docTemplate = `{
                 }
             }
         },
+        "controllers.VideoPageResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/json.LinkJson"
+                    }
+                },
+                "_total": {
+                    "type": "integer"
+                },
+                "videos": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controllers.VideoInfo"
+                    }
+                }
+            }
+        },
+        "controllers.VideoPositionRequest": {
+            "type": "object",
+            "properties": {
+                "position": {
+                    "type": "number",
+                    "example": 754.2
+                }
+            }
+        },
+        "controllers.VideoSubtitlesResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/json.LinkJson"
+                    }
+                },
+                "subtitles": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/json.SubtitleJson"
+                    }
+                }
+            }
+        },
+        "controllers.VideoUpdateRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "A description"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "nature",
+                        "mountain"
+                    ]
+                },
+                "title": {
+                    "type": "string",
+                    "example": "A new title"
+                }
+            }
+        },
+        "controllers.WatchHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/json.LinkJson"
+                    }
+                },
+                "history": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/json.WatchPositionJson"
+                    }
+                }
+            }
+        },
+        "json.ApiKeyJson": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string",
+                    "example": "2022-04-15T12:59:52Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aaaa-b56b-..."
+                },
+                "lastUsedAt": {
+                    "type": "string",
+                    "example": "2022-04-15T12:59:52Z"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "ci-pipeline"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "vgl_3kq9Xb2Z"
+                },
+                "revokedAt": {
+                    "type": "string",
+                    "example": "2022-04-15T12:59:52Z"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "videos:read",
+                        "videos:write"
+                    ]
+                }
+            }
+        },
+        "json.ChapterJson": {
+            "type": "object",
+            "properties": {
+                "start": {
+                    "description": "In seconds",
+                    "type": "number",
+                    "example": 60.5
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Introduction"
+                }
+            }
+        },
         "json.LinkJson": {
             "type": "object",
             "properties": {
@@ -707,6 +3357,66 @@ const docTemplate = `{
                 }
             }
         },
+        "json.PlaylistJson": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/json.LinkJson"
+                    }
+                },
+                "createdAt": {
+                    "type": "string",
+                    "example": "2022-04-15T12:59:52Z"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Videos to watch the first week"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aaaa-b56b-..."
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Onboarding"
+                },
+                "updatedAt": {
+                    "type": "string",
+                    "example": "2022-04-15T12:59:52Z"
+                },
+                "videos": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/json.VideoJson"
+                    }
+                }
+            }
+        },
+        "json.SubtitleJson": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/json.LinkJson"
+                    }
+                },
+                "createdAt": {
+                    "type": "string",
+                    "example": "2022-04-15T12:59:52Z"
+                },
+                "label": {
+                    "type": "string",
+                    "example": "English"
+                },
+                "language": {
+                    "type": "string",
+                    "example": "en"
+                }
+            }
+        },
         "json.TransformerServiceJson": {
             "type": "object",
             "properties": {
@@ -716,9 +3426,56 @@ const docTemplate = `{
                 }
             }
         },
+        "json.UserJson": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string",
+                    "example": "2022-04-15T12:59:52Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aaaa-b56b-..."
+                },
+                "username": {
+                    "type": "string",
+                    "example": "alice"
+                }
+            }
+        },
         "json.VideoInfo": {
             "type": "object",
             "properties": {
+                "audioLanguages": {
+                    "description": "Of the audio renditions, the default one first",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "eng",
+                        "fre"
+                    ]
+                },
+                "description": {
+                    "type": "string",
+                    "example": "A description"
+                },
+                "lastPosition": {
+                    "description": "Of the authenticated user, to resume the video",
+                    "type": "number",
+                    "example": 754.2
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "nature",
+                        "mountain"
+                    ]
+                },
                 "title": {
                     "type": "string",
                     "example": "amazingtitle"
@@ -736,6 +3493,10 @@ const docTemplate = `{
                     "type": "string",
                     "example": "2022-04-15T12:59:52Z"
                 },
+                "description": {
+                    "type": "string",
+                    "example": "A description"
+                },
                 "id": {
                     "type": "string",
                     "example": "aaaa-b56b-..."
@@ -744,6 +3505,16 @@ const docTemplate = `{
                     "type": "string",
                     "example": "VIDEO_STATUS_ENCODING"
                 },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "nature",
+                        "mountain"
+                    ]
+                },
                 "title": {
                     "type": "string",
                     "example": "A Title"
@@ -758,6 +3529,37 @@ const docTemplate = `{
                 }
             }
         },
+        "json.VideoStatsJson": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/json.LinkJson"
+                    }
+                },
+                "averageCompletion": {
+                    "type": "number",
+                    "example": 0.75
+                },
+                "uniqueViewers": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "videoId": {
+                    "type": "string",
+                    "example": "aaaa-b56b-..."
+                },
+                "views": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "watchTime": {
+                    "type": "number",
+                    "example": 1534.5
+                }
+            }
+        },
         "json.VideoStatus": {
             "type": "object",
             "properties": {
@@ -768,6 +3570,33 @@ const docTemplate = `{
                 "title": {
                     "type": "string",
                     "example": "AmazingTitle"
+                },
+                "uploadProgress": {
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
+        "json.WatchPositionJson": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/json.LinkJson"
+                    }
+                },
+                "position": {
+                    "type": "number",
+                    "example": 754.2
+                },
+                "videoId": {
+                    "type": "string",
+                    "example": "aaaa-b56b-..."
+                },
+                "watchedAt": {
+                    "type": "string",
+                    "example": "2022-04-15T12:59:52Z"
                 }
             }
         }
@@ -8,22 +8,26 @@ import (
 
 // VideoJson DTO
 type VideoJson struct {
-	ID         string     `json:"id" example:"aaaa-b56b-..."`
-	Title      string     `json:"title" example:"A Title"`
-	Status     string     `json:"status" example:"VIDEO_STATUS_ENCODING"`
-	UploadedAt *time.Time `json:"uploadedAt" example:"2022-04-15T12:59:52Z"`
-	CreatedAt  *time.Time `json:"createdAt" example:"2022-04-15T12:59:52Z"`
-	UpdatedAt  *time.Time `json:"updatedAt" example:"2022-04-15T12:59:52Z"`
+	ID          string     `json:"id" example:"aaaa-b56b-..."`
+	Title       string     `json:"title" example:"A Title"`
+	Status      string     `json:"status" example:"VIDEO_STATUS_ENCODING"`
+	UploadedAt  *time.Time `json:"uploadedAt" example:"2022-04-15T12:59:52Z"`
+	CreatedAt   *time.Time `json:"createdAt" example:"2022-04-15T12:59:52Z"`
+	UpdatedAt   *time.Time `json:"updatedAt" example:"2022-04-15T12:59:52Z"`
+	Description string     `json:"description" example:"A description"`
+	Tags        []string   `json:"tags" example:"nature,mountain"`
 }
 
 func VideoToVideoJson(video *models.Video) VideoJson {
 	videoJson := VideoJson{
-		ID:         video.ID,
-		Title:      video.Title,
-		Status:     video.Status.String(),
-		CreatedAt:  video.CreatedAt,
-		UploadedAt: video.UploadedAt,
-		UpdatedAt:  video.UpdatedAt,
+		ID:          video.ID,
+		Title:       video.Title,
+		Status:      video.Status.String(),
+		CreatedAt:   video.CreatedAt,
+		UploadedAt:  video.UploadedAt,
+		UpdatedAt:   video.UpdatedAt,
+		Description: video.Description,
+		Tags:        tagsToJson(video.Tags),
 	}
 
 	return videoJson
@@ -31,14 +35,22 @@ func VideoToVideoJson(video *models.Video) VideoJson {
 
 // VideoStatus DTO
 type VideoStatus struct {
-	Title  string `json:"title" example:"AmazingTitle"`
-	Status string `json:"status" example:"UPLOADED"`
+	Title          string `json:"title" example:"AmazingTitle"`
+	Status         string `json:"status" example:"UPLOADED"`
+	UploadProgress int    `json:"uploadProgress" example:"42"`
 }
 
-func VideoToStatusJson(video *models.Video) VideoStatus {
+// VideoToStatusJson uploadProgress (in percent) is only used while the video is not uploaded yet
+func VideoToStatusJson(video *models.Video, uploadProgress int) VideoStatus {
 	videoStatus := VideoStatus{
-		Title:  video.Title,
-		Status: video.Status.String(),
+		Title:          video.Title,
+		Status:         video.Status.String(),
+		UploadProgress: uploadProgress,
+	}
+
+	switch video.Status {
+	case models.UPLOADED, models.ENCODING, models.COMPLETE, models.ARCHIVE, models.FAIL_ENCODE:
+		videoStatus.UploadProgress = 100
 	}
 
 	return videoStatus
@@ -47,19 +59,211 @@ func VideoToStatusJson(video *models.Video) VideoStatus {
 // VideoInfo DTO
 
 type VideoInfo struct {
-	Title          string `json:"title" example:"amazingtitle"`
-	UploadDateUnix int64  `json:"uploadDateUnix" example:"1652173257"`
+	Title          string   `json:"title" example:"amazingtitle"`
+	UploadDateUnix int64    `json:"uploadDateUnix" example:"1652173257"`
+	Description    string   `json:"description" example:"A description"`
+	Tags           []string `json:"tags" example:"nature,mountain"`
+	AudioLanguages []string `json:"audioLanguages" example:"eng,fre"`       // Of the audio renditions, the default one first
+	LastPosition   *float64 `json:"lastPosition,omitempty" example:"754.2"` // Of the authenticated user, to resume the video
 }
 
 func VideoToInfoJson(video *models.Video) VideoInfo {
 	videoInfo := VideoInfo{
 		Title:          video.Title,
 		UploadDateUnix: video.UploadedAt.Unix(),
+		Description:    video.Description,
+		Tags:           tagsToJson(video.Tags),
+		AudioLanguages: tagsToJson(video.AudioLanguages),
 	}
 
 	return videoInfo
 }
 
+// tagsToJson returns an empty list instead of null when there is no tag (or language)
+func tagsToJson(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// UserJson DTO, without the password hash
+
+type UserJson struct {
+	ID        string     `json:"id" example:"aaaa-b56b-..."`
+	Username  string     `json:"username" example:"alice"`
+	CreatedAt *time.Time `json:"createdAt" example:"2022-04-15T12:59:52Z"`
+}
+
+func UserToUserJson(user *models.User) UserJson {
+	userJson := UserJson{
+		ID:        user.ID,
+		Username:  user.Username,
+		CreatedAt: user.CreatedAt,
+	}
+
+	return userJson
+}
+
+// ApiKeyJson DTO, without the key and its hash
+
+type ApiKeyJson struct {
+	ID         string     `json:"id" example:"aaaa-b56b-..."`
+	Name       string     `json:"name" example:"ci-pipeline"`
+	Prefix     string     `json:"prefix" example:"vgl_3kq9Xb2Z"`
+	Scopes     []string   `json:"scopes" example:"videos:read,videos:write"`
+	CreatedAt  *time.Time `json:"createdAt" example:"2022-04-15T12:59:52Z"`
+	LastUsedAt *time.Time `json:"lastUsedAt" example:"2022-04-15T12:59:52Z"`
+	RevokedAt  *time.Time `json:"revokedAt" example:"2022-04-15T12:59:52Z"`
+}
+
+func ApiKeyToApiKeyJson(apiKey *models.ApiKey) ApiKeyJson {
+	apiKeyJson := ApiKeyJson{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.Scopes,
+		CreatedAt:  apiKey.CreatedAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		RevokedAt:  apiKey.RevokedAt,
+	}
+
+	return apiKeyJson
+}
+
+// PlaylistJson DTO, with its videos only when they are requested
+
+type PlaylistJson struct {
+	ID          string              `json:"id" example:"aaaa-b56b-..."`
+	Title       string              `json:"title" example:"Onboarding"`
+	Description string              `json:"description" example:"Videos to watch the first week"`
+	CreatedAt   *time.Time          `json:"createdAt" example:"2022-04-15T12:59:52Z"`
+	UpdatedAt   *time.Time          `json:"updatedAt" example:"2022-04-15T12:59:52Z"`
+	Videos      *[]VideoJson        `json:"videos,omitempty"`
+	Links       map[string]LinkJson `json:"_links"`
+}
+
+func PlaylistToPlaylistJson(playlist *models.Playlist) PlaylistJson {
+	playlistJson := PlaylistJson{
+		ID:          playlist.ID,
+		Title:       playlist.Title,
+		Description: playlist.Description,
+		CreatedAt:   playlist.CreatedAt,
+		UpdatedAt:   playlist.UpdatedAt,
+		Links:       map[string]LinkJson{},
+	}
+
+	if playlist.Videos != nil {
+		videos := make([]VideoJson, 0, len(playlist.Videos))
+		for i := range playlist.Videos {
+			videos = append(videos, VideoToVideoJson(&playlist.Videos[i]))
+		}
+		playlistJson.Videos = &videos
+	}
+
+	path := "api/v1/playlists/" + playlist.ID
+	playlistJson.Links["self"] = LinkToLinkJson(models.CreateLink(path, "GET"))
+	playlistJson.Links["videos"] = LinkToLinkJson(models.CreateLink(path+"/videos", "POST"))
+	if playlist.CoverVideo != nil {
+		playlistJson.Links["cover"] = LinkToLinkJson(models.CreateLink("api/v1/videos/"+*playlist.CoverVideo+"/cover", "GET"))
+	}
+
+	return playlistJson
+}
+
+// WatchPositionJson DTO
+
+type WatchPositionJson struct {
+	VideoID   string              `json:"videoId" example:"aaaa-b56b-..."`
+	Position  float64             `json:"position" example:"754.2"`
+	WatchedAt *time.Time          `json:"watchedAt" example:"2022-04-15T12:59:52Z"`
+	Links     map[string]LinkJson `json:"_links"`
+}
+
+func WatchPositionToWatchPositionJson(position *models.WatchPosition) WatchPositionJson {
+	positionJson := WatchPositionJson{
+		VideoID:   position.VideoID,
+		Position:  position.Position,
+		WatchedAt: position.WatchedAt,
+		Links:     map[string]LinkJson{},
+	}
+
+	path := "api/v1/videos/" + position.VideoID
+	positionJson.Links["self"] = LinkToLinkJson(models.CreateLink(path+"/position", "GET"))
+	positionJson.Links["info"] = LinkToLinkJson(models.CreateLink(path+"/info", "GET"))
+
+	return positionJson
+}
+
+// VideoStatsJson DTO
+
+type VideoStatsJson struct {
+	VideoID           string              `json:"videoId" example:"aaaa-b56b-..."`
+	Views             int                 `json:"views" example:"42"`
+	UniqueViewers     int                 `json:"uniqueViewers" example:"12"`
+	WatchTime         float64             `json:"watchTime" example:"1534.5"`
+	AverageCompletion float64             `json:"averageCompletion" example:"0.75"`
+	Links             map[string]LinkJson `json:"_links"`
+}
+
+func VideoStatsToVideoStatsJson(stats *models.VideoStats) VideoStatsJson {
+	statsJson := VideoStatsJson{
+		VideoID:           stats.VideoID,
+		Views:             stats.Views,
+		UniqueViewers:     stats.UniqueViewers,
+		WatchTime:         stats.WatchTime,
+		AverageCompletion: stats.AverageCompletion,
+		Links:             map[string]LinkJson{},
+	}
+
+	path := "api/v1/videos/" + stats.VideoID
+	statsJson.Links["self"] = LinkToLinkJson(models.CreateLink(path+"/stats", "GET"))
+	statsJson.Links["info"] = LinkToLinkJson(models.CreateLink(path+"/info", "GET"))
+
+	return statsJson
+}
+
+// SubtitleJson DTO
+
+type SubtitleJson struct {
+	Language  string              `json:"language" example:"en"`
+	Label     string              `json:"label" example:"English"`
+	CreatedAt *time.Time          `json:"createdAt" example:"2022-04-15T12:59:52Z"`
+	Links     map[string]LinkJson `json:"_links"`
+}
+
+func SubtitleToSubtitleJson(subtitle *models.Subtitle) SubtitleJson {
+	subtitleJson := SubtitleJson{
+		Language:  subtitle.Language,
+		Label:     subtitle.Label,
+		CreatedAt: subtitle.CreatedAt,
+		Links:     map[string]LinkJson{},
+	}
+
+	path := "api/v1/videos/" + subtitle.VideoID
+	streamPath := path + "/streams/subtitles/" + subtitle.Language
+	subtitleJson.Links["vtt"] = LinkToLinkJson(models.CreateLink(streamPath+"/subtitles.vtt", "GET"))
+	subtitleJson.Links["playlist"] = LinkToLinkJson(models.CreateLink(streamPath+"/index.m3u8", "GET"))
+	subtitleJson.Links["delete"] = LinkToLinkJson(models.CreateLink(path+"/subtitles/"+subtitle.Language, "DELETE"))
+
+	return subtitleJson
+}
+
+// ChapterJson DTO
+
+type ChapterJson struct {
+	Start float64 `json:"start" example:"60.5"` // In seconds
+	Title string  `json:"title" example:"Introduction"`
+}
+
+func ChapterToChapterJson(chapter models.Chapter) ChapterJson {
+	return ChapterJson{Start: chapter.Start, Title: chapter.Title}
+}
+
+func ChapterJsonToChapter(chapterJson ChapterJson) models.Chapter {
+	return models.Chapter{Start: chapterJson.Start, Title: chapterJson.Title}
+}
+
 // LinkJson DTO
 
 type LinkJson struct {
@@ -37,10 +37,14 @@ func VideoProtobufToVideo(videoProto *contracts.Video) *models.Video {
 	}
 
 	video := models.Video{
-		ID:         videoProto.Id,
-		Status:     protoToModelStatus[videoProto.Status],
-		SourcePath: videoProto.Source,
-		CoverPath:  videoProto.CoverPath,
+		ID:             videoProto.Id,
+		Status:         protoToModelStatus[videoProto.Status],
+		SourcePath:     videoProto.Source,
+		CoverPath:      videoProto.CoverPath,
+		AudioLanguages: videoProto.AudioLanguages,
+	}
+	for _, chapter := range videoProto.Chapters {
+		video.Chapters = append(video.Chapters, models.Chapter{Start: chapter.Start, Title: chapter.Title})
 	}
 
 	return &video
@@ -53,10 +57,14 @@ func VideoToVideoProtobuf(video *models.Video) *contracts.Video {
 	}
 
 	videoData := &contracts.Video{
-		Id:        video.ID,
-		Status:    modelToProtoStatus[video.Status],
-		Source:    video.SourcePath,
-		CoverPath: video.CoverPath,
+		Id:             video.ID,
+		Status:         modelToProtoStatus[video.Status],
+		Source:         video.SourcePath,
+		CoverPath:      video.CoverPath,
+		AudioLanguages: video.AudioLanguages,
+	}
+	for _, chapter := range video.Chapters {
+		videoData.Chapters = append(videoData.Chapters, &contracts.Chapter{Start: chapter.Start, Title: chapter.Title})
 	}
 
 	return videoData
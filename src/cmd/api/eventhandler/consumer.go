@@ -17,7 +17,7 @@ import (
 	"github.com/Sogilis/Voogle/src/cmd/api/models"
 )
 
-func ConsumeEvents(cfg config.Config, amqpVideoStatusUpdate clients.AmqpClient, videosDAO *dao.VideosDAO) {
+func ConsumeEvents(cfg config.Config, amqpVideoStatusUpdate clients.AmqpClient, videosDAO *dao.VideosDAO, audioLanguagesDAO *dao.AudioLanguagesDAO, chaptersDAO *dao.ChaptersDAO) {
 	// amqpClient for encoded video (encoder->api)
 	amqpClientVideoEncode, err := clients.NewAmqpClient(cfg.RabbitmqUser, cfg.RabbitmqPwd, cfg.RabbitmqAddr)
 	if err != nil {
@@ -58,6 +58,10 @@ func ConsumeEvents(cfg config.Config, amqpVideoStatusUpdate clients.AmqpClient,
 				log.Errorf("Unable to update videos with status  %v: %v", videoDb.Status, err)
 			}
 			if video.Status == models.COMPLETE {
+				if err := audioLanguagesDAO.UpdateVideoAudioLanguages(context.Background(), video.ID, video.AudioLanguages); err != nil {
+					log.Errorf("Unable to update audio languages of video %v: %v", video.ID, err)
+				}
+				importChapters(chaptersDAO, video)
 				metrics.CounterVideoEncodeSuccess.Inc()
 			} else if video.Status == models.FAIL_ENCODE {
 				metrics.CounterVideoEncodeFail.Inc()
@@ -75,6 +79,32 @@ func ConsumeEvents(cfg config.Config, amqpVideoStatusUpdate clients.AmqpClient,
 	}
 }
 
+// importChapters sets the chapter markers found in the source of the video, unless chapters were already defined
+func importChapters(chaptersDAO *dao.ChaptersDAO, video *models.Video) {
+	if len(video.Chapters) == 0 {
+		return
+	}
+
+	existing, err := chaptersDAO.GetVideoChapters(context.Background(), video.ID)
+	if err != nil {
+		log.Errorf("Failed to get chapters of video %v from database : %v", video.ID, err)
+		return
+	}
+	if len(existing) > 0 {
+		log.Debug("Chapters of video ", video.ID, " already defined, source chapters ignored")
+		return
+	}
+
+	chapters, err := models.NormalizeChapters(video.Chapters)
+	if err != nil {
+		log.Errorf("Invalid source chapters of video %v : %v", video.ID, err)
+		return
+	}
+	if err := chaptersDAO.UpdateVideoChapters(context.Background(), video.ID, chapters); err != nil {
+		log.Errorf("Unable to import chapters of video %v: %v", video.ID, err)
+	}
+}
+
 func publishStatus(amqpVideoStatus clients.AmqpClient, video *models.Video) {
 	msg, err := proto.Marshal(protobuf.VideoToVideoProtobuf(video))
 	if err != nil {
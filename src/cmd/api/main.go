@@ -14,9 +14,11 @@ import (
 	"github.com/Sogilis/Voogle/src/pkg/clients"
 	"github.com/Sogilis/Voogle/src/pkg/events"
 
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
 	"github.com/Sogilis/Voogle/src/cmd/api/config"
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
 	"github.com/Sogilis/Voogle/src/cmd/api/eventhandler"
+	"github.com/Sogilis/Voogle/src/cmd/api/retention"
 	"github.com/Sogilis/Voogle/src/cmd/api/router"
 )
 
@@ -36,11 +38,34 @@ func main() {
 		log.SetLevel(log.DebugLevel)
 	}
 
+	keys, err := auth.ParseSigningKeys(cfg.JWTKeys)
+	if err != nil {
+		log.Fatal("Invalid JWT_KEYS : ", err)
+	}
+	if _, ok := keys[cfg.JWTKeyID]; len(keys) > 0 && !ok {
+		log.Fatal("JWT_KEY_ID must be the ID of one of the JWT_KEYS")
+	}
+	if len(keys) == 0 {
+		log.Info("No JWT_KEYS, only basic auth is available")
+	}
+	if cfg.StreamTokenSecret == "" {
+		log.Info("No STREAM_TOKEN_SECRET, streams require credentials")
+	}
+
 	// Create routers
 	routerClients, routerDAOs := createRouters(cfg)
 	defer routerDAOs.Db.Close()
 	defer routerDAOs.VideosDAO.Close()
 	defer routerDAOs.UploadsDAO.Close()
+	defer routerDAOs.TagsDAO.Close()
+	defer routerDAOs.UsersDAO.Close()
+	defer routerDAOs.ApiKeysDAO.Close()
+	defer routerDAOs.PlaylistsDAO.Close()
+	defer routerDAOs.PlaybackDAO.Close()
+	defer routerDAOs.WatchHistoryDAO.Close()
+	defer routerDAOs.SubtitlesDAO.Close()
+	defer routerDAOs.AudioLanguagesDAO.Close()
+	defer routerDAOs.ChaptersDAO.Close()
 
 	// Start service discovery
 	go func() {
@@ -55,10 +80,15 @@ func main() {
 		}
 	}()
 
+	handler, err := router.NewRouter(cfg, routerClients, routerDAOs)
+	if err != nil {
+		log.Fatal("Cannot create router : ", err)
+	}
+
 	// Start API server
 	log.Info("Starting server on port : ", cfg.Port)
 	srv := &http.Server{
-		Handler: router.NewRouter(cfg, routerClients, routerDAOs),
+		Handler: handler,
 		Addr:    fmt.Sprintf("0.0.0.0:%v", cfg.Port),
 	}
 	go func() {
@@ -68,7 +98,19 @@ func main() {
 	}()
 
 	// Start encoder event listener
-	go eventhandler.ConsumeEvents(cfg, routerClients.AmqpVideoStatusUpdate, &routerDAOs.VideosDAO)
+	go eventhandler.ConsumeEvents(cfg, routerClients.AmqpVideoStatusUpdate, &routerDAOs.VideosDAO, &routerDAOs.AudioLanguagesDAO, &routerDAOs.ChaptersDAO)
+
+	// Start archived videos purge
+	ctxRetention, cancelRetention := context.WithCancel(context.Background())
+	defer cancelRetention()
+	purger := retention.Purger{
+		S3Client:     routerClients.S3Client,
+		VideosDAO:    &routerDAOs.VideosDAO,
+		UploadsDAO:   &routerDAOs.UploadsDAO,
+		PlaylistsDAO: &routerDAOs.PlaylistsDAO,
+		Period:       cfg.RetentionPeriod,
+	}
+	go purger.Run(ctxRetention, cfg.RetentionInterval)
 
 	// Wait for SIGINT.
 	sig := make(chan os.Signal, 1)
@@ -76,6 +118,7 @@ func main() {
 	<-sig
 
 	routerClients.ServiceDiscovery.Stop()
+	cancelRetention()
 
 	// Graceful shutdown for api server
 	ctxServer, cancelServer := context.WithTimeout(context.Background(), GORILLA_MUX_SHUTDOWN_TIMEOUT)
@@ -117,6 +160,17 @@ func createRouters(cfg config.Config) (*router.Clients, *router.DAOs) {
 		log.Fatal("Failed to open connection with database: ", err)
 	}
 
+	// Users first, videos reference their owner
+	usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+	if err != nil {
+		log.Fatal("Failed to create users DAO : ", err)
+	}
+
+	apiKeysDAO, err := dao.CreateApiKeysDAO(context.Background(), db)
+	if err != nil {
+		log.Fatal("Failed to create api keys DAO : ", err)
+	}
+
 	videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
 	if err != nil {
 		log.Fatal("Failed to create videos DAO : ", err)
@@ -127,6 +181,41 @@ func createRouters(cfg config.Config) (*router.Clients, *router.DAOs) {
 		log.Fatal("Failed to create uploads DAO : ", err)
 	}
 
+	tagsDAO, err := dao.CreateTagsDAO(context.Background(), db)
+	if err != nil {
+		log.Fatal("Failed to create tags DAO : ", err)
+	}
+
+	playlistsDAO, err := dao.CreatePlaylistsDAO(context.Background(), db)
+	if err != nil {
+		log.Fatal("Failed to create playlists DAO : ", err)
+	}
+
+	playbackDAO, err := dao.CreatePlaybackDAO(context.Background(), db)
+	if err != nil {
+		log.Fatal("Failed to create playback DAO : ", err)
+	}
+
+	watchHistoryDAO, err := dao.CreateWatchHistoryDAO(context.Background(), db)
+	if err != nil {
+		log.Fatal("Failed to create watch history DAO : ", err)
+	}
+
+	subtitlesDAO, err := dao.CreateSubtitlesDAO(context.Background(), db)
+	if err != nil {
+		log.Fatal("Failed to create subtitles DAO : ", err)
+	}
+
+	audioLanguagesDAO, err := dao.CreateAudioLanguagesDAO(context.Background(), db)
+	if err != nil {
+		log.Fatal("Failed to create audio languages DAO : ", err)
+	}
+
+	chaptersDAO, err := dao.CreateChaptersDAO(context.Background(), db)
+	if err != nil {
+		log.Fatal("Failed to create chapters DAO : ", err)
+	}
+
 	discoveryClient, err := clients.NewServiceDiscovery(cfg.ConsulHost)
 	if err != nil {
 		log.Fatal("Cannot create consul client : ", err)
@@ -138,12 +227,22 @@ func createRouters(cfg config.Config) (*router.Clients, *router.DAOs) {
 		AmqpVideoStatusUpdate: amqpVideoStatusUpdate,
 		ServiceDiscovery:      discoveryClient,
 		UUIDGen:               clients.NewUuidGenerator(),
+		ImageConverter:        clients.NewImageConverter(),
 	}
 
 	routerDAOs := &router.DAOs{
-		Db:         db,
-		VideosDAO:  *videosDAO,
-		UploadsDAO: *uploadsDAO,
+		Db:                db,
+		VideosDAO:         *videosDAO,
+		UploadsDAO:        *uploadsDAO,
+		TagsDAO:           *tagsDAO,
+		UsersDAO:          *usersDAO,
+		ApiKeysDAO:        *apiKeysDAO,
+		PlaylistsDAO:      *playlistsDAO,
+		PlaybackDAO:       *playbackDAO,
+		WatchHistoryDAO:   *watchHistoryDAO,
+		SubtitlesDAO:      *subtitlesDAO,
+		AudioLanguagesDAO: *audioLanguagesDAO,
+		ChaptersDAO:       *chaptersDAO,
 	}
 
 	return routerClients, routerDAOs
@@ -95,6 +95,28 @@ var (
 	})
 )
 
+var (
+	CounterVideoPurged = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "api_video_retention_purged",
+		Help: "The total number of archived videos purged by the retention policy",
+	})
+)
+
+var (
+	CounterVideoPurgedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "api_video_retention_purged_bytes",
+		Help: "The total size in bytes of the archived videos purged by the retention policy",
+	})
+)
+
+var RateLimitRejections = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "api_rate_limit_rejections_total",
+		Help: "The total number of requests rejected by the rate limits",
+	},
+	[]string{"class", "reason"},
+)
+
 func StoreTranformationTime(start time.Time, transformers []string) {
 	elapsed := time.Since(start)
 	if len(transformers) == 1 {
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// Scopes of the API keys
+const (
+	ScopeVideosRead   = "videos:read"
+	ScopeVideosWrite  = "videos:write"
+	ScopeVideosDelete = "videos:delete"
+)
+
+var Scopes = []string{ScopeVideosRead, ScopeVideosWrite, ScopeVideosDelete}
+
+type ApiKey struct {
+	ID         string
+	Name       string
+	Prefix     string // First characters of the key, to recognize it
+	KeyHash    string // SHA-256 hash, the key itself is never stored
+	Scopes     []string
+	CreatedAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// HasScope returns true if the key has been granted this scope
+func (k ApiKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidScope returns true if the scope exists
+func IsValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
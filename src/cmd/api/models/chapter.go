@@ -0,0 +1,51 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of the chapters of a video, matching the database columns
+const (
+	MaxChapterTitleLength = 128
+	MaxChapters           = 100
+)
+
+// Chapter of a video, from its start (in seconds) to the start of the next chapter, or to the end of the video
+type Chapter struct {
+	Start float64
+	Title string
+}
+
+// NormalizeChapters trims the titles, rounds the starts to the millisecond and sorts the chapters on their start.
+// Two chapters cannot start at the same time.
+func NormalizeChapters(chapters []Chapter) ([]Chapter, error) {
+	if len(chapters) > MaxChapters {
+		return nil, fmt.Errorf("a video can have at most %v chapters", MaxChapters)
+	}
+
+	normalized := make([]Chapter, 0, len(chapters))
+	for _, chapter := range chapters {
+		title := strings.TrimSpace(chapter.Title)
+		if title == "" || utf8.RuneCountInString(title) > MaxChapterTitleLength || strings.ContainsAny(title, "\r\n") {
+			return nil, fmt.Errorf("chapter titles must have between 1 and %v characters on a single line", MaxChapterTitleLength)
+		}
+		if math.IsNaN(chapter.Start) || math.IsInf(chapter.Start, 0) || chapter.Start < 0 {
+			return nil, fmt.Errorf("chapter %v must start at a positive time", title)
+		}
+
+		normalized = append(normalized, Chapter{Start: math.Round(chapter.Start*1000) / 1000, Title: title})
+	}
+
+	sort.SliceStable(normalized, func(i, j int) bool { return normalized[i].Start < normalized[j].Start })
+	for i := 1; i < len(normalized); i++ {
+		if normalized[i].Start == normalized[i-1].Start {
+			return nil, fmt.Errorf("chapters %v and %v start at the same time", normalized[i-1].Title, normalized[i].Title)
+		}
+	}
+
+	return normalized, nil
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type PaginationAttribute int
 
 const (
@@ -7,6 +9,7 @@ const (
 	UPLOADEDAT
 	CREATEDAT
 	UPDATEDAT
+	VIEWS // Only for the pages of the videos list
 )
 
 type Pagination struct {
@@ -15,3 +18,45 @@ type Pagination struct {
 	Ascending bool
 	Attribute PaginationAttribute
 }
+
+// NoUploadDate sorts the videos not uploaded yet before the others
+var NoUploadDate = time.Date(1000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// VideoCursor is the position of a video in a list sorted on an attribute.
+// Videos with the same attribute value are sorted on their ID, or on their title then their ID for views.
+type VideoCursor struct {
+	Value interface{}
+	Title string // Only for views
+	ID    string
+}
+
+// NewVideoCursor returns the position of the video in a list sorted on the attribute
+func NewVideoCursor(video Video, attribute PaginationAttribute) VideoCursor {
+	cursor := VideoCursor{ID: video.ID}
+	switch attribute {
+	case TITLE:
+		cursor.Value = video.Title
+	case UPLOADEDAT:
+		cursor.Value = NoUploadDate
+		if video.UploadedAt != nil {
+			cursor.Value = *video.UploadedAt
+		}
+	case CREATEDAT:
+		cursor.Value = timeOrZero(video.CreatedAt)
+	case UPDATEDAT:
+		cursor.Value = timeOrZero(video.UpdatedAt)
+	}
+	return cursor
+}
+
+// NewVideoViewsCursor returns the position of the video in a list sorted on its number of views
+func NewVideoViewsCursor(video VideoViews) VideoCursor {
+	return VideoCursor{Value: video.Views, Title: video.Video.Title, ID: video.Video.ID}
+}
+
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
@@ -0,0 +1,16 @@
+package models
+
+// VideoStats aggregates the playback sessions of a video having watched at least one segment
+type VideoStats struct {
+	VideoID           string
+	Views             int
+	UniqueViewers     int
+	WatchTime         float64 // In seconds, segments being assumed of the same duration
+	AverageCompletion float64 // Between 0 and 1
+}
+
+// VideoViews is a video with its number of views
+type VideoViews struct {
+	Video Video
+	Views int
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// Playlist is an ordered set of videos, like a training course
+type Playlist struct {
+	ID          string
+	Title       string
+	Description string
+	OwnerID     *string // User who created the playlist, nil for the shared account
+	CreatedAt   *time.Time
+	UpdatedAt   *time.Time
+	CoverVideo  *string // First video of the playlist, its cover is the one of the playlist
+	Videos      []Video // Ordered by position, only when requested
+}
+
+// PlaylistPosition is the position of a video in a playlist, from 0
+type PlaylistPosition struct {
+	PlaylistID string
+	Position   int
+}
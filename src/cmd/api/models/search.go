@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// VideoSearch is a full-text search over the title, the description and the tags of the videos.
+// Nil filters are not applied.
+type VideoSearch struct {
+	Query          string
+	Status         *VideoStatus
+	UploadedAfter  *time.Time
+	UploadedBefore *time.Time
+	OwnerID        *string
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// Subtitle is a subtitles track of a video, in one language. Its WebVTT file and its segments are stored
+// on S3 under the video prefix, in subtitles/{language}/.
+type Subtitle struct {
+	VideoID   string
+	Language  string // BCP 47 language tag, like "en" or "pt-BR"
+	Label     string // Name of the track shown by the players
+	CreatedAt *time.Time
+}
@@ -20,4 +20,8 @@ type Upload struct {
 	CreatedAt  *time.Time
 	UpdatedAt  *time.Time
 	Progress   int
+	Length     int64
+	Offset     int64
+	Parts      int32
+	S3UploadID string
 }
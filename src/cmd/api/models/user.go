@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+type User struct {
+	ID           string
+	Username     string
+	PasswordHash string // bcrypt hash, the password itself is never stored
+	CreatedAt    *time.Time
+	UpdatedAt    *time.Time
+}
@@ -71,12 +71,19 @@ func StringToVideoStatus(v string) (VideoStatus, error) {
 }
 
 type Video struct {
-	ID         string
-	Title      string
-	Status     VideoStatus
-	UploadedAt *time.Time
-	CreatedAt  *time.Time
-	UpdatedAt  *time.Time
-	SourcePath string
-	CoverPath  string
+	ID             string
+	Title          string
+	Status         VideoStatus
+	UploadedAt     *time.Time
+	CreatedAt      *time.Time
+	UpdatedAt      *time.Time
+	SourcePath     string
+	CoverPath      string
+	SourceHash     *string // SHA-256 of the source, nil until the source is uploaded
+	Description    string
+	OwnerID        *string // User who uploaded the video, nil when uploaded with the shared account
+	ArchivedAt     *time.Time
+	Tags           []string  // Stored apart from the video, not loaded with it
+	AudioLanguages []string  // Default language first, stored apart from the video, not loaded with it
+	Chapters       []Chapter // Stored apart from the video, not loaded with it
 }
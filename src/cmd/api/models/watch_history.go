@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// WatchPosition is the last position reported by a user while watching a video, to resume it
+type WatchPosition struct {
+	UserID    string
+	VideoID   string
+	Position  float64 // In seconds from the start of the video
+	WatchedAt *time.Time
+}
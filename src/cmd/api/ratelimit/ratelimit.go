@@ -0,0 +1,203 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/metrics"
+)
+
+// Class of a request, each class has its own budget
+type Class string
+
+const (
+	ClassRead      Class = "read"
+	ClassUpload    Class = "upload"
+	ClassTransform Class = "transform"
+)
+
+// Buckets full since sweepInterval are forgotten, to not keep every client seen
+const sweepInterval = time.Minute
+
+// Limit of a token bucket : Rate tokens are added every second, up to Burst. A zero rate disables the limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter holds one token bucket per client
+type Limiter struct {
+	limit     Limit
+	now       func() time.Time
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewLimiter(limit Limit) *Limiter {
+	return NewLimiterWithClock(limit, time.Now)
+}
+
+// NewLimiterWithClock returns a limiter reading the time from now
+func NewLimiterWithClock(limit Limit, now func() time.Time) *Limiter {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &Limiter{limit: limit, now: now, buckets: map[string]*bucket{}, lastSweep: now()}
+}
+
+// Allow takes a token from the bucket of the client, or returns how long to wait for the next one
+func (l *Limiter) Allow(client string) (bool, time.Duration) {
+	if l.limit.Rate <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: float64(l.limit.Burst), last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = math.Min(float64(l.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*l.limit.Rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - b.tokens) / l.limit.Rate * float64(time.Second))
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.limit.Rate >= float64(l.limit.Burst) {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// ConcurrencyLimiter limits the number of requests of a client handled at the same time
+type ConcurrencyLimiter struct {
+	max      int
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+// NewConcurrencyLimiter returns a limiter of max requests per client, 0 disables the limit
+func NewConcurrencyLimiter(max int) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{max: max, inFlight: map[string]int{}}
+}
+
+// Acquire returns false if the client already has the maximum of requests in flight, else it must call Release
+func (c *ConcurrencyLimiter) Acquire(client string) bool {
+	if c.max <= 0 {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.inFlight[client] >= c.max {
+		return false
+	}
+	c.inFlight[client]++
+	return true
+}
+
+func (c *ConcurrencyLimiter) Release(client string) {
+	if c.max <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inFlight[client]--
+	if c.inFlight[client] <= 0 {
+		delete(c.inFlight, client)
+	}
+}
+
+// RateLimiter rejects the API requests of the clients over their budget of their class with a 429
+type RateLimiter struct {
+	Limiters   map[Class]*Limiter
+	Transforms *ConcurrencyLimiter
+	ClientID   func(r *http.Request) string
+}
+
+func (rl RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class, limited := Classify(r)
+		if !limited {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		client := rl.ClientID(r)
+		if limiter, ok := rl.Limiters[class]; ok {
+			if allowed, retryAfter := limiter.Allow(client); !allowed {
+				reject(w, class, "rate", client, retryAfter)
+				return
+			}
+		}
+
+		if class == ClassTransform && rl.Transforms != nil {
+			if !rl.Transforms.Acquire(client) {
+				reject(w, class, "concurrency", client, time.Second)
+				return
+			}
+			defer rl.Transforms.Release(client)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Classify returns the class of an API request, false for the other requests which are not limited
+func Classify(r *http.Request) (Class, bool) {
+	if !strings.HasPrefix(r.URL.Path, "/api/") {
+		return "", false
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && strings.Contains(r.URL.Path, "/videos/upload") {
+		return ClassUpload, true
+	}
+	if strings.Contains(r.URL.Path, "/streams/") && r.URL.Query().Has("filter") {
+		return ClassTransform, true
+	}
+	return ClassRead, true
+}
+
+func reject(w http.ResponseWriter, class Class, reason, client string, retryAfter time.Duration) {
+	log.Debug("Too many ", class, " requests of ", client, " (", reason, ")")
+	metrics.RateLimitRejections.WithLabelValues(string(class), reason).Inc()
+
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+}
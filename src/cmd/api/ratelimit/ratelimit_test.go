@@ -0,0 +1,158 @@
+package ratelimit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/ratelimit"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := ratelimit.NewLimiterWithClock(ratelimit.Limit{Rate: 2, Burst: 3}, func() time.Time { return now })
+
+	// The burst is available at once
+	for i := 0; i < 3; i++ {
+		allowed, _ := limiter.Allow("client")
+		require.True(t, allowed)
+	}
+	allowed, retryAfter := limiter.Allow("client")
+	require.False(t, allowed)
+	require.Equal(t, 500*time.Millisecond, retryAfter)
+
+	// Other clients have their own bucket
+	allowed, _ = limiter.Allow("other")
+	require.True(t, allowed)
+
+	// Tokens are added over time, up to the burst
+	now = now.Add(500 * time.Millisecond)
+	allowed, _ = limiter.Allow("client")
+	require.True(t, allowed)
+	allowed, _ = limiter.Allow("client")
+	require.False(t, allowed)
+
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		allowed, _ := limiter.Allow("client")
+		require.True(t, allowed)
+	}
+	allowed, _ = limiter.Allow("client")
+	require.False(t, allowed)
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.Limit{})
+	for i := 0; i < 100; i++ {
+		allowed, _ := limiter.Allow("client")
+		require.True(t, allowed)
+	}
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	limiter := ratelimit.NewConcurrencyLimiter(2)
+	require.True(t, limiter.Acquire("client"))
+	require.True(t, limiter.Acquire("client"))
+	require.False(t, limiter.Acquire("client"))
+	require.True(t, limiter.Acquire("other"))
+
+	limiter.Release("client")
+	require.True(t, limiter.Acquire("client"))
+}
+
+func TestMiddleware(t *testing.T) { //nolint:cyclop
+	cases := []struct {
+		name             string
+		giveMethod       string
+		giveRequests     []string
+		expectedHTTPCode int
+	}{
+		{
+			name:             "Reads over budget",
+			giveMethod:       "GET",
+			giveRequests:     []string{"/api/v1/videos", "/api/v1/videos", "/api/v1/videos"},
+			expectedHTTPCode: 429,
+		},
+		{
+			name:             "Transformations over budget",
+			giveMethod:       "GET",
+			giveRequests:     []string{"/api/v1/videos/1/streams/v0/segment0.ts?filter=gray", "/api/v1/videos/1/streams/v0/segment1.ts?filter=gray"},
+			expectedHTTPCode: 429,
+		},
+		{
+			name:             "Transformations and reads have separate budgets",
+			giveMethod:       "GET",
+			giveRequests:     []string{"/api/v1/videos/1/streams/v0/segment0.ts?filter=gray", "/api/v1/videos/1/streams/v0/segment0.ts"},
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "Uploads over budget",
+			giveMethod:       "POST",
+			giveRequests:     []string{"/api/v1/videos/upload", "/api/v1/videos/uploads"},
+			expectedHTTPCode: 429,
+		},
+		{
+			name:             "Other routes are not limited",
+			giveMethod:       "GET",
+			giveRequests:     []string{"/metrics", "/metrics", "/metrics"},
+			expectedHTTPCode: 200,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			rateLimiter := ratelimit.RateLimiter{
+				Limiters: map[ratelimit.Class]*ratelimit.Limiter{
+					ratelimit.ClassRead:      ratelimit.NewLimiter(ratelimit.Limit{Rate: 1, Burst: 2}),
+					ratelimit.ClassUpload:    ratelimit.NewLimiter(ratelimit.Limit{Rate: 0.1, Burst: 1}),
+					ratelimit.ClassTransform: ratelimit.NewLimiter(ratelimit.Limit{Rate: 0.1, Burst: 1}),
+				},
+				ClientID: func(r *http.Request) string { return "client" },
+			}
+			handler := rateLimiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			var w *httptest.ResponseRecorder
+			for _, request := range tt.giveRequests {
+				w = httptest.NewRecorder()
+				handler.ServeHTTP(w, httptest.NewRequest(tt.giveMethod, request, nil))
+			}
+
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			if tt.expectedHTTPCode == 429 {
+				require.NotEmpty(t, w.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
+
+func TestMiddlewareConcurrency(t *testing.T) {
+	rateLimiter := ratelimit.RateLimiter{
+		Limiters:   map[ratelimit.Class]*ratelimit.Limiter{},
+		Transforms: ratelimit.NewConcurrencyLimiter(1),
+		ClientID:   func(r *http.Request) string { return "client" },
+	}
+
+	// The second transformation is requested while the first one is in progress
+	var nested *httptest.ResponseRecorder
+	var handler http.Handler
+	handler = rateLimiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if nested == nil {
+			nested = httptest.NewRecorder()
+			handler.ServeHTTP(nested, httptest.NewRequest("GET", "/api/v1/videos/1/streams/v0/segment1.ts?filter=gray", nil))
+		}
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/videos/1/streams/v0/segment0.ts?filter=gray", nil))
+	require.Equal(t, 200, w.Code)
+	require.Equal(t, 429, nested.Code)
+	require.Equal(t, "1", nested.Header().Get("Retry-After"))
+
+	// Released once handled
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/videos/1/streams/v0/segment0.ts?filter=gray", nil))
+	require.Equal(t, 200, w.Code)
+}
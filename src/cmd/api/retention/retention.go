@@ -0,0 +1,117 @@
+package retention
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/metrics"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+// Purger deletes the videos archived for longer than the retention period, counted from their archived_at date.
+type Purger struct {
+	S3Client     clients.IS3Client
+	VideosDAO    *dao.VideosDAO
+	UploadsDAO   *dao.UploadsDAO
+	PlaylistsDAO *dao.PlaylistsDAO
+	Period       time.Duration
+}
+
+// ExpiredVideo is an archived video to purge, with the size of its files on S3
+type ExpiredVideo struct {
+	Video models.Video
+	Size  int64
+}
+
+// Enabled returns false when no retention period is set : archived videos are kept forever
+func (p Purger) Enabled() bool {
+	return p.Period > 0
+}
+
+// ExpiredVideos returns the videos that would be purged now, the oldest archive first
+func (p Purger) ExpiredVideos(ctx context.Context) ([]ExpiredVideo, error) {
+	if !p.Enabled() {
+		return nil, nil
+	}
+
+	videos, err := p.VideosDAO.GetVideosArchivedBefore(ctx, time.Now().Add(-p.Period))
+	if err != nil {
+		log.Error("Cannot get expired archived videos : ", err)
+		return nil, err
+	}
+
+	expired := make([]ExpiredVideo, 0, len(videos))
+	for _, video := range videos {
+		size, err := p.S3Client.ObjectsSize(ctx, video.ID+"/")
+		if err != nil {
+			// The video can be purged anyway, only its size is unknown
+			log.Error("Cannot get size of video "+video.ID+" on S3 : ", err)
+			size = 0
+		}
+		expired = append(expired, ExpiredVideo{Video: video, Size: size})
+	}
+
+	return expired, nil
+}
+
+// Purge deletes the expired videos from S3 then from the database, and returns the number of purged videos.
+// A video that cannot be deleted is skipped : it stays archived, so that the next purge tries again, its files
+// already removed from S3 being removed again without error.
+func (p Purger) Purge(ctx context.Context) (int, error) {
+	expired, err := p.ExpiredVideos(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, e := range expired {
+		if err := p.S3Client.RemoveObjectsWithPrefix(ctx, e.Video.ID+"/"); err != nil {
+			log.Error("Cannot remove expired video "+e.Video.ID+" from S3 : ", err)
+			continue
+		}
+
+		if err := dao.DeleteVideoAndUploads(ctx, p.VideosDAO, p.UploadsDAO, p.PlaylistsDAO, e.Video.ID); err != nil {
+			log.Error("Cannot purge video "+e.Video.ID+" : ", err)
+			continue
+		}
+
+		log.Info("Video ", e.Video.ID, " purged, archived since ", e.Video.ArchivedAt)
+		metrics.CounterVideoPurged.Inc()
+		metrics.CounterVideoPurgedBytes.Add(float64(e.Size))
+		purged++
+	}
+
+	return purged, nil
+}
+
+// Run purges the expired videos at each interval, until the context is done
+func (p Purger) Run(ctx context.Context, interval time.Duration) {
+	if !p.Enabled() {
+		log.Info("Retention policy disabled, archived videos are kept")
+		return
+	}
+
+	log.Info("Retention policy : archived videos are purged after ", p.Period)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := p.Purge(ctx)
+		if err != nil {
+			log.Error("Retention purge failed : ", err)
+		} else if purged > 0 {
+			log.Info(purged, " archived videos purged")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package retention_test
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/retention"
+)
+
+func TestPurge(t *testing.T) { //nolint:cyclop
+	video1ID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	video2ID := "b7e6a0c1-5bc6-4a50-9176-ab0371aa65fe"
+	archivedAt := time.Now().Add(-1000 * time.Hour)
+
+	cases := []struct {
+		name              string
+		givePeriod        time.Duration
+		giveVideos        []string
+		giveDatabaseErr   bool
+		giveDeleteErr     string
+		giveRemoveErr     string
+		expectedPurged    int
+		expectedRemoved   []string
+		expectedQueryDone bool
+	}{
+		{
+			name:              "Purge expired videos",
+			givePeriod:        720 * time.Hour,
+			giveVideos:        []string{video1ID, video2ID},
+			expectedPurged:    2,
+			expectedRemoved:   []string{video1ID + "/", video2ID + "/"},
+			expectedQueryDone: true,
+		},
+		{
+			name:              "Purge nothing",
+			givePeriod:        720 * time.Hour,
+			expectedQueryDone: true,
+		},
+		{
+			name:       "Purge nothing with retention disabled",
+			givePeriod: 0,
+		},
+		{
+			name:              "Purge skips video with database error",
+			givePeriod:        720 * time.Hour,
+			giveVideos:        []string{video1ID, video2ID},
+			giveDeleteErr:     video1ID,
+			expectedPurged:    1,
+			expectedRemoved:   []string{video1ID + "/", video2ID + "/"},
+			expectedQueryDone: true,
+		},
+		{
+			name:              "Purge skips video with S3 error",
+			givePeriod:        720 * time.Hour,
+			giveVideos:        []string{video1ID, video2ID},
+			giveRemoveErr:     video2ID,
+			expectedPurged:    1,
+			expectedRemoved:   []string{video1ID + "/", video2ID + "/"},
+			expectedQueryDone: true,
+		},
+		{
+			name:              "Purge fails with database error",
+			givePeriod:        720 * time.Hour,
+			giveDatabaseErr:   true,
+			expectedQueryDone: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectUploadsDAOCreation(mock)
+			dao_test.ExpectPlaylistsDAOCreation(mock)
+
+			if tt.expectedQueryDone {
+				archivedBeforeQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideosArchivedBefore])
+				deleteUpload := regexp.QuoteMeta(dao.UploadsRequests[dao.DeleteUpload])
+				deleteVideo := regexp.QuoteMeta(dao.VideosRequests[dao.DeleteVideo])
+				getPlaylistPositions := regexp.QuoteMeta(dao.PlaylistsRequests[dao.GetVideoPlaylistPositions])
+				removeFromPlaylists := regexp.QuoteMeta(dao.PlaylistsRequests[dao.RemoveVideoFromPlaylists])
+
+				if tt.giveDatabaseErr {
+					mock.ExpectQuery(archivedBeforeQuery).WillReturnError(fmt.Errorf("database internal error"))
+				} else {
+					videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "source_hash", "description", "owner_id", "archived_at"}
+					videosRows := sqlmock.NewRows(videosColumns)
+					for _, id := range tt.giveVideos {
+						videosRows.AddRow(id, "title-"+id, int(models.ARCHIVE), archivedAt, archivedAt, time.Now(), id+"/source.mp4", id+"/cover.jpeg", nil, "", nil, archivedAt)
+					}
+					mock.ExpectQuery(archivedBeforeQuery).WithArgs(int(models.ARCHIVE), sqlmock.AnyArg()).WillReturnRows(videosRows)
+
+					for _, id := range tt.giveVideos {
+						if id == tt.giveRemoveErr {
+							// The video is kept in the database, as long as its files are on S3
+							continue
+						}
+						mock.ExpectBegin()
+						mock.ExpectExec(deleteUpload).WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 1))
+						mock.ExpectQuery(getPlaylistPositions).WithArgs(id).WillReturnRows(sqlmock.NewRows([]string{"playlist_id", "position"}))
+						mock.ExpectExec(removeFromPlaylists).WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 0))
+						if id == tt.giveDeleteErr {
+							mock.ExpectExec(deleteVideo).WithArgs(id).WillReturnError(fmt.Errorf("database internal error"))
+							mock.ExpectRollback()
+						} else {
+							mock.ExpectExec(deleteVideo).WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 1))
+							mock.ExpectCommit()
+						}
+					}
+				}
+			}
+
+			var removed []string
+			s3Client := clients.NewS3ClientDummy(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil,
+				func(path string) (int64, error) { return 1024, nil }, nil,
+				func(prefix string) error {
+					removed = append(removed, prefix)
+					if prefix == tt.giveRemoveErr+"/" {
+						return fmt.Errorf("cannot remove objects")
+					}
+					return nil
+				})
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			uploadsDAO, err := dao.CreateUploadsDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			playlistsDAO, err := dao.CreatePlaylistsDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			purger := retention.Purger{
+				S3Client:     s3Client,
+				VideosDAO:    videosDAO,
+				UploadsDAO:   uploadsDAO,
+				PlaylistsDAO: playlistsDAO,
+				Period:       tt.givePeriod,
+			}
+
+			purged, err := purger.Purge(context.Background())
+			if tt.giveDatabaseErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.expectedPurged, purged)
+			require.Equal(t, tt.expectedRemoved, removed)
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/goji/httpauth"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
@@ -18,11 +17,15 @@ import (
 
 	"github.com/Sogilis/Voogle/src/pkg/clients"
 
+	"github.com/Sogilis/Voogle/src/cmd/api/auth"
 	"github.com/Sogilis/Voogle/src/cmd/api/config"
 	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
 	_ "github.com/Sogilis/Voogle/src/cmd/api/docs"
 	"github.com/Sogilis/Voogle/src/cmd/api/metrics"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/ratelimit"
+	"github.com/Sogilis/Voogle/src/cmd/api/retention"
 )
 
 type Clients struct {
@@ -31,11 +34,21 @@ type Clients struct {
 	AmqpVideoStatusUpdate clients.AmqpClient
 	ServiceDiscovery      clients.ServiceDiscovery
 	UUIDGen               clients.IUUIDGenerator
+	ImageConverter        clients.IImageConverter
 }
 type DAOs struct {
-	Db         *sql.DB
-	VideosDAO  dao.VideosDAO
-	UploadsDAO dao.UploadsDAO
+	Db                *sql.DB
+	VideosDAO         dao.VideosDAO
+	UploadsDAO        dao.UploadsDAO
+	TagsDAO           dao.TagsDAO
+	UsersDAO          dao.UsersDAO
+	ApiKeysDAO        dao.ApiKeysDAO
+	PlaylistsDAO      dao.PlaylistsDAO
+	PlaybackDAO       dao.PlaybackDAO
+	WatchHistoryDAO   dao.WatchHistoryDAO
+	SubtitlesDAO      dao.SubtitlesDAO
+	AudioLanguagesDAO dao.AudioLanguagesDAO
+	ChaptersDAO       dao.ChaptersDAO
 }
 
 type responseWriter struct {
@@ -53,42 +66,129 @@ type responseWriter struct {
 // @license.url LICENSE.txt
 // @host localhost:4444
 // @BasePath /
-func NewRouter(config config.Config, clients *Clients, DAOs *DAOs) http.Handler {
+func NewRouter(config config.Config, clients *Clients, DAOs *DAOs) (http.Handler, error) {
+	keys, err := auth.ParseSigningKeys(config.JWTKeys)
+	if err != nil {
+		return nil, err
+	}
+	authenticator := auth.Authenticator{
+		UserAuth:   config.UserAuth,
+		PwdAuth:    config.PwdAuth,
+		UsersDAO:   &DAOs.UsersDAO,
+		ApiKeysDAO: &DAOs.ApiKeysDAO,
+		Tokens: auth.TokenManager{
+			Keys:              keys,
+			SigningKeyID:      config.JWTKeyID,
+			AccessExpiration:  config.JWTAccessExpiration,
+			RefreshExpiration: config.JWTRefreshExpiration,
+		},
+		Streams: auth.StreamSigner{
+			Secret:     []byte(config.StreamTokenSecret),
+			Expiration: config.StreamTokenExpiration,
+		},
+	}
+
+	rateLimiter := ratelimit.RateLimiter{
+		Limiters: map[ratelimit.Class]*ratelimit.Limiter{
+			ratelimit.ClassRead:      ratelimit.NewLimiter(ratelimit.Limit{Rate: config.RateLimitReadRate, Burst: config.RateLimitReadBurst}),
+			ratelimit.ClassUpload:    ratelimit.NewLimiter(ratelimit.Limit{Rate: config.RateLimitUploadRate, Burst: config.RateLimitUploadBurst}),
+			ratelimit.ClassTransform: ratelimit.NewLimiter(ratelimit.Limit{Rate: config.RateLimitTransformRate, Burst: config.RateLimitTransformBurst}),
+		},
+		Transforms: ratelimit.NewConcurrencyLimiter(config.RateLimitTransformInFlight),
+		ClientID:   auth.ClientID,
+	}
+
 	r := mux.NewRouter()
 	r.Use(prometheusMiddleware)
-
-	r.PathPrefix("/ws").Handler(controllers.WSHandler{Config: config, AmqpVideoStatusUpdate: clients.AmqpVideoStatusUpdate}).Methods("GET")
+	// Identifies the clients for the rate limits and the viewers for the playback analytics
+	r.Use(authenticator.IdentifyMiddleware(config.RateLimitTrustProxy))
+	r.Use(rateLimiter.Middleware)
 
 	r.PathPrefix("/metrics").Handler(promhttp.Handler()).Methods("GET", "POST")
 	r.PathPrefix("/swagger").Handler(httpSwagger.WrapHandler)
 
 	r.PathPrefix("/health").Handler(controllers.HealthComponentHandler{}).Methods("GET")
 
+	r.PathPrefix("/ws").Handler(controllers.WSHandler{Authenticator: authenticator, AmqpVideoStatusUpdate: clients.AmqpVideoStatusUpdate}).Methods("GET")
+
+	// Login endpoints are public : they are needed to get credentials
+	r.Path("/api/v1/users/login").Handler(controllers.UserLoginHandler{Authenticator: authenticator}).Methods("POST")
+	r.Path("/api/v1/users/refresh").Handler(controllers.UserRefreshHandler{Authenticator: authenticator, UsersDAO: &DAOs.UsersDAO}).Methods("POST")
+
+	// Streams are authorized by the stream token of the video, or of the playlist, so that players can request them without credentials
+	streams := r.PathPrefix("/api/v1/videos/{id}/streams").Subrouter()
+	streams.Use(authenticator.StreamMiddleware)
+	streams.Path("/master.m3u8").Handler(controllers.VideoGetMasterHandler{S3Client: clients.S3Client, PlaybackDAO: &DAOs.PlaybackDAO, SubtitlesDAO: &DAOs.SubtitlesDAO, UUIDGen: clients.UUIDGen, StreamSigner: authenticator.Streams, ClientID: auth.ClientID}).Methods("GET")
+	streams.Path("/thumbnails/{filename}").Handler(controllers.VideoGetThumbnailsHandler{S3Client: clients.S3Client, UUIDGen: clients.UUIDGen}).Methods("GET")
+	streams.Path("/chapters.vtt").Handler(controllers.VideoGetChaptersHandler{S3Client: clients.S3Client, ChaptersDAO: &DAOs.ChaptersDAO, UUIDGen: clients.UUIDGen}).Methods("GET")
+	streams.Path("/subtitles/{language}/{filename}").Handler(controllers.VideoGetSubtitlesHandler{S3Client: clients.S3Client, UUIDGen: clients.UUIDGen, StreamSigner: authenticator.Streams}).Methods("GET")
+	streams.Path("/{quality}/{filename}").Handler(controllers.VideoGetSubPartHandler{S3Client: clients.S3Client, PlaybackDAO: &DAOs.PlaybackDAO, ChaptersDAO: &DAOs.ChaptersDAO, UUIDGen: clients.UUIDGen, ServiceDiscovery: clients.ServiceDiscovery, StreamSigner: authenticator.Streams}).Methods("GET")
+
+	playlistStreams := r.PathPrefix("/api/v1/playlists/{id}/streams").Subrouter()
+	playlistStreams.Use(authenticator.StreamMiddleware)
+	playlistStreams.Path("/master.m3u8").Handler(controllers.PlaylistGetMasterHandler{S3Client: clients.S3Client, PlaylistsDAO: &DAOs.PlaylistsDAO, UUIDGen: clients.UUIDGen, StreamSigner: authenticator.Streams}).Methods("GET")
+	playlistStreams.Path("/v{level:[0-9]+}/segment_index.m3u8").Handler(controllers.PlaylistGetVariantHandler{S3Client: clients.S3Client, PlaylistsDAO: &DAOs.PlaylistsDAO, UUIDGen: clients.UUIDGen, StreamSigner: authenticator.Streams}).Methods("GET")
+	playlistStreams.Path("/audio_{language:[a-z]+}/segment_index.m3u8").Handler(controllers.PlaylistGetAudioHandler{S3Client: clients.S3Client, PlaylistsDAO: &DAOs.PlaylistsDAO, UUIDGen: clients.UUIDGen, StreamSigner: authenticator.Streams}).Methods("GET")
+
 	v1 := r.PathPrefix("/api/v1").Subrouter()
-	v1.Use(httpauth.SimpleBasicAuth(config.UserAuth, config.PwdAuth))
-
-	v1.PathPrefix("/videos/{id}/streams/master.m3u8").Handler(controllers.VideoGetMasterHandler{S3Client: clients.S3Client, UUIDGen: clients.UUIDGen}).Methods("GET")
-	v1.PathPrefix("/videos/{id}/streams/{quality}/{filename}").Handler(controllers.VideoGetSubPartHandler{S3Client: clients.S3Client, UUIDGen: clients.UUIDGen, ServiceDiscovery: clients.ServiceDiscovery}).Methods("GET")
-	v1.PathPrefix("/videos/transformer/list").Handler(controllers.VideoTransformerListHandler{ServiceDiscovery: clients.ServiceDiscovery}).Methods("GET")
-	v1.PathPrefix("/videos/{id}/cover").Handler(controllers.VideoCoverHandler{S3Client: clients.S3Client, VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen}).Methods("GET")
-	v1.PathPrefix("/videos/list/{attribute}/{order}/{page}/{limit}/{status}").Handler(controllers.VideosListHandler{VideosDAO: &DAOs.VideosDAO}).Methods("GET")
-	v1.PathPrefix("/videos/{id}/delete").Handler(controllers.VideoDeleteHandler{S3Client: clients.S3Client, VideosDAO: &DAOs.VideosDAO, UploadsDAO: &DAOs.UploadsDAO, UUIDGen: clients.UUIDGen}).Methods("DELETE")
-	v1.PathPrefix("/videos/{id}/archive").Handler(controllers.VideoArchiveHandler{VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen}).Methods("PUT")
-	v1.PathPrefix("/videos/{id}/unarchive").Handler(controllers.VideoUnarchiveHandler{VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen}).Methods("PUT")
-	v1.PathPrefix("/videos/{id}/info").Handler(controllers.VideoGetInfoHandler{VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen}).Methods("GET")
-	v1.PathPrefix("/videos/upload").Handler(controllers.VideoUploadHandler{S3Client: clients.S3Client, AmqpClient: clients.AmqpClient, AmqpVideoStatusUpdate: clients.AmqpVideoStatusUpdate, VideosDAO: &DAOs.VideosDAO, UploadsDAO: &DAOs.UploadsDAO, UUIDGen: clients.UUIDGen}).Methods("POST")
-	v1.PathPrefix("/videos/{id}/status").Handler(controllers.VideoGetStatusHandler{VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen}).Methods("GET")
-
-	return handlers.CORS(getCORS())(r)
+	v1.Use(authenticator.Middleware)
+
+	// Users are registered and API keys managed by the shared account only, the other routes require the scope of the API keys
+	v1.Path("/users/register").Handler(auth.RequireAdmin(controllers.UserRegisterHandler{UsersDAO: &DAOs.UsersDAO, UUIDGen: clients.UUIDGen})).Methods("POST")
+	v1.Path("/apikeys").Handler(auth.RequireAdmin(controllers.ApiKeyCreateHandler{ApiKeysDAO: &DAOs.ApiKeysDAO, UUIDGen: clients.UUIDGen})).Methods("POST")
+	v1.Path("/apikeys").Handler(auth.RequireAdmin(controllers.ApiKeysListHandler{ApiKeysDAO: &DAOs.ApiKeysDAO})).Methods("GET")
+	v1.Path("/apikeys/{id}").Handler(auth.RequireAdmin(controllers.ApiKeyRevokeHandler{ApiKeysDAO: &DAOs.ApiKeysDAO, UUIDGen: clients.UUIDGen})).Methods("DELETE")
+
+	v1.PathPrefix("/videos/transformer/list").Handler(auth.RequireScope(models.ScopeVideosRead, controllers.VideoTransformerListHandler{ServiceDiscovery: clients.ServiceDiscovery})).Methods("GET")
+	v1.PathPrefix("/videos/{id}/cover").Handler(auth.RequireScope(models.ScopeVideosRead, controllers.VideoCoverHandler{S3Client: clients.S3Client, VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen})).Methods("GET")
+	v1.PathPrefix("/videos/{id}/cover").Handler(auth.RequireScope(models.ScopeVideosWrite, controllers.VideoCoverUpdateHandler{S3Client: clients.S3Client, AmqpVideoStatusUpdate: clients.AmqpVideoStatusUpdate, VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen, ImageConverter: clients.ImageConverter})).Methods("PUT")
+	v1.Path("/videos").Handler(auth.RequireScope(models.ScopeVideosRead, controllers.VideosQueryHandler{VideosDAO: &DAOs.VideosDAO, PlaybackDAO: &DAOs.PlaybackDAO})).Methods("GET")
+	v1.Path("/videos/search").Handler(auth.RequireScope(models.ScopeVideosRead, controllers.VideosSearchHandler{VideosDAO: &DAOs.VideosDAO})).Methods("GET")
+	v1.Path("/videos/retention").Handler(auth.RequireAdmin(controllers.VideosRetentionHandler{Purger: retention.Purger{S3Client: clients.S3Client, VideosDAO: &DAOs.VideosDAO, UploadsDAO: &DAOs.UploadsDAO, PlaylistsDAO: &DAOs.PlaylistsDAO, Period: config.RetentionPeriod}})).Methods("GET")
+	v1.PathPrefix("/videos/list/{attribute}/{order}/{page}/{limit}/{status}").Handler(auth.RequireScope(models.ScopeVideosRead, controllers.VideosListHandler{VideosDAO: &DAOs.VideosDAO, PlaybackDAO: &DAOs.PlaybackDAO})).Methods("GET")
+	v1.PathPrefix("/videos/{id}/delete").Handler(auth.RequireScope(models.ScopeVideosDelete, controllers.VideoDeleteHandler{S3Client: clients.S3Client, VideosDAO: &DAOs.VideosDAO, UploadsDAO: &DAOs.UploadsDAO, PlaylistsDAO: &DAOs.PlaylistsDAO, UUIDGen: clients.UUIDGen})).Methods("DELETE")
+	v1.PathPrefix("/videos/{id}/archive").Handler(auth.RequireScope(models.ScopeVideosWrite, controllers.VideoArchiveHandler{VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen})).Methods("PUT")
+	v1.PathPrefix("/videos/{id}/unarchive").Handler(auth.RequireScope(models.ScopeVideosWrite, controllers.VideoUnarchiveHandler{VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen})).Methods("PUT")
+	v1.PathPrefix("/videos/{id}/info").Handler(auth.RequireScope(models.ScopeVideosRead, controllers.VideoGetInfoHandler{VideosDAO: &DAOs.VideosDAO, TagsDAO: &DAOs.TagsDAO, AudioLanguagesDAO: &DAOs.AudioLanguagesDAO, WatchHistoryDAO: &DAOs.WatchHistoryDAO, UUIDGen: clients.UUIDGen})).Methods("GET")
+	v1.PathPrefix("/videos/uploads/presigned").Handler(auth.RequireScope(models.ScopeVideosWrite, controllers.VideoPresignedUploadHandler{S3Client: clients.S3Client, AmqpVideoStatusUpdate: clients.AmqpVideoStatusUpdate, VideosDAO: &DAOs.VideosDAO, UploadsDAO: &DAOs.UploadsDAO, UUIDGen: clients.UUIDGen, PresignExpiration: config.S3PresignExpiration})).Methods("POST")
+	v1.PathPrefix("/videos/uploads/{id}/complete").Handler(auth.RequireScope(models.ScopeVideosWrite, controllers.VideoPresignedUploadCompleteHandler{S3Client: clients.S3Client, AmqpClient: clients.AmqpClient, AmqpVideoStatusUpdate: clients.AmqpVideoStatusUpdate, VideosDAO: &DAOs.VideosDAO, UploadsDAO: &DAOs.UploadsDAO, UUIDGen: clients.UUIDGen})).Methods("POST")
+	v1.PathPrefix("/videos/uploads/{id}").Handler(auth.RequireScope(models.ScopeVideosWrite, controllers.VideoResumableUploadOffsetHandler{VideosDAO: &DAOs.VideosDAO, UploadsDAO: &DAOs.UploadsDAO, UUIDGen: clients.UUIDGen})).Methods("HEAD")
+	v1.PathPrefix("/videos/uploads/{id}").Handler(auth.RequireScope(models.ScopeVideosWrite, controllers.VideoResumableUploadChunkHandler{S3Client: clients.S3Client, AmqpClient: clients.AmqpClient, AmqpVideoStatusUpdate: clients.AmqpVideoStatusUpdate, VideosDAO: &DAOs.VideosDAO, UploadsDAO: &DAOs.UploadsDAO, UUIDGen: clients.UUIDGen})).Methods("PATCH")
+	v1.PathPrefix("/videos/uploads").Handler(auth.RequireScope(models.ScopeVideosWrite, controllers.VideoResumableUploadCreateHandler{S3Client: clients.S3Client, VideosDAO: &DAOs.VideosDAO, UploadsDAO: &DAOs.UploadsDAO, UUIDGen: clients.UUIDGen})).Methods("POST")
+	v1.PathPrefix("/videos/upload").Handler(auth.RequireScope(models.ScopeVideosWrite, controllers.VideoUploadHandler{S3Client: clients.S3Client, AmqpClient: clients.AmqpClient, AmqpVideoStatusUpdate: clients.AmqpVideoStatusUpdate, VideosDAO: &DAOs.VideosDAO, UploadsDAO: &DAOs.UploadsDAO, UUIDGen: clients.UUIDGen})).Methods("POST")
+	v1.Path("/videos/{id}/position").Handler(auth.RequireScope(models.ScopeVideosRead, controllers.VideoPositionGetHandler{WatchHistoryDAO: &DAOs.WatchHistoryDAO, UUIDGen: clients.UUIDGen})).Methods("GET")
+	v1.Path("/videos/{id}/position").Handler(auth.RequireScope(models.ScopeVideosWrite, controllers.VideoPositionUpdateHandler{VideosDAO: &DAOs.VideosDAO, WatchHistoryDAO: &DAOs.WatchHistoryDAO, UUIDGen: clients.UUIDGen})).Methods("PUT")
+	v1.Path("/history").Handler(auth.RequireScope(models.ScopeVideosRead, controllers.WatchHistoryHandler{WatchHistoryDAO: &DAOs.WatchHistoryDAO})).Methods("GET")
+	v1.Path("/videos/{id}/chapters").Handler(auth.RequireScope(models.ScopeVideosRead, controllers.VideoChaptersGetHandler{VideosDAO: &DAOs.VideosDAO, ChaptersDAO: &DAOs.ChaptersDAO, UUIDGen: clients.UUIDGen})).Methods("GET")
+	v1.Path("/videos/{id}/chapters").Handler(auth.RequireScope(models.ScopeVideosWrite, controllers.VideoChaptersUpdateHandler{VideosDAO: &DAOs.VideosDAO, ChaptersDAO: &DAOs.ChaptersDAO, UUIDGen: clients.UUIDGen})).Methods("PUT")
+	v1.Path("/videos/{id}/subtitles").Handler(auth.RequireScope(models.ScopeVideosRead, controllers.VideoSubtitlesListHandler{VideosDAO: &DAOs.VideosDAO, SubtitlesDAO: &DAOs.SubtitlesDAO, UUIDGen: clients.UUIDGen})).Methods("GET")
+	v1.Path("/videos/{id}/subtitles/{language}").Handler(auth.RequireScope(models.ScopeVideosWrite, controllers.VideoSubtitleUploadHandler{S3Client: clients.S3Client, VideosDAO: &DAOs.VideosDAO, SubtitlesDAO: &DAOs.SubtitlesDAO, UUIDGen: clients.UUIDGen})).Methods("PUT")
+	v1.Path("/videos/{id}/subtitles/{language}").Handler(auth.RequireScope(models.ScopeVideosWrite, controllers.VideoSubtitleDeleteHandler{S3Client: clients.S3Client, VideosDAO: &DAOs.VideosDAO, SubtitlesDAO: &DAOs.SubtitlesDAO, UUIDGen: clients.UUIDGen})).Methods("DELETE")
+	v1.Path("/videos/{id}/stats").Handler(auth.RequireScope(models.ScopeVideosRead, controllers.VideoGetStatsHandler{VideosDAO: &DAOs.VideosDAO, PlaybackDAO: &DAOs.PlaybackDAO, UUIDGen: clients.UUIDGen})).Methods("GET")
+	v1.PathPrefix("/videos/{id}/status").Handler(auth.RequireScope(models.ScopeVideosRead, controllers.VideoGetStatusHandler{VideosDAO: &DAOs.VideosDAO, UploadsDAO: &DAOs.UploadsDAO, UUIDGen: clients.UUIDGen})).Methods("GET")
+	v1.Path("/videos/{id}").Handler(auth.RequireScope(models.ScopeVideosWrite, controllers.VideoUpdateHandler{VideosDAO: &DAOs.VideosDAO, TagsDAO: &DAOs.TagsDAO, UUIDGen: clients.UUIDGen})).Methods("PATCH")
+
+	v1.Path("/playlists").Handler(auth.RequireScope(models.ScopeVideosRead, controllers.PlaylistsListHandler{PlaylistsDAO: &DAOs.PlaylistsDAO})).Methods("GET")
+	v1.Path("/playlists").Handler(auth.RequireScope(models.ScopeVideosWrite, controllers.PlaylistCreateHandler{PlaylistsDAO: &DAOs.PlaylistsDAO, UUIDGen: clients.UUIDGen})).Methods("POST")
+	v1.Path("/playlists/{id}").Handler(auth.RequireScope(models.ScopeVideosRead, controllers.PlaylistGetHandler{PlaylistsDAO: &DAOs.PlaylistsDAO, UUIDGen: clients.UUIDGen})).Methods("GET")
+	v1.Path("/playlists/{id}").Handler(auth.RequireScope(models.ScopeVideosWrite, controllers.PlaylistUpdateHandler{PlaylistsDAO: &DAOs.PlaylistsDAO, UUIDGen: clients.UUIDGen})).Methods("PATCH")
+	v1.Path("/playlists/{id}").Handler(auth.RequireScope(models.ScopeVideosDelete, controllers.PlaylistDeleteHandler{PlaylistsDAO: &DAOs.PlaylistsDAO, UUIDGen: clients.UUIDGen})).Methods("DELETE")
+	v1.Path("/playlists/{id}/videos").Handler(auth.RequireScope(models.ScopeVideosWrite, controllers.PlaylistVideoAddHandler{PlaylistsDAO: &DAOs.PlaylistsDAO, VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen})).Methods("POST")
+	v1.Path("/playlists/{id}/videos").Handler(auth.RequireScope(models.ScopeVideosWrite, controllers.PlaylistVideosReorderHandler{PlaylistsDAO: &DAOs.PlaylistsDAO, UUIDGen: clients.UUIDGen})).Methods("PUT")
+	v1.Path("/playlists/{id}/videos/{videoId}").Handler(auth.RequireScope(models.ScopeVideosWrite, controllers.PlaylistVideoRemoveHandler{PlaylistsDAO: &DAOs.PlaylistsDAO, UUIDGen: clients.UUIDGen})).Methods("DELETE")
+
+	return handlers.CORS(getCORS())(r), nil
 }
 
-func getCORS() (handlers.CORSOption, handlers.CORSOption, handlers.CORSOption, handlers.CORSOption) {
+func getCORS() (handlers.CORSOption, handlers.CORSOption, handlers.CORSOption, handlers.CORSOption, handlers.CORSOption) {
 	corsObj := handlers.AllowedOrigins([]string{"*"})
-	methods := handlers.AllowedMethods([]string{"GET", "POST", "PUT", "OPTIONS", "DELETE"})
-	headers := handlers.AllowedHeaders([]string{"Authorization"})
+	methods := handlers.AllowedMethods([]string{"GET", "POST", "PUT", "PATCH", "HEAD", "OPTIONS", "DELETE"})
+	// tus headers are needed by resumable uploads
+	headers := handlers.AllowedHeaders([]string{"Authorization", "Content-Type", "X-API-Key", "Tus-Resumable", "Upload-Length", "Upload-Metadata", "Upload-Offset"})
+	exposedHeaders := handlers.ExposedHeaders([]string{"Location", "Retry-After", "Tus-Resumable", "Upload-Length", "Upload-Offset"})
 	credentials := handlers.AllowCredentials()
 
-	return corsObj, methods, headers, credentials
+	return corsObj, methods, headers, exposedHeaders, credentials
 }
 
 // Metrics
@@ -14,7 +14,11 @@ import (
 	"github.com/Sogilis/Voogle/src/pkg/ffmpeg"
 )
 
-// Process input video into a HLS video
+// Sprite sheets and WebVTT track of the scrubbing thumbnails, next to the HLS output
+const thumbnailsDir = "thumbnails"
+
+// Process input video into a HLS video. The cover path of the video is updated with its compressed
+// cover, or with a poster frame of the video when no cover was uploaded.
 func Process(s3Client clients.IS3Client, videoData *contracts.Video) error {
 	// Going to the working directory
 	processingFolder := filepath.Join(os.TempDir(), "/encoder-processing-dir")
@@ -45,6 +49,11 @@ func Process(s3Client clients.IS3Client, videoData *contracts.Video) error {
 		return err
 	}
 
+	// Scrubbing thumbnails, the video can be watched without them
+	if err = ffmpeg.GenerateThumbnails(filepath.Base(videoData.GetSource()), thumbnailsDir); err != nil {
+		log.Error("Failed to generate thumbnails : ", err)
+	}
+
 	// Download and write the cover file on the filesystem
 	isCoverFetch, err := fetchCoverSource(s3Client, videoData)
 	if err != nil {
@@ -52,12 +61,15 @@ func Process(s3Client clients.IS3Client, videoData *contracts.Video) error {
 		return err
 	}
 
-	// Cover image compression
+	// Cover image compression, or poster frame when no cover was uploaded
 	if isCoverFetch {
 		if err = compressCover(videoData); err != nil {
 			log.Error("Failed to compress cover image")
 			return err
 		}
+	} else if err = extractPoster(videoData); err != nil {
+		// The video can be watched without cover
+		log.Error("Failed to extract poster frame : ", err)
 	}
 
 	log.Info("Processing of video ", videoData.GetId(), "done - Uploading to S3")
@@ -99,11 +111,34 @@ func encode(data *contracts.Video) error {
 		}
 	}
 
+	// Each language is encoded as an audio rendition, the added empty track having none
+	audioStreams, err := ffmpeg.ExtractAudioStreams(sourcefile)
+	if err != nil {
+		return err
+	}
+	data.AudioLanguages = []string{}
+	if withSound {
+		for _, stream := range audioStreams {
+			data.AudioLanguages = append(data.AudioLanguages, stream.Language)
+		}
+	}
+
+	// Chapter markers of the source, imported by the api when the video has no chapter yet.
+	// The video can be watched without them.
+	chapters, err := ffmpeg.ExtractChapters(sourcefile)
+	if err != nil {
+		log.Error("Failed to extract chapters : ", err)
+	}
+	data.Chapters = []*contracts.Chapter{}
+	for _, chapter := range chapters {
+		data.Chapters = append(data.Chapters, &contracts.Chapter{Start: chapter.Start, Title: chapter.Title})
+	}
+
 	res, err := ffmpeg.ExtractResolution(sourcefile)
 	if err != nil {
 		return err
 	}
-	if err = ffmpeg.ConvertToHLS(sourcefile, res); err != nil {
+	if err = ffmpeg.ConvertToHLS(sourcefile, res, audioStreams); err != nil {
 		return err
 	}
 	return nil
@@ -137,13 +172,22 @@ func compressCover(videoData *contracts.Video) error {
 	return nil
 }
 
+// extractPoster writes a representative frame of the video as cover
+func extractPoster(videoData *contracts.Video) error {
+	if err := ffmpeg.ExtractPosterFrame(filepath.Base(videoData.GetSource()), "poster.png"); err != nil {
+		return err
+	}
+	return ffmpeg.ConvertImg("poster.png", "cover.jpeg")
+}
+
 func uploadFiles(s3Client clients.IS3Client, data *contracts.Video) error {
 	err := filepath.Walk(".",
 		func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return err
 			}
-			if path == "." || (!strings.HasSuffix(path, ".ts") && !strings.HasSuffix(path, ".m3u8") && !strings.HasSuffix(path, ".jpeg")) {
+			isThumbnail := strings.HasPrefix(path, thumbnailsDir+"/") && (strings.HasSuffix(path, ".jpg") || strings.HasSuffix(path, ".vtt"))
+			if path == "." || (!strings.HasSuffix(path, ".ts") && !strings.HasSuffix(path, ".m3u8") && !strings.HasSuffix(path, ".jpeg") && !isThumbnail) {
 				log.Debug("Skipping ", path)
 				return nil
 			}
@@ -158,12 +202,17 @@ func uploadFiles(s3Client clients.IS3Client, data *contracts.Video) error {
 		return err
 	}
 
-	// Remove cover image on S3 if needed
+	// Replace the cover path by the compressed cover (or the poster frame),
+	// and remove the uploaded cover image on S3 if needed
 	if _, err = os.Stat("cover.jpeg"); err == nil {
-		err = s3Client.RemoveObject(context.Background(), data.GetCoverPath())
-		if err != nil {
-			return err
+		coverPath := filepath.Join(data.GetId(), "cover.jpeg")
+		if data.GetCoverPath() != "" && data.GetCoverPath() != coverPath {
+			err = s3Client.RemoveObject(context.Background(), data.GetCoverPath())
+			if err != nil {
+				return err
+			}
 		}
+		data.CoverPath = coverPath
 	}
 
 	return nil
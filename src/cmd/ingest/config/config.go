@@ -0,0 +1,23 @@
+package config
+
+import (
+	"github.com/caarlos0/env/v6"
+)
+
+type Config struct {
+	DevMode bool `env:"DEV_MODE" envDefault:"false"`
+
+	APIURL string `env:"API_URL" envDefault:"http://localhost:4444"`
+	// An API key with the videos:read and videos:write scopes, or the credentials of an account
+	APIKey   string `env:"API_KEY"`
+	UserAuth string `env:"USER_AUTH"`
+	PwdAuth  string `env:"PWD_AUTH"`
+}
+
+func NewConfig() (Config, error) {
+	config := Config{}
+
+	err := env.Parse(&config)
+
+	return config, err
+}
@@ -0,0 +1,235 @@
+package ingest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/cmd/ingest/ingest"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+}
+
+func TestReadManifest(t *testing.T) {
+	cases := []struct {
+		name        string
+		filename    string
+		content     string
+		expected    ingest.Manifest
+		expectError bool
+	}{
+		{
+			name:     "CSV manifest",
+			filename: "manifest.csv",
+			content:  "filename,title,cover\nvideo1.mp4,My first video,cover1.png\nvideo2.mp4,My second video\n",
+			expected: ingest.Manifest{
+				"video1.mp4": {Filename: "video1.mp4", Title: "My first video", Cover: "cover1.png"},
+				"video2.mp4": {Filename: "video2.mp4", Title: "My second video"},
+			},
+		},
+		{
+			name:     "JSON manifest",
+			filename: "manifest.json",
+			content:  `[{"filename": "video1.mp4", "title": "My first video", "cover": "cover1.png"}]`,
+			expected: ingest.Manifest{
+				"video1.mp4": {Filename: "video1.mp4", Title: "My first video", Cover: "cover1.png"},
+			},
+		},
+		{
+			name:        "Entry without filename",
+			filename:    "manifest.json",
+			content:     `[{"title": "My first video"}]`,
+			expectError: true,
+		},
+		{
+			name:        "Unsupported format",
+			filename:    "manifest.txt",
+			content:     "video1.mp4 My first video",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{tt.filename: tt.content})
+
+			manifest, err := ingest.ReadManifest(filepath.Join(dir, tt.filename))
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, manifest)
+		})
+	}
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"video1.mp4":    "video",
+		"video1.PNG":    "cover",
+		"video2.webm":   "video",
+		"other.jpg":     "cover",
+		"notes.txt":     "notes",
+		"manifest.json": `[{"filename": "video2.webm", "title": "Second video", "cover": "other.jpg"}]`,
+	})
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "video3.mp4"), 0o755))
+
+	manifest, err := ingest.ReadManifest(ingest.FindManifest(dir))
+	require.NoError(t, err)
+
+	jobs, err := ingest.Scan(dir, manifest)
+	require.NoError(t, err)
+	require.Equal(t, []ingest.Job{
+		{VideoPath: filepath.Join(dir, "video1.mp4"), CoverPath: filepath.Join(dir, "video1.PNG"), Title: "video1"},
+		{VideoPath: filepath.Join(dir, "video2.webm"), CoverPath: filepath.Join(dir, "other.jpg"), Title: "Second video"},
+	}, jobs)
+}
+
+func TestUploader(t *testing.T) {
+	cases := []struct {
+		name        string
+		giveAPIKey  string
+		status      int
+		body        string
+		expectedID  string
+		expectedErr error
+		expectError bool
+	}{
+		{
+			name:       "Video created",
+			status:     http.StatusOK,
+			body:       `{"video": {"id": "4a6e6d4a-5a4b-4b8e-9a63-4c6c0f0e7e1d"}}`,
+			expectedID: "4a6e6d4a-5a4b-4b8e-9a63-4c6c0f0e7e1d",
+		},
+		{
+			name:       "Video created with API key",
+			giveAPIKey: "vgl_key",
+			status:     http.StatusOK,
+			body:       `{"video": {"id": "4a6e6d4a-5a4b-4b8e-9a63-4c6c0f0e7e1d"}}`,
+			expectedID: "4a6e6d4a-5a4b-4b8e-9a63-4c6c0f0e7e1d",
+		},
+		{
+			name:        "Title conflict",
+			status:      http.StatusConflict,
+			expectedErr: ingest.ErrTitleConflict,
+			expectError: true,
+		},
+		{
+			name:        "Source already uploaded",
+			status:      http.StatusConflict,
+			body:        `{"video": {"id": "0b5bd3a5-5f1e-4a8c-a54b-3f8f1b4f2b6e"}}`,
+			expectedErr: ingest.DuplicateSourceError{ID: "0b5bd3a5-5f1e-4a8c-a54b-3f8f1b4f2b6e"},
+			expectError: true,
+		},
+		{
+			name:        "Server error",
+			status:      http.StatusInternalServerError,
+			expectError: true,
+		},
+	}
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"video.mp4": "video", "video.png": "cover"})
+	job := ingest.Job{VideoPath: filepath.Join(dir, "video.mp4"), CoverPath: filepath.Join(dir, "video.png"), Title: "My video"}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				user, pwd, ok := r.BasicAuth()
+				if tt.giveAPIKey != "" {
+					require.False(t, ok)
+					require.Equal(t, tt.giveAPIKey, r.Header.Get("X-API-Key"))
+				} else {
+					require.True(t, ok)
+					require.Equal(t, "user", user)
+					require.Equal(t, "pwd", pwd)
+				}
+				require.Equal(t, "/api/v1/videos/upload", r.URL.Path)
+
+				require.NoError(t, r.ParseMultipartForm(1<<20))
+				require.Equal(t, "My video", r.FormValue("title"))
+				_, _, err := r.FormFile("video")
+				require.NoError(t, err)
+				_, _, err = r.FormFile("cover")
+				require.NoError(t, err)
+
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			uploader := ingest.Uploader{Client: server.Client(), APIURL: server.URL, APIKey: tt.giveAPIKey, UserAuth: "user", PwdAuth: "pwd"}
+			id, err := uploader.Upload(context.Background(), job)
+			if tt.expectError {
+				require.Error(t, err)
+				if tt.expectedErr != nil {
+					require.True(t, errors.Is(err, tt.expectedErr), err)
+				}
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedID, id)
+		})
+	}
+}
+
+// uploaderFunc uploads with a function, counting the uploads of each video
+type uploaderFunc struct {
+	mutex   sync.Mutex
+	uploads map[string]int
+	upload  func(job ingest.Job, attempt int) (string, error)
+}
+
+func (u *uploaderFunc) Upload(ctx context.Context, job ingest.Job) (string, error) {
+	u.mutex.Lock()
+	u.uploads[filepath.Base(job.VideoPath)]++
+	attempt := u.uploads[filepath.Base(job.VideoPath)]
+	u.mutex.Unlock()
+	return u.upload(job, attempt)
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"created.mp4": "video", "conflict.mp4": "video", "failed.mp4": "video"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The failed upload is tried again on the next poll, the other videos are uploaded once
+	uploader := &uploaderFunc{uploads: map[string]int{}}
+	uploader.upload = func(job ingest.Job, attempt int) (string, error) {
+		switch filepath.Base(job.VideoPath) {
+		case "conflict.mp4":
+			return "", ingest.ErrTitleConflict
+		case "failed.mp4":
+			if attempt == 1 {
+				return "", errors.New("server error")
+			}
+			cancel()
+		}
+		return "4a6e6d4a-5a4b-4b8e-9a63-4c6c0f0e7e1d", nil
+	}
+
+	ingester := ingest.NewIngester(dir, "", "", 1, uploader)
+	require.NoError(t, ingester.Watch(ctx, 10*time.Millisecond))
+
+	require.Equal(t, map[string]int{"created.mp4": 1, "conflict.mp4": 1, "failed.mp4": 2}, uploader.uploads)
+	require.Len(t, ingester.Report.Created, 2)
+	require.Len(t, ingester.Report.Conflicts, 1)
+	require.Len(t, ingester.Report.Failures, 1)
+}
@@ -0,0 +1,181 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Ingester struct {
+	Dir          string
+	ManifestPath string
+	ReportPath   string
+	Concurrency  int
+	Uploader     IUploader
+	Report       *Report
+
+	// Videos already ingested, and sizes seen at the last poll of the hot folder. The failed uploads are
+	// not ingested : they are tried again on the next poll.
+	mutex    sync.Mutex
+	ingested map[string]bool
+	sizes    map[string]int64
+}
+
+func NewIngester(dir, manifestPath, reportPath string, concurrency int, uploader IUploader) *Ingester {
+	if manifestPath == "" {
+		manifestPath = FindManifest(dir)
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &Ingester{
+		Dir:          dir,
+		ManifestPath: manifestPath,
+		ReportPath:   reportPath,
+		Concurrency:  concurrency,
+		Uploader:     uploader,
+		Report:       NewReport(),
+		ingested:     map[string]bool{},
+		sizes:        map[string]int64{},
+	}
+}
+
+// Once uploads all the videos of the directory
+func (i *Ingester) Once(ctx context.Context) error {
+	jobs, err := i.scan()
+	if err != nil {
+		return err
+	}
+
+	i.Run(ctx, jobs)
+	return i.writeReport()
+}
+
+// Watch polls the directory as a hot folder, until ctx is done. A video is uploaded once
+// its size did not change between two polls, so that files still being copied are skipped.
+func (i *Ingester) Watch(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		jobs, err := i.scan()
+		if err != nil {
+			log.Error("Cannot scan directory : ", err)
+		}
+
+		if ready := i.readyJobs(jobs); len(ready) > 0 {
+			i.Run(ctx, ready)
+			if err := i.writeReport(); err != nil {
+				log.Error("Cannot write report : ", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run uploads the jobs, with at most Concurrency uploads at a time
+func (i *Ingester) Run(ctx context.Context, jobs []Job) {
+	queue := make(chan Job)
+
+	var wg sync.WaitGroup
+	for w := 0; w < i.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				i.upload(ctx, job)
+			}
+		}()
+	}
+
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- job
+	}
+	close(queue)
+
+	wg.Wait()
+}
+
+func (i *Ingester) upload(ctx context.Context, job Job) {
+	log.Infof("Uploading '%v' (%v)", job.Title, job.VideoPath)
+
+	id, err := i.Uploader.Upload(ctx, job)
+	var duplicate DuplicateSourceError
+	switch {
+	case errors.As(err, &duplicate):
+		log.Warnf("'%v' already uploaded as video %v (%v)", job.Title, duplicate.ID, job.VideoPath)
+		i.Report.AddDuplicate(job, duplicate.ID)
+	case errors.Is(err, ErrTitleConflict):
+		log.Warnf("Title '%v' already exists (%v)", job.Title, job.VideoPath)
+		i.Report.AddConflict(job)
+	case err != nil:
+		log.Errorf("Cannot upload %v : %v", job.VideoPath, err)
+		i.Report.AddFailure(job, err)
+		return
+	default:
+		log.Infof("Video '%v' created with ID %v", job.Title, id)
+		i.Report.AddCreated(job, id)
+	}
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.ingested[job.VideoPath] = true
+}
+
+func (i *Ingester) scan() ([]Job, error) {
+	manifest := Manifest{}
+	if i.ManifestPath != "" {
+		var err error
+		if manifest, err = ReadManifest(i.ManifestPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return Scan(i.Dir, manifest)
+}
+
+func (i *Ingester) readyJobs(jobs []Job) []Job {
+	var ready []Job
+	sizes := map[string]int64{}
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	for _, job := range jobs {
+		if i.ingested[job.VideoPath] {
+			continue
+		}
+
+		info, err := os.Stat(job.VideoPath)
+		if err != nil {
+			continue
+		}
+		sizes[job.VideoPath] = info.Size()
+
+		if previous, ok := i.sizes[job.VideoPath]; ok && previous == info.Size() && info.Size() > 0 {
+			ready = append(ready, job)
+		}
+	}
+
+	i.sizes = sizes
+	return ready
+}
+
+func (i *Ingester) writeReport() error {
+	if i.ReportPath == "" {
+		return nil
+	}
+	return i.Report.Write(i.ReportPath)
+}
@@ -0,0 +1,116 @@
+package ingest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ManifestNames are the sidecar manifests looked for in the ingested directory,
+// when no manifest is given.
+var ManifestNames = []string{"manifest.csv", "manifest.json"}
+
+// ManifestEntry overrides the title, and optionally the cover, of a video file
+type ManifestEntry struct {
+	Filename string `json:"filename"`
+	Title    string `json:"title"`
+	Cover    string `json:"cover,omitempty"`
+}
+
+// Manifest entries by video file name
+type Manifest map[string]ManifestEntry
+
+// FindManifest returns the path of the sidecar manifest of dir, or an empty string if there is none
+func FindManifest(dir string) string {
+	for _, name := range ManifestNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// ReadManifest reads a CSV (with a 'filename,title,cover' header, cover being optional)
+// or a JSON (array of entries) manifest.
+func ReadManifest(path string) (Manifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []ManifestEntry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		entries, err = readCSVManifest(file)
+	case ".json":
+		err = json.NewDecoder(file).Decode(&entries)
+	default:
+		err = fmt.Errorf("unsupported manifest format %v, expected .csv or .json", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := Manifest{}
+	for _, entry := range entries {
+		if entry.Filename == "" {
+			return nil, errors.New("manifest entry without filename")
+		}
+		manifest[entry.Filename] = entry
+	}
+
+	return manifest, nil
+}
+
+func readCSVManifest(r io.Reader) ([]ManifestEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for index, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = index
+	}
+	if _, ok := columns["filename"]; !ok {
+		return nil, errors.New("missing 'filename' column in manifest header")
+	}
+
+	field := func(record []string, name string) string {
+		index, ok := columns[name]
+		if !ok || index >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[index])
+	}
+
+	var entries []ManifestEntry
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, ManifestEntry{
+			Filename: field(record, "filename"),
+			Title:    field(record, "title"),
+			Cover:    field(record, "cover"),
+		})
+	}
+
+	return entries, nil
+}
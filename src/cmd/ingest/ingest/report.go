@@ -0,0 +1,78 @@
+package ingest
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+type CreatedVideo struct {
+	Job
+	ID string `json:"id"`
+}
+
+type FailedVideo struct {
+	Job
+	Error string `json:"error"`
+}
+
+// Report of an ingestion. It is safe for concurrent use.
+type Report struct {
+	mutex     sync.Mutex
+	Created   []CreatedVideo `json:"created"`
+	Conflicts []Job          `json:"conflicts"`
+	// Videos already uploaded (same file), with the ID of the existing video
+	Duplicates []CreatedVideo `json:"duplicates"`
+	Failures   []FailedVideo  `json:"failures"`
+}
+
+func NewReport() *Report {
+	return &Report{
+		Created:    []CreatedVideo{},
+		Conflicts:  []Job{},
+		Duplicates: []CreatedVideo{},
+		Failures:   []FailedVideo{},
+	}
+}
+
+func (r *Report) AddCreated(job Job, id string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Created = append(r.Created, CreatedVideo{Job: job, ID: id})
+}
+
+func (r *Report) AddConflict(job Job) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Conflicts = append(r.Conflicts, job)
+}
+
+func (r *Report) AddDuplicate(job Job, id string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Duplicates = append(r.Duplicates, CreatedVideo{Job: job, ID: id})
+}
+
+func (r *Report) AddFailure(job Job, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Failures = append(r.Failures, FailedVideo{Job: job, Error: err.Error()})
+}
+
+func (r *Report) HasFailures() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.Failures) > 0
+}
+
+// Write the report as JSON. The file is replaced on each call.
+func (r *Report) Write(path string) error {
+	r.mutex.Lock()
+	payload, err := json.MarshalIndent(r, "", "  ")
+	r.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, payload, 0o644)
+}
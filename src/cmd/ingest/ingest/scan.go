@@ -0,0 +1,80 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// VideoExtensions are the files uploaded as videos. The API checks the real file type.
+var VideoExtensions = []string{".mp4", ".webm", ".mkv", ".mov", ".avi"}
+
+// CoverExtensions are the covers paired with a video with the same base name (video.mp4 and video.png)
+var CoverExtensions = []string{".png", ".jpg", ".jpeg"}
+
+// Job is a video to upload
+type Job struct {
+	VideoPath string `json:"file"`
+	CoverPath string `json:"cover,omitempty"`
+	Title     string `json:"title"`
+}
+
+// Scan returns a job for each video file in dir (sub directories are ignored), sorted by file name.
+// The title is the file name without its extension, unless overridden by the manifest.
+func Scan(dir string, manifest Manifest) ([]Job, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files by lower case name, to pair covers whatever the case of their extension
+	names := map[string]string{}
+	for _, file := range files {
+		names[strings.ToLower(file.Name())] = file.Name()
+	}
+
+	var jobs []Job
+	for _, file := range files {
+		if file.IsDir() || !hasExtension(file.Name(), VideoExtensions) {
+			continue
+		}
+
+		baseName := strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))
+		job := Job{
+			VideoPath: filepath.Join(dir, file.Name()),
+			Title:     baseName,
+		}
+
+		for _, ext := range CoverExtensions {
+			if cover, ok := names[strings.ToLower(baseName+ext)]; ok {
+				job.CoverPath = filepath.Join(dir, cover)
+				break
+			}
+		}
+
+		if entry, ok := manifest[file.Name()]; ok {
+			if entry.Title != "" {
+				job.Title = entry.Title
+			}
+			if entry.Cover != "" {
+				job.CoverPath = filepath.Join(dir, entry.Cover)
+			}
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].VideoPath < jobs[j].VideoPath })
+	return jobs, nil
+}
+
+func hasExtension(name string, extensions []string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrTitleConflict is returned when a video with the same title already exists
+var ErrTitleConflict = errors.New("title already exists")
+
+// DuplicateSourceError is returned when the same video file has already been uploaded
+type DuplicateSourceError struct {
+	ID string // ID of the existing video
+}
+
+func (e DuplicateSourceError) Error() string {
+	return "video already uploaded with ID " + e.ID
+}
+
+type IUploader interface {
+	Upload(ctx context.Context, job Job) (string, error)
+}
+
+var _ IUploader = Uploader{}
+
+// Uploader sends videos to the API upload route, the same way the webapp does.
+// It authenticates with the API key if there is one, with basic auth otherwise.
+type Uploader struct {
+	Client   *http.Client
+	APIURL   string
+	APIKey   string
+	UserAuth string
+	PwdAuth  string
+}
+
+type uploadResponse struct {
+	Video struct {
+		ID string `json:"id"`
+	} `json:"video"`
+}
+
+// Upload sends the video (and its cover) and returns the created video ID.
+// Files are streamed : they are never fully loaded in memory.
+func (u Uploader) Upload(ctx context.Context, job Job) (string, error) {
+	body, contentType := multipartBody(job)
+	defer body.Close()
+
+	url := strings.TrimSuffix(u.APIURL, "/") + "/api/v1/videos/upload"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if u.APIKey != "" {
+		req.Header.Set("X-API-Key", u.APIKey)
+	} else {
+		req.SetBasicAuth(u.UserAuth, u.PwdAuth)
+	}
+
+	res, err := u.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusConflict {
+		// The existing video is returned when the source is a duplicate, a plain message when the title is
+		var response uploadResponse
+		if err := json.NewDecoder(res.Body).Decode(&response); err == nil && response.Video.ID != "" {
+			return "", DuplicateSourceError{ID: response.Video.ID}
+		}
+		return "", ErrTitleConflict
+	}
+	if res.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return "", fmt.Errorf("upload failed with status %v : %v", res.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var response uploadResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("cannot decode upload response : %w", err)
+	}
+
+	return response.Video.ID, nil
+}
+
+func multipartBody(job Job) (io.ReadCloser, string) {
+	reader, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+
+	go func() {
+		err := writeMultipartBody(form, job)
+		if err == nil {
+			err = form.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	return reader, form.FormDataContentType()
+}
+
+func writeMultipartBody(form *multipart.Writer, job Job) error {
+	if err := form.WriteField("title", job.Title); err != nil {
+		return err
+	}
+
+	if err := writeMultipartFile(form, "video", job.VideoPath); err != nil {
+		return err
+	}
+
+	if job.CoverPath != "" {
+		return writeMultipartFile(form, "cover", job.CoverPath)
+	}
+
+	return nil
+}
+
+func writeMultipartFile(form *multipart.Writer, field, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	part, err := form.CreateFormFile(field, filepath.Base(path))
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(part, file)
+	return err
+}
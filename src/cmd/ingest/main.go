@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/ingest/config"
+	"github.com/Sogilis/Voogle/src/cmd/ingest/ingest"
+)
+
+func main() {
+	dir := flag.String("dir", "", "Directory of the videos to upload (required)")
+	watch := flag.Bool("watch", false, "Keep watching the directory as a hot folder")
+	interval := flag.Duration("interval", 10*time.Second, "Hot folder polling interval")
+	manifest := flag.String("manifest", "", "CSV or JSON manifest of the titles and covers (default: manifest.csv or manifest.json in the directory)")
+	concurrency := flag.Int("concurrency", 4, "Maximum number of uploads at the same time")
+	report := flag.String("report", "ingest-report.json", "Report of the created videos, title conflicts and failures")
+	flag.Parse()
+
+	if *dir == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.NewConfig()
+	if err != nil {
+		log.Fatal("Failed to parse Env var ", err)
+	}
+	if cfg.DevMode {
+		log.SetLevel(log.DebugLevel)
+	}
+	if cfg.APIKey == "" && (cfg.UserAuth == "" || cfg.PwdAuth == "") {
+		log.Fatal("API_KEY, or USER_AUTH and PWD_AUTH, are required")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	uploader := ingest.Uploader{
+		Client:   &http.Client{},
+		APIURL:   cfg.APIURL,
+		APIKey:   cfg.APIKey,
+		UserAuth: cfg.UserAuth,
+		PwdAuth:  cfg.PwdAuth,
+	}
+	ingester := ingest.NewIngester(*dir, *manifest, *report, *concurrency, uploader)
+
+	if *watch {
+		log.Infof("Watching %v every %v", *dir, *interval)
+		if err := ingester.Watch(ctx, *interval); err != nil {
+			log.Fatal("Failed to watch directory ", err)
+		}
+		return
+	}
+
+	log.Info("Ingesting ", *dir)
+	if err := ingester.Once(ctx); err != nil {
+		log.Fatal("Failed to ingest directory ", err)
+	}
+
+	log.Infof("%v videos created, %v title conflicts, %v already uploaded, %v failures - report written in %v",
+		len(ingester.Report.Created), len(ingester.Report.Conflicts), len(ingester.Report.Duplicates), len(ingester.Report.Failures), *report)
+	if ingester.Report.HasFailures() {
+		os.Exit(1)
+	}
+}
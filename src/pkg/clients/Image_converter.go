@@ -0,0 +1,22 @@
+package clients
+
+import (
+	"github.com/Sogilis/Voogle/src/pkg/ffmpeg"
+)
+
+type IImageConverter interface {
+	// ConvertImg crops the image to a square and scales it, as the encoder does for covers
+	ConvertImg(srcpath string, dstpath string) error
+}
+
+var _ IImageConverter = &imageConverter{}
+
+type imageConverter struct{}
+
+func NewImageConverter() IImageConverter {
+	return &imageConverter{}
+}
+
+func (c *imageConverter) ConvertImg(srcpath string, dstpath string) error {
+	return ffmpeg.ConvertImg(srcpath, dstpath)
+}
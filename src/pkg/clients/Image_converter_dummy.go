@@ -0,0 +1,18 @@
+package clients
+
+var _ IImageConverter = &imageConverterDummy{}
+
+type imageConverterDummy struct {
+	convertImg func(srcpath string, dstpath string) error
+}
+
+func NewImageConverterDummy(convertImg func(srcpath string, dstpath string) error) IImageConverter {
+	return &imageConverterDummy{convertImg}
+}
+
+func (c *imageConverterDummy) ConvertImg(srcpath string, dstpath string) error {
+	if c.convertImg != nil {
+		return c.convertImg(srcpath, dstpath)
+	}
+	return nil
+}
@@ -3,13 +3,17 @@ package clients
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -19,6 +23,15 @@ type IS3Client interface {
 	PutObjectInput(ctx context.Context, f io.Reader, path string) error
 	CreateBucketIfDoesNotExists(ctx context.Context, bucketName string) error
 	RemoveObject(ctx context.Context, path string) error
+	CreateMultipartUpload(ctx context.Context, path string) (string, error)
+	UploadPart(ctx context.Context, f io.Reader, size int64, path, uploadID string, partNumber int32) error
+	CompleteMultipartUpload(ctx context.Context, path, uploadID string) error
+	AbortMultipartUpload(ctx context.Context, path, uploadID string) error
+	PresignPutObject(ctx context.Context, path string, expires time.Duration) (string, error)
+	ObjectExists(ctx context.Context, path string) (bool, error)
+	ObjectsSize(ctx context.Context, path string) (int64, error)
+	ListObjectsWithPrefix(ctx context.Context, prefix string) ([]string, error)
+	RemoveObjectsWithPrefix(ctx context.Context, prefix string) error
 }
 
 var _ IS3Client = s3Client{}
@@ -140,34 +153,211 @@ func (s s3Client) CreateBucketIfDoesNotExists(ctx context.Context, bucketName st
 	return err
 }
 
+// RemoveObject removes the object with this exact key
 func (s s3Client) RemoveObject(ctx context.Context, path string) error {
-	// Retrieve all files in directory
-	results, err := s.awsS3Client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+	_, err := s.awsS3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(s.bucket),
-		Prefix: aws.String(path),
+		Key:    aws.String(path),
 	})
+	return err
+}
+
+// ListObjectsWithPrefix returns the keys of all the objects under prefix
+func (s s3Client) ListObjectsWithPrefix(ctx context.Context, prefix string) ([]string, error) {
+	return listObjectsWithPrefix(ctx, s.awsS3Client, s.bucket, prefix)
+}
+
+// RemoveObjectsWithPrefix removes all the objects under prefix, by batches of 1000 objects
+func (s s3Client) RemoveObjectsWithPrefix(ctx context.Context, prefix string) error {
+	return removeObjectsWithPrefix(ctx, s.awsS3Client, s.bucket, prefix)
+}
+
+// s3ObjectsAPI is the part of the S3 API used to list and remove objects
+type s3ObjectsAPI interface {
+	s3.ListObjectsV2APIClient
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+}
+
+func listObjectsWithPrefix(ctx context.Context, api s3.ListObjectsV2APIClient, bucket, prefix string) ([]string, error) {
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(api, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, content := range page.Contents {
+			keys = append(keys, *content.Key)
+		}
+	}
+
+	return keys, nil
+}
+
+// S3 deletes at most 1000 objects per request
+const maxDeleteObjects = 1000
+
+func removeObjectsWithPrefix(ctx context.Context, api s3ObjectsAPI, bucket, prefix string) error {
+	keys, err := listObjectsWithPrefix(ctx, api, bucket, prefix)
 	if err != nil {
 		return err
 	}
 
-	for _, content := range results.Contents {
-		// If we can go deeper in the filesystem, do it to remove all objects in
-		// directory
-		if *content.Key != path {
-			err := s.RemoveObject(ctx, *content.Key)
-			if err != nil {
-				return err
-			}
+	for start := 0; start < len(keys); start += maxDeleteObjects {
+		end := start + maxDeleteObjects
+		if end > len(keys) {
+			end = len(keys)
 		}
 
-		_, err = s.awsS3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
-			Bucket: aws.String(s.bucket),
-			Key:    content.Key,
+		objects := make([]types.ObjectIdentifier, 0, end-start)
+		for _, key := range keys[start:end] {
+			objects = append(objects, types.ObjectIdentifier{Key: aws.String(key)})
+		}
+
+		output, err := api.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &types.Delete{Objects: objects, Quiet: true},
 		})
 		if err != nil {
 			return err
 		}
+
+		// Objects that cannot be deleted do not fail the whole request
+		if len(output.Errors) > 0 {
+			failed := output.Errors[0]
+			return fmt.Errorf("cannot remove %d objects under %v, first one %v : %v",
+				len(output.Errors), prefix, aws.ToString(failed.Key), aws.ToString(failed.Message))
+		}
 	}
 
 	return nil
 }
+
+// CreateMultipartUpload starts a new multipart upload on path and returns its S3 upload ID
+func (s s3Client) CreateMultipartUpload(ctx context.Context, path string) (string, error) {
+	output, err := s.awsS3Client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if output.UploadId == nil {
+		return "", errors.New("no upload ID returned for multipart upload " + path)
+	}
+
+	return *output.UploadId, nil
+}
+
+// UploadPart streams one part of size bytes of a multipart upload. The payload is not
+// signed, so that the part does not need to be read twice nor kept in memory.
+func (s s3Client) UploadPart(ctx context.Context, f io.Reader, size int64, path, uploadID string, partNumber int32) error {
+	_, err := s.awsS3Client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(path),
+		UploadId:      aws.String(uploadID),
+		PartNumber:    partNumber,
+		ContentLength: size,
+		Body:          f,
+	}, s3.WithAPIOptions(v4.SwapComputePayloadSHA256ForUnsignedPayloadMiddleware))
+	return err
+}
+
+// CompleteMultipartUpload assembles all the parts already sent for uploadID into the final object
+func (s s3Client) CompleteMultipartUpload(ctx context.Context, path, uploadID string) error {
+	var parts []types.CompletedPart
+
+	paginator := s3.NewListPartsPaginator(s.awsS3Client, &s3.ListPartsInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(path),
+		UploadId: aws.String(uploadID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, part := range page.Parts {
+			parts = append(parts, types.CompletedPart{
+				ETag:       part.ETag,
+				PartNumber: part.PartNumber,
+			})
+		}
+	}
+
+	_, err := s.awsS3Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(path),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	return err
+}
+
+// AbortMultipartUpload drops a multipart upload and all the parts already sent
+func (s s3Client) AbortMultipartUpload(ctx context.Context, path, uploadID string) error {
+	_, err := s.awsS3Client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(path),
+		UploadId: aws.String(uploadID),
+	})
+	return err
+}
+
+// PresignPutObject returns an URL allowing anyone to PUT the object on path, without credentials, until it expires
+func (s s3Client) PresignPutObject(ctx context.Context, path string, expires time.Duration) (string, error) {
+	presignClient := s3.NewPresignClient(s.awsS3Client)
+
+	request, err := presignClient.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", err
+	}
+
+	return request.URL, nil
+}
+
+func (s s3Client) ObjectExists(ctx context.Context, path string) (bool, error) {
+	_, err := s.awsS3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ObjectsSize returns the total size in bytes of all the objects under path
+func (s s3Client) ObjectsSize(ctx context.Context, path string) (int64, error) {
+	var size int64
+	paginator := s3.NewListObjectsV2Paginator(s.awsS3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(path),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, content := range page.Contents {
+			size += content.Size
+		}
+	}
+
+	return size, nil
+}
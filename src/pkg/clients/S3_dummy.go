@@ -3,20 +3,60 @@ package clients
 import (
 	"context"
 	"io"
+	"time"
 )
 
 var _ IS3Client = s3ClientDummy{}
 
 type s3ClientDummy struct {
-	listObjects    func() ([]string, error)
-	getObject      func(id string) (io.Reader, error)
-	putObjectInput func(f io.Reader, title string) error
-	createBucket   func(n string) error
-	removeObject   func(id string) error
+	listObjects             func() ([]string, error)
+	getObject               func(id string) (io.Reader, error)
+	putObjectInput          func(f io.Reader, title string) error
+	createBucket            func(n string) error
+	removeObject            func(id string) error
+	createMultipartUpload   func(path string) (string, error)
+	uploadPart              func(f io.Reader, size int64, path, uploadID string, partNumber int32) error
+	completeMultipartUpload func(path, uploadID string) error
+	abortMultipartUpload    func(path, uploadID string) error
+	presignPutObject        func(path string, expires time.Duration) (string, error)
+	objectExists            func(path string) (bool, error)
+	objectsSize             func(path string) (int64, error)
+	listObjectsWithPrefix   func(prefix string) ([]string, error)
+	removeObjectsWithPrefix func(prefix string) error
 }
 
-func NewS3ClientDummy(listObjects func() ([]string, error), getObject func(string) (io.Reader, error), putObjectInput func(io.Reader, string) error, createBucket func(n string) error, removeObject func(id string) error) IS3Client {
-	return s3ClientDummy{listObjects, getObject, putObjectInput, createBucket, removeObject}
+func NewS3ClientDummy(
+	listObjects func() ([]string, error),
+	getObject func(string) (io.Reader, error),
+	putObjectInput func(io.Reader, string) error,
+	createBucket func(n string) error,
+	removeObject func(id string) error,
+	createMultipartUpload func(path string) (string, error),
+	uploadPart func(f io.Reader, size int64, path, uploadID string, partNumber int32) error,
+	completeMultipartUpload func(path, uploadID string) error,
+	abortMultipartUpload func(path, uploadID string) error,
+	presignPutObject func(path string, expires time.Duration) (string, error),
+	objectExists func(path string) (bool, error),
+	objectsSize func(path string) (int64, error),
+	listObjectsWithPrefix func(prefix string) ([]string, error),
+	removeObjectsWithPrefix func(prefix string) error,
+) IS3Client {
+	return s3ClientDummy{
+		listObjects,
+		getObject,
+		putObjectInput,
+		createBucket,
+		removeObject,
+		createMultipartUpload,
+		uploadPart,
+		completeMultipartUpload,
+		abortMultipartUpload,
+		presignPutObject,
+		objectExists,
+		objectsSize,
+		listObjectsWithPrefix,
+		removeObjectsWithPrefix,
+	}
 }
 
 func (s s3ClientDummy) ListObjects(ctx context.Context) ([]string, error) {
@@ -38,3 +78,39 @@ func (s s3ClientDummy) CreateBucketIfDoesNotExists(ctx context.Context, bucketNa
 func (s s3ClientDummy) RemoveObject(ctx context.Context, id string) error {
 	return s.removeObject(id)
 }
+
+func (s s3ClientDummy) CreateMultipartUpload(ctx context.Context, path string) (string, error) {
+	return s.createMultipartUpload(path)
+}
+
+func (s s3ClientDummy) UploadPart(ctx context.Context, f io.Reader, size int64, path, uploadID string, partNumber int32) error {
+	return s.uploadPart(f, size, path, uploadID, partNumber)
+}
+
+func (s s3ClientDummy) CompleteMultipartUpload(ctx context.Context, path, uploadID string) error {
+	return s.completeMultipartUpload(path, uploadID)
+}
+
+func (s s3ClientDummy) AbortMultipartUpload(ctx context.Context, path, uploadID string) error {
+	return s.abortMultipartUpload(path, uploadID)
+}
+
+func (s s3ClientDummy) PresignPutObject(ctx context.Context, path string, expires time.Duration) (string, error) {
+	return s.presignPutObject(path, expires)
+}
+
+func (s s3ClientDummy) ObjectExists(ctx context.Context, path string) (bool, error) {
+	return s.objectExists(path)
+}
+
+func (s s3ClientDummy) ObjectsSize(ctx context.Context, path string) (int64, error) {
+	return s.objectsSize(path)
+}
+
+func (s s3ClientDummy) ListObjectsWithPrefix(ctx context.Context, prefix string) ([]string, error) {
+	return s.listObjectsWithPrefix(prefix)
+}
+
+func (s s3ClientDummy) RemoveObjectsWithPrefix(ctx context.Context, prefix string) error {
+	return s.removeObjectsWithPrefix(prefix)
+}
@@ -0,0 +1,146 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/require"
+)
+
+// s3ObjectsFake lists its keys by pages of 1000, like S3, and records the deleted batches
+type s3ObjectsFake struct {
+	keys         []string
+	failedKeys   map[string]bool
+	deleteErr    error
+	listRequests int
+	batches      [][]string
+}
+
+func (f *s3ObjectsFake) ListObjectsV2(_ context.Context, params *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	f.listRequests++
+
+	start := 0
+	if params.ContinuationToken != nil {
+		start, _ = strconv.Atoi(*params.ContinuationToken)
+	}
+	end := start + 1000
+	if end > len(f.keys) {
+		end = len(f.keys)
+	}
+
+	output := &s3.ListObjectsV2Output{IsTruncated: end < len(f.keys)}
+	for _, key := range f.keys[start:end] {
+		output.Contents = append(output.Contents, types.Object{Key: aws.String(key)})
+	}
+	if output.IsTruncated {
+		output.NextContinuationToken = aws.String(strconv.Itoa(end))
+	}
+
+	return output, nil
+}
+
+func (f *s3ObjectsFake) DeleteObjects(_ context.Context, params *s3.DeleteObjectsInput, _ ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	if f.deleteErr != nil {
+		return nil, f.deleteErr
+	}
+
+	output := &s3.DeleteObjectsOutput{}
+	batch := []string{}
+	for _, object := range params.Delete.Objects {
+		batch = append(batch, *object.Key)
+		if f.failedKeys[*object.Key] {
+			output.Errors = append(output.Errors, types.Error{Key: object.Key, Message: aws.String("Access Denied")})
+		}
+	}
+	f.batches = append(f.batches, batch)
+
+	return output, nil
+}
+
+func Test_RemoveObjectsWithPrefix(t *testing.T) {
+	keys := make([]string, 2500)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("video/segment%d.ts", i)
+	}
+
+	cases := []struct {
+		Name              string
+		GivenKeys         []string
+		GivenFailedKeys   []string
+		GivenDeleteErr    error
+		ExpectListPages   int
+		ExpectBatchesSize []int
+		ExpectError       bool
+	}{
+		{
+			Name:              "No objects",
+			ExpectListPages:   1,
+			ExpectBatchesSize: []int{},
+		},
+		{
+			Name:              "Objects on several pages, removed by batches of 1000",
+			GivenKeys:         keys,
+			ExpectListPages:   3,
+			ExpectBatchesSize: []int{1000, 1000, 500},
+		},
+		{
+			Name:              "Objects not removed in the second batch",
+			GivenKeys:         keys,
+			GivenFailedKeys:   []string{"video/segment1500.ts", "video/segment1999.ts"},
+			ExpectListPages:   3,
+			ExpectBatchesSize: []int{1000, 1000},
+			ExpectError:       true,
+		},
+		{
+			Name:              "Delete request fails",
+			GivenKeys:         keys,
+			GivenDeleteErr:    fmt.Errorf("S3 error"),
+			ExpectListPages:   3,
+			ExpectBatchesSize: []int{},
+			ExpectError:       true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			fake := &s3ObjectsFake{
+				keys:       tt.GivenKeys,
+				failedKeys: map[string]bool{},
+				deleteErr:  tt.GivenDeleteErr,
+				batches:    [][]string{},
+			}
+			for _, key := range tt.GivenFailedKeys {
+				fake.failedKeys[key] = true
+			}
+
+			err := removeObjectsWithPrefix(context.Background(), fake, "bucket", "video/")
+			if tt.ExpectError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			if len(tt.GivenFailedKeys) > 0 {
+				require.Contains(t, err.Error(), "cannot remove 2 objects under video/, first one video/segment1500.ts")
+			}
+
+			require.Equal(t, tt.ExpectListPages, fake.listRequests)
+
+			batchesSize := []int{}
+			removed := []string{}
+			for _, batch := range fake.batches {
+				batchesSize = append(batchesSize, len(batch))
+				removed = append(removed, batch...)
+			}
+			require.Equal(t, tt.ExpectBatchesSize, batchesSize)
+			if !tt.ExpectError {
+				require.Equal(t, len(tt.GivenKeys), len(removed))
+				require.Subset(t, removed, tt.GivenKeys)
+			}
+		})
+	}
+}
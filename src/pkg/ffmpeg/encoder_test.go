@@ -13,6 +13,7 @@ func Test_GenerateCommand(t *testing.T) {
 		Name            string
 		GivenFilePath   string
 		GivenResolution resolution
+		GivenAudio      []AudioStream
 		ExpectCommand   string
 		ExpectArgs      string
 		ExpectError     bool
@@ -28,39 +29,69 @@ func Test_GenerateCommand(t *testing.T) {
 			Name:            "With resolution: 640x480",
 			GivenFilePath:   "someName.mp4",
 			GivenResolution: resolution{x: 640, y: 480},
+			GivenAudio:      []AudioStream{{Index: 1, Language: "und"}},
 			ExpectCommand:   "ffmpeg",
-			ExpectArgs:      "-y -i someName.mp4 -pix_fmt yuv420p -vcodec libx264 -preset fast -g 48 -sc_threshold 0 -map 0:0 -map 0:1 -s:v:0 640x480 -c:v:0 libx264 -b:v:0 1000k -c:a aac -b:a 128k -ac 2 -var_stream_map v:0,a:0 -master_pl_name master.m3u8 -f hls -hls_time 6 -hls_list_size 0 -hls_segment_filename v%v/segment%d.ts v%v/segment_index.m3u8",
+			ExpectArgs:      "-y -i someName.mp4 -pix_fmt yuv420p -vcodec libx264 -preset fast -g 48 -sc_threshold 0 -map 0:v:0 -map 0:1 -s:v:0 640x480 -c:v:0 libx264 -b:v:0 1000k -c:a aac -b:a 128k -ac 2 -var_stream_map v:0,a:0 -master_pl_name master.m3u8 -f hls -hls_time 6 -hls_list_size 0 -hls_segment_filename v%v/segment%d.ts v%v/segment_index.m3u8",
 			ExpectError:     false,
 		},
 		{
 			Name:            "With resolution: 1280x720",
 			GivenFilePath:   "someName.mp4",
 			GivenResolution: resolution{x: 1280, y: 720},
+			GivenAudio:      []AudioStream{{Index: 1, Language: "und"}},
 			ExpectCommand:   "ffmpeg",
-			ExpectArgs:      "-y -i someName.mp4 -pix_fmt yuv420p -vcodec libx264 -preset fast -g 48 -sc_threshold 0 -map 0:0 -map 0:1 -map 0:0 -map 0:1 -s:v:0 640x480 -c:v:0 libx264 -b:v:0 1000k -s:v:1 1280x720 -c:v:1 libx264 -b:v:1 2000k -c:a aac -b:a 128k -ac 2 -var_stream_map v:0,a:0 v:1,a:1 -master_pl_name master.m3u8 -f hls -hls_time 6 -hls_list_size 0 -hls_segment_filename v%v/segment%d.ts v%v/segment_index.m3u8",
+			ExpectArgs:      "-y -i someName.mp4 -pix_fmt yuv420p -vcodec libx264 -preset fast -g 48 -sc_threshold 0 -map 0:v:0 -map 0:1 -map 0:v:0 -map 0:1 -s:v:0 640x480 -c:v:0 libx264 -b:v:0 1000k -s:v:1 1280x720 -c:v:1 libx264 -b:v:1 2000k -c:a aac -b:a 128k -ac 2 -var_stream_map v:0,a:0 v:1,a:1 -master_pl_name master.m3u8 -f hls -hls_time 6 -hls_list_size 0 -hls_segment_filename v%v/segment%d.ts v%v/segment_index.m3u8",
 			ExpectError:     false,
 		},
 		{
 			Name:            "With resolution 1920x1080",
 			GivenFilePath:   "someName.mp4",
 			GivenResolution: resolution{x: 1920, y: 1080},
+			GivenAudio:      []AudioStream{{Index: 1, Language: "und"}},
 			ExpectCommand:   "ffmpeg",
-			ExpectArgs:      "-y -i someName.mp4 -pix_fmt yuv420p -vcodec libx264 -preset fast -g 48 -sc_threshold 0 -map 0:0 -map 0:1 -map 0:0 -map 0:1 -map 0:0 -map 0:1 -s:v:0 640x480 -c:v:0 libx264 -b:v:0 1000k -s:v:1 1280x720 -c:v:1 libx264 -b:v:1 2000k -s:v:2 1920x1080 -c:v:2 libx264 -b:v:2 4000k -c:a aac -b:a 128k -ac 2 -var_stream_map v:0,a:0 v:1,a:1 v:2,a:2 -master_pl_name master.m3u8 -f hls -hls_time 6 -hls_list_size 0 -hls_segment_filename v%v/segment%d.ts v%v/segment_index.m3u8",
+			ExpectArgs:      "-y -i someName.mp4 -pix_fmt yuv420p -vcodec libx264 -preset fast -g 48 -sc_threshold 0 -map 0:v:0 -map 0:1 -map 0:v:0 -map 0:1 -map 0:v:0 -map 0:1 -s:v:0 640x480 -c:v:0 libx264 -b:v:0 1000k -s:v:1 1280x720 -c:v:1 libx264 -b:v:1 2000k -s:v:2 1920x1080 -c:v:2 libx264 -b:v:2 4000k -c:a aac -b:a 128k -ac 2 -var_stream_map v:0,a:0 v:1,a:1 v:2,a:2 -master_pl_name master.m3u8 -f hls -hls_time 6 -hls_list_size 0 -hls_segment_filename v%v/segment%d.ts v%v/segment_index.m3u8",
 			ExpectError:     false,
 		},
 		{
 			Name:            "With resolution 3840x2160",
 			GivenFilePath:   "someName.mp4",
 			GivenResolution: resolution{x: 3840, y: 2160},
+			GivenAudio:      []AudioStream{{Index: 1, Language: "und"}},
 			ExpectCommand:   "ffmpeg",
-			ExpectArgs:      "-y -i someName.mp4 -pix_fmt yuv420p -vcodec libx264 -preset fast -g 48 -sc_threshold 0 -map 0:0 -map 0:1 -map 0:0 -map 0:1 -map 0:0 -map 0:1 -map 0:0 -map 0:1 -s:v:0 640x480 -c:v:0 libx264 -b:v:0 1000k -s:v:1 1280x720 -c:v:1 libx264 -b:v:1 2000k -s:v:2 1920x1080 -c:v:2 libx264 -b:v:2 4000k -s:v:3 3840x2160 -c:v:3 libx264 -b:v:3 8000k -c:a aac -b:a 128k -ac 2 -var_stream_map v:0,a:0 v:1,a:1 v:2,a:2 v:3,a:3 -master_pl_name master.m3u8 -f hls -hls_time 6 -hls_list_size 0 -hls_segment_filename v%v/segment%d.ts v%v/segment_index.m3u8",
+			ExpectArgs:      "-y -i someName.mp4 -pix_fmt yuv420p -vcodec libx264 -preset fast -g 48 -sc_threshold 0 -map 0:v:0 -map 0:1 -map 0:v:0 -map 0:1 -map 0:v:0 -map 0:1 -map 0:v:0 -map 0:1 -s:v:0 640x480 -c:v:0 libx264 -b:v:0 1000k -s:v:1 1280x720 -c:v:1 libx264 -b:v:1 2000k -s:v:2 1920x1080 -c:v:2 libx264 -b:v:2 4000k -s:v:3 3840x2160 -c:v:3 libx264 -b:v:3 8000k -c:a aac -b:a 128k -ac 2 -var_stream_map v:0,a:0 v:1,a:1 v:2,a:2 v:3,a:3 -master_pl_name master.m3u8 -f hls -hls_time 6 -hls_list_size 0 -hls_segment_filename v%v/segment%d.ts v%v/segment_index.m3u8",
+			ExpectError:     false,
+		},
+		{
+			Name:            "With a single audio language after other streams",
+			GivenFilePath:   "someName.mp4",
+			GivenResolution: resolution{x: 640, y: 480},
+			GivenAudio:      []AudioStream{{Index: 2, Language: "eng"}},
+			ExpectCommand:   "ffmpeg",
+			ExpectArgs:      "-y -i someName.mp4 -pix_fmt yuv420p -vcodec libx264 -preset fast -g 48 -sc_threshold 0 -map 0:v:0 -map 0:2 -s:v:0 640x480 -c:v:0 libx264 -b:v:0 1000k -c:a aac -b:a 128k -ac 2 -var_stream_map v:0,a:0 -master_pl_name master.m3u8 -f hls -hls_time 6 -hls_list_size 0 -hls_segment_filename v%v/segment%d.ts v%v/segment_index.m3u8",
+			ExpectError:     false,
+		},
+		{
+			Name:            "Without audio",
+			GivenFilePath:   "someName.mp4",
+			GivenResolution: resolution{x: 1280, y: 720},
+			ExpectCommand:   "ffmpeg",
+			ExpectArgs:      "-y -i someName.mp4 -pix_fmt yuv420p -vcodec libx264 -preset fast -g 48 -sc_threshold 0 -map 0:v:0 -map 0:v:0 -s:v:0 640x480 -c:v:0 libx264 -b:v:0 1000k -s:v:1 1280x720 -c:v:1 libx264 -b:v:1 2000k -c:a aac -b:a 128k -ac 2 -var_stream_map v:0 v:1 -master_pl_name master.m3u8 -f hls -hls_time 6 -hls_list_size 0 -hls_segment_filename v%v/segment%d.ts v%v/segment_index.m3u8",
+			ExpectError:     false,
+		},
+		{
+			Name:            "With resolution 1280x720 and two audio languages",
+			GivenFilePath:   "someName.mp4",
+			GivenResolution: resolution{x: 1280, y: 720},
+			GivenAudio:      []AudioStream{{Index: 1, Language: "eng"}, {Index: 3, Language: "fre"}},
+			ExpectCommand:   "ffmpeg",
+			ExpectArgs:      "-y -i someName.mp4 -pix_fmt yuv420p -vcodec libx264 -preset fast -g 48 -sc_threshold 0 -map 0:v:0 -map 0:v:0 -map 0:1 -map 0:3 -s:v:0 640x480 -c:v:0 libx264 -b:v:0 1000k -s:v:1 1280x720 -c:v:1 libx264 -b:v:1 2000k -c:a aac -b:a 128k -ac 2 -var_stream_map v:0,agroup:audio,name:v0 v:1,agroup:audio,name:v1 a:0,agroup:audio,language:eng,name:audio_eng,default:yes a:1,agroup:audio,language:fre,name:audio_fre -master_pl_name master.m3u8 -f hls -hls_time 6 -hls_list_size 0 -hls_segment_filename %v/segment%d.ts %v/segment_index.m3u8",
 			ExpectError:     false,
 		},
 	}
 
 	for _, tt := range cases {
 		t.Run(tt.Name, func(t *testing.T) {
-			cmd, args, err := generateCommand(tt.GivenFilePath, tt.GivenResolution)
+			cmd, args, err := generateCommand(tt.GivenFilePath, tt.GivenResolution, tt.GivenAudio)
 			if tt.ExpectError {
 				require.NotNil(t, err)
 				return
@@ -92,7 +123,8 @@ func Test_convertToHLS(t *testing.T) {
 		t.Run(tt.Name, func(t *testing.T) {
 			_ = os.Mkdir("tmpVideoTest", os.ModePerm)
 			_ = os.Chdir("tmpVideoTest")
-			err := ConvertToHLS(tt.GivenFilePath, tt.GivenResolution)
+			audioStreams, _ := ExtractAudioStreams(tt.GivenFilePath)
+			err := ConvertToHLS(tt.GivenFilePath, tt.GivenResolution, audioStreams)
 			if tt.ExpectError {
 				require.NotNil(t, err)
 				return
@@ -8,8 +8,11 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func ConvertToHLS(source string, res resolution) error {
-	cmd, args, err := generateCommand(source, res)
+// AudioVariantPrefix starts the folder names of the audio renditions, when the video has several languages
+const AudioVariantPrefix = "audio_"
+
+func ConvertToHLS(source string, res resolution, audioStreams []AudioStream) error {
+	cmd, args, err := generateCommand(source, res, audioStreams)
 	if err != nil {
 		return err
 	}
@@ -20,14 +23,14 @@ func ConvertToHLS(source string, res resolution) error {
 	return err
 }
 
-func generateCommand(filepath string, res resolution) (string, []string, error) {
+func generateCommand(filepath string, res resolution, audioStreams []AudioStream) (string, []string, error) {
 	// Example of the biggest command that can be generated
 	// ffmpeg -y -i <filepath> \
 	//              -pix_fmt yuv420p \
 	//              -vcodec libx264 \
 	//              -preset fast \
 	//              -g 48 -sc_threshold 0 \
-	//              -map 0:0 -map 0:1 -map 0:0 -map 0:1 -map 0:0 -map 0:1 -map 0:0 -map 0:1 \
+	//              -map 0:v:0 -map 0:1 -map 0:v:0 -map 0:1 -map 0:v:0 -map 0:1 -map 0:v:0 -map 0:1 \
 	//              -s:v:0 640x480 -c:v:0 libx264 -b:v:0 1000k \
 	//              -s:v:1 1280x720 -c:v:1 libx264 -b:v:1 2000k  \
 	//              -s:v:2 1920x1080 -c:v:2 libx264 -b:v:2 4000k  \
@@ -38,6 +41,14 @@ func generateCommand(filepath string, res resolution) (string, []string, error)
 	//              -f hls -hls_time 6 -hls_list_size 0 \
 	//              -hls_segment_filename "v%v/segment%d.ts" \
 	//              v%v/segment_index.m3u8
+	//
+	// 0:1 is the probed index of the audio stream. Without audio, only the video is mapped : -var_stream_map "v:0 v:1 ..."
+	//
+	// With several audio languages, each language is an audio rendition shared by the video variants instead :
+	//              -map 0:v:0 (per video variant) -map 0:<audio index> (per language) \
+	//              -var_stream_map "v:0,agroup:audio,name:v0 ... a:0,agroup:audio,language:eng,name:audio_eng,default:yes ..." \
+	//              -hls_segment_filename "%v/segment%d.ts" \
+	//              %v/segment_index.m3u8
 
 	if res.x < 640 && res.y < 480 {
 		return "", nil, fmt.Errorf("resolution (%d,%d) is below minimal resolution (640x480)", res.x, res.y)
@@ -45,31 +56,73 @@ func generateCommand(filepath string, res resolution) (string, []string, error)
 
 	command := "ffmpeg"
 	args := []string{"-y", "-i", filepath, "-pix_fmt", "yuv420p", "-vcodec", "libx264", "-preset", "fast", "-g", "48", "-sc_threshold", "0"}
-	sound := []string{"-map", "0:0", "-map", "0:1"}
 	resolutionTarget := []string{"-s:v:0", "640x480", "-c:v:0", "libx264", "-b:v:0", "1000k"}
-	streamMap := "v:0,a:0"
+	variants := 1
 
 	if res.GreaterOrEqualResolution(resolution{1280, 720}) {
-		sound = append(sound, "-map", "0:0", "-map", "0:1")
 		resolutionTarget = append(resolutionTarget, "-s:v:1", "1280x720", "-c:v:1", "libx264", "-b:v:1", "2000k")
-		streamMap = streamMap + " v:1,a:1"
+		variants++
 	}
 	if res.GreaterOrEqualResolution(resolution{1920, 1080}) {
-		sound = append(sound, "-map", "0:0", "-map", "0:1")
 		resolutionTarget = append(resolutionTarget, "-s:v:2", "1920x1080", "-c:v:2", "libx264", "-b:v:2", "4000k")
-		streamMap = streamMap + " v:2,a:2"
+		variants++
 	}
 	if res.GreaterOrEqualResolution(resolution{3840, 2160}) {
-		sound = append(sound, "-map", "0:0", "-map", "0:1")
 		resolutionTarget = append(resolutionTarget, "-s:v:3", "3840x2160", "-c:v:3", "libx264", "-b:v:3", "8000k")
-		streamMap = streamMap + " v:3,a:3"
+		variants++
+	}
+
+	sound, streamMap := muxedAudio(variants, audioStreams)
+	output := "v%v"
+	if len(audioStreams) > 1 {
+		sound, streamMap = audioRenditions(variants, audioStreams)
+		output = "%v"
 	}
 
 	args = append(args, sound...)
 	args = append(args, resolutionTarget...)
 	args = append(args, "-c:a", "aac", "-b:a", "128k", "-ac", "2")
 	args = append(args, "-var_stream_map", streamMap)
-	args = append(args, "-master_pl_name", "master.m3u8", "-f", "hls", "-hls_time", "6", "-hls_list_size", "0", "-hls_segment_filename", "v%v/segment%d.ts", "v%v/segment_index.m3u8")
+	args = append(args, "-master_pl_name", "master.m3u8", "-f", "hls", "-hls_time", "6", "-hls_list_size", "0", "-hls_segment_filename", output+"/segment%d.ts", output+"/segment_index.m3u8")
 
 	return command, args, nil
 }
+
+// muxedAudio returns the mapping and the stream map of the video variants, each one muxed with the audio stream
+// if the video has one. The streams are mapped on their type and probed index, whatever their order in the source.
+func muxedAudio(variants int, audioStreams []AudioStream) ([]string, string) {
+	maps := []string{}
+	streamMap := []string{}
+	for i := 0; i < variants; i++ {
+		maps = append(maps, "-map", "0:v:0")
+		if len(audioStreams) == 0 {
+			streamMap = append(streamMap, fmt.Sprintf("v:%d", i))
+			continue
+		}
+		maps = append(maps, "-map", fmt.Sprintf("0:%d", audioStreams[0].Index))
+		streamMap = append(streamMap, fmt.Sprintf("v:%d,a:%d", i, i))
+	}
+
+	return maps, strings.Join(streamMap, " ")
+}
+
+// audioRenditions returns the mapping and the stream map of the video variants sharing one audio
+// rendition per language, the first language being the default one
+func audioRenditions(variants int, audioStreams []AudioStream) ([]string, string) {
+	maps := []string{}
+	streamMap := []string{}
+	for i := 0; i < variants; i++ {
+		maps = append(maps, "-map", "0:v:0")
+		streamMap = append(streamMap, fmt.Sprintf("v:%d,agroup:audio,name:v%d", i, i))
+	}
+	for i, stream := range audioStreams {
+		maps = append(maps, "-map", fmt.Sprintf("0:%d", stream.Index))
+		audio := fmt.Sprintf("a:%d,agroup:audio,language:%s,name:%s%s", i, stream.Language, AudioVariantPrefix, stream.Language)
+		if i == 0 {
+			audio += ",default:yes"
+		}
+		streamMap = append(streamMap, audio)
+	}
+
+	return maps, strings.Join(streamMap, " ")
+}
@@ -1,11 +1,20 @@
 package ffmpeg
 
 import (
+	"encoding/json"
+	"math"
 	"os/exec"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
 
+// UndeterminedLanguage is the ISO 639 code of the audio streams without language
+const UndeterminedLanguage = "und"
+
+var languageRegex = regexp.MustCompile(`^[a-z]{2,3}$`)
+
 type resolution struct {
 	x uint64
 	y uint64
@@ -48,3 +57,98 @@ func ExtractResolution(filepath string) (resolution, error) {
 
 	return resolution{x, y}, nil
 }
+
+// AudioStream is an audio stream of a video, with the ISO 639 language of its tags
+type AudioStream struct {
+	Index    int
+	Language string
+}
+
+// Extract the audio streams of the video, one per language
+func ExtractAudioStreams(filepath string) ([]AudioStream, error) {
+	// ffprobe -v error -select_streams a -show_entries stream=index:stream_tags=language -of csv=p=0 <filepath>
+	rawOutput, err := exec.Command("ffprobe", "-v", "error", "-select_streams", "a", "-show_entries", "stream=index:stream_tags=language", "-of", "csv=p=0", filepath).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseAudioStreams(string(rawOutput[:]))
+}
+
+// parseAudioStreams reads the "index,language" lines of ffprobe. Streams without valid language are
+// undetermined ("und"), and only the first stream of each language is kept.
+func parseAudioStreams(output string) ([]AudioStream, error) {
+	streams := []AudioStream{}
+	languages := map[string]bool{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		rawIndex, language, _ := strings.Cut(line, ",")
+		index, err := strconv.Atoi(rawIndex)
+		if err != nil {
+			return nil, err
+		}
+		language = strings.ToLower(strings.TrimSpace(language))
+		if !languageRegex.MatchString(language) {
+			language = UndeterminedLanguage
+		}
+
+		if !languages[language] {
+			languages[language] = true
+			streams = append(streams, AudioStream{Index: index, Language: language})
+		}
+	}
+
+	return streams, nil
+}
+
+// Chapter is a chapter marker of a video, starting at Start seconds
+type Chapter struct {
+	Start float64
+	Title string
+}
+
+// Extract the chapter markers of the video metadata, sorted on their start
+func ExtractChapters(filepath string) ([]Chapter, error) {
+	// ffprobe -v error -show_chapters -of json <filepath>
+	rawOutput, err := exec.Command("ffprobe", "-v", "error", "-show_chapters", "-of", "json", filepath).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseChapters(rawOutput)
+}
+
+// parseChapters reads the json chapters of ffprobe. Chapters without title are numbered.
+func parseChapters(output []byte) ([]Chapter, error) {
+	var probe struct {
+		Chapters []struct {
+			StartTime string            `json:"start_time"`
+			Tags      map[string]string `json:"tags"`
+		} `json:"chapters"`
+	}
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return nil, err
+	}
+
+	chapters := make([]Chapter, 0, len(probe.Chapters))
+	for _, probeChapter := range probe.Chapters {
+		start, err := strconv.ParseFloat(probeChapter.StartTime, 64)
+		if err != nil {
+			return nil, err
+		}
+		chapters = append(chapters, Chapter{Start: math.Max(0, start), Title: strings.TrimSpace(probeChapter.Tags["title"])})
+	}
+
+	sort.SliceStable(chapters, func(i, j int) bool { return chapters[i].Start < chapters[j].Start })
+	for i := range chapters {
+		if chapters[i].Title == "" {
+			chapters[i].Title = "Chapter " + strconv.Itoa(i+1)
+		}
+	}
+
+	return chapters, nil
+}
@@ -87,3 +87,100 @@ func Test_videoHaveSound(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseAudioStreams(t *testing.T) {
+	cases := []struct {
+		Name          string
+		GivenOutput   string
+		ExpectStreams []AudioStream
+		ExpectError   bool
+	}{
+		{
+			Name:          "Several languages",
+			GivenOutput:   "1,eng\n2,FRE\n",
+			ExpectStreams: []AudioStream{{Index: 1, Language: "eng"}, {Index: 2, Language: "fre"}},
+		},
+		{
+			Name:          "Without language tag",
+			GivenOutput:   "1\n",
+			ExpectStreams: []AudioStream{{Index: 1, Language: UndeterminedLanguage}},
+		},
+		{
+			Name:          "Invalid language tag",
+			GivenOutput:   "1,../x\n",
+			ExpectStreams: []AudioStream{{Index: 1, Language: UndeterminedLanguage}},
+		},
+		{
+			Name:          "Same language twice",
+			GivenOutput:   "1,eng\n2,eng\n3,spa\n",
+			ExpectStreams: []AudioStream{{Index: 1, Language: "eng"}, {Index: 3, Language: "spa"}},
+		},
+		{
+			Name:          "Without audio",
+			GivenOutput:   "",
+			ExpectStreams: []AudioStream{},
+		},
+		{
+			Name:        "Invalid index",
+			GivenOutput: "a,eng\n",
+			ExpectError: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			streams, err := parseAudioStreams(tt.GivenOutput)
+			if tt.ExpectError {
+				require.NotNil(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.ExpectStreams, streams)
+		})
+	}
+}
+
+func Test_parseChapters(t *testing.T) {
+	cases := []struct {
+		Name           string
+		GivenOutput    string
+		ExpectChapters []Chapter
+		ExpectError    bool
+	}{
+		{
+			Name: "Chapters with and without title",
+			GivenOutput: `{"chapters": [
+				{"id": 1, "time_base": "1/1000", "start": 60500, "start_time": "60.500000", "end": 120000, "end_time": "120.000000"},
+				{"id": 0, "time_base": "1/1000", "start": 0, "start_time": "0.000000", "end": 60500, "end_time": "60.500000", "tags": {"title": " Intro "}}
+			]}`,
+			ExpectChapters: []Chapter{{Start: 0, Title: "Intro"}, {Start: 60.5, Title: "Chapter 2"}},
+		},
+		{
+			Name:           "Without chapter",
+			GivenOutput:    `{"chapters": []}`,
+			ExpectChapters: []Chapter{},
+		},
+		{
+			Name:        "Invalid start",
+			GivenOutput: `{"chapters": [{"start_time": "N/A"}]}`,
+			ExpectError: true,
+		},
+		{
+			Name:        "Invalid json",
+			GivenOutput: `chapters`,
+			ExpectError: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			chapters, err := parseChapters([]byte(tt.GivenOutput))
+			if tt.ExpectError {
+				require.NotNil(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.ExpectChapters, chapters)
+		})
+	}
+}
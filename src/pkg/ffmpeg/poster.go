@@ -0,0 +1,139 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"image"
+	_ "image/png"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Positions of the poster frame candidates, in fraction of the video duration.
+// The very beginning and end are skipped : they are often black or fading.
+var posterCandidates = []float64{0.2, 0.35, 0.5, 0.65, 0.8, 0.1, 0.9}
+
+// Luminance bounds (0-255) of a representative frame : darker frames are black or fading in,
+// brighter ones are white flashes, and low contrast ones are flat colors or fades.
+const (
+	minPosterLuminance = 30
+	maxPosterLuminance = 225
+	minPosterContrast  = 20
+)
+
+// ExtractPosterFrame writes a representative frame of the video as a PNG image. Candidate frames are
+// taken along the video, and the first one that is neither too dark, too bright nor too flat is kept.
+// If none is, the one with the most contrast is kept.
+func ExtractPosterFrame(srcpath string, dstpath string) error {
+	duration, err := ExtractDuration(srcpath)
+	if err != nil {
+		return err
+	}
+
+	candidatePath := func(i int) string { return "poster-candidate-" + strconv.Itoa(i) + ".png" }
+	defer func() {
+		for i := range posterCandidates {
+			_ = os.Remove(candidatePath(i))
+		}
+	}()
+
+	bestPath := ""
+	bestContrast := -1.0
+	for i, position := range posterCandidates {
+		framePath := candidatePath(i)
+
+		if err := ExtractFrame(srcpath, duration*position, framePath); err != nil {
+			log.Debug("Cannot extract poster candidate at ", duration*position, "s : ", err)
+			continue
+		}
+
+		luminance, contrast, err := frameLuminance(framePath)
+		if err != nil {
+			log.Debug("Cannot read poster candidate at ", duration*position, "s : ", err)
+			continue
+		}
+
+		if isRepresentativeFrame(luminance, contrast) {
+			log.Debug("Poster frame found at ", duration*position, "s")
+			return os.Rename(framePath, dstpath)
+		}
+
+		if contrast > bestContrast {
+			bestPath, bestContrast = framePath, contrast
+		}
+	}
+
+	if bestPath == "" {
+		return fmt.Errorf("no frame can be extracted from %v", srcpath)
+	}
+
+	log.Debug("No representative poster frame, keeping the one with the most contrast")
+	return os.Rename(bestPath, dstpath)
+}
+
+// ExtractDuration returns the duration of the video in seconds
+func ExtractDuration(filepath string) (float64, error) {
+	// ffprobe -v error -show_entries format=duration -of csv=p=0 <filepath>
+	rawOutput, err := exec.Command("ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "csv=p=0", filepath).Output()
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseFloat(strings.TrimSpace(string(rawOutput)), 64)
+}
+
+// ExtractFrame writes the frame of the video at the given position (in seconds) as an image,
+// scaled down to 480 pixels wide : enough for a cover, and cheap to analyze
+func ExtractFrame(srcpath string, position float64, dstpath string) error {
+	_, err := exec.Command("ffmpeg", "-y", "-ss", strconv.FormatFloat(position, 'f', 3, 64), "-i", srcpath,
+		"-frames:v", "1", "-vf", "scale='min(480,iw)':-2", dstpath).CombinedOutput()
+	return err
+}
+
+func isRepresentativeFrame(luminance, contrast float64) bool {
+	return luminance >= minPosterLuminance && luminance <= maxPosterLuminance && contrast >= minPosterContrast
+}
+
+// frameLuminance returns the mean and the standard deviation of the luminance (0-255) of the image
+func frameLuminance(path string) (float64, float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	mean, stddev := imageLuminance(img)
+	return mean, stddev, nil
+}
+
+func imageLuminance(img image.Image) (float64, float64) {
+	bounds := img.Bounds()
+	pixels := float64(bounds.Dx() * bounds.Dy())
+	if pixels == 0 {
+		return 0, 0
+	}
+
+	var sum, sumSquares float64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			// BT.601 luma, from 16 bits to 8 bits channels
+			luma := (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 257
+			sum += luma
+			sumSquares += luma * luma
+		}
+	}
+
+	mean := sum / pixels
+	variance := sumSquares/pixels - mean*mean
+	return mean, math.Sqrt(math.Max(variance, 0))
+}
@@ -0,0 +1,87 @@
+package ffmpeg
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PosterFrameHeuristic(t *testing.T) {
+	uniform := func(c color.Color) image.Image {
+		img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 16; x++ {
+				img.Set(x, y, c)
+			}
+		}
+		return img
+	}
+	checkerboard := func(dark, light color.Color) image.Image {
+		img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 16; x++ {
+				if (x+y)%2 == 0 {
+					img.Set(x, y, dark)
+				} else {
+					img.Set(x, y, light)
+				}
+			}
+		}
+		return img
+	}
+
+	cases := []struct {
+		Name             string
+		GivenImage       image.Image
+		ExpectLuminance  float64
+		ExpectContrast   float64
+		ExpectRepresents bool
+	}{
+		{
+			Name:             "Black frame",
+			GivenImage:       uniform(color.Black),
+			ExpectLuminance:  0,
+			ExpectContrast:   0,
+			ExpectRepresents: false,
+		},
+		{
+			Name:             "White frame",
+			GivenImage:       uniform(color.White),
+			ExpectLuminance:  255,
+			ExpectContrast:   0,
+			ExpectRepresents: false,
+		},
+		{
+			Name:             "Flat gray frame",
+			GivenImage:       uniform(color.Gray{Y: 128}),
+			ExpectLuminance:  128,
+			ExpectContrast:   0,
+			ExpectRepresents: false,
+		},
+		{
+			Name:             "Dark fading frame",
+			GivenImage:       checkerboard(color.Gray{Y: 0}, color.Gray{Y: 40}),
+			ExpectLuminance:  20,
+			ExpectContrast:   20,
+			ExpectRepresents: false,
+		},
+		{
+			Name:             "Contrasted frame",
+			GivenImage:       checkerboard(color.Gray{Y: 50}, color.Gray{Y: 200}),
+			ExpectLuminance:  125,
+			ExpectContrast:   75,
+			ExpectRepresents: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			luminance, contrast := imageLuminance(tt.GivenImage)
+			require.InDelta(t, tt.ExpectLuminance, luminance, 0.5)
+			require.InDelta(t, tt.ExpectContrast, contrast, 0.5)
+			require.Equal(t, tt.ExpectRepresents, isRepresentativeFrame(luminance, contrast))
+		})
+	}
+}
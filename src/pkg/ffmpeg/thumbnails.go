@@ -0,0 +1,84 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Scrubbing thumbnails : one thumbnail every ThumbnailInterval seconds, tiled in sprite sheets
+const (
+	ThumbnailInterval = 5
+	ThumbnailWidth    = 160
+	ThumbnailHeight   = 90
+	SpriteColumns     = 10
+	SpriteRows        = 10
+
+	ThumbnailsVTT = "thumbnails.vtt"
+)
+
+// SpriteName returns the file name of the index-th sprite sheet
+func SpriteName(index int) string {
+	return "sprite" + strconv.Itoa(index) + ".jpg"
+}
+
+// GenerateThumbnails writes the sprite sheets of the video and the WebVTT file mapping each time range
+// to its thumbnail in the sprites, in the given directory.
+func GenerateThumbnails(srcpath string, dir string) error {
+	duration, err := ExtractDuration(srcpath)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	// ffmpeg -i <srcpath> -vf "fps=1/5,scale=160:90:force_original_aspect_ratio=decrease,pad=160:90:(ow-iw)/2:(oh-ih)/2,tile=10x10" \
+	//        -q:v 5 -start_number 0 <dir>/sprite%d.jpg
+	filter := fmt.Sprintf("fps=1/%d,scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,tile=%dx%d",
+		ThumbnailInterval, ThumbnailWidth, ThumbnailHeight, ThumbnailWidth, ThumbnailHeight, SpriteColumns, SpriteRows)
+	rawOutput, err := exec.Command("ffmpeg", "-y", "-i", srcpath, "-vf", filter, "-q:v", "5", "-start_number", "0",
+		filepath.Join(dir, "sprite%d.jpg")).CombinedOutput()
+	log.Debug("FFMPEG output: ", string(rawOutput[:]))
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, ThumbnailsVTT), []byte(generateThumbnailsVTT(duration)), 0o644)
+}
+
+// generateThumbnailsVTT returns the WebVTT track of the thumbnails of a video of this duration (in seconds).
+// Each cue points to a region of a sprite sheet with a media fragment (#xywh=x,y,width,height).
+func generateThumbnailsVTT(duration float64) string {
+	var vtt strings.Builder
+	vtt.WriteString("WEBVTT\n")
+
+	perSprite := SpriteColumns * SpriteRows
+	count := int(math.Ceil(duration / ThumbnailInterval))
+	for i := 0; i < count; i++ {
+		start := float64(i * ThumbnailInterval)
+		end := math.Min(start+ThumbnailInterval, duration)
+
+		position := i % perSprite
+		x := (position % SpriteColumns) * ThumbnailWidth
+		y := (position / SpriteColumns) * ThumbnailHeight
+
+		fmt.Fprintf(&vtt, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			vttTimestamp(start), vttTimestamp(end), SpriteName(i/perSprite), x, y, ThumbnailWidth, ThumbnailHeight)
+	}
+
+	return vtt.String()
+}
+
+// vttTimestamp formats seconds as a WebVTT timestamp (hh:mm:ss.ttt)
+func vttTimestamp(seconds float64) string {
+	millis := int64(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", millis/3600000, millis/60000%60, millis/1000%60, millis%1000)
+}
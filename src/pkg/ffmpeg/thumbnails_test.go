@@ -0,0 +1,65 @@
+package ffmpeg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GenerateThumbnailsVTT(t *testing.T) {
+	cases := []struct {
+		Name          string
+		GivenDuration float64
+		ExpectCues    int
+		ExpectLines   []string
+	}{
+		{
+			Name:          "Shorter than one interval",
+			GivenDuration: 3.2,
+			ExpectCues:    1,
+			ExpectLines: []string{
+				"00:00:00.000 --> 00:00:03.200",
+				"sprite0.jpg#xywh=0,0,160,90",
+			},
+		},
+		{
+			Name:          "Thumbnails on several rows",
+			GivenDuration: 62,
+			ExpectCues:    13,
+			ExpectLines: []string{
+				"00:00:05.000 --> 00:00:10.000",
+				"sprite0.jpg#xywh=160,0,160,90",
+				"00:01:00.000 --> 00:01:02.000",
+				"sprite0.jpg#xywh=320,90,160,90",
+			},
+		},
+		{
+			Name:          "Thumbnails on several sprites",
+			GivenDuration: 3725,
+			ExpectCues:    745,
+			ExpectLines: []string{
+				"00:08:15.000 --> 00:08:20.000",
+				"sprite0.jpg#xywh=1440,810,160,90",
+				"00:08:20.000 --> 00:08:25.000",
+				"sprite1.jpg#xywh=0,0,160,90",
+				"01:02:00.000 --> 01:02:05.000",
+				"sprite7.jpg#xywh=640,360,160,90",
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			vtt := generateThumbnailsVTT(tt.GivenDuration)
+
+			require.True(t, strings.HasPrefix(vtt, "WEBVTT\n\n"))
+			require.Equal(t, tt.ExpectCues, strings.Count(vtt, " --> "))
+
+			lines := strings.Split(vtt, "\n")
+			for _, line := range tt.ExpectLines {
+				require.Contains(t, lines, line)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package hls
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidPlaylist = errors.New("invalid m3u8 playlist")
+
+var bandwidthRegex = regexp.MustCompile(`(?:^|,)BANDWIDTH=(\d+)`)
+var audioGroupRegex = regexp.MustCompile(`(?:^|,)AUDIO="([^"]*)"`)
+var attributeRegex = regexp.MustCompile(`([A-Z0-9-]+)=("[^"]*"|[^,]*)`)
+
+// Variant is a variant stream of a master playlist
+type Variant struct {
+	Attributes string // Attributes of the EXT-X-STREAM-INF tag, as is
+	Bandwidth  int
+	URI        string
+}
+
+// AudioGroup returns the group of the audio renditions played with the variant stream, empty when its audio
+// is muxed with its video
+func (v Variant) AudioGroup() string {
+	if match := audioGroupRegex.FindStringSubmatch(v.Attributes); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// WithAudioGroup returns the variant stream played with the audio renditions of the group, instead of the
+// group it referenced if any
+func (v Variant) WithAudioGroup(group string) Variant {
+	attributes := strings.TrimPrefix(audioGroupRegex.ReplaceAllString(v.Attributes, ""), ",")
+	if attributes != "" {
+		attributes += ","
+	}
+	v.Attributes = attributes + `AUDIO="` + group + `"`
+	return v
+}
+
+// MasterPlaylist lists the variant streams of a video, one per quality, and their alternative renditions
+type MasterPlaylist struct {
+	Version    int
+	Renditions []Rendition
+	Variants   []Variant
+}
+
+// Segment of a media playlist. Discontinuity is set when the segment does not follow the previous one,
+// like the first segment of another video.
+type Segment struct {
+	Duration      float64
+	Title         string
+	URI           string
+	Discontinuity bool
+}
+
+// MediaPlaylist lists the segments of a variant stream
+type MediaPlaylist struct {
+	Version        int
+	TargetDuration int
+	Segments       []Segment
+}
+
+// ParseMaster reads a master playlist. Tags other than the variant streams and their renditions are ignored.
+func ParseMaster(playlist io.Reader) (*MasterPlaylist, error) {
+	master := &MasterPlaylist{}
+	var variant *Variant
+
+	err := scanPlaylist(playlist, func(line string) error {
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-VERSION:"):
+			version, err := strconv.Atoi(strings.TrimPrefix(line, "#EXT-X-VERSION:"))
+			if err != nil {
+				return fmt.Errorf("%w : version %v", ErrInvalidPlaylist, line)
+			}
+			master.Version = version
+
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			attributes := strings.TrimPrefix(line, "#EXT-X-STREAM-INF:")
+			match := bandwidthRegex.FindStringSubmatch(attributes)
+			if match == nil {
+				return fmt.Errorf("%w : variant without bandwidth", ErrInvalidPlaylist)
+			}
+			bandwidth, err := strconv.Atoi(match[1])
+			if err != nil {
+				return fmt.Errorf("%w : bandwidth %v", ErrInvalidPlaylist, match[1])
+			}
+			variant = &Variant{Attributes: attributes, Bandwidth: bandwidth}
+
+		case strings.HasPrefix(line, "#EXT-X-MEDIA:"):
+			master.Renditions = append(master.Renditions, parseRendition(strings.TrimPrefix(line, "#EXT-X-MEDIA:")))
+
+		case strings.HasPrefix(line, "#"):
+
+		default:
+			if variant == nil {
+				return fmt.Errorf("%w : URI %v without EXT-X-STREAM-INF", ErrInvalidPlaylist, line)
+			}
+			variant.URI = line
+			master.Variants = append(master.Variants, *variant)
+			variant = nil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return master, nil
+}
+
+// parseRendition reads the attributes of an EXT-X-MEDIA tag. Unknown attributes are ignored.
+func parseRendition(attributes string) Rendition {
+	rendition := Rendition{}
+	for _, match := range attributeRegex.FindAllStringSubmatch(attributes, -1) {
+		value := strings.Trim(match[2], `"`)
+		switch match[1] {
+		case "TYPE":
+			rendition.Type = value
+		case "GROUP-ID":
+			rendition.GroupID = value
+		case "NAME":
+			rendition.Name = value
+		case "LANGUAGE":
+			rendition.Language = value
+		case "DEFAULT":
+			rendition.Default = value == "YES"
+		case "URI":
+			rendition.URI = value
+		}
+	}
+	return rendition
+}
+
+// ParseMedia reads a media playlist. Tags other than the segments, their discontinuities and the target
+// duration are ignored.
+func ParseMedia(playlist io.Reader) (*MediaPlaylist, error) {
+	media := &MediaPlaylist{}
+	var segment *Segment
+	discontinuity := false
+
+	err := scanPlaylist(playlist, func(line string) error {
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-VERSION:"):
+			version, err := strconv.Atoi(strings.TrimPrefix(line, "#EXT-X-VERSION:"))
+			if err != nil {
+				return fmt.Errorf("%w : version %v", ErrInvalidPlaylist, line)
+			}
+			media.Version = version
+
+		case strings.HasPrefix(line, "#EXT-X-TARGETDURATION:"):
+			targetDuration, err := strconv.Atoi(strings.TrimPrefix(line, "#EXT-X-TARGETDURATION:"))
+			if err != nil {
+				return fmt.Errorf("%w : target duration %v", ErrInvalidPlaylist, line)
+			}
+			media.TargetDuration = targetDuration
+
+		case line == "#EXT-X-DISCONTINUITY":
+			discontinuity = true
+
+		case strings.HasPrefix(line, "#EXTINF:"):
+			durationValue, title, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			duration, err := strconv.ParseFloat(durationValue, 64)
+			if err != nil {
+				return fmt.Errorf("%w : segment duration %v", ErrInvalidPlaylist, line)
+			}
+			segment = &Segment{Duration: duration, Title: title}
+
+		case strings.HasPrefix(line, "#"):
+
+		default:
+			if segment == nil {
+				return fmt.Errorf("%w : URI %v without EXTINF", ErrInvalidPlaylist, line)
+			}
+			segment.URI = line
+			segment.Discontinuity = discontinuity
+			media.Segments = append(media.Segments, *segment)
+			segment = nil
+			discontinuity = false
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return media, nil
+}
+
+// scanPlaylist calls parseLine for each non empty line of the playlist, after checking its header
+func scanPlaylist(playlist io.Reader, parseLine func(line string) error) error {
+	scanner := bufio.NewScanner(playlist)
+	header := true
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if header {
+			if line != "#EXTM3U" {
+				return fmt.Errorf("%w : missing #EXTM3U header", ErrInvalidPlaylist)
+			}
+			header = false
+			continue
+		}
+
+		if err := parseLine(line); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if header {
+		return fmt.Errorf("%w : empty playlist", ErrInvalidPlaylist)
+	}
+
+	return nil
+}
+
+// Encode returns the m3u8 text of the master playlist
+func (m MasterPlaylist) Encode() []byte {
+	var encoded strings.Builder
+	encoded.WriteString("#EXTM3U\n")
+	if m.Version > 0 {
+		encoded.WriteString("#EXT-X-VERSION:" + strconv.Itoa(m.Version) + "\n")
+	}
+	for _, rendition := range m.Renditions {
+		encoded.WriteString(rendition.Encode() + "\n")
+	}
+	for _, variant := range m.Variants {
+		encoded.WriteString("#EXT-X-STREAM-INF:" + variant.Attributes + "\n")
+		encoded.WriteString(variant.URI + "\n")
+	}
+
+	return []byte(encoded.String())
+}
+
+// Encode returns the m3u8 text of the media playlist, as a complete video on demand
+func (m MediaPlaylist) Encode() []byte {
+	var encoded strings.Builder
+	encoded.WriteString("#EXTM3U\n")
+	if m.Version > 0 {
+		encoded.WriteString("#EXT-X-VERSION:" + strconv.Itoa(m.Version) + "\n")
+	}
+	encoded.WriteString("#EXT-X-TARGETDURATION:" + strconv.Itoa(m.TargetDuration) + "\n")
+	encoded.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n")
+	encoded.WriteString("#EXT-X-PLAYLIST-TYPE:VOD\n")
+	for _, segment := range m.Segments {
+		if segment.Discontinuity {
+			encoded.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		encoded.WriteString("#EXTINF:" + strconv.FormatFloat(segment.Duration, 'f', 6, 64) + "," + segment.Title + "\n")
+		encoded.WriteString(segment.URI + "\n")
+	}
+	encoded.WriteString("#EXT-X-ENDLIST\n")
+
+	return []byte(encoded.String())
+}
+
+// Duration returns the duration of the media playlist, in seconds
+func (m MediaPlaylist) Duration() float64 {
+	duration := 0.0
+	for _, segment := range m.Segments {
+		duration += segment.Duration
+	}
+	return duration
+}
+
+// Concat returns the media playlists played one after the other : a discontinuity starts each of them,
+// except the first one. The target duration is the longest one, so that it stays an upper bound of the
+// segment durations.
+func Concat(playlists []MediaPlaylist) MediaPlaylist {
+	concatenated := MediaPlaylist{}
+	for i, playlist := range playlists {
+		if playlist.Version > concatenated.Version {
+			concatenated.Version = playlist.Version
+		}
+		if playlist.TargetDuration > concatenated.TargetDuration {
+			concatenated.TargetDuration = playlist.TargetDuration
+		}
+
+		for j, segment := range playlist.Segments {
+			if j == 0 && i > 0 && len(concatenated.Segments) > 0 {
+				segment.Discontinuity = true
+			}
+			if duration := int(math.Ceil(segment.Duration)); duration > concatenated.TargetDuration {
+				concatenated.TargetDuration = duration
+			}
+			concatenated.Segments = append(concatenated.Segments, segment)
+		}
+	}
+
+	return concatenated
+}
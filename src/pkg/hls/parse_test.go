@@ -0,0 +1,118 @@
+package hls_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/hls"
+)
+
+func Test_ParseMaster(t *testing.T) {
+	master, err := hls.ParseMaster(strings.NewReader("#EXTM3U\r\n" +
+		"#EXT-X-VERSION:3\r\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=1240800,RESOLUTION=640x480,CODECS=\"avc1.64001e,mp4a.40.2\"\r\n" +
+		"v0/segment_index.m3u8\r\n" +
+		"\r\n" +
+		"#EXT-X-STREAM-INF:AVERAGE-BANDWIDTH=2000000,BANDWIDTH=2340800,RESOLUTION=1280x720\r\n" +
+		"v1/segment_index.m3u8\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, &hls.MasterPlaylist{
+		Version: 3,
+		Variants: []hls.Variant{
+			{Attributes: "BANDWIDTH=1240800,RESOLUTION=640x480,CODECS=\"avc1.64001e,mp4a.40.2\"", Bandwidth: 1240800, URI: "v0/segment_index.m3u8"},
+			{Attributes: "AVERAGE-BANDWIDTH=2000000,BANDWIDTH=2340800,RESOLUTION=1280x720", Bandwidth: 2340800, URI: "v1/segment_index.m3u8"},
+		},
+	}, master)
+
+	require.Equal(t, "#EXTM3U\n"+
+		"#EXT-X-VERSION:3\n"+
+		"#EXT-X-STREAM-INF:BANDWIDTH=1240800,RESOLUTION=640x480,CODECS=\"avc1.64001e,mp4a.40.2\"\n"+
+		"v0/segment_index.m3u8\n"+
+		"#EXT-X-STREAM-INF:AVERAGE-BANDWIDTH=2000000,BANDWIDTH=2340800,RESOLUTION=1280x720\n"+
+		"v1/segment_index.m3u8\n", string(master.Encode()))
+
+	require.Equal(t, "", master.Variants[0].AudioGroup())
+	require.Equal(t, "group_audio", hls.Variant{Attributes: `BANDWIDTH=1240800,CODECS="avc1.64001e",AUDIO="group_audio"`}.AudioGroup())
+
+	_, err = hls.ParseMaster(strings.NewReader("#EXTM3U\n#EXT-X-STREAM-INF:RESOLUTION=640x480\nv0/segment_index.m3u8\n"))
+	require.ErrorIs(t, err, hls.ErrInvalidPlaylist)
+
+	_, err = hls.ParseMaster(strings.NewReader("v0/segment_index.m3u8\n"))
+	require.ErrorIs(t, err, hls.ErrInvalidPlaylist)
+}
+
+func Test_ParseMasterRenditions(t *testing.T) {
+	master, err := hls.ParseMaster(strings.NewReader("#EXTM3U\n" +
+		"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"group_audio\",NAME=\"audio_eng\",DEFAULT=YES,LANGUAGE=\"eng\",URI=\"audio_eng/segment_index.m3u8\"\n" +
+		"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"group_audio\",NAME=\"audio_fre\",DEFAULT=NO,LANGUAGE=\"fre\",URI=\"audio_fre/segment_index.m3u8\"\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=1240800,CODECS=\"avc1.64001e\",AUDIO=\"group_audio\"\n" +
+		"v0/segment_index.m3u8\n"))
+	require.NoError(t, err)
+	require.Equal(t, []hls.Rendition{
+		{Type: "AUDIO", GroupID: "group_audio", Name: "audio_eng", Language: "eng", Default: true, URI: "audio_eng/segment_index.m3u8"},
+		{Type: "AUDIO", GroupID: "group_audio", Name: "audio_fre", Language: "fre", URI: "audio_fre/segment_index.m3u8"},
+	}, master.Renditions)
+
+	require.Equal(t, "#EXTM3U\n"+
+		"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"group_audio\",NAME=\"audio_eng\",LANGUAGE=\"eng\",DEFAULT=YES,AUTOSELECT=YES,URI=\"audio_eng/segment_index.m3u8\"\n"+
+		"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"group_audio\",NAME=\"audio_fre\",LANGUAGE=\"fre\",DEFAULT=NO,AUTOSELECT=YES,URI=\"audio_fre/segment_index.m3u8\"\n"+
+		"#EXT-X-STREAM-INF:BANDWIDTH=1240800,CODECS=\"avc1.64001e\",AUDIO=\"group_audio\"\n"+
+		"v0/segment_index.m3u8\n", string(master.Encode()))
+
+	require.Equal(t, `BANDWIDTH=1240800,CODECS="avc1.64001e",AUDIO="audio"`, master.Variants[0].WithAudioGroup("audio").Attributes)
+	require.Equal(t, `BANDWIDTH=1240800,AUDIO="audio"`, hls.Variant{Attributes: `AUDIO="group_audio",BANDWIDTH=1240800`}.WithAudioGroup("audio").Attributes)
+	require.Equal(t, `BANDWIDTH=1240800,AUDIO="audio"`, hls.Variant{Attributes: "BANDWIDTH=1240800"}.WithAudioGroup("audio").Attributes)
+}
+
+func Test_ParseMedia(t *testing.T) {
+	media, err := hls.ParseMedia(strings.NewReader("#EXTM3U\n" +
+		"#EXT-X-VERSION:3\n" +
+		"#EXT-X-TARGETDURATION:6\n" +
+		"#EXT-X-MEDIA-SEQUENCE:0\n" +
+		"#EXTINF:6.000000,\n" +
+		"segment0.ts\n" +
+		"#EXT-X-DISCONTINUITY\n" +
+		"#EXTINF:2.500000,title\n" +
+		"segment1.ts\n" +
+		"#EXT-X-ENDLIST\n"))
+	require.NoError(t, err)
+	require.Equal(t, &hls.MediaPlaylist{
+		Version:        3,
+		TargetDuration: 6,
+		Segments: []hls.Segment{
+			{Duration: 6, URI: "segment0.ts"},
+			{Duration: 2.5, Title: "title", URI: "segment1.ts", Discontinuity: true},
+		},
+	}, media)
+	require.Equal(t, 8.5, media.Duration())
+
+	_, err = hls.ParseMedia(strings.NewReader("#EXTM3U\nsegment0.ts\n"))
+	require.ErrorIs(t, err, hls.ErrInvalidPlaylist)
+
+	_, err = hls.ParseMedia(strings.NewReader("#EXTM3U\n#EXTINF:six,\nsegment0.ts\n"))
+	require.ErrorIs(t, err, hls.ErrInvalidPlaylist)
+}
+
+func Test_Concat(t *testing.T) {
+	concatenated := hls.Concat([]hls.MediaPlaylist{
+		{Version: 3, TargetDuration: 6, Segments: []hls.Segment{{Duration: 6, URI: "a/segment0.ts"}, {Duration: 1, URI: "a/segment1.ts"}}},
+		{Version: 3, TargetDuration: 6},
+		{Version: 4, TargetDuration: 4, Segments: []hls.Segment{{Duration: 7.2, URI: "b/segment0.ts"}}},
+	})
+
+	require.Equal(t, "#EXTM3U\n"+
+		"#EXT-X-VERSION:4\n"+
+		"#EXT-X-TARGETDURATION:8\n"+
+		"#EXT-X-MEDIA-SEQUENCE:0\n"+
+		"#EXT-X-PLAYLIST-TYPE:VOD\n"+
+		"#EXTINF:6.000000,\n"+
+		"a/segment0.ts\n"+
+		"#EXTINF:1.000000,\n"+
+		"a/segment1.ts\n"+
+		"#EXT-X-DISCONTINUITY\n"+
+		"#EXTINF:7.200000,\n"+
+		"b/segment0.ts\n"+
+		"#EXT-X-ENDLIST\n", string(concatenated.Encode()))
+}
@@ -0,0 +1,203 @@
+package hls
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tags like EXT-X-MEDIA or EXT-X-MAP give their URI as an attribute
+var uriAttributeRegex = regexp.MustCompile(`URI="([^"]*)"`)
+
+// RewriteURIs returns the playlist with each of its URIs replaced by rewrite : the URI lines of the
+// variant streams and of the segments, and the URI attributes of the tags. Other lines are kept as is.
+func RewriteURIs(playlist io.Reader, rewrite func(uri string) string) ([]byte, error) {
+	var rewritten bytes.Buffer
+	scanner := bufio.NewScanner(playlist)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#"):
+			line = uriAttributeRegex.ReplaceAllStringFunc(line, func(attribute string) string {
+				uri := uriAttributeRegex.FindStringSubmatch(attribute)[1]
+				return `URI="` + rewrite(uri) + `"`
+			})
+		default:
+			line = rewrite(trimmed)
+		}
+
+		rewritten.WriteString(line)
+		rewritten.WriteString("\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rewritten.Bytes(), nil
+}
+
+// WithQueryParam returns the URI with the query parameter set, or the URI itself if it cannot be parsed
+func WithQueryParam(uri, key, value string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+
+	query := u.Query()
+	query.Set(key, value)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// Rendition is an alternative rendition of the variant streams, declared by an EXT-X-MEDIA tag, like a subtitles track
+type Rendition struct {
+	Type     string // AUDIO, SUBTITLES, ...
+	GroupID  string
+	Name     string
+	Language string
+	Default  bool
+	URI      string
+}
+
+// Encode returns the EXT-X-MEDIA tag of the rendition. Its name must not contain double quotes.
+func (r Rendition) Encode() string {
+	attributes := []string{
+		"TYPE=" + r.Type,
+		`GROUP-ID="` + r.GroupID + `"`,
+		`NAME="` + r.Name + `"`,
+	}
+	if r.Language != "" {
+		attributes = append(attributes, `LANGUAGE="`+r.Language+`"`)
+	}
+	if r.Default {
+		attributes = append(attributes, "DEFAULT=YES")
+	} else {
+		attributes = append(attributes, "DEFAULT=NO")
+	}
+	attributes = append(attributes, "AUTOSELECT=YES", `URI="`+r.URI+`"`)
+
+	return "#EXT-X-MEDIA:" + strings.Join(attributes, ",")
+}
+
+// AddRenditions returns the master playlist with the renditions declared before its first variant stream, each
+// variant stream referencing the group of each type of rendition (SUBTITLES="group" for instance). Other lines
+// are kept as is.
+func AddRenditions(master io.Reader, renditions []Rendition) ([]byte, error) {
+	if len(renditions) == 0 {
+		return io.ReadAll(master)
+	}
+
+	var groups []string
+	declared := map[string]bool{}
+	var declarations strings.Builder
+	for _, rendition := range renditions {
+		declarations.WriteString(rendition.Encode() + "\n")
+		if !declared[rendition.Type] {
+			declared[rendition.Type] = true
+			groups = append(groups, rendition.Type+`="`+rendition.GroupID+`"`)
+		}
+	}
+
+	var rewritten bytes.Buffer
+	scanner := bufio.NewScanner(master)
+	inserted := false
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if strings.HasPrefix(strings.TrimSpace(line), "#EXT-X-STREAM-INF:") {
+			if !inserted {
+				rewritten.WriteString(declarations.String())
+				inserted = true
+			}
+			line = strings.TrimSpace(line) + "," + strings.Join(groups, ",")
+		}
+
+		rewritten.WriteString(line)
+		rewritten.WriteString("\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !inserted {
+		rewritten.WriteString(declarations.String())
+	}
+
+	return rewritten.Bytes(), nil
+}
+
+// dateRangeFormat is the ISO 8601 format of the dates of the playlists, to the millisecond
+const dateRangeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// Attribute values are quoted strings : double quotes and line breaks cannot be written as is
+var quotedStringReplacer = strings.NewReplacer(`"`, "'", "\r", " ", "\n", " ")
+
+// DateRange is a range of the timeline declared by an EXT-X-DATERANGE tag, like a chapter of the video
+type DateRange struct {
+	ID       string
+	Class    string
+	Start    time.Time
+	Duration float64 // In seconds, omitted when 0
+	Title    string  // X-TITLE client attribute, omitted when empty
+}
+
+// Encode returns the EXT-X-DATERANGE tag of the date range
+func (d DateRange) Encode() string {
+	attributes := []string{`ID="` + quotedStringReplacer.Replace(d.ID) + `"`}
+	if d.Class != "" {
+		attributes = append(attributes, `CLASS="`+quotedStringReplacer.Replace(d.Class)+`"`)
+	}
+	attributes = append(attributes, `START-DATE="`+d.Start.UTC().Format(dateRangeFormat)+`"`)
+	if d.Duration > 0 {
+		attributes = append(attributes, "DURATION="+strconv.FormatFloat(d.Duration, 'f', 3, 64))
+	}
+	if d.Title != "" {
+		attributes = append(attributes, `X-TITLE="`+quotedStringReplacer.Replace(d.Title)+`"`)
+	}
+
+	return "#EXT-X-DATERANGE:" + strings.Join(attributes, ",")
+}
+
+// AddDateRanges returns the media playlist with its first segment dated at start (EXT-X-PROGRAM-DATE-TIME),
+// followed by the date ranges, so that their dates map onto its timeline. Other lines are kept as is.
+func AddDateRanges(media io.Reader, start time.Time, ranges []DateRange) ([]byte, error) {
+	if len(ranges) == 0 {
+		return io.ReadAll(media)
+	}
+
+	var declarations strings.Builder
+	declarations.WriteString("#EXT-X-PROGRAM-DATE-TIME:" + start.UTC().Format(dateRangeFormat) + "\n")
+	for _, dateRange := range ranges {
+		declarations.WriteString(dateRange.Encode() + "\n")
+	}
+
+	var rewritten bytes.Buffer
+	scanner := bufio.NewScanner(media)
+	inserted := false
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if !inserted && strings.HasPrefix(strings.TrimSpace(line), "#EXTINF:") {
+			rewritten.WriteString(declarations.String())
+			inserted = true
+		}
+
+		rewritten.WriteString(line)
+		rewritten.WriteString("\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !inserted {
+		rewritten.WriteString(declarations.String())
+	}
+
+	return rewritten.Bytes(), nil
+}
@@ -0,0 +1,125 @@
+package hls_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/hls"
+)
+
+func Test_RewriteURIs(t *testing.T) {
+	cases := []struct {
+		Name           string
+		GivenPlaylist  string
+		ExpectPlaylist string
+	}{
+		{
+			Name: "Master playlist",
+			GivenPlaylist: "#EXTM3U\n" +
+				"#EXT-X-VERSION:3\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=1240800,RESOLUTION=640x480,CODECS=\"avc1.64001e,mp4a.40.2\"\n" +
+				"v0/segment_index.m3u8\n" +
+				"\n",
+			ExpectPlaylist: "#EXTM3U\n" +
+				"#EXT-X-VERSION:3\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=1240800,RESOLUTION=640x480,CODECS=\"avc1.64001e,mp4a.40.2\"\n" +
+				"v0/segment_index.m3u8?token=abc\n" +
+				"\n",
+		},
+		{
+			Name: "Media playlist with CRLF",
+			GivenPlaylist: "#EXTM3U\r\n" +
+				"#EXT-X-TARGETDURATION:6\r\n" +
+				"#EXTINF:6.000000,\r\n" +
+				"segment0.ts\r\n" +
+				"#EXTINF:2.500000,\r\n" +
+				"segment1.ts\r\n" +
+				"#EXT-X-ENDLIST\r\n",
+			ExpectPlaylist: "#EXTM3U\n" +
+				"#EXT-X-TARGETDURATION:6\n" +
+				"#EXTINF:6.000000,\n" +
+				"segment0.ts?token=abc\n" +
+				"#EXTINF:2.500000,\n" +
+				"segment1.ts?token=abc\n" +
+				"#EXT-X-ENDLIST\n",
+		},
+		{
+			Name: "URI attributes",
+			GivenPlaylist: "#EXTM3U\n" +
+				"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"audio\",NAME=\"en\",URI=\"audio/en.m3u8?lang=en\"\n" +
+				"#EXT-X-MAP:URI=\"init.mp4\"\n",
+			ExpectPlaylist: "#EXTM3U\n" +
+				"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"audio\",NAME=\"en\",URI=\"audio/en.m3u8?lang=en&token=abc\"\n" +
+				"#EXT-X-MAP:URI=\"init.mp4?token=abc\"\n",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			playlist, err := hls.RewriteURIs(strings.NewReader(tt.GivenPlaylist), func(uri string) string {
+				return hls.WithQueryParam(uri, "token", "abc")
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.ExpectPlaylist, string(playlist))
+		})
+	}
+}
+
+func Test_AddRenditions(t *testing.T) {
+	renditions := []hls.Rendition{
+		{Type: "SUBTITLES", GroupID: "subtitles", Name: "English", Language: "en", Default: true, URI: "subtitles/en/index.m3u8"},
+		{Type: "SUBTITLES", GroupID: "subtitles", Name: "Français", Language: "fr", URI: "subtitles/fr/index.m3u8"},
+	}
+
+	master, err := hls.AddRenditions(strings.NewReader("#EXTM3U\r\n"+
+		"#EXT-X-VERSION:3\r\n"+
+		"#EXT-X-STREAM-INF:BANDWIDTH=1240800,RESOLUTION=640x480\r\n"+
+		"v0/segment_index.m3u8\r\n"+
+		"#EXT-X-STREAM-INF:BANDWIDTH=2340800,RESOLUTION=1280x720\r\n"+
+		"v1/segment_index.m3u8\r\n"), renditions)
+	require.NoError(t, err)
+	require.Equal(t, "#EXTM3U\n"+
+		"#EXT-X-VERSION:3\n"+
+		"#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"subtitles\",NAME=\"English\",LANGUAGE=\"en\",DEFAULT=YES,AUTOSELECT=YES,URI=\"subtitles/en/index.m3u8\"\n"+
+		"#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"subtitles\",NAME=\"Français\",LANGUAGE=\"fr\",DEFAULT=NO,AUTOSELECT=YES,URI=\"subtitles/fr/index.m3u8\"\n"+
+		"#EXT-X-STREAM-INF:BANDWIDTH=1240800,RESOLUTION=640x480,SUBTITLES=\"subtitles\"\n"+
+		"v0/segment_index.m3u8\n"+
+		"#EXT-X-STREAM-INF:BANDWIDTH=2340800,RESOLUTION=1280x720,SUBTITLES=\"subtitles\"\n"+
+		"v1/segment_index.m3u8\n", string(master))
+
+	// Without rendition, the master is unchanged
+	master, err = hls.AddRenditions(strings.NewReader("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1240800\nv0/segment_index.m3u8\n"), nil)
+	require.NoError(t, err)
+	require.Equal(t, "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1240800\nv0/segment_index.m3u8\n", string(master))
+}
+
+func Test_AddDateRanges(t *testing.T) {
+	start := time.Unix(0, 0)
+	ranges := []hls.DateRange{
+		{ID: "chapter-0", Class: "chapter", Start: start, Duration: 60, Title: "Intro"},
+		{ID: "chapter-1", Class: "chapter", Start: start.Add(60500 * time.Millisecond), Title: "The \"end\""},
+	}
+
+	media, err := hls.AddDateRanges(strings.NewReader("#EXTM3U\r\n"+
+		"#EXT-X-TARGETDURATION:6\r\n"+
+		"#EXTINF:6.000000,\r\n"+
+		"segment0.ts\r\n"+
+		"#EXT-X-ENDLIST\r\n"), start, ranges)
+	require.NoError(t, err)
+	require.Equal(t, "#EXTM3U\n"+
+		"#EXT-X-TARGETDURATION:6\n"+
+		"#EXT-X-PROGRAM-DATE-TIME:1970-01-01T00:00:00.000Z\n"+
+		"#EXT-X-DATERANGE:ID=\"chapter-0\",CLASS=\"chapter\",START-DATE=\"1970-01-01T00:00:00.000Z\",DURATION=60.000,X-TITLE=\"Intro\"\n"+
+		"#EXT-X-DATERANGE:ID=\"chapter-1\",CLASS=\"chapter\",START-DATE=\"1970-01-01T00:01:00.500Z\",X-TITLE=\"The 'end'\"\n"+
+		"#EXTINF:6.000000,\n"+
+		"segment0.ts\n"+
+		"#EXT-X-ENDLIST\n", string(media))
+
+	// Without date range, the playlist is unchanged
+	media, err = hls.AddDateRanges(strings.NewReader("#EXTM3U\n#EXTINF:6.000000,\nsegment0.ts\n"), start, nil)
+	require.NoError(t, err)
+	require.Equal(t, "#EXTM3U\n#EXTINF:6.000000,\nsegment0.ts\n", string(media))
+}
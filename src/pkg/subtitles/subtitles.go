@@ -0,0 +1,206 @@
+package subtitles
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidSubtitles = errors.New("invalid subtitles")
+
+// Timestamps are hh:mm:ss.ttt, the hours being optional in WebVTT. SRT separates the milliseconds with a comma.
+var timestampRegex = regexp.MustCompile(`^(?:(\d+):)?(\d{2}):(\d{2})[.,](\d{3})$`)
+
+// Cue is a text displayed between two times of the video, in seconds
+type Cue struct {
+	ID       string
+	Start    float64
+	End      float64
+	Settings string // WebVTT cue settings, like "line:0 align:start"
+	Text     string
+}
+
+// Parse reads SRT or WebVTT subtitles, WebVTT being recognized by its header. Only the cues are kept :
+// the WebVTT comments, styles and regions are left out.
+func Parse(subtitles io.Reader) ([]Cue, error) {
+	blocks, err := readBlocks(subtitles)
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("%w : empty file", ErrInvalidSubtitles)
+	}
+
+	webVTT := blocks[0][0] == "WEBVTT" || strings.HasPrefix(blocks[0][0], "WEBVTT ") || strings.HasPrefix(blocks[0][0], "WEBVTT\t")
+	if webVTT {
+		blocks = blocks[1:]
+	}
+
+	cues := []Cue{}
+	for _, block := range blocks {
+		if webVTT && (strings.HasPrefix(block[0], "NOTE") || block[0] == "STYLE" || block[0] == "REGION") {
+			continue
+		}
+
+		cue, err := parseCue(block)
+		if err != nil {
+			return nil, err
+		}
+		cues = append(cues, *cue)
+	}
+	if len(cues) == 0 {
+		return nil, fmt.Errorf("%w : no cue", ErrInvalidSubtitles)
+	}
+
+	return cues, nil
+}
+
+// readBlocks returns the lines of the subtitles grouped by the blank lines separating them
+func readBlocks(subtitles io.Reader) ([][]string, error) {
+	blocks := [][]string{}
+	var block []string
+
+	scanner := bufio.NewScanner(subtitles)
+	first := true
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff")
+			first = false
+		}
+
+		if strings.TrimSpace(line) == "" {
+			if block != nil {
+				blocks = append(blocks, block)
+				block = nil
+			}
+			continue
+		}
+		block = append(block, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if block != nil {
+		blocks = append(blocks, block)
+	}
+
+	return blocks, nil
+}
+
+// parseCue reads a cue : its optional identifier, its timing line and its text lines
+func parseCue(block []string) (*Cue, error) {
+	cue := &Cue{}
+	if !strings.Contains(block[0], "-->") {
+		cue.ID = strings.TrimSpace(block[0])
+		block = block[1:]
+	}
+	if len(block) == 0 {
+		return nil, fmt.Errorf("%w : cue %v without timing", ErrInvalidSubtitles, cue.ID)
+	}
+
+	start, rest, found := strings.Cut(block[0], "-->")
+	if !found {
+		return nil, fmt.Errorf("%w : timing %v", ErrInvalidSubtitles, block[0])
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w : timing %v", ErrInvalidSubtitles, block[0])
+	}
+
+	var err error
+	if cue.Start, err = parseTimestamp(strings.TrimSpace(start)); err != nil {
+		return nil, err
+	}
+	if cue.End, err = parseTimestamp(fields[0]); err != nil {
+		return nil, err
+	}
+	if cue.End < cue.Start {
+		return nil, fmt.Errorf("%w : cue ending before its start %v", ErrInvalidSubtitles, block[0])
+	}
+
+	// SRT coordinates (X1:... Y2:...) have no WebVTT equivalent
+	for _, setting := range fields[1:] {
+		if !strings.HasPrefix(setting, "X") && !strings.HasPrefix(setting, "Y") {
+			cue.Settings = strings.TrimSpace(cue.Settings + " " + setting)
+		}
+	}
+
+	cue.Text = strings.Join(block[1:], "\n")
+	return cue, nil
+}
+
+func parseTimestamp(timestamp string) (float64, error) {
+	match := timestampRegex.FindStringSubmatch(timestamp)
+	if match == nil {
+		return 0, fmt.Errorf("%w : timestamp %v", ErrInvalidSubtitles, timestamp)
+	}
+
+	hours := 0
+	if match[1] != "" {
+		hours, _ = strconv.Atoi(match[1])
+	}
+	minutes, _ := strconv.Atoi(match[2])
+	seconds, _ := strconv.Atoi(match[3])
+	millis, _ := strconv.Atoi(match[4])
+	if minutes > 59 || seconds > 59 {
+		return 0, fmt.Errorf("%w : timestamp %v", ErrInvalidSubtitles, timestamp)
+	}
+
+	return float64(hours*3600+minutes*60+seconds) + float64(millis)/1000, nil
+}
+
+// formatTimestamp formats seconds as a WebVTT timestamp (hh:mm:ss.ttt)
+func formatTimestamp(seconds float64) string {
+	millis := int64(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", millis/3600000, millis/60000%60, millis/1000%60, millis%1000)
+}
+
+// EncodeVTT returns the WebVTT file of the cues
+func EncodeVTT(cues []Cue) []byte {
+	var vtt strings.Builder
+	vtt.WriteString("WEBVTT\n")
+	for _, cue := range cues {
+		vtt.WriteString("\n")
+		if cue.ID != "" {
+			vtt.WriteString(cue.ID + "\n")
+		}
+		vtt.WriteString(formatTimestamp(cue.Start) + " --> " + formatTimestamp(cue.End))
+		if cue.Settings != "" {
+			vtt.WriteString(" " + cue.Settings)
+		}
+		vtt.WriteString("\n" + cue.Text + "\n")
+	}
+
+	return []byte(vtt.String())
+}
+
+// Segment splits the cues in segments of this duration, in seconds, up to the end of the last cue. A cue is
+// in each segment it overlaps, and a segment without cue is empty.
+func Segment(cues []Cue, duration float64) [][]Cue {
+	end := 0.0
+	for _, cue := range cues {
+		end = math.Max(end, cue.End)
+	}
+
+	count := int(math.Max(1, math.Ceil(end/duration)))
+	segments := make([][]Cue, count)
+	for i := range segments {
+		segmentStart := float64(i) * duration
+		segmentEnd := segmentStart + duration
+
+		segments[i] = []Cue{}
+		for _, cue := range cues {
+			if cue.Start < segmentEnd && (cue.End > segmentStart || cue.Start == segmentStart) {
+				segments[i] = append(segments[i], cue)
+			}
+		}
+	}
+
+	return segments
+}
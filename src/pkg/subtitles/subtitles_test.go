@@ -0,0 +1,123 @@
+package subtitles_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/subtitles"
+)
+
+func Test_Parse(t *testing.T) {
+	cases := []struct {
+		Name         string
+		GivenFile    string
+		ExpectCues   []subtitles.Cue
+		ExpectsError bool
+	}{
+		{
+			Name: "SRT with BOM and CRLF",
+			GivenFile: "\ufeff1\r\n" +
+				"00:00:01,000 --> 00:00:04,500\r\n" +
+				"Hello\r\n" +
+				"<i>world</i>\r\n" +
+				"\r\n" +
+				"2\r\n" +
+				"00:01:02,250 --> 00:01:03,000 X1:40 X2:600 Y1:20 Y2:50\r\n" +
+				"Bye\r\n",
+			ExpectCues: []subtitles.Cue{
+				{ID: "1", Start: 1, End: 4.5, Text: "Hello\n<i>world</i>"},
+				{ID: "2", Start: 62.25, End: 63, Text: "Bye"},
+			},
+		},
+		{
+			Name: "WebVTT with comments and settings",
+			GivenFile: "WEBVTT - English\n" +
+				"Kind: captions\n" +
+				"\n" +
+				"NOTE written by hand\n" +
+				"\n" +
+				"STYLE\n" +
+				"::cue { color: yellow }\n" +
+				"\n" +
+				"01:00.000 --> 01:02.000 line:0 align:start\n" +
+				"Top left\n" +
+				"\n" +
+				"intro\n" +
+				"01:00:00.000 --> 01:00:01.000\n" +
+				"An hour later\n",
+			ExpectCues: []subtitles.Cue{
+				{Start: 60, End: 62, Settings: "line:0 align:start", Text: "Top left"},
+				{ID: "intro", Start: 3600, End: 3601, Text: "An hour later"},
+			},
+		},
+		{
+			Name:         "Empty file",
+			GivenFile:    "\n\n",
+			ExpectsError: true,
+		},
+		{
+			Name:         "WebVTT without cue",
+			GivenFile:    "WEBVTT\n\nNOTE nothing\n",
+			ExpectsError: true,
+		},
+		{
+			Name:         "Invalid timestamp",
+			GivenFile:    "1\n00:00:01 --> 00:00:02,000\nHello\n",
+			ExpectsError: true,
+		},
+		{
+			Name:         "Cue ending before its start",
+			GivenFile:    "1\n00:00:03,000 --> 00:00:02,000\nHello\n",
+			ExpectsError: true,
+		},
+		{
+			Name:         "Not subtitles",
+			GivenFile:    "Hello world\n",
+			ExpectsError: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			cues, err := subtitles.Parse(strings.NewReader(tt.GivenFile))
+			if tt.ExpectsError {
+				require.ErrorIs(t, err, subtitles.ErrInvalidSubtitles)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.ExpectCues, cues)
+		})
+	}
+}
+
+func Test_EncodeVTT(t *testing.T) {
+	vtt := subtitles.EncodeVTT([]subtitles.Cue{
+		{ID: "1", Start: 1, End: 4.5, Text: "Hello\nworld"},
+		{Start: 3725.25, End: 3726, Settings: "align:start", Text: "Bye"},
+	})
+
+	require.Equal(t, "WEBVTT\n"+
+		"\n"+
+		"1\n"+
+		"00:00:01.000 --> 00:00:04.500\n"+
+		"Hello\nworld\n"+
+		"\n"+
+		"01:02:05.250 --> 01:02:06.000 align:start\n"+
+		"Bye\n", string(vtt))
+}
+
+func Test_Segment(t *testing.T) {
+	first := subtitles.Cue{Start: 1, End: 4, Text: "first"}
+	overlapping := subtitles.Cue{Start: 8, End: 12, Text: "overlapping"}
+	last := subtitles.Cue{Start: 31, End: 32.5, Text: "last"}
+
+	segments := subtitles.Segment([]subtitles.Cue{first, overlapping, last}, 10)
+	require.Equal(t, [][]subtitles.Cue{
+		{first, overlapping},
+		{overlapping},
+		{},
+		{last},
+	}, segments)
+}